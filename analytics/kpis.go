@@ -0,0 +1,122 @@
+package analytics
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"recipes-api/metrics"
+	"recipes-api/models"
+	"recipes-api/service"
+	"recipes-api/tracing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis"
+	"gorm.io/gorm"
+)
+
+const activeClientsKeyPrefix = "kpi:clients:"
+
+// KPIs keeps the business gauges in metrics up to date. Recipe counts are
+// read from the recipes_list projection. Clients are counted in a Redis
+// HyperLogLog per hour, shared by all instances, so the count is cheap and
+// approximate.
+type KPIs struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+
+	mu      sync.Mutex
+	clients map[string]struct{}
+}
+
+func NewKPIs(db *gorm.DB, redisClient *redis.Client) *KPIs {
+	return &KPIs{db: db, redisClient: redisClient, clients: map[string]struct{}{}}
+}
+
+// Middleware notes the IP of every client.
+func (k *KPIs) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ip := c.ClientIP()
+		k.mu.Lock()
+		k.clients[ip] = struct{}{}
+		k.mu.Unlock()
+
+		c.Next()
+	}
+}
+
+// Run refreshes the gauges at the given interval. It never returns.
+func (k *KPIs) Run(interval time.Duration) {
+	for range time.Tick(interval) {
+		if err := k.Refresh(context.Background()); err != nil {
+			slog.Error("Error refreshing KPIs", "error", err)
+		}
+	}
+}
+
+// Refresh adds the clients seen since the last call to the current hour
+// and recomputes the gauges. Gauges that can't be computed keep their
+// previous value.
+func (k *KPIs) Refresh(ctx context.Context) error {
+	k.mu.Lock()
+	clients := k.clients
+	k.clients = map[string]struct{}{}
+	k.mu.Unlock()
+
+	now := time.Now().UTC()
+	return errors.Join(k.refreshRecipes(ctx, now), k.refreshClients(ctx, now, clients))
+}
+
+func (k *KPIs) refreshRecipes(ctx context.Context, now time.Time) error {
+	db := service.ReadReplica(k.db.WithContext(ctx))
+
+	var total, published int64
+	if err := db.Model(&models.RecipeSummary{}).Count(&total).Error; err != nil {
+		return err
+	}
+	if err := db.Model(&models.RecipeSummary{}).Where("published_at >= ?", now.Add(-24*time.Hour)).Count(&published).Error; err != nil {
+		return err
+	}
+
+	metrics.RecipesTotal.Set(float64(total))
+	metrics.RecipesPublished24h.Set(float64(published))
+	return nil
+}
+
+func (k *KPIs) refreshClients(ctx context.Context, now time.Time, clients map[string]struct{}) error {
+	r := tracing.Redis(ctx, k.redisClient)
+
+	if len(clients) > 0 {
+		members := make([]any, 0, len(clients))
+		for ip := range clients {
+			members = append(members, ip)
+		}
+		key := activeClientsKey(now)
+		pipe := r.Pipeline()
+		pipe.PFAdd(key, members...)
+		pipe.Expire(key, 25*time.Hour)
+		_, err := pipe.Exec()
+		pipe.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	keys := make([]string, 24)
+	for i := range keys {
+		keys[i] = activeClientsKey(now.Add(-time.Duration(i) * time.Hour))
+	}
+	count, err := r.PFCount(keys...).Result()
+	if err != nil {
+		return err
+	}
+
+	metrics.ActiveUsers24h.Set(float64(count))
+	return nil
+}
+
+func activeClientsKey(t time.Time) string {
+	return activeClientsKeyPrefix + t.Format("2006010215")
+}
@@ -0,0 +1,167 @@
+// Package analytics keeps daily usage aggregates in Postgres: requests per
+// route, searched tags and cache hits. They feed the weekly report.
+package analytics
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"recipes-api/apiversion"
+	"recipes-api/metrics"
+	"recipes-api/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type requestKey struct {
+	day   time.Time
+	route string
+}
+
+type searchKey struct {
+	day   time.Time
+	query string
+}
+
+// Recorder aggregates in memory and adds the totals to the tables on every
+// flush, so the request path never waits on the database.
+type Recorder struct {
+	db *gorm.DB
+
+	mu       sync.Mutex
+	requests map[requestKey]*models.RequestStat
+	searches map[searchKey]int64
+
+	// cache lookups are read from the Prometheus counters; these are the
+	// values at the last flush
+	hits, misses float64
+}
+
+func NewRecorder(db *gorm.DB) *Recorder {
+	return &Recorder{
+		db:       db,
+		requests: map[requestKey]*models.RequestStat{},
+		searches: map[searchKey]int64{},
+		hits:     counterValue(metrics.CacheLookups.WithLabelValues("hit")),
+		misses:   counterValue(metrics.CacheLookups.WithLabelValues("miss")),
+	}
+}
+
+// Middleware records the route, status and duration of every request that
+// matched a route, and the tag of every search.
+func (r *Recorder) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			return
+		}
+		elapsed := time.Since(start).Milliseconds()
+		day := today()
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		key := requestKey{day: day, route: c.Request.Method + " " + route}
+		stat, ok := r.requests[key]
+		if !ok {
+			stat = &models.RequestStat{Day: key.day, Route: key.route}
+			r.requests[key] = stat
+		}
+		stat.Requests++
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			stat.Errors++
+		}
+		stat.TotalMS += elapsed
+		stat.MaxMS = max(stat.MaxMS, elapsed)
+
+		if apiversion.Unversioned(route) == "/recipes/search" {
+			if tag := strings.ToLower(strings.TrimSpace(c.Query("tag"))); tag != "" {
+				r.searches[searchKey{day: day, query: tag}]++
+			}
+		}
+	}
+}
+
+// Run flushes at the given interval. It never returns.
+func (r *Recorder) Run(interval time.Duration) {
+	for range time.Tick(interval) {
+		if err := r.Flush(); err != nil {
+			slog.Error("Error flushing analytics", "error", err)
+		}
+	}
+}
+
+// Flush adds everything recorded since the last flush to the tables. On
+// error the data is dropped rather than counted twice.
+func (r *Recorder) Flush() error {
+	r.mu.Lock()
+	requests, searches := r.requests, r.searches
+	r.requests = map[requestKey]*models.RequestStat{}
+	r.searches = map[searchKey]int64{}
+
+	hits := counterValue(metrics.CacheLookups.WithLabelValues("hit"))
+	misses := counterValue(metrics.CacheLookups.WithLabelValues("miss"))
+	cache := models.CacheStat{Day: today(), Hits: int64(hits - r.hits), Misses: int64(misses - r.misses)}
+	r.hits, r.misses = hits, misses
+	r.mu.Unlock()
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, stat := range requests {
+			err := tx.Clauses(clause.OnConflict{
+				Columns: []clause.Column{{Name: "day"}, {Name: "route"}},
+				DoUpdates: clause.Assignments(map[string]any{
+					"requests": gorm.Expr("request_stats.requests + excluded.requests"),
+					"errors":   gorm.Expr("request_stats.errors + excluded.errors"),
+					"total_ms": gorm.Expr("request_stats.total_ms + excluded.total_ms"),
+					"max_ms":   gorm.Expr("GREATEST(request_stats.max_ms, excluded.max_ms)"),
+				}),
+			}).Create(stat).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		for key, count := range searches {
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "day"}, {Name: "query"}},
+				DoUpdates: clause.Assignments(map[string]any{"count": gorm.Expr("search_stats.count + excluded.count")}),
+			}).Create(&models.SearchStat{Day: key.day, Query: key.query, Count: count}).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		if cache.Hits == 0 && cache.Misses == 0 {
+			return nil
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "day"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"hits":   gorm.Expr("cache_stats.hits + excluded.hits"),
+				"misses": gorm.Expr("cache_stats.misses + excluded.misses"),
+			}),
+		}).Create(&cache).Error
+	})
+}
+
+func today() time.Time {
+	return time.Now().UTC().Truncate(24 * time.Hour)
+}
+
+func counterValue(c prometheus.Counter) float64 {
+	var m dto.Metric
+	if err := c.Write(&m); err != nil {
+		return 0
+	}
+	return m.GetCounter().GetValue()
+}
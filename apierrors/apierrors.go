@@ -0,0 +1,221 @@
+// Package apierrors gives every error the API responds with the same shape:
+//
+//	{"error": "Recipe not found", "code": "not_found", "details": ...}
+//
+// error is a message for people and stays where clients have always read
+// it; code is stable and meant for programs to branch on; details, when
+// present, depend on the code, e.g. the field errors of a failed
+// validation. The RequestID middleware adds the request's ID as requestId.
+//
+// Messages are translated into the language of the request's i18n
+// localizer. Parts that vary are placeholders filled in with With, so the
+// message can still be looked up: BadRequest("Unknown field {field}").
+//
+// Clients that accept JSON:API get the same fields as a JSON:API error
+// object instead: {"errors": [{"status", "code", "detail", "meta"}]}.
+package apierrors
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"maps"
+	"net/http"
+
+	"recipes-api/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Codes of the errors. A status maps to one code, except 503, which is
+// read_only while writes are paused and unavailable otherwise.
+const (
+	CodeInvalidRequest       = "invalid_request"
+	CodeValidationFailed     = "validation_failed"
+	CodeUnauthorized         = "unauthorized"
+	CodeForbidden            = "forbidden"
+	CodeNotFound             = "not_found"
+	CodeMethodNotAllowed     = "method_not_allowed"
+	CodeConflict             = "conflict"
+	CodePreconditionRequired = "precondition_required"
+	CodeTooLarge             = "too_large"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeRateLimited          = "rate_limited"
+	CodeInternal             = "internal"
+	CodeBadGateway           = "bad_gateway"
+	CodeUnavailable          = "unavailable"
+	CodeReadOnly             = "read_only"
+)
+
+// Error is an error the API responds with, and the body it is sent as.
+type Error struct {
+	Status  int    `json:"-"`
+	Message string `json:"error"`
+	Code    string `json:"code"`
+	Details any    `json:"details,omitempty"`
+
+	// template is Message before its placeholders were filled in.
+	template string
+	params   map[string]string
+}
+
+// FieldError explains why a field of a request body is invalid. Field is
+// the JSON path of the field, e.g. "tags[2]".
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+
+	template string
+	params   map[string]string
+}
+
+// NewFieldError returns the error of field, with message's placeholders
+// filled in from params.
+func NewFieldError(field, message string, params map[string]string) FieldError {
+	return FieldError{Field: field, Message: i18n.Fill(message, params), template: message, params: params}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// With returns a copy of e with the placeholder {name} of its message
+// filled in with value.
+func (e *Error) With(name, value string) *Error {
+	copied := *e
+	copied.params = maps.Clone(e.params)
+	if copied.params == nil {
+		copied.params = map[string]string{}
+	}
+	copied.params[name] = value
+	copied.Message = i18n.Fill(e.template, copied.params)
+	return &copied
+}
+
+// WithDetails returns a copy of e carrying details.
+func (e *Error) WithDetails(details any) *Error {
+	copied := *e
+	copied.Details = details
+	return &copied
+}
+
+func New(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message, template: message}
+}
+
+func BadRequest(message string) *Error {
+	return New(http.StatusBadRequest, CodeInvalidRequest, message)
+}
+
+// Validation is a body that decoded but has invalid fields.
+func Validation(fields []FieldError) *Error {
+	return New(http.StatusUnprocessableEntity, CodeValidationFailed, "Validation failed").WithDetails(fields)
+}
+
+func Unauthorized(message string) *Error {
+	return New(http.StatusUnauthorized, CodeUnauthorized, message)
+}
+
+func Forbidden(message string) *Error {
+	return New(http.StatusForbidden, CodeForbidden, message)
+}
+
+func NotFound(message string) *Error {
+	return New(http.StatusNotFound, CodeNotFound, message)
+}
+
+func MethodNotAllowed(message string) *Error {
+	return New(http.StatusMethodNotAllowed, CodeMethodNotAllowed, message)
+}
+
+func Conflict(message string) *Error {
+	return New(http.StatusConflict, CodeConflict, message)
+}
+
+// PreconditionRequired is a write that has to say which version it
+// changes.
+func PreconditionRequired(message string) *Error {
+	return New(http.StatusPreconditionRequired, CodePreconditionRequired, message)
+}
+
+func TooLarge(message string) *Error {
+	return New(http.StatusRequestEntityTooLarge, CodeTooLarge, message)
+}
+
+func UnsupportedMediaType(message string) *Error {
+	return New(http.StatusUnsupportedMediaType, CodeUnsupportedMediaType, message)
+}
+
+func RateLimited(message string) *Error {
+	return New(http.StatusTooManyRequests, CodeRateLimited, message)
+}
+
+// Internal is a failure that is the server's fault. The message should say
+// what failed, not why: the cause is logged, not sent.
+func Internal(message string) *Error {
+	return New(http.StatusInternalServerError, CodeInternal, message)
+}
+
+// BadGateway is a failure of a server the API called on the client's
+// behalf, such as a page it was asked to fetch.
+func BadGateway(message string) *Error {
+	return New(http.StatusBadGateway, CodeBadGateway, message)
+}
+
+func Unavailable(message string) *Error {
+	return New(http.StatusServiceUnavailable, CodeUnavailable, message)
+}
+
+func ReadOnly(message string) *Error {
+	return New(http.StatusServiceUnavailable, CodeReadOnly, message)
+}
+
+// Write responds with err. Errors that aren't API errors become a generic
+// 500 and are logged, so their text, which may describe internals, isn't
+// sent. A 500 of a request that ran out of time is a 503 instead, since
+// it may well work when tried again.
+func Write(c *gin.Context, err error) {
+	var e *Error
+	if !errors.As(err, &e) {
+		slog.ErrorContext(c.Request.Context(), "Request failed", "path", c.FullPath(), "error", err)
+		e = Internal("Internal server error")
+	}
+	if e.Status == http.StatusInternalServerError && errors.Is(c.Request.Context().Err(), context.DeadlineExceeded) {
+		e = Unavailable("The request took too long")
+	}
+	if l := i18n.FromContext(c.Request.Context()); l != nil {
+		e = e.localize(l)
+		c.Header("Content-Language", l.Language().String())
+	}
+	if AcceptsJSONAPI(c.Request) {
+		c.Header("Content-Type", JSONAPIMediaType)
+		c.JSON(e.Status, e.jsonAPI())
+		return
+	}
+	c.JSON(e.Status, e)
+}
+
+// localize returns a copy of e with its message, and those of its field
+// errors, translated.
+func (e *Error) localize(l *i18n.Localizer) *Error {
+	copied := *e
+	if e.template != "" {
+		copied.Message = l.Translate(e.template, e.params)
+	}
+	if fields, ok := e.Details.([]FieldError); ok {
+		translated := make([]FieldError, len(fields))
+		for i, field := range fields {
+			field.Message = l.Translate(field.template, field.params)
+			translated[i] = field
+		}
+		copied.Details = translated
+	}
+	return &copied
+}
+
+// Abort responds with err and stops the handlers after the current one
+// from running, for use in middleware.
+func Abort(c *gin.Context, err error) {
+	c.Abort()
+	Write(c, err)
+}
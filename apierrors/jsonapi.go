@@ -0,0 +1,41 @@
+package apierrors
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// JSONAPIMediaType is the media type of JSON:API documents. Clients that
+// accept it get responses, errors included, shaped per https://jsonapi.org.
+const JSONAPIMediaType = "application/vnd.api+json"
+
+// AcceptsJSONAPI reports whether r lists the JSON:API media type in its
+// Accept header.
+func AcceptsJSONAPI(r *http.Request) bool {
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err == nil && mediaType == JSONAPIMediaType {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonAPIError is an Error as a JSON:API error object. The RequestID
+// middleware fills in its id.
+type jsonAPIError struct {
+	Status string         `json:"status"`
+	Code   string         `json:"code"`
+	Detail string         `json:"detail"`
+	Meta   map[string]any `json:"meta,omitempty"`
+}
+
+func (e *Error) jsonAPI() map[string]any {
+	object := jsonAPIError{Status: strconv.Itoa(e.Status), Code: e.Code, Detail: e.Message}
+	if e.Details != nil {
+		object.Meta = map[string]any{"details": e.Details}
+	}
+	return map[string]any{"errors": []jsonAPIError{object}}
+}
@@ -0,0 +1,77 @@
+// Package apiversion mounts the API under a path prefix per major version,
+// /v1 for now. A breaking change gets a new prefix, served next to the
+// old ones, instead of changing the routes clients already call; payload
+// shapes within a version are left to the serializer.
+//
+// The routes of V1 are also served at their old unprefixed paths, marked
+// deprecated, for clients from before versioning.
+package apiversion
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// V1 is the prefix of the first version of the API.
+const V1 = "/v1"
+
+var prefix = regexp.MustCompile(`^/v[0-9]+(/|$)`)
+
+// Unversioned returns path, or a route pattern, without its version
+// prefix, so what is keyed by path, like the authorization policy, applies
+// to every version of a route.
+func Unversioned(path string) string {
+	if loc := prefix.FindStringIndex(path); loc != nil {
+		return "/" + path[loc[1]:]
+	}
+	return path
+}
+
+// Deprecated marks responses as coming from routes replaced by the ones
+// under successor: a Deprecation header, a Link to the same path under
+// successor and, when one is set, the Sunset date after which they may
+// stop working.
+func Deprecated(successor string, sunset time.Time) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Deprecation", "true")
+		c.Writer.Header().Add("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, successor+Unversioned(c.Request.URL.Path)))
+		if !sunset.IsZero() {
+			c.Header("Sunset", sunset.Format(http.TimeFormat))
+		}
+		c.Next()
+	}
+}
+
+// Routes registers every route on each of its groups, e.g. on /v1 and on
+// the deprecated paths without a prefix.
+type Routes []gin.IRoutes
+
+func (r Routes) Handle(method, path string, handlers ...gin.HandlerFunc) {
+	for _, group := range r {
+		group.Handle(method, path, handlers...)
+	}
+}
+
+func (r Routes) GET(path string, handlers ...gin.HandlerFunc) {
+	r.Handle(http.MethodGet, path, handlers...)
+}
+
+func (r Routes) POST(path string, handlers ...gin.HandlerFunc) {
+	r.Handle(http.MethodPost, path, handlers...)
+}
+
+func (r Routes) PUT(path string, handlers ...gin.HandlerFunc) {
+	r.Handle(http.MethodPut, path, handlers...)
+}
+
+func (r Routes) PATCH(path string, handlers ...gin.HandlerFunc) {
+	r.Handle(http.MethodPatch, path, handlers...)
+}
+
+func (r Routes) DELETE(path string, handlers ...gin.HandlerFunc) {
+	r.Handle(http.MethodDelete, path, handlers...)
+}
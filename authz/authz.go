@@ -0,0 +1,142 @@
+// Package authz decides which roles may call which routes. The rules are
+// data, kept in the runtime settings, so access can be changed without a
+// release.
+package authz
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Built-in roles. Requests without a token are anonymous; the admin token
+// grants admin. Other roles come from the configured API tokens.
+const (
+	RoleAnonymous = "anonymous"
+	RoleAdmin     = "admin"
+)
+
+const (
+	Allow = "allow"
+	Deny  = "deny"
+
+	// Any matches every role, method or path.
+	Any = "*"
+)
+
+// Rule allows or denies roles the methods on routes. Paths are route
+// patterns as registered ("/recipes/:id"); a trailing "/*" also matches
+// everything below the path. The first matching rule decides; requests no
+// rule matches are denied.
+type Rule struct {
+	Effect  string   `json:"effect"`
+	Roles   []string `json:"roles"`
+	Methods []string `json:"methods"`
+	Paths   []string `json:"paths"`
+}
+
+// DefaultPolicy keeps /admin and /webhooks to admins and leaves the rest
+// open.
+func DefaultPolicy() []Rule {
+	return []Rule{
+		{Effect: Allow, Roles: []string{RoleAdmin}, Methods: []string{Any}, Paths: []string{Any}},
+		{Effect: Deny, Roles: []string{Any}, Methods: []string{Any}, Paths: []string{"/admin", "/admin/*", "/webhooks/*"}},
+		{Effect: Allow, Roles: []string{Any}, Methods: []string{Any}, Paths: []string{Any}},
+	}
+}
+
+func (r Rule) Validate() error {
+	if r.Effect != Allow && r.Effect != Deny {
+		return fmt.Errorf("effect %q is not allow or deny", r.Effect)
+	}
+	if len(r.Roles) == 0 || len(r.Methods) == 0 || len(r.Paths) == 0 {
+		return errors.New("roles, methods and paths must not be empty")
+	}
+	for _, path := range r.Paths {
+		if path != Any && !strings.HasPrefix(path, "/") {
+			return fmt.Errorf("path %q must start with / or be *", path)
+		}
+	}
+	return nil
+}
+
+func (r Rule) matches(role, method, route string) bool {
+	return matchAny(r.Roles, role) && matchAny(r.Methods, method) && slices.ContainsFunc(r.Paths, func(path string) bool {
+		return matchPath(path, route)
+	})
+}
+
+// Allowed reports whether role may call method on route under the rules.
+func Allowed(rules []Rule, role, method, route string) bool {
+	for _, rule := range rules {
+		if rule.matches(role, method, route) {
+			return rule.Effect == Allow
+		}
+	}
+	return false
+}
+
+func matchAny(list []string, value string) bool {
+	return slices.ContainsFunc(list, func(item string) bool {
+		return item == Any || strings.EqualFold(item, value)
+	})
+}
+
+func matchPath(pattern, route string) bool {
+	if pattern == Any || pattern == route {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "/*"); ok {
+		return strings.HasPrefix(route, prefix+"/")
+	}
+	return false
+}
+
+// Tokens maps bearer tokens to roles.
+type Tokens map[string]string
+
+// ParseTokens reads "role=token" entries.
+func ParseTokens(entries []string) (Tokens, error) {
+	tokens := Tokens{}
+	for _, entry := range entries {
+		role, token, ok := strings.Cut(entry, "=")
+		if !ok || role == "" || token == "" {
+			return nil, fmt.Errorf("API token entry %q is not role=token", entry)
+		}
+		if role == RoleAnonymous {
+			return nil, fmt.Errorf("API tokens can't grant the %s role", RoleAnonymous)
+		}
+		tokens[token] = role
+	}
+	return tokens, nil
+}
+
+// Role returns the role of a bearer token, comparing in constant time.
+func (t Tokens) Role(token string) (string, bool) {
+	role, found := "", false
+	for candidate, r := range t {
+		if subtle.ConstantTimeCompare([]byte(candidate), []byte(token)) == 1 {
+			role, found = r, true
+		}
+	}
+	return role, found
+}
+
+type roleKey struct{}
+
+// WithRole returns a copy of ctx carrying the caller's role, for code
+// below the transport that decides by it.
+func WithRole(ctx context.Context, role string) context.Context {
+	return context.WithValue(ctx, roleKey{}, role)
+}
+
+// RoleFrom returns the role carried by ctx, anonymous when there is none.
+func RoleFrom(ctx context.Context) string {
+	if role, ok := ctx.Value(roleKey{}).(string); ok {
+		return role
+	}
+	return RoleAnonymous
+}
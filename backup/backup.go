@@ -0,0 +1,486 @@
+// Package backup dumps the catalog, and restores dumps of it, for moving
+// it between environments. A dump holds the recipes, with their
+// instructions inlined, the tags, cuisines and categories, and the
+// metadata of the recipes' photos. The photos themselves stay in storage.
+//
+// There are no user accounts to dump: admins are API key holders,
+// configured rather than stored.
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"recipes-api/models"
+	"recipes-api/service"
+	"recipes-api/taxonomy"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Version is the version of the dump format, bumped when a dump of this
+// version could no longer be restored as is.
+const Version = 1
+
+const batchSize = 500
+
+// Sections of a dump, in the order they are written and restored: terms
+// before the recipes filed under them.
+const (
+	Tags       = "tags"
+	Cuisines   = "cuisines"
+	Categories = "categories"
+	Images     = "images"
+	Recipes    = "recipes"
+)
+
+const manifestName = "manifest.json"
+
+// Manifest describes a dump. It heads the JSON document and is the first
+// file of an archive.
+type Manifest struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exportedAt"`
+}
+
+// Image is the metadata of a recipe's photo, listed for copying the
+// objects between buckets. Recipes carry their own, so images aren't
+// restored from this section.
+type Image struct {
+	RecipeID string `json:"recipeId"`
+	models.Image
+}
+
+// Summary counts the rows restored, by section.
+type Summary struct {
+	Tags       int `json:"tags"`
+	Cuisines   int `json:"cuisines"`
+	Categories int `json:"categories"`
+	Recipes    int `json:"recipes"`
+}
+
+// WriteJSON streams a dump as a single JSON document: the manifest's
+// fields followed by an array per section.
+func WriteJSON(ctx context.Context, db *gorm.DB, w io.Writer) error {
+	buf := bufio.NewWriter(w)
+	manifest, _ := json.Marshal(Manifest{Version: Version, ExportedAt: time.Now().UTC()})
+	buf.Write(manifest[:len(manifest)-1])
+
+	section, first := "", true
+	err := dump(ctx, db, func(name string, rows []any) error {
+		if name != section {
+			if section != "" {
+				buf.WriteString("]")
+			}
+			fmt.Fprintf(buf, ",%q:[", name)
+			section, first = name, true
+		}
+		for _, row := range rows {
+			data, err := json.Marshal(row)
+			if err != nil {
+				return err
+			}
+			if !first {
+				buf.WriteByte(',')
+			}
+			buf.Write(data)
+			first = false
+		}
+		// each batch goes out as it's read
+		if err := buf.Flush(); err != nil {
+			return err
+		}
+		flush(w)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	buf.WriteString("]}")
+	return buf.Flush()
+}
+
+// WriteTarGz streams a dump as a gzipped tar archive: manifest.json, then
+// a file per batch of rows of each section, such as recipes/0001.json
+// holding a JSON array.
+func WriteTarGz(ctx context.Context, db *gorm.DB, w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	archive := tar.NewWriter(gz)
+	now := time.Now().UTC()
+
+	file := func(name string, v any) error {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		header := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(data)), ModTime: now, Typeflag: tar.TypeReg}
+		if err := archive.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := archive.Write(data); err != nil {
+			return err
+		}
+		if err := archive.Flush(); err != nil {
+			return err
+		}
+		if err := gz.Flush(); err != nil {
+			return err
+		}
+		flush(w)
+		return nil
+	}
+
+	if err := file(manifestName, Manifest{Version: Version, ExportedAt: now}); err != nil {
+		return err
+	}
+	batches := map[string]int{}
+	err := dump(ctx, db, func(name string, rows []any) error {
+		if len(rows) == 0 && batches[name] > 0 {
+			return nil
+		}
+		batches[name]++
+		return file(fmt.Sprintf("%s/%04d.json", name, batches[name]), rows)
+	})
+	if err != nil {
+		return err
+	}
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// flush sends what has been written on to the client, when w is a
+// response.
+func flush(w io.Writer) {
+	if f, ok := w.(interface{ Flush() }); ok {
+		f.Flush()
+	}
+}
+
+// dump reads every section in order, passing rows to emit a batch at a
+// time. Each section is emitted at least once, if empty.
+func dump(ctx context.Context, db *gorm.DB, emit func(section string, rows []any) error) error {
+	db = db.WithContext(ctx)
+
+	var tags []models.Tag
+	if err := db.Order("name").Find(&tags).Error; err != nil {
+		return err
+	}
+	if err := emit(Tags, anys(tags)); err != nil {
+		return err
+	}
+
+	for _, kind := range []struct {
+		section string
+		table   string
+	}{{Cuisines, taxonomy.Cuisines.Table}, {Categories, taxonomy.Categories.Table}} {
+		var terms []models.Term
+		if err := db.Table(kind.table).Order("name").Find(&terms).Error; err != nil {
+			return err
+		}
+		if err := emit(kind.section, anys(terms)); err != nil {
+			return err
+		}
+	}
+
+	var images []Image
+	var withImages []models.Recipe
+	err := db.Model(&models.Recipe{}).Select("id", "image").Where("image IS NOT NULL").
+		FindInBatches(&withImages, batchSize, func(*gorm.DB, int) error {
+			for _, recipe := range withImages {
+				if recipe.Image != nil {
+					images = append(images, Image{RecipeID: recipe.ID, Image: *recipe.Image})
+				}
+			}
+			if err := emit(Images, anys(images)); err != nil {
+				return err
+			}
+			images = images[:0]
+			return nil
+		}).Error
+	if err != nil {
+		return err
+	}
+	// FindInBatches calls nothing for an empty table
+	if err := emit(Images, nil); err != nil {
+		return err
+	}
+
+	var recipes []models.Recipe
+	err = db.Model(&models.Recipe{}).FindInBatches(&recipes, batchSize, func(*gorm.DB, int) error {
+		batch := make([]*models.Recipe, len(recipes))
+		for i := range recipes {
+			batch[i] = &recipes[i]
+		}
+		if err := service.LoadInstructions(db, batch...); err != nil {
+			return err
+		}
+		for i := range recipes {
+			recipes[i].InstructionsOffloaded = false
+		}
+		return emit(Recipes, anys(recipes))
+	}).Error
+	if err != nil {
+		return err
+	}
+	return emit(Recipes, nil)
+}
+
+func anys[T any](rows []T) []any {
+	out := make([]any, len(rows))
+	for i, row := range rows {
+		out[i] = row
+	}
+	return out
+}
+
+// ErrInvalid is returned, wrapped, for input that isn't a dump this
+// build can restore.
+var ErrInvalid = errors.New("invalid dump")
+
+func invalid(err error) error {
+	return fmt.Errorf("%w: %v", ErrInvalid, err)
+}
+
+// Restore loads a dump written by WriteJSON or WriteTarGz, told apart by
+// the gzip header, in one transaction so a dump that fails to load leaves
+// the database as it was. Rows are upserted: recipes and terms by ID, tags
+// by name. Restoring goes around the event bus, so callers rebuild the
+// projections and the search index and flush the cache afterwards.
+func Restore(ctx context.Context, db *gorm.DB, r io.Reader) (Summary, error) {
+	in := bufio.NewReader(r)
+	magic, _ := in.Peek(2)
+	archive := bytes.Equal(magic, []byte{0x1f, 0x8b})
+
+	var summary Summary
+	err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		restorer := &restorer{tx: tx, summary: &summary}
+		if archive {
+			return restorer.readTarGz(in)
+		}
+		return restorer.readJSON(in)
+	})
+	return summary, err
+}
+
+type restorer struct {
+	tx       *gorm.DB
+	summary  *Summary
+	manifest bool
+}
+
+func (s *restorer) checkVersion(version int) error {
+	if version != Version {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalid, version)
+	}
+	s.manifest = true
+	return nil
+}
+
+// readJSON walks the document a section at a time, restoring the rows of
+// each in batches rather than decoding the whole dump at once.
+func (s *restorer) readJSON(r io.Reader) error {
+	dec := json.NewDecoder(r)
+	if err := expect(dec, json.Delim('{')); err != nil {
+		return err
+	}
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return invalid(err)
+		}
+		key, _ := token.(string)
+
+		switch key {
+		case "version":
+			var version int
+			if err := dec.Decode(&version); err != nil {
+				return invalid(err)
+			}
+			if err := s.checkVersion(version); err != nil {
+				return err
+			}
+		case Tags, Cuisines, Categories, Recipes:
+			if !s.manifest {
+				return fmt.Errorf("%w: the version must come first", ErrInvalid)
+			}
+			if err := expect(dec, json.Delim('[')); err != nil {
+				return err
+			}
+			var raw []json.RawMessage
+			for dec.More() {
+				var row json.RawMessage
+				if err := dec.Decode(&row); err != nil {
+					return invalid(err)
+				}
+				if raw = append(raw, row); len(raw) == batchSize {
+					if err := s.restore(key, raw); err != nil {
+						return err
+					}
+					raw = raw[:0]
+				}
+			}
+			if err := s.restore(key, raw); err != nil {
+				return err
+			}
+			if err := expect(dec, json.Delim(']')); err != nil {
+				return err
+			}
+		default:
+			// exportedAt, images and anything a later version adds
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return invalid(err)
+			}
+		}
+	}
+	return expect(dec, json.Delim('}'))
+}
+
+func expect(dec *json.Decoder, delim json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return invalid(err)
+	}
+	if token != delim {
+		return fmt.Errorf("%w: expected %s, found %v", ErrInvalid, delim, token)
+	}
+	return nil
+}
+
+// readTarGz restores the files of an archive in the order they were
+// written.
+func (s *restorer) readTarGz(r io.Reader) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return invalid(err)
+	}
+	archive := tar.NewReader(gz)
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return invalid(err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := strings.TrimPrefix(path.Clean(header.Name), "./")
+		if name == manifestName {
+			var manifest Manifest
+			if err := json.NewDecoder(archive).Decode(&manifest); err != nil {
+				return invalid(fmt.Errorf("%s: %w", name, err))
+			}
+			if err := s.checkVersion(manifest.Version); err != nil {
+				return err
+			}
+			continue
+		}
+
+		section := path.Dir(name)
+		if section != Tags && section != Cuisines && section != Categories && section != Recipes {
+			continue
+		}
+		if !s.manifest {
+			return fmt.Errorf("%w: %s must come first", ErrInvalid, manifestName)
+		}
+		var raw []json.RawMessage
+		if err := json.NewDecoder(archive).Decode(&raw); err != nil {
+			return invalid(fmt.Errorf("%s: %w", name, err))
+		}
+		if err := s.restore(section, raw); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	if !s.manifest {
+		return fmt.Errorf("%w: no %s", ErrInvalid, manifestName)
+	}
+	return nil
+}
+
+// restore upserts a batch of rows of a section.
+func (s *restorer) restore(section string, raw []json.RawMessage) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	switch section {
+	case Tags:
+		rows, err := decode[models.Tag](raw)
+		if err != nil {
+			return err
+		}
+		// the IDs are the source's own; the recipes' links are rebuilt
+		// from their names
+		tags := make([]models.Tag, len(rows))
+		for i, tag := range rows {
+			// dumps made before tags had labels go by the name
+			label := cmp.Or(tag.Label, strings.TrimSpace(tag.Name))
+			tags[i] = models.Tag{Name: models.TagName(tag.Name), Label: label}
+		}
+		if err := s.tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "name"}}, DoNothing: true}).Create(&tags).Error; err != nil {
+			return err
+		}
+		s.summary.Tags += len(tags)
+	case Cuisines, Categories:
+		terms, err := decode[models.Term](raw)
+		if err != nil {
+			return err
+		}
+		table := taxonomy.Cuisines.Table
+		if section == Categories {
+			table = taxonomy.Categories.Table
+		}
+		if err := s.tx.Table(table).Clauses(clause.OnConflict{UpdateAll: true}).Create(&terms).Error; err != nil {
+			return err
+		}
+		if section == Cuisines {
+			s.summary.Cuisines += len(terms)
+		} else {
+			s.summary.Categories += len(terms)
+		}
+	case Recipes:
+		recipes, err := decode[models.Recipe](raw)
+		if err != nil {
+			return err
+		}
+		for i := range recipes {
+			if recipes[i].ID == "" {
+				return fmt.Errorf("%w: recipe %q has no id", ErrInvalid, recipes[i].Name)
+			}
+			if recipes[i], err = service.OffloadInstructions(s.tx, recipes[i]); err != nil {
+				return err
+			}
+		}
+		if err := s.tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&recipes).Error; err != nil {
+			return err
+		}
+		s.summary.Recipes += len(recipes)
+	}
+	return nil
+}
+
+func decode[T any](raw []json.RawMessage) ([]T, error) {
+	rows := make([]T, len(raw))
+	for i, data := range raw {
+		if err := json.Unmarshal(data, &rows[i]); err != nil {
+			return nil, invalid(err)
+		}
+	}
+	return rows, nil
+}
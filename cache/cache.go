@@ -0,0 +1,164 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"recipes-api/metrics"
+	"recipes-api/models"
+	"recipes-api/tracing"
+
+	"github.com/go-redis/redis"
+)
+
+const (
+	RecipesAllKey      = "recipes:all"
+	RecipeSummariesKey = "recipes:summaries"
+	RankedSummariesKey = "recipes:summaries:ranked"
+	TagsKey            = "recipes:tags"
+	FeedRSSKey         = "recipes:feed:rss"
+	FeedAtomKey        = "recipes:feed:atom"
+	StatsKey           = "recipes:stats"
+	// SearchesKey holds the keys of the cached tag searches, so writes
+	// can drop them all.
+	SearchesKey      = "recipes:searches"
+	recipeKeyPrefix  = "recipes:id:"
+	relatedKeyPrefix = "recipes:related:"
+	searchKeyPrefix  = "recipes:search:"
+)
+
+func RecipeKey(id string) string {
+	return recipeKeyPrefix + id
+}
+
+// RelatedKey is where the recipes related to a recipe are cached.
+func RelatedKey(id string) string {
+	return relatedKeyPrefix + id
+}
+
+// SearchKey is where the IDs of the recipes a tag search found are cached.
+func SearchKey(tag string) string {
+	return searchKeyPrefix + strings.ToLower(tag)
+}
+
+// SetSearch caches the IDs of the recipes a tag search found, noting the
+// key in SearchesKey for InvalidateRecipes.
+func SetSearch(ctx context.Context, client *redis.Client, tag string, ids []string, ttl time.Duration) error {
+	data, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+
+	pipe := tracing.Redis(ctx, client).Pipeline()
+	defer pipe.Close()
+
+	pipe.Set(SearchKey(tag), data, ttl)
+	pipe.SAdd(SearchesKey, SearchKey(tag))
+	// the set outlives every key in it
+	pipe.Expire(SearchesKey, ttl)
+	_, err = pipe.Exec()
+	return err
+}
+
+// RecipeKeys returns the keys of all individually cached recipes, by id.
+func RecipeKeys(ctx context.Context, client *redis.Client) (map[string]string, error) {
+	iter := tracing.Redis(ctx, client).Scan(0, recipeKeyPrefix+"*", 100).Iterator()
+
+	keys := map[string]string{}
+	for iter.Next() {
+		keys[strings.TrimPrefix(iter.Val(), recipeKeyPrefix)] = iter.Val()
+	}
+	return keys, iter.Err()
+}
+
+// InvalidateRecipes drops cached recipe listings and tag searches after a
+// write, along with the cached copies of the given recipes.
+func InvalidateRecipes(ctx context.Context, client *redis.Client, ids ...string) {
+	c := tracing.Redis(ctx, client)
+	keys := []string{RecipesAllKey, RecipeSummariesKey, RankedSummariesKey, TagsKey, FeedRSSKey, FeedAtomKey, SearchesKey}
+	for _, id := range ids {
+		keys = append(keys, RecipeKey(id), RelatedKey(id))
+	}
+	keys = append(keys, c.SMembers(SearchesKey).Val()...)
+	c.Del(keys...)
+}
+
+// FlushRecipes drops every cached recipe entry. Meant for bulk resets where
+// the changed IDs are not known.
+func FlushRecipes(ctx context.Context, client *redis.Client) error {
+	c := tracing.Redis(ctx, client)
+	iter := c.Scan(0, "recipes:*", 100).Iterator()
+
+	var keys []string
+	for iter.Next() {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+
+	return c.Del(keys...).Err()
+}
+
+// GetRecipes reads many cached recipes with a single MGET. Recipes that are
+// not cached (or fail to decode) are returned in missing, in request order.
+func GetRecipes(ctx context.Context, client *redis.Client, ids []string) (map[string]models.Recipe, []string, error) {
+	found := make(map[string]models.Recipe, len(ids))
+	if len(ids) == 0 {
+		return found, nil, nil
+	}
+
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = RecipeKey(id)
+	}
+	metrics.CacheBatchSize.WithLabelValues("mget").Observe(float64(len(keys)))
+
+	values, err := tracing.Redis(ctx, client).MGet(keys...).Result()
+	if err != nil {
+		return found, ids, err
+	}
+
+	var missing []string
+	for i, value := range values {
+		raw, ok := value.(string)
+		var recipe models.Recipe
+		if !ok || json.Unmarshal([]byte(raw), &recipe) != nil {
+			missing = append(missing, ids[i])
+			continue
+		}
+		found[ids[i]] = recipe
+	}
+
+	metrics.CacheLookups.WithLabelValues("hit").Add(float64(len(found)))
+	metrics.CacheLookups.WithLabelValues("miss").Add(float64(len(missing)))
+
+	return found, missing, nil
+}
+
+// SetRecipes caches recipes individually in one pipelined round trip.
+func SetRecipes(ctx context.Context, client *redis.Client, recipes []models.Recipe, ttl time.Duration) error {
+	if len(recipes) == 0 {
+		return nil
+	}
+	metrics.CacheBatchSize.WithLabelValues("pipeline").Observe(float64(len(recipes)))
+
+	pipe := tracing.Redis(ctx, client).Pipeline()
+	defer pipe.Close()
+
+	for _, recipe := range recipes {
+		data, err := json.Marshal(recipe)
+		if err != nil {
+			return err
+		}
+		pipe.Set(RecipeKey(recipe.ID), data, ttl)
+	}
+
+	_, err := pipe.Exec()
+	return err
+}
@@ -0,0 +1,156 @@
+// Package chaos injects faults into HTTP requests, Redis and the database so
+// client retries and circuit breakers can be exercised in staging.
+package chaos
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"sync"
+	"time"
+
+	"recipes-api/apierrors"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ErrInjected is returned by Redis and database calls failed on purpose.
+var ErrInjected = errors.New("chaos: injected fault")
+
+// Config sets how often each kind of fault happens. Rates are
+// probabilities between 0 and 1; nothing is injected unless Enabled is set.
+type Config struct {
+	Enabled        bool    `json:"enabled"`
+	LatencyRate    float64 `json:"latencyRate"`
+	LatencyMS      int     `json:"latencyMs"`
+	ErrorRate      float64 `json:"errorRate"`
+	RedisErrorRate float64 `json:"redisErrorRate"`
+	DBErrorRate    float64 `json:"dbErrorRate"`
+}
+
+func (c Config) Validate() error {
+	for name, rate := range map[string]float64{
+		"latencyRate":    c.LatencyRate,
+		"errorRate":      c.ErrorRate,
+		"redisErrorRate": c.RedisErrorRate,
+		"dbErrorRate":    c.DBErrorRate,
+	} {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("%s must be between 0 and 1", name)
+		}
+	}
+	if c.LatencyMS < 0 {
+		return errors.New("latencyMs must not be negative")
+	}
+	return nil
+}
+
+// Injector holds the current fault configuration. A nil *Injector means
+// chaos mode is off and none of the hooks are installed.
+type Injector struct {
+	mu  sync.RWMutex
+	cfg Config
+}
+
+func NewInjector() *Injector {
+	return &Injector{}
+}
+
+func (i *Injector) Config() Config {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.cfg
+}
+
+func (i *Injector) SetConfig(cfg Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	i.mu.Lock()
+	i.cfg = cfg
+	i.mu.Unlock()
+	return nil
+}
+
+func (i *Injector) hit(rate func(Config) float64) bool {
+	cfg := i.Config()
+	return cfg.Enabled && rand.Float64() < rate(cfg)
+}
+
+// Middleware delays and fails requests at the configured rates. Install it
+// on the public router only, so faults can always be switched off again.
+func (i *Injector) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		cfg := i.Config()
+		if !cfg.Enabled {
+			c.Next()
+			return
+		}
+
+		if rand.Float64() < cfg.LatencyRate {
+			select {
+			case <-time.After(time.Duration(cfg.LatencyMS) * time.Millisecond):
+			case <-c.Request.Context().Done():
+			}
+		}
+		if rand.Float64() < cfg.ErrorRate {
+			apierrors.Abort(c, apierrors.Internal("Injected fault"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// RegisterGORM fails database statements at the configured DB error rate.
+func (i *Injector) RegisterGORM(db *gorm.DB) error {
+	inject := func(tx *gorm.DB) {
+		if i.hit(func(c Config) float64 { return c.DBErrorRate }) {
+			tx.AddError(ErrInjected)
+		}
+	}
+
+	cb := db.Callback()
+	if err := cb.Create().Before("gorm:create").Register("chaos:create", inject); err != nil {
+		return err
+	}
+	if err := cb.Query().Before("gorm:query").Register("chaos:query", inject); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register("chaos:update", inject); err != nil {
+		return err
+	}
+	if err := cb.Delete().Before("gorm:delete").Register("chaos:delete", inject); err != nil {
+		return err
+	}
+	if err := cb.Row().Before("gorm:row").Register("chaos:row", inject); err != nil {
+		return err
+	}
+	return cb.Raw().Before("gorm:raw").Register("chaos:raw", inject)
+}
+
+// RedisDialer returns a dialer for redis.Options whose connections fail
+// writes at the configured Redis error rate, so commands see a real
+// connection error and the broken connection is dropped from the pool.
+func (i *Injector) RedisDialer(addr string) func() (net.Conn, error) {
+	return func() (net.Conn, error) {
+		conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		return &faultyConn{Conn: conn, injector: i}, nil
+	}
+}
+
+type faultyConn struct {
+	net.Conn
+	injector *Injector
+}
+
+func (c *faultyConn) Write(b []byte) (int, error) {
+	if c.injector.hit(func(cfg Config) float64 { return cfg.RedisErrorRate }) {
+		return 0, ErrInjected
+	}
+	return c.Conn.Write(b)
+}
@@ -0,0 +1,626 @@
+// Package config holds the options the server needs at startup: where the
+// database and Redis are, which addresses to listen on, cache TTLs and the
+// optional integrations. Unlike settings they can't change while the server
+// runs.
+//
+// Every option has a default and can be set, from lowest to highest
+// precedence, in a JSON config file, in the environment or with a command
+// line flag. The file is named by -config or CONFIG_FILE and uses the flag
+// names as keys, e.g. {"redis-addr": "cache:6379", "cache-ttl": "10m"}.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"recipes-api/authz"
+	"recipes-api/email"
+	"recipes-api/ids"
+	"recipes-api/outbound"
+	"recipes-api/startup"
+	"recipes-api/storage"
+
+	"golang.org/x/text/language"
+)
+
+type Config struct {
+	Database Database
+	Redis    Redis
+	Server   Server
+	Cache    Cache
+
+	SettingsFile string
+	SeedFile     string
+	// LocalesDir holds translation bundles adding to or overriding the
+	// built-in ones.
+	LocalesDir string
+	// ContentLanguage is the language recipes are written in; readers
+	// asking for another one get a translation where there is one.
+	ContentLanguage string
+	// AutoMigrate makes serve apply pending migrations instead of refusing
+	// to start. SeedOnStart makes it add the seed recipes that are missing,
+	// as the seed command does.
+	AutoMigrate bool
+	SeedOnStart bool
+	// AppEnv names the deployment; "test" serves the fixture endpoints.
+	AppEnv string
+
+	ChaosMode   bool
+	SandboxMode bool
+
+	ImageMaxBytes int64
+	// DigestWindow is how long subscription notifications are collected
+	// before a digest is sent.
+	DigestWindow time.Duration
+	// IntegrityInterval is how often orphaned data is cleaned up; zero
+	// leaves it to the admin endpoint.
+	IntegrityInterval time.Duration
+	// OutboxRetention is how long published events are kept for webhook
+	// replays.
+	OutboxRetention time.Duration
+
+	// IDs selects how new recipes' IDs are made.
+	IDs ids.Config
+
+	Startup   startup.RetryPolicy
+	Outbound  outbound.Policy
+	Nutrition Nutrition
+	S3        storage.S3Config
+	SMTP      email.Config
+	Reports   Reports
+	Search    Search
+	Region    Region
+}
+
+// Database is the postgres connection. A non-empty DSN is used as is and
+// the other fields are ignored.
+type Database struct {
+	DSN      string
+	Host     string
+	Port     string
+	User     string
+	Password string
+	Name     string
+	SSLMode  string
+	// Replicas are DSNs of read replicas that recipe listings and searches
+	// are spread over. LocalReplicas are those in the deployment's region;
+	// when there are any, reads go to them only and Replicas are left to
+	// the other regions.
+	Replicas      []string
+	LocalReplicas []string
+
+	// Pool sizes the connection pool. Zero MaxOpenConns and
+	// ConnMaxLifetime mean no limit.
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	// PingTimeout bounds each connection check at startup.
+	PingTimeout time.Duration
+}
+
+type Redis struct {
+	Addr     string
+	Password string
+	DB       int
+}
+
+type Server struct {
+	Addr      string
+	AdminAddr string
+	GRPCPort  string
+	// AdminToken grants the admin role; with none set nobody has it.
+	// APITokens grant other roles, as "role=token" entries; what each role
+	// may do is up to the policy in the settings.
+	AdminToken      string
+	APITokens       []string
+	ShutdownTimeout time.Duration
+	// RequestTimeout bounds how long a request runs before its database
+	// queries are cancelled; zero leaves requests unbounded.
+	RequestTimeout time.Duration
+	// PreviewSecret signs preview links to unpublished recipes. Without
+	// one a random secret is used, and links stop working on restart.
+	PreviewSecret string
+}
+
+// Cache holds how long cached recipes and listings (summaries, feeds) live.
+type Cache struct {
+	RecipeTTL time.Duration
+	ListTTL   time.Duration
+}
+
+// Nutrition selects the nutrition provider; an empty Provider disables
+// nutrition lookups.
+type Nutrition struct {
+	Provider string
+	AppID    string
+	APIKey   string
+}
+
+// Reports lists where the weekly report goes. With neither set no report
+// is sent.
+type Reports struct {
+	Emails     []string
+	WebhookURL string
+}
+
+// Region places the deployment when the API runs in several regions, each
+// with its own Redis and database replicas. Deployments outside Primary,
+// the region the primary database is in, serve reads locally and forward
+// writes to PrimaryURL, the API of the primary region, and admin writes to
+// PrimaryAdminURL, its admin listener. Search indexes are kept up to date
+// by the primary region, so regions should share them. An empty Primary
+// means a single region. Secret, shared by the regions, marks the writes
+// they forward.
+type Region struct {
+	Name            string
+	Primary         string
+	PrimaryURL      string
+	PrimaryAdminURL string
+	Secret          string
+}
+
+// IsPrimary reports whether the deployment is in the primary region.
+func (r Region) IsPrimary() bool {
+	return r.Primary == "" || r.Primary == r.Name
+}
+
+// Search backends.
+const (
+	SearchPostgres      = "postgres"
+	SearchRediSearch    = "redisearch"
+	SearchElasticsearch = "elasticsearch"
+)
+
+// Search selects where recipe searches run. With RediSearch or
+// Elasticsearch, which also covers OpenSearch, recipes are mirrored into
+// Index.
+type Search struct {
+	Backend          string
+	ElasticsearchURL string
+	Index            string
+	// Shadow is a second backend ShadowPercent of the searches are
+	// replayed against, comparing its results with Backend's, which are
+	// the ones served. Empty turns shadow reads off.
+	Shadow        string
+	ShadowPercent int
+}
+
+// Indexed reports whether a backend in use, served or shadow, keeps an
+// index that has to follow the recipes.
+func (s Search) Indexed() bool {
+	return s.Backend != SearchPostgres || s.Shadow != "" && s.Shadow != SearchPostgres
+}
+
+func Defaults() Config {
+	return Config{
+		Database: Database{
+			Port:            "5432",
+			SSLMode:         "disable",
+			MaxOpenConns:    25,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: 30 * time.Minute,
+			PingTimeout:     5 * time.Second,
+		},
+		Redis: Redis{Addr: "localhost:6379"},
+		Server: Server{
+			Addr:            ":8080",
+			AdminAddr:       ":8081",
+			GRPCPort:        "9090",
+			ShutdownTimeout: 30 * time.Second,
+			RequestTimeout:  30 * time.Second,
+		},
+		Cache:             Cache{RecipeTTL: 5 * time.Minute, ListTTL: 5 * time.Minute},
+		SettingsFile:      "settings.json",
+		SeedFile:          "recipes.json",
+		ContentLanguage:   "en",
+		ImageMaxBytes:     5 << 20,
+		DigestWindow:      5 * time.Minute,
+		IntegrityInterval: 24 * time.Hour,
+		OutboxRetention:   30 * 24 * time.Hour,
+		IDs:               ids.Config{Strategy: ids.XID},
+		Startup:           startup.RetryPolicy{Attempts: 5, Delay: time.Second},
+		Outbound:          outbound.Policy{MaxResponseBytes: 10 << 20},
+		SMTP:              email.Config{From: "recipes-api@localhost"},
+		Search:            Search{Backend: SearchPostgres, Index: "recipes", ShadowPercent: 100},
+	}
+}
+
+// option ties a config field to its flag (also its config file key) and
+// its environment variable.
+type option struct {
+	flag  string
+	env   string
+	usage string
+	value flag.Value
+}
+
+func (c *Config) options() []option {
+	return []option{
+		{"db-dsn", "DATABASE_DSN", "postgres DSN, overrides the other db options", (*stringValue)(&c.Database.DSN)},
+		{"db-host", "HOST", "postgres host", (*stringValue)(&c.Database.Host)},
+		{"db-port", "PORT", "postgres port", (*stringValue)(&c.Database.Port)},
+		{"db-user", "DBUSER", "postgres user", (*stringValue)(&c.Database.User)},
+		{"db-password", "PASSWORD", "postgres password", (*stringValue)(&c.Database.Password)},
+		{"db-name", "DBNAME", "postgres database", (*stringValue)(&c.Database.Name)},
+		{"db-sslmode", "DB_SSLMODE", "postgres sslmode", (*stringValue)(&c.Database.SSLMode)},
+		{"db-replicas", "DATABASE_REPLICA_DSNS", "comma separated DSNs of read replicas", (*listValue)(&c.Database.Replicas)},
+		{"db-local-replicas", "DATABASE_LOCAL_REPLICA_DSNS", "comma separated DSNs of read replicas in this region, preferred over db-replicas", (*listValue)(&c.Database.LocalReplicas)},
+		{"db-max-open-conns", "DB_MAX_OPEN_CONNS", "maximum open database connections, 0 for no limit", (*intValue)(&c.Database.MaxOpenConns)},
+		{"db-max-idle-conns", "DB_MAX_IDLE_CONNS", "maximum idle database connections kept in the pool", (*intValue)(&c.Database.MaxIdleConns)},
+		{"db-conn-max-lifetime", "DB_CONN_MAX_LIFETIME", "how long a database connection is reused, 0 for no limit", (*durationValue)(&c.Database.ConnMaxLifetime)},
+		{"db-ping-timeout", "DB_PING_TIMEOUT", "how long to wait for the database to answer at startup", (*durationValue)(&c.Database.PingTimeout)},
+
+		{"redis-addr", "REDIS_ADDR", "Redis address", (*stringValue)(&c.Redis.Addr)},
+		{"redis-password", "REDIS_PASSWORD", "Redis password", (*stringValue)(&c.Redis.Password)},
+		{"redis-db", "REDIS_DB", "Redis database number", (*intValue)(&c.Redis.DB)},
+
+		{"addr", "HTTP_ADDR", "API listen address", (*stringValue)(&c.Server.Addr)},
+		{"admin-addr", "ADMIN_ADDR", "admin listen address", (*stringValue)(&c.Server.AdminAddr)},
+		{"grpc-port", "GRPC_PORT", "gRPC port", (*stringValue)(&c.Server.GRPCPort)},
+		{"admin-token", "ADMIN_TOKEN", "token granting the admin role, required by /admin", (*stringValue)(&c.Server.AdminToken)},
+		{"api-tokens", "API_TOKENS", "comma separated role=token entries granting other roles", (*listValue)(&c.Server.APITokens)},
+		{"preview-secret", "PREVIEW_SECRET", "secret signing preview links to unpublished recipes", (*stringValue)(&c.Server.PreviewSecret)},
+		{"shutdown-timeout", "SHUTDOWN_TIMEOUT", "how long to wait for in-flight requests on shutdown", (*durationValue)(&c.Server.ShutdownTimeout)},
+		{"request-timeout", "REQUEST_TIMEOUT", "how long a request may run, 0 for no limit", (*durationValue)(&c.Server.RequestTimeout)},
+
+		{"cache-ttl", "CACHE_TTL", "how long recipes stay cached", (*durationValue)(&c.Cache.RecipeTTL)},
+		{"list-cache-ttl", "LIST_CACHE_TTL", "how long recipe listings and feeds stay cached", (*durationValue)(&c.Cache.ListTTL)},
+
+		{"settings-file", "SETTINGS_FILE", "runtime settings file", (*stringValue)(&c.SettingsFile)},
+		{"seed-file", "SEED_FILE", "recipes loaded at startup", (*stringValue)(&c.SeedFile)},
+		{"locales-dir", "LOCALES_DIR", "directory of translation bundles for error messages", (*stringValue)(&c.LocalesDir)},
+		{"content-language", "CONTENT_LANGUAGE", "language recipes are written in", (*stringValue)(&c.ContentLanguage)},
+		{"auto-migrate", "AUTO_MIGRATE", "apply pending migrations when serving", (*boolValue)(&c.AutoMigrate)},
+		{"seed-on-start", "SEED_ON_START", "add missing seed recipes when serving", (*boolValue)(&c.SeedOnStart)},
+		{"app-env", "APP_ENV", "deployment environment", (*stringValue)(&c.AppEnv)},
+		{"chaos", "CHAOS_MODE", "enable fault injection", (*boolValue)(&c.ChaosMode)},
+		{"sandbox", "SANDBOX_MODE", "capture outgoing emails and webhooks", (*boolValue)(&c.SandboxMode)},
+		{"image-max-bytes", "IMAGE_MAX_BYTES", "largest accepted image upload", (*int64Value)(&c.ImageMaxBytes)},
+		{"digest-window", "SUBSCRIPTION_DIGEST_WINDOW", "how long subscription changes are batched", (*durationValue)(&c.DigestWindow)},
+		{"integrity-interval", "INTEGRITY_INTERVAL", "how often orphaned data is cleaned up, 0 to disable", (*durationValue)(&c.IntegrityInterval)},
+		{"outbox-retention", "OUTBOX_RETENTION", "how long events are kept for webhook replays", (*durationValue)(&c.OutboxRetention)},
+
+		{"id-strategy", "ID_STRATEGY", "how new recipe IDs are made: xid, uuidv7 or snowflake", (*stringValue)(&c.IDs.Strategy)},
+		{"id-node", "ID_NODE", "node number of this server in snowflake IDs, unique within the deployment", (*int64Value)(&c.IDs.Node)},
+
+		{"startup-retries", "STARTUP_RETRIES", "connection attempts for each dependency", (*intValue)(&c.Startup.Attempts)},
+		{"startup-retry-delay", "STARTUP_RETRY_DELAY", "delay before the first retry", (*durationValue)(&c.Startup.Delay)},
+
+		{"outbound-allow-hosts", "OUTBOUND_ALLOW_HOSTS", "hosts outgoing requests may reach", (*listValue)(&c.Outbound.AllowHosts)},
+		{"outbound-deny-hosts", "OUTBOUND_DENY_HOSTS", "hosts outgoing requests may not reach", (*listValue)(&c.Outbound.DenyHosts)},
+		{"outbound-allow-private", "OUTBOUND_ALLOW_PRIVATE", "allow outgoing requests to private addresses", (*boolValue)(&c.Outbound.AllowPrivate)},
+		{"outbound-max-bytes", "OUTBOUND_MAX_BYTES", "largest accepted outgoing response", (*int64Value)(&c.Outbound.MaxResponseBytes)},
+
+		{"nutrition-provider", "NUTRITION_PROVIDER", "nutrition provider", (*stringValue)(&c.Nutrition.Provider)},
+		{"nutrition-app-id", "NUTRITION_APP_ID", "nutrition provider app id", (*stringValue)(&c.Nutrition.AppID)},
+		{"nutrition-api-key", "NUTRITION_API_KEY", "nutrition provider API key", (*stringValue)(&c.Nutrition.APIKey)},
+
+		{"s3-endpoint", "S3_ENDPOINT", "image storage endpoint, empty disables uploads", (*stringValue)(&c.S3.Endpoint)},
+		{"s3-access-key", "S3_ACCESS_KEY", "image storage access key", (*stringValue)(&c.S3.AccessKey)},
+		{"s3-secret-key", "S3_SECRET_KEY", "image storage secret key", (*stringValue)(&c.S3.SecretKey)},
+		{"s3-bucket", "S3_BUCKET", "image storage bucket", (*stringValue)(&c.S3.Bucket)},
+		{"s3-region", "S3_REGION", "image storage region", (*stringValue)(&c.S3.Region)},
+		{"s3-use-ssl", "S3_USE_SSL", "use TLS for image storage", (*boolValue)(&c.S3.UseSSL)},
+		{"s3-public-url", "S3_PUBLIC_URL", "base URL of served images", (*stringValue)(&c.S3.PublicURL)},
+
+		{"smtp-addr", "SMTP_ADDR", "SMTP server, empty disables email", (*stringValue)(&c.SMTP.Addr)},
+		{"smtp-username", "SMTP_USERNAME", "SMTP user", (*stringValue)(&c.SMTP.Username)},
+		{"smtp-password", "SMTP_PASSWORD", "SMTP password", (*stringValue)(&c.SMTP.Password)},
+		{"smtp-from", "SMTP_FROM", "sender address of emails", (*stringValue)(&c.SMTP.From)},
+
+		{"report-emails", "REPORT_EMAILS", "recipients of the weekly report", (*listValue)(&c.Reports.Emails)},
+		{"report-webhook-url", "REPORT_WEBHOOK_URL", "webhook the weekly report is posted to", (*stringValue)(&c.Reports.WebhookURL)},
+
+		{"search-backend", "SEARCH_BACKEND", "where searches run: postgres, redisearch or elasticsearch", (*stringValue)(&c.Search.Backend)},
+		{"elasticsearch-url", "ELASTICSEARCH_URL", "Elasticsearch or OpenSearch URL", (*stringValue)(&c.Search.ElasticsearchURL)},
+		{"search-index", "SEARCH_INDEX", "name of the recipes search index", (*stringValue)(&c.Search.Index)},
+		{"search-shadow", "SEARCH_SHADOW", "backend searches are also run against and compared with, not served", (*stringValue)(&c.Search.Shadow)},
+		{"search-shadow-percent", "SEARCH_SHADOW_PERCENT", "share of searches run against the shadow backend", (*intValue)(&c.Search.ShadowPercent)},
+
+		{"region", "REGION", "region the deployment runs in, added to metrics and traces", (*stringValue)(&c.Region.Name)},
+		{"primary-region", "PRIMARY_REGION", "region of the primary database, empty for a single region", (*stringValue)(&c.Region.Primary)},
+		{"primary-region-url", "PRIMARY_REGION_URL", "API base URL of the primary region, writes are forwarded to it", (*stringValue)(&c.Region.PrimaryURL)},
+		{"primary-region-admin-url", "PRIMARY_REGION_ADMIN_URL", "admin API base URL of the primary region, admin writes are forwarded to it", (*stringValue)(&c.Region.PrimaryAdminURL)},
+		{"region-secret", "REGION_SECRET", "secret shared by the regions, sent with forwarded writes", (*stringValue)(&c.Region.Secret)},
+	}
+}
+
+// Load builds the config from the defaults, the config file, the
+// environment and args, in increasing precedence, and validates it. The
+// options are added to fs, which may already hold flags of its own.
+func Load(fs *flag.FlagSet, args []string) (Config, error) {
+	// flags win over everything else, but they also name the config file,
+	// so parse them into a scratch copy first and apply them last
+	flagged := Defaults()
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "JSON config file")
+	for _, o := range flagged.options() {
+		fs.Var(o.value, o.flag, o.usage)
+	}
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+	if fs.NArg() > 0 {
+		return Config{}, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+
+	c := Defaults()
+	options := c.options()
+
+	if *configFile != "" {
+		if err := c.loadFile(*configFile, options); err != nil {
+			return Config{}, err
+		}
+	}
+
+	var errs []error
+	for _, o := range options {
+		if v, ok := os.LookupEnv(o.env); ok && v != "" {
+			if err := o.value.Set(v); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", o.env, err))
+			}
+		}
+	}
+
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	for _, o := range options {
+		if set[o.flag] {
+			if err := o.value.Set(fs.Lookup(o.flag).Value.String()); err != nil {
+				errs = append(errs, fmt.Errorf("-%s: %w", o.flag, err))
+			}
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return Config{}, err
+	}
+	if err := c.Validate(); err != nil {
+		return Config{}, err
+	}
+	return c, nil
+}
+
+// loadFile applies a JSON object keyed by flag name. Values may be strings,
+// numbers, booleans or, for lists, arrays of strings.
+func (c *Config) loadFile(path string, options []option) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	known := map[string]flag.Value{}
+	for _, o := range options {
+		known[o.flag] = o.value
+	}
+
+	var errs []error
+	for key, msg := range raw {
+		value, ok := known[key]
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: unknown option %q", path, key))
+			continue
+		}
+
+		var s string
+		var list []string
+		switch {
+		case json.Unmarshal(msg, &s) == nil:
+		case json.Unmarshal(msg, &list) == nil:
+			s = strings.Join(list, ",")
+		default:
+			s = string(msg)
+		}
+		if err := value.Set(s); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s: %w", path, key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ConnString returns the postgres connection string.
+func (d Database) ConnString() string {
+	if d.DSN != "" {
+		return d.DSN
+	}
+	return fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=%s TimeZone=UTC", d.Host, d.User, d.Password, d.Name, d.Port, d.SSLMode)
+}
+
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	if c.Database.DSN == "" {
+		check(c.Database.Host != "", "db-host (HOST) is required")
+		check(c.Database.User != "", "db-user (DBUSER) is required")
+		check(c.Database.Name != "", "db-name (DBNAME) is required")
+	}
+	check(c.Database.MaxOpenConns >= 0, "db-max-open-conns must not be negative")
+	check(c.Database.MaxIdleConns >= 0, "db-max-idle-conns must not be negative")
+	check(c.Database.MaxOpenConns == 0 || c.Database.MaxIdleConns <= c.Database.MaxOpenConns, "db-max-idle-conns must not exceed db-max-open-conns")
+	check(c.Database.ConnMaxLifetime >= 0, "db-conn-max-lifetime must not be negative")
+	check(c.Database.PingTimeout > 0, "db-ping-timeout must be positive")
+	check(c.Redis.Addr != "", "redis-addr is required")
+	check(c.Redis.DB >= 0, "redis-db must not be negative")
+
+	check(c.Server.Addr != "", "addr is required")
+	check(c.Server.AdminAddr != "", "admin-addr is required")
+	port, err := strconv.Atoi(c.Server.GRPCPort)
+	check(err == nil && port > 0 && port < 1<<16, "grpc-port %q is not a valid port", c.Server.GRPCPort)
+	check(c.Server.ShutdownTimeout > 0, "shutdown-timeout must be positive")
+	check(c.Server.RequestTimeout >= 0, "request-timeout must not be negative")
+	if _, err := authz.ParseTokens(c.Server.APITokens); err != nil {
+		errs = append(errs, fmt.Errorf("api-tokens: %w", err))
+	}
+	check(c.Server.PreviewSecret == "" || len(c.Server.PreviewSecret) >= 32, "preview-secret must be at least 32 characters")
+
+	check(c.Cache.RecipeTTL > 0, "cache-ttl must be positive")
+	check(c.Cache.ListTTL > 0, "list-cache-ttl must be positive")
+	check(c.SettingsFile != "", "settings-file is required")
+	check(c.SeedFile != "", "seed-file is required")
+	_, langErr := language.Parse(c.ContentLanguage)
+	check(langErr == nil, "content-language %q is not a language tag", c.ContentLanguage)
+	check(c.ImageMaxBytes > 0, "image-max-bytes must be positive")
+	check(c.DigestWindow > 0, "digest-window must be positive")
+	check(c.IntegrityInterval >= 0, "integrity-interval must not be negative")
+	check(c.OutboxRetention > 0, "outbox-retention must be positive")
+	switch c.IDs.Strategy {
+	case ids.XID, ids.UUIDv7:
+	case ids.Snowflake:
+		check(c.IDs.Node >= 0 && c.IDs.Node <= ids.MaxNode, "id-node must be between 0 and %d", ids.MaxNode)
+	default:
+		check(false, "id-strategy %q is not xid, uuidv7 or snowflake", c.IDs.Strategy)
+	}
+	check(c.Startup.Attempts > 0, "startup-retries must be positive")
+	check(c.Startup.Delay > 0, "startup-retry-delay must be positive")
+	check(c.Outbound.MaxResponseBytes > 0, "outbound-max-bytes must be positive")
+
+	if c.S3.Endpoint != "" {
+		check(c.S3.Bucket != "", "s3-bucket is required with s3-endpoint")
+	}
+	if c.SMTP.Addr != "" {
+		check(c.SMTP.From != "", "smtp-from is required with smtp-addr")
+	}
+	if c.Reports.WebhookURL != "" {
+		u, err := url.Parse(c.Reports.WebhookURL)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "report-webhook-url %q is not an http(s) URL", c.Reports.WebhookURL)
+	}
+	checkBackend := func(flag, backend string) {
+		switch backend {
+		case SearchPostgres:
+		case SearchRediSearch:
+			check(c.Search.Index != "", "search-index is required with %s redisearch", flag)
+		case SearchElasticsearch:
+			check(c.Search.ElasticsearchURL != "", "elasticsearch-url is required with %s elasticsearch", flag)
+			check(c.Search.Index != "", "search-index is required with %s elasticsearch", flag)
+		default:
+			check(false, "%s %q is not postgres, redisearch or elasticsearch", flag, backend)
+		}
+	}
+	checkBackend("search-backend", c.Search.Backend)
+	if c.Search.Shadow != "" {
+		checkBackend("search-shadow", c.Search.Shadow)
+		check(c.Search.Shadow != c.Search.Backend, "search-shadow must differ from search-backend")
+		check(c.Search.ShadowPercent >= 0 && c.Search.ShadowPercent <= 100, "search-shadow-percent must be between 0 and 100")
+	}
+	if c.Region.Primary != "" {
+		check(c.Region.Name != "", "region is required with primary-region")
+	}
+	if !c.Region.IsPrimary() {
+		u, err := url.Parse(c.Region.PrimaryURL)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "primary-region-url %q is not an http(s) URL, required outside the primary region", c.Region.PrimaryURL)
+		u, err = url.Parse(c.Region.PrimaryAdminURL)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "primary-region-admin-url %q is not an http(s) URL, required outside the primary region", c.Region.PrimaryAdminURL)
+		check(len(c.Region.Secret) >= 32, "region-secret must be at least 32 characters outside the primary region")
+	}
+
+	return errors.Join(errs...)
+}
+
+type stringValue string
+
+func (v *stringValue) Set(s string) error { *v = stringValue(s); return nil }
+func (v *stringValue) String() string {
+	if v == nil {
+		return ""
+	}
+	return string(*v)
+}
+
+type intValue int
+
+func (v *intValue) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("%q is not a number", s)
+	}
+	*v = intValue(n)
+	return nil
+}
+func (v *intValue) String() string {
+	if v == nil {
+		return "0"
+	}
+	return strconv.Itoa(int(*v))
+}
+
+type int64Value int64
+
+func (v *int64Value) Set(s string) error {
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%q is not a number", s)
+	}
+	*v = int64Value(n)
+	return nil
+}
+func (v *int64Value) String() string {
+	if v == nil {
+		return "0"
+	}
+	return strconv.FormatInt(int64(*v), 10)
+}
+
+type boolValue bool
+
+func (v *boolValue) Set(s string) error {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return fmt.Errorf("%q is not a boolean", s)
+	}
+	*v = boolValue(b)
+	return nil
+}
+func (v *boolValue) String() string {
+	if v == nil {
+		return "false"
+	}
+	return strconv.FormatBool(bool(*v))
+}
+func (v *boolValue) IsBoolFlag() bool { return true }
+
+type durationValue time.Duration
+
+func (v *durationValue) Set(s string) error {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("%q is not a duration", s)
+	}
+	*v = durationValue(d)
+	return nil
+}
+func (v *durationValue) String() string {
+	if v == nil {
+		return "0s"
+	}
+	return time.Duration(*v).String()
+}
+
+// listValue takes a comma or space separated list.
+type listValue []string
+
+func (v *listValue) Set(s string) error {
+	*v = strings.Fields(strings.ReplaceAll(s, ",", " "))
+	return nil
+}
+func (v *listValue) String() string {
+	if v == nil {
+		return ""
+	}
+	return strings.Join(*v, ",")
+}
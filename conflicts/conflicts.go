@@ -0,0 +1,129 @@
+// Package conflicts merges two versions of a recipe edited independently,
+// e.g. on a client that was offline and on the server.
+package conflicts
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"recipes-api/models"
+)
+
+// Policies decide which version of a field wins.
+const (
+	// LastWriterWins keeps, per field, the value changed most recently
+	// according to FieldsUpdatedAt. Ties go to the remote version.
+	LastWriterWins = "last-writer-wins"
+	RemoteWins     = "remote-wins"
+	LocalWins      = "local-wins"
+)
+
+var Policies = []string{LastWriterWins, RemoteWins, LocalWins}
+
+// Fields are the recipe fields conflicts are resolved for; the others are
+// managed by the server.
+var Fields = []string{"name", "tags", "ingredients", "instructions", "totalTimeMinutes", "prepMinutes", "cookMinutes", "difficulty", "cuisineId", "categoryId", "servings"}
+
+// Resolution explains the outcome for a field the versions disagree on.
+type Resolution struct {
+	Field           string    `json:"field"`
+	Winner          string    `json:"winner"`
+	LocalUpdatedAt  time.Time `json:"localUpdatedAt"`
+	RemoteUpdatedAt time.Time `json:"remoteUpdatedAt"`
+}
+
+func IsPolicy(policy string) bool {
+	return slices.Contains(Policies, policy)
+}
+
+// Resolve merges local into remote field by field. The result is remote
+// with the fields local won, and their times, taken over.
+func Resolve(local, remote models.Recipe, policy string) (models.Recipe, []Resolution, error) {
+	if !IsPolicy(policy) {
+		return models.Recipe{}, nil, fmt.Errorf("unknown conflict policy %q", policy)
+	}
+
+	merged := remote
+	merged.FieldsUpdatedAt = map[string]time.Time{}
+	for field, t := range remote.FieldsUpdatedAt {
+		merged.FieldsUpdatedAt[field] = t
+	}
+
+	resolutions := []Resolution{}
+	for _, field := range Fields {
+		if equal(local, remote, field) {
+			continue
+		}
+
+		r := Resolution{
+			Field:           field,
+			Winner:          "remote",
+			LocalUpdatedAt:  local.FieldUpdatedAt(field),
+			RemoteUpdatedAt: remote.FieldUpdatedAt(field),
+		}
+		if policy == LocalWins || policy == LastWriterWins && r.LocalUpdatedAt.After(r.RemoteUpdatedAt) {
+			r.Winner = "local"
+			take(&merged, local, field)
+			merged.FieldsUpdatedAt[field] = r.LocalUpdatedAt
+		}
+		resolutions = append(resolutions, r)
+	}
+
+	return merged, resolutions, nil
+}
+
+func equal(a, b models.Recipe, field string) bool {
+	switch field {
+	case "name":
+		return a.Name == b.Name
+	case "tags":
+		return slices.Equal(a.Tags, b.Tags)
+	case "ingredients":
+		return slices.Equal(a.Ingredients, b.Ingredients)
+	case "instructions":
+		return slices.Equal(a.Instructions, b.Instructions)
+	case "totalTimeMinutes":
+		return a.TotalTimeMinutes == b.TotalTimeMinutes
+	case "prepMinutes":
+		return a.PrepMinutes == b.PrepMinutes
+	case "cookMinutes":
+		return a.CookMinutes == b.CookMinutes
+	case "difficulty":
+		return a.Difficulty == b.Difficulty
+	case "cuisineId":
+		return a.CuisineID == b.CuisineID
+	case "categoryId":
+		return a.CategoryID == b.CategoryID
+	case "servings":
+		return a.Servings == b.Servings
+	}
+	return true
+}
+
+func take(dst *models.Recipe, src models.Recipe, field string) {
+	switch field {
+	case "name":
+		dst.Name = src.Name
+	case "tags":
+		dst.Tags = src.Tags
+	case "ingredients":
+		dst.Ingredients = src.Ingredients
+	case "instructions":
+		dst.Instructions = src.Instructions
+	case "totalTimeMinutes":
+		dst.TotalTimeMinutes, dst.TotalTimeEstimated = src.TotalTimeMinutes, src.TotalTimeEstimated
+	case "prepMinutes":
+		dst.PrepMinutes = src.PrepMinutes
+	case "cookMinutes":
+		dst.CookMinutes = src.CookMinutes
+	case "difficulty":
+		dst.Difficulty = src.Difficulty
+	case "cuisineId":
+		dst.CuisineID = src.CuisineID
+	case "categoryId":
+		dst.CategoryID = src.CategoryID
+	case "servings":
+		dst.Servings = src.Servings
+	}
+}
@@ -0,0 +1,154 @@
+// Package conversions converts cooking measures between units, and whole
+// ingredient lines between the metric and imperial systems.
+package conversions
+
+import (
+	"errors"
+	"math"
+
+	"recipes-api/ingredients"
+)
+
+// Measurement systems.
+const (
+	Metric   = "metric"
+	Imperial = "imperial"
+)
+
+var (
+	ErrUnknownUnit  = errors.New("unknown unit")
+	ErrIncompatible = errors.New("units measure different things")
+)
+
+type kind int
+
+const (
+	volume kind = iota + 1
+	mass
+)
+
+// unit is a convertible unit: what it measures, its size in milliliters
+// or grams and the system it belongs to. Imperial volumes are US
+// customary measures. Spoons belong to neither system, as metric recipes
+// measure with them too.
+type unit struct {
+	kind   kind
+	size   float64
+	system string
+}
+
+var units = map[string]unit{
+	"milliliter":  {volume, 1, Metric},
+	"liter":       {volume, 1000, Metric},
+	"teaspoon":    {volume, 4.92892, ""},
+	"tablespoon":  {volume, 14.7868, ""},
+	"fluid ounce": {volume, 29.5735, Imperial},
+	"cup":         {volume, 236.588, Imperial},
+	"pint":        {volume, 473.176, Imperial},
+	"quart":       {volume, 946.353, Imperial},
+	"gallon":      {volume, 3785.41, Imperial},
+	"milligram":   {mass, 0.001, Metric},
+	"gram":        {mass, 1, Metric},
+	"kilogram":    {mass, 1000, Metric},
+	"ounce":       {mass, 28.3495, Imperial},
+	"pound":       {mass, 453.592, Imperial},
+}
+
+// ladders list the units amounts are written in per system and kind,
+// smallest first. An amount takes the largest unit it fills at least
+// min of.
+var ladders = map[string]map[kind][]struct {
+	unit string
+	min  float64
+}{
+	Metric: {
+		volume: {{"milliliter", 0}, {"liter", 1}},
+		mass:   {{"gram", 0}, {"kilogram", 1}},
+	},
+	Imperial: {
+		volume: {{"teaspoon", 0}, {"tablespoon", 1}, {"cup", 0.25}, {"quart", 1}},
+		mass:   {{"ounce", 0}, {"pound", 1}},
+	},
+}
+
+// Unit returns the canonical name of a unit spelling, e.g. milliliter for
+// "ml", if it is one that can be converted.
+func Unit(s string) (string, error) {
+	name, ok := ingredients.CanonicalUnit(s)
+	if !ok {
+		return "", ErrUnknownUnit
+	}
+	if _, ok := units[name]; !ok {
+		return "", ErrUnknownUnit
+	}
+	return name, nil
+}
+
+// Convert converts amount from one unit to another. Units may be given in
+// any spelling Unit knows.
+func Convert(amount float64, from, to string) (float64, error) {
+	from, err := Unit(from)
+	if err != nil {
+		return 0, err
+	}
+	to, err = Unit(to)
+	if err != nil {
+		return 0, err
+	}
+	if units[from].kind != units[to].kind {
+		return 0, ErrIncompatible
+	}
+	return round(amount * units[from].size / units[to].size), nil
+}
+
+// ToSystem converts amount of unit into the unit of system that reads
+// best, e.g. 2 cups to 473 ml in metric. Units it can't convert, and
+// amounts already in system, are returned as they are.
+func ToSystem(amount float64, unitName, system string) (float64, string) {
+	u, ok := units[unitName]
+	if !ok || u.system == system || u.system == "" {
+		return amount, unitName
+	}
+	base := amount * u.size
+	ladder := ladders[system][u.kind]
+	best := ladder[0].unit
+	for _, step := range ladder[1:] {
+		if base/units[step.unit].size >= step.min {
+			best = step.unit
+		}
+	}
+	return round(base / units[best].size), best
+}
+
+// Line converts the quantity of an ingredient line into system, reporting
+// whether it changed.
+func Line(line ingredients.Line, system string) (ingredients.Line, bool) {
+	amount, unitName := ToSystem(line.Quantity, line.Unit, system)
+	if unitName == line.Unit {
+		return line, false
+	}
+	if line.QuantityMax > 0 {
+		// in the unit of the amount, even if the maximum alone would read
+		// better in another
+		line.QuantityMax, _ = Convert(line.QuantityMax, line.Unit, unitName)
+	}
+	line.Quantity, line.Unit = amount, unitName
+	return line, true
+}
+
+// Ingredients converts the quantities of ingredient lines into system.
+// Lines without a convertible quantity are kept as written.
+func Ingredients(lines []string, system string) []string {
+	out := make([]string, len(lines))
+	for i, input := range lines {
+		out[i] = input
+		if line, ok := Line(ingredients.Parse(input), system); ok {
+			out[i] = line.String()
+		}
+	}
+	return out
+}
+
+func round(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
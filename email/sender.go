@@ -0,0 +1,62 @@
+// Package email sends plain-text notification emails over SMTP.
+package email
+
+import (
+	"errors"
+	"fmt"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"recipes-api/sandbox"
+)
+
+var ErrDisabled = errors.New("email delivery is not configured")
+
+type Config struct {
+	// Addr is the SMTP server as host:port. Without it nothing is sent.
+	Addr     string
+	Username string
+	Password string
+	From     string
+}
+
+// Sender delivers emails. With a non-nil recorder they are captured
+// instead of sent.
+type Sender struct {
+	config   Config
+	recorder *sandbox.Recorder
+}
+
+func NewSender(config Config, recorder *sandbox.Recorder) *Sender {
+	return &Sender{config: config, recorder: recorder}
+}
+
+// Enabled reports whether emails go anywhere.
+func (s *Sender) Enabled() bool {
+	return s.recorder != nil || s.config.Addr != ""
+}
+
+func (s *Sender) Send(to, subject, body string) error {
+	if strings.ContainsAny(to+subject, "\r\n") {
+		return errors.New("email headers must not contain line breaks")
+	}
+
+	if s.recorder != nil {
+		s.recorder.Capture(sandbox.Message{Kind: sandbox.KindEmail, Target: to, Subject: subject, Body: body})
+		return nil
+	}
+	if s.config.Addr == "" {
+		return ErrDisabled
+	}
+
+	var auth smtp.Auth
+	if s.config.Username != "" {
+		host, _, _ := strings.Cut(s.config.Addr, ":")
+		auth = smtp.PlainAuth("", s.config.Username, s.config.Password, host)
+	}
+
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s",
+		s.config.From, to, subject, time.Now().Format(time.RFC1123Z), strings.ReplaceAll(body, "\n", "\r\n"))
+	return smtp.SendMail(s.config.Addr, auth, s.config.From, []string{to}, []byte(msg))
+}
@@ -0,0 +1,65 @@
+package events
+
+import (
+	"reflect"
+	"strings"
+
+	"recipes-api/models"
+)
+
+// Change is the old and new value of a single recipe field.
+type Change struct {
+	Old any `json:"old"`
+	New any `json:"new"`
+}
+
+// Diff compares two versions of a recipe field by field and returns the
+// changed ones keyed by their JSON name.
+func Diff(before, after models.Recipe) map[string]Change {
+	changes := map[string]Change{}
+
+	oldValue := reflect.ValueOf(before)
+	newValue := reflect.ValueOf(after)
+	t := oldValue.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		if !t.Field(i).IsExported() {
+			continue
+		}
+
+		name := jsonName(t.Field(i))
+		if name == "" {
+			continue
+		}
+
+		o := oldValue.Field(i).Interface()
+		n := newValue.Field(i).Interface()
+		if !reflect.DeepEqual(o, n) {
+			changes[name] = Change{Old: o, New: n}
+		}
+	}
+
+	return changes
+}
+
+// Fields lists the recipe fields that can show up in Changes.
+func Fields() []string {
+	var fields []string
+	t := reflect.TypeOf(models.Recipe{})
+	for i := 0; i < t.NumField(); i++ {
+		if name := jsonName(t.Field(i)); name != "" && t.Field(i).IsExported() {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+// jsonName returns the JSON name of a field, or "" for fields that are
+// not diffed: those without JSON and bookkeeping tagged diff:"-".
+func jsonName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "-" || field.Tag.Get("diff") == "-" {
+		return ""
+	}
+	return name
+}
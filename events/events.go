@@ -0,0 +1,91 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"recipes-api/models"
+	"recipes-api/reqid"
+
+	"github.com/rs/xid"
+)
+
+const (
+	RecipeCreated  = "recipe.created"
+	RecipeUpdated  = "recipe.updated"
+	RecipeDeleted  = "recipe.deleted"
+	RecipeRestored = "recipe.restored"
+)
+
+// Event is the payload emitted to subscribers whenever a recipe changes.
+// Changes is only set for recipe.updated and lists the fields that differ
+// between the previous and the new state. Its JSON form is the current
+// schema version; use Versioned for older ones.
+type Event struct {
+	ID            string            `json:"id"`
+	Type          string            `json:"type"`
+	SchemaVersion string            `json:"schemaVersion"`
+	OccurredAt    time.Time         `json:"occurredAt"`
+	Recipe        models.Recipe     `json:"recipe"`
+	Changes       map[string]Change `json:"changes,omitempty"`
+	// Origin identifies the request that caused the event. It isn't part
+	// of the payload.
+	Origin reqid.IDs `json:"-"`
+}
+
+// Context returns a background context carrying the IDs of the request
+// that caused the event, for logs and calls made while handling it.
+func (e Event) Context() context.Context {
+	return reqid.NewContext(context.Background(), e.Origin)
+}
+
+func NewEvent(eventType string, recipe models.Recipe) Event {
+	return Event{
+		ID:            xid.New().String(),
+		Type:          eventType,
+		SchemaVersion: CurrentSchemaVersion,
+		OccurredAt:    time.Now().UTC(),
+		Recipe:        recipe,
+	}
+}
+
+// Handler receives published events. Handlers run on the publishing
+// goroutine, so anything slow should be handed off to a worker.
+type Handler func(Event)
+
+type Bus struct {
+	mu       sync.RWMutex
+	handlers []Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+func (b *Bus) Subscribe(h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, h)
+}
+
+// Publish hands the event to every subscriber, stamped with the request IDs from ctx.
+func (b *Bus) Publish(ctx context.Context, e Event) {
+	e.Origin = reqid.FromContext(ctx)
+
+	b.mu.RLock()
+	handlers := b.handlers
+	b.mu.RUnlock()
+
+	for _, h := range handlers {
+		func() {
+			defer func() {
+				if rec := recover(); rec != nil {
+					slog.ErrorContext(ctx, "Event handler panicked", "event_type", e.Type, "panic", rec)
+				}
+			}()
+			h(e)
+		}()
+	}
+}
@@ -0,0 +1,126 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"recipes-api/models"
+
+	"gorm.io/gorm"
+)
+
+// Outbox persists every published event so it can be re-delivered later.
+// Events are written by a worker, off the publishing goroutine, unless it
+// has fallen behind, and kept for the retention period.
+type Outbox struct {
+	db        *gorm.DB
+	retention time.Duration
+	queue     chan outboxJob
+}
+
+type outboxJob struct {
+	ctx   context.Context
+	entry models.OutboxEvent
+}
+
+func NewOutbox(db *gorm.DB, retention time.Duration) *Outbox {
+	o := &Outbox{db: db, retention: retention, queue: make(chan outboxJob, 1000)}
+	go o.work()
+	return o
+}
+
+// Record queues the event to be stored. It is meant to be subscribed to
+// the bus. Once the queue is full the event is stored right away instead,
+// slowing publishing down rather than losing it.
+func (o *Outbox) Record(e Event) {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		slog.ErrorContext(e.Context(), "Error encoding event", "event_id", e.ID, "error", err)
+		return
+	}
+
+	entry := models.OutboxEvent{
+		ID:         e.ID,
+		Type:       e.Type,
+		RecipeID:   e.Recipe.ID,
+		Payload:    payload,
+		OccurredAt: e.OccurredAt,
+	}
+	// the request may be over by the time the worker gets to it
+	job := outboxJob{ctx: context.WithoutCancel(e.Context()), entry: entry}
+	select {
+	case o.queue <- job:
+	default:
+		slog.WarnContext(e.Context(), "Outbox queue full, storing event synchronously", "event_id", e.ID)
+		o.store(job)
+	}
+}
+
+// Backlog returns how many events are waiting to be stored and how many fit.
+func (o *Outbox) Backlog() (queued, capacity int) {
+	return len(o.queue), cap(o.queue)
+}
+
+func (o *Outbox) work() {
+	for job := range o.queue {
+		o.store(job)
+	}
+}
+
+func (o *Outbox) store(job outboxJob) {
+	if err := o.db.WithContext(job.ctx).Create(&job.entry).Error; err != nil {
+		slog.ErrorContext(job.ctx, "Error recording event in outbox", "event_id", job.entry.ID, "error", err)
+	}
+}
+
+// Prune deletes the events older than the retention period and returns
+// how many there were.
+func (o *Outbox) Prune(ctx context.Context) (int64, error) {
+	result := o.db.WithContext(ctx).Where("occurred_at < ?", time.Now().Add(-o.retention)).Delete(&models.OutboxEvent{})
+	return result.RowsAffected, result.Error
+}
+
+// RunPrune prunes the outbox every interval until the process exits.
+func (o *Outbox) RunPrune(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		pruned, err := o.Prune(context.Background())
+		if err != nil {
+			slog.Error("Error pruning event outbox", "error", err)
+			continue
+		}
+		if pruned > 0 {
+			slog.Info("Pruned event outbox", "events", pruned)
+		}
+	}
+}
+
+// Since returns the first limit events that occurred in [since, until),
+// oldest first. A zero until means up to now. Callers page through a
+// longer range by asking again from the occurrence time of the event
+// after the last one they got.
+func (o *Outbox) Since(ctx context.Context, since, until time.Time, limit int) ([]Event, error) {
+	query := o.db.WithContext(ctx).Where("occurred_at >= ?", since)
+	if !until.IsZero() {
+		query = query.Where("occurred_at < ?", until)
+	}
+
+	var entries []models.OutboxEvent
+	if err := query.Order("occurred_at ASC, id ASC").Limit(limit).Find(&entries).Error; err != nil {
+		return nil, err
+	}
+
+	list := make([]Event, 0, len(entries))
+	for _, entry := range entries {
+		var e Event
+		if err := json.Unmarshal(entry.Payload, &e); err != nil {
+			return nil, err
+		}
+		list = append(list, e)
+	}
+
+	return list, nil
+}
@@ -0,0 +1,84 @@
+package events
+
+import (
+	"embed"
+	"fmt"
+	"time"
+)
+
+const (
+	SchemaV1 = "v1"
+	SchemaV2 = "v2"
+
+	CurrentSchemaVersion = SchemaV2
+)
+
+// SchemaVersions lists every payload version that can still be requested, oldest first.
+var SchemaVersions = []string{SchemaV1, SchemaV2}
+
+// Types lists the event types covered by the schemas.
+var Types = []string{RecipeCreated, RecipeUpdated, RecipeDeleted, RecipeRestored}
+
+//go:embed schemas/*.json
+var schemaFS embed.FS
+
+// Schema returns the JSON Schema document describing payloads of the given version.
+func Schema(version string) ([]byte, error) {
+	if !IsSchemaVersion(version) {
+		return nil, fmt.Errorf("unknown schema version %q", version)
+	}
+	return schemaFS.ReadFile("schemas/" + version + ".json")
+}
+
+func IsSchemaVersion(version string) bool {
+	for _, v := range SchemaVersions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// RecipeV1 is the recipe shape of v1 payloads, before nutrition and images existed.
+type RecipeV1 struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	Tags         []string  `json:"tags"`
+	Ingredients  []string  `json:"ingredients"`
+	Instructions []string  `json:"instructions"`
+	PublishedAt  time.Time `json:"publishedAt"`
+}
+
+type EventV1 struct {
+	ID            string    `json:"id"`
+	Type          string    `json:"type"`
+	SchemaVersion string    `json:"schemaVersion"`
+	OccurredAt    time.Time `json:"occurredAt"`
+	Recipe        RecipeV1  `json:"recipe"`
+}
+
+// Versioned converts the event to the payload shape of the given schema version.
+func (e Event) Versioned(version string) (any, error) {
+	switch version {
+	case SchemaV1:
+		return EventV1{
+			ID:            e.ID,
+			Type:          e.Type,
+			SchemaVersion: SchemaV1,
+			OccurredAt:    e.OccurredAt,
+			Recipe: RecipeV1{
+				ID:           e.Recipe.ID,
+				Name:         e.Recipe.Name,
+				Tags:         e.Recipe.Tags,
+				Ingredients:  e.Recipe.Ingredients,
+				Instructions: e.Recipe.Instructions,
+				PublishedAt:  e.Recipe.PublishedAt,
+			},
+		}, nil
+	case SchemaV2:
+		e.SchemaVersion = SchemaV2
+		return e, nil
+	default:
+		return nil, fmt.Errorf("unknown schema version %q", version)
+	}
+}
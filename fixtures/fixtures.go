@@ -0,0 +1,82 @@
+package fixtures
+
+import (
+	"time"
+
+	"recipes-api/events"
+	"recipes-api/models"
+)
+
+// Timestamp is the fixed point in time used by every fixture.
+var Timestamp = time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+// Recipes returns a fixed set of recipes with stable IDs and timestamps.
+func Recipes() []models.Recipe {
+	return []models.Recipe{
+		{
+			ID:           "fixture-recipe-1",
+			Name:         "Fixture Pancakes",
+			Tags:         []string{"breakfast", "vegetarian"},
+			Ingredients:  []string{"1 cup flour", "1 egg", "1 cup milk"},
+			Instructions: []string{"Whisk everything together.", "Fry ladlefuls in a hot pan."},
+			Nutrition:    &models.Nutrition{Calories: 650, Protein: 25, Fat: 14, Carbs: 100},
+			PublishedAt:  Timestamp,
+		},
+		{
+			ID:           "fixture-recipe-2",
+			Name:         "Fixture Tomato Soup",
+			Tags:         []string{"soup", "vegan"},
+			Ingredients:  []string{"6 tomatoes", "1 onion", "2 cups vegetable stock"},
+			Instructions: []string{"Soften the onion.", "Add tomatoes and stock and simmer for 20 minutes.", "Blend until smooth."},
+			PublishedAt:  Timestamp.Add(time.Hour),
+		},
+		{
+			ID:           "fixture-recipe-3",
+			Name:         "Fixture Grilled Chicken",
+			Tags:         []string{"main", "chicken"},
+			Ingredients:  []string{"2 chicken breasts", "1 tbsp olive oil", "salt", "pepper"},
+			Instructions: []string{"Season the chicken.", "Grill for 6 minutes per side."},
+			Image: &models.Image{
+				Key:         "recipes/fixture-recipe-3/fixture.jpg",
+				URL:         "https://images.example.com/recipes/fixture-recipe-3/fixture.jpg",
+				ContentType: "image/jpeg",
+				Size:        102400,
+			},
+			PublishedAt: Timestamp.Add(2 * time.Hour),
+		},
+	}
+}
+
+func Recipe(id string) (models.Recipe, bool) {
+	for _, recipe := range Recipes() {
+		if recipe.ID == id {
+			return recipe, true
+		}
+	}
+	return models.Recipe{}, false
+}
+
+// Event returns an example event of the given type for the first fixture recipe.
+func Event(eventType string) (events.Event, bool) {
+	recipe := Recipes()[0]
+
+	e := events.Event{
+		ID:            "fixture-event-" + eventType,
+		Type:          eventType,
+		SchemaVersion: events.CurrentSchemaVersion,
+		OccurredAt:    Timestamp,
+		Recipe:        recipe,
+	}
+
+	switch eventType {
+	case events.RecipeCreated, events.RecipeDeleted, events.RecipeRestored:
+	case events.RecipeUpdated:
+		before := recipe
+		before.Name = "Fixture Pancake"
+		e.Changes = events.Diff(before, recipe)
+	default:
+		return events.Event{}, false
+	}
+
+	return e, true
+}
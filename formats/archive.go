@@ -0,0 +1,206 @@
+package formats
+
+import (
+	"archive/zip"
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"recipes-api/models"
+)
+
+// ArchiveFormats are the import formats read from the zip exports of other
+// recipe managers.
+var ArchiveFormats = []string{"paprika", "mealie", "nextcloud"}
+
+// maxArchiveEntryBytes caps how much one file of an archive may unpack to,
+// so a small archive can't expand to fill memory.
+const maxArchiveEntryBytes = 20 << 20
+
+var (
+	errNotArchive    = errors.New("expected a zip archive")
+	amountPattern    = regexp.MustCompile(`\d+(?:[.,]\d+)?`)
+	durationPattern  = regexp.MustCompile(`(?i)(\d+(?:[.,]\d+)?)\s*(d|days?|h|hrs?|hours?|m|mins?|minutes?)\b`)
+	nutritionPattern = regexp.MustCompile(`(?i)^\s*([a-z ]+?)\s*[:=]\s*(.+)$`)
+)
+
+// ReadArchive reads the export of another recipe manager, telling which one
+// made it from the files it holds.
+func ReadArchive(r io.ReaderAt, size int64) ([]Row, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, errNotArchive
+	}
+	has := func(match func(name string) bool) bool {
+		return slices.ContainsFunc(archive.File, func(file *zip.File) bool { return match(path.Base(file.Name)) })
+	}
+	switch {
+	case has(func(name string) bool { return strings.HasSuffix(name, ".paprikarecipe") }):
+		return readPaprika(archive)
+	case has(func(name string) bool { return name == "recipe.json" }):
+		return readNextcloud(archive)
+	case has(func(name string) bool { return strings.HasSuffix(name, ".json") }):
+		return readMealie(archive)
+	}
+	return nil, errors.New("unrecognized archive, expected a Paprika, Mealie or Nextcloud Cookbook export")
+}
+
+// openArchive opens r as a zip archive for the reader of one format.
+func openArchive(r io.ReaderAt, size int64) (*zip.Reader, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, errNotArchive
+	}
+	return archive, nil
+}
+
+// readEntry unpacks a file of an archive, failing once it unpacks to more
+// than maxArchiveEntryBytes.
+func readEntry(file *zip.File) ([]byte, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, maxArchiveEntryBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxArchiveEntryBytes {
+		return nil, fmt.Errorf("%s unpacks to more than %d MB", file.Name, maxArchiveEntryBytes>>20)
+	}
+	return data, nil
+}
+
+// findEntry returns the file of archive at name.
+func findEntry(archive *zip.Reader, name string) *zip.File {
+	for _, file := range archive.File {
+		if file.Name == name {
+			return file
+		}
+	}
+	return nil
+}
+
+// lines splits text into its non-blank lines.
+func lines(text string) []string {
+	var all []string
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			all = append(all, line)
+		}
+	}
+	return all
+}
+
+// amount reads the first number in text, such as 250 in "250 kcal".
+func amount(text string) (float64, bool) {
+	match := amountPattern.FindString(text)
+	if match == "" {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(strings.ReplaceAll(match, ",", "."), 64)
+	return n, err == nil
+}
+
+// humanMinutes reads a duration as people write it, "1 hr 20 mins" or
+// "45 minutes", as well as ISO 8601 ones and bare numbers of minutes.
+func humanMinutes(text string) int {
+	if m := minutes(text); m > 0 {
+		return m
+	}
+	if n, err := strconv.Atoi(strings.TrimSpace(text)); err == nil && n > 0 {
+		return n
+	}
+	var total float64
+	for _, m := range durationPattern.FindAllStringSubmatch(text, -1) {
+		n, _ := strconv.ParseFloat(strings.ReplaceAll(m[1], ",", "."), 64)
+		switch unit := strings.ToLower(m[2]); {
+		case strings.HasPrefix(unit, "d"):
+			total += n * 24 * 60
+		case strings.HasPrefix(unit, "h"):
+			total += n * 60
+		default:
+			total += n
+		}
+	}
+	return int(total + 0.5)
+}
+
+// schemaNutrition reads the nutrition of one serving from the
+// schema.org/NutritionInformation keys of values, which hold texts such as
+// "250 kcal" or "12 g".
+func schemaNutrition(values map[string]any) (models.Nutrition, bool) {
+	var n models.Nutrition
+	found := false
+	for key, field := range map[string]*float64{
+		"calories":            &n.Calories,
+		"proteinContent":      &n.Protein,
+		"fatContent":          &n.Fat,
+		"carbohydrateContent": &n.Carbs,
+	} {
+		if value, ok := amount(firstText(values[key])); ok {
+			*field, found = value, true
+		}
+	}
+	return n, found
+}
+
+// recipeNutrition turns the nutrition of one serving into the totals of the
+// recipe, which can only be done when its servings are known.
+func recipeNutrition(perServing models.Nutrition, servings int) *models.Nutrition {
+	if servings <= 0 {
+		return nil
+	}
+	s := float64(servings)
+	return &models.Nutrition{
+		Calories: perServing.Calories * s,
+		Protein:  perServing.Protein * s,
+		Fat:      perServing.Fat * s,
+		Carbs:    perServing.Carbs * s,
+	}
+}
+
+// unmapped returns the keys of object with a value, other than mapped
+// ones, sorted.
+func unmapped(object map[string]any, mapped ...string) []string {
+	var keys []string
+	for key, value := range object {
+		if slices.Contains(mapped, key) || strings.HasPrefix(key, "@") || isEmpty(value) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+func isEmpty(value any) bool {
+	switch value := value.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(value) == ""
+	case float64:
+		return value == 0
+	case bool:
+		return !value
+	case []any:
+		return len(value) == 0
+	case map[string]any:
+		for _, child := range value {
+			if !isEmpty(child) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
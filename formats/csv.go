@@ -0,0 +1,125 @@
+package formats
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"recipes-api/models"
+)
+
+// ListSeparator joins the items of list fields (tags, ingredients,
+// instructions) inside a single CSV cell.
+const ListSeparator = "|"
+
+// CSVHeader is the column layout written by CSVWriter.
+var CSVHeader = []string{"id", "name", "tags", "ingredients", "instructions", "publishedAt"}
+
+// CSVWriter writes recipes as CSV rows, flattening list fields with ListSeparator.
+type CSVWriter struct {
+	w *csv.Writer
+}
+
+func NewCSVWriter(w io.Writer) *CSVWriter {
+	return &CSVWriter{w: csv.NewWriter(w)}
+}
+
+func (c *CSVWriter) WriteHeader() error {
+	return c.w.Write(CSVHeader)
+}
+
+func (c *CSVWriter) Write(recipe models.Recipe) error {
+	return c.w.Write([]string{
+		recipe.ID,
+		recipe.Name,
+		joinList(recipe.Tags),
+		joinList(recipe.Ingredients),
+		joinList(recipe.Instructions),
+		recipe.PublishedAt.UTC().Format(time.RFC3339),
+	})
+}
+
+// Flush writes buffered rows to the underlying writer and reports any write error.
+func (c *CSVWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// ReadCSV parses recipes from CSV with a header row. Columns are matched by
+// name (name, tags, ingredients, instructions); unknown columns are ignored.
+func ReadCSV(r io.Reader) ([]Row, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading CSV header: %w", err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, errors.New("CSV header must contain a name column")
+	}
+
+	var rows []Row
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				rows = append(rows, Row{Err: err})
+				continue
+			}
+			return nil, err
+		}
+
+		if len(record) != len(header) {
+			rows = append(rows, Row{Err: fmt.Errorf("expected %d fields, got %d", len(header), len(record))})
+			continue
+		}
+
+		cell := func(name string) string {
+			if i, ok := columns[name]; ok {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		rows = append(rows, Row{Recipe: models.Recipe{
+			Name:         cell("name"),
+			Tags:         splitList(cell("tags")),
+			Ingredients:  splitList(cell("ingredients")),
+			Instructions: splitList(cell("instructions")),
+		}})
+	}
+
+	return rows, nil
+}
+
+func splitList(cell string) []string {
+	var items []string
+	for _, item := range strings.Split(cell, ListSeparator) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func joinList(items []string) string {
+	cleaned := make([]string, 0, len(items))
+	for _, item := range items {
+		cleaned = append(cleaned, strings.TrimSpace(item))
+	}
+	return strings.Join(cleaned, ListSeparator)
+}
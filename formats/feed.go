@@ -0,0 +1,173 @@
+package formats
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+
+	"recipes-api/models"
+)
+
+// FeedInfo describes the feed itself. BaseURL is used to build recipe links.
+type FeedInfo struct {
+	Title   string
+	BaseURL string
+	SelfURL string
+}
+
+type rss struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	AtomLink      atomLink  `xml:"atom:link"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        string   `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+	Categories  []string `xml:"category"`
+	Description string   `xml:"description"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"feed"`
+	NS      string      `xml:"xmlns,attr"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Updated    string         `xml:"updated"`
+	Published  string         `xml:"published"`
+	Link       atomLink       `xml:"link"`
+	Categories []atomCategory `xml:"category"`
+	Content    atomContent    `xml:"content"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// WriteRSS writes an RSS 2.0 feed of the recipes in the given order.
+func WriteRSS(w io.Writer, info FeedInfo, recipes []models.Recipe) error {
+	channel := rssChannel{
+		Title:         info.Title,
+		Link:          info.BaseURL + "/recipes",
+		Description:   "Newest recipes",
+		AtomLink:      atomLink{Href: info.SelfURL, Rel: "self", Type: "application/rss+xml"},
+		LastBuildDate: feedUpdated(recipes).Format(time.RFC1123Z),
+	}
+
+	for _, recipe := range recipes {
+		link := recipeLink(info, recipe)
+		channel.Items = append(channel.Items, rssItem{
+			Title:       recipe.Name,
+			Link:        link,
+			GUID:        link,
+			PubDate:     recipe.PublishedAt.UTC().Format(time.RFC1123Z),
+			Categories:  recipe.Tags,
+			Description: feedContent(recipe),
+		})
+	}
+
+	return writeXML(w, rss{Version: "2.0", Atom: "http://www.w3.org/2005/Atom", Channel: channel})
+}
+
+// WriteAtom writes an Atom 1.0 feed of the recipes in the given order.
+func WriteAtom(w io.Writer, info FeedInfo, recipes []models.Recipe) error {
+	feed := atomFeed{
+		NS:      "http://www.w3.org/2005/Atom",
+		ID:      info.SelfURL,
+		Title:   info.Title,
+		Updated: feedUpdated(recipes).Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: info.SelfURL, Rel: "self", Type: "application/atom+xml"},
+			{Href: info.BaseURL + "/recipes", Rel: "alternate"},
+		},
+	}
+
+	for _, recipe := range recipes {
+		link := recipeLink(info, recipe)
+		entry := atomEntry{
+			ID:        link,
+			Title:     recipe.Name,
+			Updated:   recipe.PublishedAt.UTC().Format(time.RFC3339),
+			Published: recipe.PublishedAt.UTC().Format(time.RFC3339),
+			Link:      atomLink{Href: link, Rel: "alternate"},
+			Content:   atomContent{Type: "html", Body: feedContent(recipe)},
+		}
+		for _, tag := range recipe.Tags {
+			entry.Categories = append(entry.Categories, atomCategory{Term: tag})
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	return writeXML(w, feed)
+}
+
+func writeXML(w io.Writer, v any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(v)
+}
+
+func recipeLink(info FeedInfo, recipe models.Recipe) string {
+	return fmt.Sprintf("%s/recipes/%s", info.BaseURL, recipe.ID)
+}
+
+func feedUpdated(recipes []models.Recipe) time.Time {
+	var latest time.Time
+	for _, recipe := range recipes {
+		if recipe.PublishedAt.After(latest) {
+			latest = recipe.PublishedAt
+		}
+	}
+	if latest.IsZero() {
+		latest = time.Now()
+	}
+	return latest.UTC()
+}
+
+// feedContent renders the ingredients as an HTML list for feed readers.
+func feedContent(recipe models.Recipe) string {
+	var b strings.Builder
+	b.WriteString("<ul>")
+	for _, ingredient := range recipe.Ingredients {
+		fmt.Fprintf(&b, "<li>%s</li>", html.EscapeString(strings.TrimSpace(ingredient)))
+	}
+	b.WriteString("</ul>")
+	return b.String()
+}
@@ -0,0 +1,20 @@
+// Package formats converts recipes to and from external representations.
+package formats
+
+import "recipes-api/models"
+
+// Row is one record read from an import source. Err is set when the
+// record could not be parsed; the other rows are unaffected.
+type Row struct {
+	Recipe models.Recipe
+	Err    error
+
+	// Nutrition, Image and Unmapped are only read from the export
+	// archives of other recipe managers. Nutrition is the totals of the
+	// recipe when the source had them, Image the bytes of its photo.
+	Nutrition *models.Nutrition
+	Image     []byte
+	// Unmapped names the fields of the source that had a value but have
+	// no place in a recipe, so they can be reported as left behind.
+	Unmapped []string
+}
@@ -0,0 +1,266 @@
+package formats
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+
+	"recipes-api/models"
+
+	"golang.org/x/net/html"
+)
+
+// ReadHTML extracts the recipe of a web page. It is taken from schema.org
+// JSON-LD when the page has it, then from schema.org microdata, and
+// otherwise from common markup: the list items of elements whose class
+// names the ingredients or the instructions, under the page's title.
+func ReadHTML(r io.Reader) (models.Recipe, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return models.Recipe{}, err
+	}
+
+	for _, find := range []func(*html.Node) (map[string]any, bool){jsonLDObject, microdataObject, markupObject} {
+		object, ok := find(doc)
+		if !ok {
+			continue
+		}
+		recipe := SchemaOrgRecipe(object)
+		if len(recipe.Ingredients) == 0 && len(recipe.Instructions) == 0 {
+			continue
+		}
+		if recipe.Name == "" {
+			recipe.Name = pageTitle(doc)
+		}
+		return recipe, nil
+	}
+	return models.Recipe{}, errors.New("no recipe found on the page")
+}
+
+// jsonLDObject returns the schema.org/Recipe of the page's JSON-LD scripts.
+func jsonLDObject(doc *html.Node) (map[string]any, bool) {
+	var found map[string]any
+	walk(doc, func(n *html.Node) bool {
+		if found != nil {
+			return false
+		}
+		if n.Type == html.ElementNode && n.Data == "script" && strings.EqualFold(attr(n, "type"), JSONLDContentType) {
+			var value any
+			if json.Unmarshal([]byte(text(n)), &value) == nil {
+				found, _ = findSchemaRecipe(value)
+			}
+			return false
+		}
+		return true
+	})
+	return found, found != nil
+}
+
+// microdataObject returns the properties of the page's schema.org/Recipe
+// item, in the shape of its JSON-LD.
+func microdataObject(doc *html.Node) (map[string]any, bool) {
+	var found map[string]any
+	walk(doc, func(n *html.Node) bool {
+		if found != nil {
+			return false
+		}
+		if hasAttr(n, "itemscope") && strings.HasSuffix(attr(n, "itemtype"), "schema.org/Recipe") {
+			found = microdataItem(n)
+			return false
+		}
+		return true
+	})
+	return found, found != nil
+}
+
+// microdataItem collects the properties of an item. Every property is a
+// list, as it may be repeated; nested items are objects of their own.
+func microdataItem(item *html.Node) map[string]any {
+	object := map[string]any{}
+	for child := item.FirstChild; child != nil; child = child.NextSibling {
+		walk(child, func(n *html.Node) bool {
+			if n.Type != html.ElementNode {
+				return false
+			}
+			if props := attr(n, "itemprop"); props != "" {
+				value := microdataValue(n)
+				for _, prop := range strings.Fields(props) {
+					list, _ := object[prop].([]any)
+					object[prop] = append(list, value)
+				}
+			}
+			// the properties of nested items are theirs
+			return !hasAttr(n, "itemscope")
+		})
+	}
+	return object
+}
+
+func microdataValue(n *html.Node) any {
+	if hasAttr(n, "itemscope") {
+		return microdataItem(n)
+	}
+	switch n.Data {
+	case "meta":
+		return attr(n, "content")
+	case "time":
+		if datetime := attr(n, "datetime"); datetime != "" {
+			return datetime
+		}
+	case "img":
+		return attr(n, "src")
+	case "a", "link":
+		return attr(n, "href")
+	}
+	if content := attr(n, "content"); content != "" {
+		return content
+	}
+	// a list marked as one property, such as the steps, is its items
+	if n.Data == "ol" || n.Data == "ul" {
+		var items []any
+		for _, t := range itemTexts(n) {
+			items = append(items, t)
+		}
+		return items
+	}
+	return text(n)
+}
+
+// markupObject reads the page the way its reader would: the list items,
+// or else paragraphs, of the elements whose class mentions ingredients or
+// instructions.
+func markupObject(doc *html.Node) (map[string]any, bool) {
+	ingredients := classItems(doc, ingredientHeadings)
+	instructions := classItems(doc, instructionHeadings)
+	if len(ingredients) == 0 && len(instructions) == 0 {
+		return nil, false
+	}
+	return map[string]any{"recipeIngredient": ingredients, "recipeInstructions": instructions}, true
+}
+
+// classItems returns the items of the elements whose class contains one of
+// names, singular or plural: a list, or each item marked on its own.
+func classItems(doc *html.Node, names []string) []any {
+	var items []any
+	walk(doc, func(n *html.Node) bool {
+		class := strings.ToLower(attr(n, "class"))
+		if n.Type != html.ElementNode || class == "" || !containsAny(class, names) {
+			return true
+		}
+		for _, t := range itemTexts(n) {
+			items = append(items, t)
+		}
+		return false
+	})
+	return items
+}
+
+func itemTexts(n *html.Node) []string {
+	if n.Data == "li" || n.Data == "p" {
+		if t := text(n); t != "" {
+			return []string{t}
+		}
+		return nil
+	}
+	for _, tag := range []string{"li", "p"} {
+		var texts []string
+		walk(n, func(item *html.Node) bool {
+			if item.Type != html.ElementNode || item.Data != tag {
+				return true
+			}
+			if t := text(item); t != "" {
+				texts = append(texts, t)
+			}
+			return false
+		})
+		if len(texts) > 0 {
+			return texts
+		}
+	}
+	return nil
+}
+
+// pageTitle is the og:title of the page, or its first heading, or its
+// title.
+func pageTitle(doc *html.Node) string {
+	var ogTitle, heading, title string
+	walk(doc, func(n *html.Node) bool {
+		if n.Type != html.ElementNode {
+			return true
+		}
+		switch {
+		case n.Data == "meta" && attr(n, "property") == "og:title" && ogTitle == "":
+			ogTitle = clean(attr(n, "content"))
+		case n.Data == "h1" && heading == "":
+			heading = text(n)
+		case n.Data == "title" && title == "":
+			title = text(n)
+		}
+		return true
+	})
+	for _, candidate := range []string{ogTitle, heading, title} {
+		if candidate != "" {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// walk calls visit on n and its descendants in document order, skipping
+// the descendants of nodes visit returns false for.
+func walk(n *html.Node, visit func(*html.Node) bool) {
+	if !visit(n) {
+		return
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		walk(child, visit)
+	}
+}
+
+// text is the text of n, with its whitespace collapsed. Scripts are only
+// read for themselves, so JSON-LD can be decoded.
+func text(n *html.Node) string {
+	var b strings.Builder
+	walk(n, func(node *html.Node) bool {
+		switch {
+		case node.Type == html.TextNode:
+			b.WriteString(node.Data)
+			b.WriteByte(' ')
+		case node != n && node.Type == html.ElementNode && (node.Data == "script" || node.Data == "style"):
+			return false
+		}
+		return true
+	})
+	if n.Data == "script" {
+		return b.String()
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAny(s string, substrings []string) bool {
+	for _, sub := range substrings {
+		if strings.Contains(s, strings.TrimSuffix(sub, "s")) {
+			return true
+		}
+	}
+	return false
+}
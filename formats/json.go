@@ -0,0 +1,30 @@
+package formats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"recipes-api/models"
+)
+
+// ReadJSON parses a JSON array of recipes. Each element is decoded on its
+// own so a malformed record only fails its own row.
+func ReadJSON(r io.Reader) ([]Row, error) {
+	var items []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&items); err != nil {
+		return nil, fmt.Errorf("expected a JSON array of recipes: %w", err)
+	}
+
+	rows := make([]Row, 0, len(items))
+	for _, item := range items {
+		var recipe models.Recipe
+		if err := json.Unmarshal(item, &recipe); err != nil {
+			rows = append(rows, Row{Err: err})
+			continue
+		}
+		rows = append(rows, Row{Recipe: recipe})
+	}
+
+	return rows, nil
+}
@@ -0,0 +1,114 @@
+package formats
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"recipes-api/models"
+)
+
+// JSONLDContentType is the media type of schema.org JSON-LD documents.
+const JSONLDContentType = "application/ld+json"
+
+// JSONLDRecipe is a schema.org/Recipe document.
+type JSONLDRecipe struct {
+	Context            string                `json:"@context"`
+	Type               string                `json:"@type"`
+	Identifier         string                `json:"identifier"`
+	Name               string                `json:"name"`
+	Image              []string              `json:"image,omitempty"`
+	DatePublished      string                `json:"datePublished"`
+	Keywords           string                `json:"keywords,omitempty"`
+	RecipeYield        string                `json:"recipeYield,omitempty"`
+	PrepTime           string                `json:"prepTime,omitempty"`
+	CookTime           string                `json:"cookTime,omitempty"`
+	TotalTime          string                `json:"totalTime,omitempty"`
+	RecipeIngredient   []string              `json:"recipeIngredient"`
+	RecipeInstructions []JSONLDHowToStep     `json:"recipeInstructions"`
+	Nutrition          *JSONLDNutritionFacts `json:"nutrition,omitempty"`
+}
+
+type JSONLDHowToStep struct {
+	Type     string `json:"@type"`
+	Position int    `json:"position"`
+	Text     string `json:"text"`
+}
+
+type JSONLDNutritionFacts struct {
+	Type                string `json:"@type"`
+	Calories            string `json:"calories"`
+	ProteinContent      string `json:"proteinContent"`
+	FatContent          string `json:"fatContent"`
+	CarbohydrateContent string `json:"carbohydrateContent"`
+}
+
+// JSONLD maps a recipe onto schema.org/Recipe.
+func JSONLD(recipe models.Recipe) JSONLDRecipe {
+	doc := JSONLDRecipe{
+		Context:            "https://schema.org",
+		Type:               "Recipe",
+		Identifier:         recipe.ID,
+		Name:               recipe.Name,
+		DatePublished:      recipe.PublishedAt.UTC().Format(time.RFC3339),
+		Keywords:           strings.Join(recipe.Tags, ", "),
+		RecipeIngredient:   make([]string, 0, len(recipe.Ingredients)),
+		RecipeInstructions: make([]JSONLDHowToStep, 0, len(recipe.Instructions)),
+	}
+	if recipe.Servings > 0 {
+		doc.RecipeYield = strconv.Itoa(recipe.Servings)
+	}
+	doc.PrepTime = duration(recipe.PrepMinutes)
+	doc.CookTime = duration(recipe.CookMinutes)
+	doc.TotalTime = duration(recipe.TotalTimeMinutes)
+
+	for _, ingredient := range recipe.Ingredients {
+		doc.RecipeIngredient = append(doc.RecipeIngredient, strings.TrimSpace(ingredient))
+	}
+	for i, step := range recipe.Instructions {
+		doc.RecipeInstructions = append(doc.RecipeInstructions, JSONLDHowToStep{
+			Type:     "HowToStep",
+			Position: i + 1,
+			Text:     strings.TrimSpace(step),
+		})
+	}
+
+	if recipe.Image != nil {
+		// larger images first, as search engines pick the first usable one
+		doc.Image = append(doc.Image, recipe.Image.URL)
+		for _, name := range []string{"large", "medium"} {
+			if variant, ok := recipe.Image.Variants[name]; ok {
+				doc.Image = append(doc.Image, variant.URL)
+			}
+		}
+	}
+
+	if n := recipe.Nutrition; n != nil {
+		doc.Nutrition = &JSONLDNutritionFacts{
+			Type:                "NutritionInformation",
+			Calories:            fmt.Sprintf("%.0f calories", n.Calories),
+			ProteinContent:      fmt.Sprintf("%.1f g", n.Protein),
+			FatContent:          fmt.Sprintf("%.1f g", n.Fat),
+			CarbohydrateContent: fmt.Sprintf("%.1f g", n.Carbs),
+		}
+	}
+
+	return doc
+}
+
+// duration writes minutes as an ISO 8601 duration such as PT1H30M, or
+// nothing for zero.
+func duration(minutes int) string {
+	if minutes <= 0 {
+		return ""
+	}
+	d := "PT"
+	if hours := minutes / 60; hours > 0 {
+		d += strconv.Itoa(hours) + "H"
+	}
+	if minutes%60 > 0 {
+		d += strconv.Itoa(minutes%60) + "M"
+	}
+	return d
+}
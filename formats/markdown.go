@@ -0,0 +1,133 @@
+package formats
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"recipes-api/models"
+)
+
+var (
+	listItemPattern     = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s+(.*)$`)
+	ingredientHeadings  = []string{"ingredients"}
+	instructionHeadings = []string{"instructions", "directions", "method", "steps", "preparation"}
+)
+
+// WriteMarkdown renders a recipe as a Markdown document.
+func WriteMarkdown(w io.Writer, recipe models.Recipe) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# %s\n\n", strings.TrimSpace(recipe.Name))
+	if len(recipe.Tags) > 0 {
+		fmt.Fprintf(&b, "Tags: %s\n\n", strings.Join(recipe.Tags, ", "))
+	}
+
+	b.WriteString("## Ingredients\n\n")
+	for _, ingredient := range recipe.Ingredients {
+		fmt.Fprintf(&b, "- %s\n", strings.TrimSpace(ingredient))
+	}
+
+	b.WriteString("\n## Instructions\n\n")
+	for i, step := range recipe.Instructions {
+		fmt.Fprintf(&b, "%d. %s\n", i+1, strings.TrimSpace(step))
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ReadMarkdown parses one or more recipes from Markdown. Every level-one
+// heading starts a recipe; list items under an "Ingredients" heading become
+// ingredients and those under "Instructions" (or Directions, Method, Steps)
+// become steps. A "Tags:" line sets the tags.
+func ReadMarkdown(r io.Reader) ([]Row, error) {
+	var rows []Row
+	var current *models.Recipe
+	section := ""
+
+	flush := func() {
+		if current == nil {
+			return
+		}
+		row := Row{Recipe: *current}
+		if len(current.Ingredients) == 0 && len(current.Instructions) == 0 {
+			row.Err = fmt.Errorf("recipe %q has no ingredients or instructions section", current.Name)
+		}
+		rows = append(rows, row)
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case strings.HasPrefix(line, "# "):
+			flush()
+			current = &models.Recipe{Name: strings.TrimSpace(strings.TrimPrefix(line, "# "))}
+			section = ""
+			continue
+		case current == nil || line == "":
+			continue
+		case strings.HasPrefix(line, "#"):
+			section = headingSection(strings.TrimLeft(line, "# "))
+			continue
+		case strings.HasPrefix(strings.ToLower(line), "tags:"):
+			current.Tags = splitTags(line[len("tags:"):])
+			continue
+		}
+
+		item := line
+		if m := listItemPattern.FindStringSubmatch(line); m != nil {
+			item = strings.TrimSpace(m[1])
+		} else if section == "ingredients" {
+			// ingredients are only taken from list items
+			continue
+		}
+
+		switch section {
+		case "ingredients":
+			current.Ingredients = append(current.Ingredients, item)
+		case "instructions":
+			current.Instructions = append(current.Instructions, item)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+
+	if len(rows) == 0 {
+		return nil, errors.New("no recipe found, expected a level-one heading with the recipe name")
+	}
+
+	return rows, nil
+}
+
+func headingSection(heading string) string {
+	heading = strings.ToLower(strings.TrimSpace(heading))
+	for _, h := range ingredientHeadings {
+		if heading == h {
+			return "ingredients"
+		}
+	}
+	for _, h := range instructionHeadings {
+		if heading == h {
+			return "instructions"
+		}
+	}
+	return ""
+}
+
+func splitTags(value string) []string {
+	var tags []string
+	for _, tag := range strings.Split(value, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
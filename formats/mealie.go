@@ -0,0 +1,264 @@
+package formats
+
+import (
+	"archive/zip"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// mealieBookkeeping are the fields of a Mealie recipe that describe the
+// app's copy of it rather than the recipe.
+var mealieBookkeeping = []string{"id", "slug", "userId", "groupId", "householdId", "dateAdded", "dateUpdated", "createdAt", "updatedAt", "update_at", "lastMade", "settings", "extras"}
+
+// ReadMealie reads a Mealie export: a zip archive holding either a JSON
+// file per recipe, with its photo under images/, or a full backup's
+// database.json.
+func ReadMealie(r io.ReaderAt, size int64) ([]Row, error) {
+	archive, err := openArchive(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return readMealie(archive)
+}
+
+func readMealie(archive *zip.Reader) ([]Row, error) {
+	var rows []Row
+	for _, file := range archive.File {
+		switch {
+		case path.Base(file.Name) == "database.json":
+			return readMealieDatabase(archive, file)
+		case strings.HasSuffix(file.Name, ".json"):
+			data, err := readEntry(file)
+			if err != nil {
+				rows = append(rows, Row{Err: err})
+				continue
+			}
+			var object map[string]any
+			if err := json.Unmarshal(data, &object); err != nil {
+				rows = append(rows, Row{Err: fmt.Errorf("%s: %w", file.Name, err)})
+				continue
+			}
+			if _, _, ok := mealieField(object, "recipeIngredient", "recipe_ingredient"); !ok {
+				// not a recipe, such as the export's metadata
+				continue
+			}
+			rows = append(rows, mealieRecipe(object, mealieImage(archive, path.Dir(file.Name))))
+		}
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("no recipes found in the Mealie export")
+	}
+	return rows, nil
+}
+
+// mealieField returns the first of names object has, as newer exports
+// use camelCase and older ones snake_case.
+func mealieField(object map[string]any, names ...string) (any, string, bool) {
+	for _, name := range names {
+		if value, ok := object[name]; ok {
+			return value, name, true
+		}
+	}
+	return nil, "", false
+}
+
+// mealieImage reads the original photo kept under dir/images, if any.
+func mealieImage(archive *zip.Reader, dir string) []byte {
+	for _, file := range archive.File {
+		if path.Dir(file.Name) == path.Join(dir, "images") && strings.HasPrefix(path.Base(file.Name), "original.") {
+			if image, err := readEntry(file); err == nil {
+				return image
+			}
+		}
+	}
+	return nil
+}
+
+// mealieRecipe maps a recipe in the shape of Mealie's API.
+func mealieRecipe(object map[string]any, image []byte) Row {
+	mapped := slices.Clone(mealieBookkeeping)
+	value := func(names ...string) any {
+		v, name, ok := mealieField(object, names...)
+		if ok {
+			mapped = append(mapped, name)
+		}
+		return v
+	}
+
+	row := Row{}
+	row.Recipe.Name = firstText(value("name"))
+	ingredients, _ := value("recipeIngredient", "recipe_ingredient").([]any)
+	for _, ingredient := range ingredients {
+		if text := mealieIngredient(ingredient); text != "" {
+			row.Recipe.Ingredients = append(row.Recipe.Ingredients, text)
+		}
+	}
+	row.Recipe.Instructions = steps(value("recipeInstructions", "recipe_instructions"))
+	row.Recipe.Tags = texts(value("tags"))
+	for _, category := range texts(value("recipeCategory", "recipe_category")) {
+		if !slices.Contains(row.Recipe.Tags, category) {
+			row.Recipe.Tags = append(row.Recipe.Tags, category)
+		}
+	}
+
+	servings, _ := amount(firstText(value("recipeServings", "recipe_servings")))
+	if yield, ok := amount(firstText(value("recipeYield", "recipe_yield"))); servings == 0 && ok {
+		servings = yield
+	}
+	row.Recipe.Servings = int(servings)
+	row.Recipe.PrepMinutes = humanMinutes(firstText(value("prepTime", "prep_time")))
+	row.Recipe.CookMinutes = humanMinutes(firstText(value("performTime", "perform_time", "cookTime", "cook_time")))
+	row.Recipe.TotalTimeMinutes = humanMinutes(firstText(value("totalTime", "total_time")))
+
+	if values, ok := object["nutrition"].(map[string]any); ok {
+		if perServing, ok := schemaNutrition(values); ok {
+			if row.Nutrition = recipeNutrition(perServing, row.Recipe.Servings); row.Nutrition != nil {
+				mapped = append(mapped, "nutrition")
+			}
+		}
+	}
+	if len(image) > 0 {
+		row.Image = image
+		mapped = append(mapped, "image")
+	}
+
+	row.Unmapped = unmapped(object, mapped...)
+	return row
+}
+
+// mealieIngredient writes an ingredient as text: as the user entered it
+// when known, or else from its amount, unit, food and note.
+func mealieIngredient(ingredient any) string {
+	object, ok := ingredient.(map[string]any)
+	if !ok {
+		return firstText(ingredient)
+	}
+	for _, key := range []string{"originalText", "original_text", "display"} {
+		if text := firstText(object[key]); text != "" {
+			return text
+		}
+	}
+	var parts []string
+	if quantity, ok := object["quantity"].(float64); ok && quantity > 0 {
+		parts = append(parts, strconv.FormatFloat(quantity, 'f', -1, 64))
+	}
+	for _, key := range []string{"unit", "food", "note"} {
+		if text := firstText(object[key]); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// readMealieDatabase reads the recipes of a full Mealie backup, whose
+// database.json holds every table as a list of rows. Each recipe is put
+// back into the shape of the API, from its own row and those of the
+// tables around it, and mapped like the recipes of a plain export.
+func readMealieDatabase(archive *zip.Reader, file *zip.File) ([]Row, error) {
+	data, err := readEntry(file)
+	if err != nil {
+		return nil, err
+	}
+	var tables map[string][]map[string]any
+	if err := json.Unmarshal(data, &tables); err != nil {
+		return nil, fmt.Errorf("%s: %w", file.Name, err)
+	}
+
+	names := func(table string) map[string]string {
+		byID := map[string]string{}
+		for _, row := range tables[table] {
+			byID[fmt.Sprint(row["id"])] = firstText(row["name"])
+		}
+		return byID
+	}
+	units, foods, tags, categories := names("ingredient_units"), names("ingredient_foods"), names("tags"), names("categories")
+
+	// rows of the tables linked to recipes, by recipe and in order
+	linked := func(table string) map[string][]map[string]any {
+		byRecipe := map[string][]map[string]any{}
+		for _, row := range tables[table] {
+			id := fmt.Sprint(row["recipe_id"])
+			byRecipe[id] = append(byRecipe[id], row)
+		}
+		for _, rows := range byRecipe {
+			slices.SortStableFunc(rows, func(a, b map[string]any) int {
+				x, _ := a["position"].(float64)
+				y, _ := b["position"].(float64)
+				return cmp.Compare(x, y)
+			})
+		}
+		return byRecipe
+	}
+	ingredients, instructions := linked("recipes_ingredients"), linked("recipe_instructions")
+	recipeTags, recipeCategories, nutrition := linked("recipes_to_tags"), linked("recipes_to_categories"), linked("recipe_nutrition")
+
+	var rows []Row
+	for _, recipe := range tables["recipes"] {
+		id := fmt.Sprint(recipe["id"])
+		object := map[string]any{}
+		for column, value := range recipe {
+			if !mealieColumnBookkeeping(column) {
+				object[column] = value
+			}
+		}
+
+		var list []any
+		for _, row := range ingredients[id] {
+			list = append(list, map[string]any{
+				"original_text": row["original_text"],
+				"quantity":      row["quantity"],
+				"unit":          units[fmt.Sprint(row["unit_id"])],
+				"food":          foods[fmt.Sprint(row["food_id"])],
+				"note":          row["note"],
+			})
+		}
+		object["recipe_ingredient"] = list
+
+		list = nil
+		for _, row := range instructions[id] {
+			list = append(list, map[string]any{"text": row["text"]})
+		}
+		object["recipe_instructions"] = list
+
+		list = nil
+		for _, row := range recipeTags[id] {
+			list = append(list, tags[fmt.Sprint(row["tag_id"])])
+		}
+		object["tags"] = list
+
+		list = nil
+		for _, row := range recipeCategories[id] {
+			list = append(list, categories[fmt.Sprint(row["category_id"])])
+		}
+		object["recipe_category"] = list
+
+		if rows := nutrition[id]; len(rows) > 0 {
+			object["nutrition"] = map[string]any{
+				"calories":            rows[0]["calories"],
+				"proteinContent":      rows[0]["protein_content"],
+				"fatContent":          rows[0]["fat_content"],
+				"carbohydrateContent": rows[0]["carbohydrate_content"],
+			}
+		}
+
+		rows = append(rows, mealieRecipe(object, mealieImage(archive, path.Join(path.Dir(file.Name), "data", "recipes", id))))
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("no recipes found in the Mealie backup")
+	}
+	return rows, nil
+}
+
+// mealieColumnBookkeeping reports whether a column of the recipes table is
+// the app's own, such as keys and timestamps.
+func mealieColumnBookkeeping(column string) bool {
+	return slices.Contains([]string{"id", "slug", "date_added", "date_updated", "last_made"}, column) ||
+		strings.HasSuffix(column, "_id") || strings.HasSuffix(column, "_at") || strings.HasSuffix(column, "_normalized") ||
+		strings.HasPrefix(column, "is_") || strings.HasPrefix(column, "settings")
+}
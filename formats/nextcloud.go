@@ -0,0 +1,75 @@
+package formats
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+)
+
+// nextcloudBookkeeping are the fields of a Nextcloud Cookbook recipe that
+// describe the app's copy of it rather than the recipe.
+var nextcloudBookkeeping = []string{"id", "dateCreated", "dateModified", "printImage", "imagePlaceholderUrl"}
+
+// ReadNextcloud reads a Nextcloud Cookbook export: a zip archive with a
+// folder per recipe, holding its schema.org recipe.json and its photo as
+// full.jpg.
+func ReadNextcloud(r io.ReaderAt, size int64) ([]Row, error) {
+	archive, err := openArchive(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return readNextcloud(archive)
+}
+
+func readNextcloud(archive *zip.Reader) ([]Row, error) {
+	var rows []Row
+	for _, file := range archive.File {
+		if path.Base(file.Name) == "recipe.json" {
+			rows = append(rows, nextcloudRow(archive, file))
+		}
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("no recipe.json found in the Nextcloud Cookbook export")
+	}
+	return rows, nil
+}
+
+func nextcloudRow(archive *zip.Reader, file *zip.File) Row {
+	data, err := readEntry(file)
+	if err != nil {
+		return Row{Err: err}
+	}
+	var object map[string]any
+	if err := json.Unmarshal(data, &object); err != nil {
+		return Row{Err: fmt.Errorf("%s: %w", file.Name, err)}
+	}
+
+	mapped := append([]string{"name", "recipeIngredient", "recipeInstructions", "keywords", "recipeCategory", "recipeYield", "prepTime", "cookTime", "totalTime"}, nextcloudBookkeeping...)
+	row := Row{Recipe: SchemaOrgRecipe(object)}
+	for _, category := range texts(object["recipeCategory"]) {
+		if !slices.Contains(row.Recipe.Tags, category) {
+			row.Recipe.Tags = append(row.Recipe.Tags, category)
+		}
+	}
+
+	if values, ok := object["nutrition"].(map[string]any); ok {
+		if perServing, ok := schemaNutrition(values); ok {
+			if row.Nutrition = recipeNutrition(perServing, row.Recipe.Servings); row.Nutrition != nil {
+				mapped = append(mapped, "nutrition")
+			}
+		}
+	}
+
+	if photo := findEntry(archive, path.Join(path.Dir(file.Name), "full.jpg")); photo != nil {
+		if image, err := readEntry(photo); err == nil {
+			row.Image = image
+			mapped = append(mapped, "image", "imageUrl")
+		}
+	}
+
+	row.Unmapped = unmapped(object, mapped...)
+	return row
+}
@@ -0,0 +1,118 @@
+package formats
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"recipes-api/models"
+)
+
+// paprikaBookkeeping are the fields of a Paprika recipe that describe the
+// app's copy of it rather than the recipe.
+var paprikaBookkeeping = []string{"uid", "hash", "created", "photo", "photo_hash", "photo_large", "on_favorites", "on_grocery_list", "is_pinned", "in_trash", "scale"}
+
+// paprikaNutrients maps the labels of Paprika's free-text nutrition to
+// schema.org/NutritionInformation keys.
+var paprikaNutrients = map[string]string{
+	"calories":           "calories",
+	"protein":            "proteinContent",
+	"fat":                "fatContent",
+	"total fat":          "fatContent",
+	"carbs":              "carbohydrateContent",
+	"carbohydrates":      "carbohydrateContent",
+	"total carbohydrate": "carbohydrateContent",
+}
+
+// ReadPaprika reads a Paprika export (.paprikarecipes): a zip archive of
+// recipes, each gzipped JSON with the photo inlined.
+func ReadPaprika(r io.ReaderAt, size int64) ([]Row, error) {
+	archive, err := openArchive(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return readPaprika(archive)
+}
+
+func readPaprika(archive *zip.Reader) ([]Row, error) {
+	var rows []Row
+	for _, file := range archive.File {
+		if strings.HasSuffix(file.Name, ".paprikarecipe") {
+			rows = append(rows, paprikaRow(file))
+		}
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("no recipes found in the Paprika export")
+	}
+	return rows, nil
+}
+
+func paprikaRow(file *zip.File) Row {
+	data, err := readEntry(file)
+	if err != nil {
+		return Row{Err: err}
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return Row{Err: fmt.Errorf("%s: %w", file.Name, err)}
+	}
+	var object map[string]any
+	if err := json.NewDecoder(io.LimitReader(gz, maxArchiveEntryBytes)).Decode(&object); err != nil {
+		return Row{Err: fmt.Errorf("%s: %w", file.Name, err)}
+	}
+	return paprikaRecipe(object)
+}
+
+func paprikaRecipe(object map[string]any) Row {
+	text := func(key string) string {
+		s, _ := object[key].(string)
+		return s
+	}
+	mapped := append([]string{"name", "ingredients", "directions", "categories", "servings", "prep_time", "cook_time", "total_time"}, paprikaBookkeeping...)
+
+	recipe := models.Recipe{
+		Name:             firstText(object["name"]),
+		Ingredients:      lines(text("ingredients")),
+		Instructions:     lines(text("directions")),
+		Tags:             texts(object["categories"]),
+		PrepMinutes:      humanMinutes(text("prep_time")),
+		CookMinutes:      humanMinutes(text("cook_time")),
+		TotalTimeMinutes: humanMinutes(text("total_time")),
+	}
+	if servings, ok := amount(text("servings")); ok {
+		recipe.Servings = int(servings)
+	}
+	if difficulty := strings.ToLower(text("difficulty")); slices.Contains(models.Difficulties, difficulty) {
+		recipe.Difficulty = difficulty
+		mapped = append(mapped, "difficulty")
+	}
+	row := Row{Recipe: recipe}
+
+	values := map[string]any{}
+	for _, line := range lines(text("nutritional_info")) {
+		if m := nutritionPattern.FindStringSubmatch(line); m != nil {
+			if key, ok := paprikaNutrients[strings.ToLower(m[1])]; ok {
+				values[key] = m[2]
+			}
+		}
+	}
+	if perServing, ok := schemaNutrition(values); ok {
+		if row.Nutrition = recipeNutrition(perServing, recipe.Servings); row.Nutrition != nil {
+			mapped = append(mapped, "nutritional_info")
+		}
+	}
+
+	if photo, err := base64.StdEncoding.DecodeString(text("photo_data")); err == nil && len(photo) > 0 {
+		row.Image = photo
+		mapped = append(mapped, "photo_data", "image_url")
+	}
+
+	row.Unmapped = unmapped(object, mapped...)
+	return row
+}
@@ -0,0 +1,78 @@
+package formats
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"recipes-api/models"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// PDFImage is an optional photo to place under the title. Type is the
+// fpdf image type, "JPG" or "PNG".
+type PDFImage struct {
+	Reader io.Reader
+	Type   string
+}
+
+// WritePDF renders a printable A4 page for the recipe.
+func WritePDF(w io.Writer, recipe models.Recipe, image *PDFImage) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle(recipe.Name, true)
+	pdf.SetMargins(20, 20, 20)
+	pdf.SetAutoPageBreak(true, 20)
+	pdf.AddPage()
+
+	// core fonts are cp1252, so translate the UTF-8 text first
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	width, _ := pdf.GetPageSize()
+	left, _, right, _ := pdf.GetMargins()
+	contentWidth := width - left - right
+
+	pdf.SetFont("Helvetica", "B", 20)
+	pdf.MultiCell(contentWidth, 9, tr(recipe.Name), "", "L", false)
+
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.SetTextColor(100, 100, 100)
+	meta := "Published " + recipe.PublishedAt.UTC().Format("2 January 2006")
+	if len(recipe.Tags) > 0 {
+		meta += "  |  " + strings.Join(recipe.Tags, ", ")
+	}
+	pdf.MultiCell(contentWidth, 6, tr(meta), "", "L", false)
+	pdf.SetTextColor(0, 0, 0)
+	pdf.Ln(4)
+
+	if image != nil {
+		info := pdf.RegisterImageOptionsReader("photo", fpdf.ImageOptions{ImageType: image.Type}, image.Reader)
+		if pdf.Ok() {
+			imageWidth := contentWidth * 0.75
+			imageHeight := imageWidth * info.Height() / info.Width()
+			pdf.ImageOptions("photo", left+(contentWidth-imageWidth)/2, pdf.GetY(), imageWidth, imageHeight, true, fpdf.ImageOptions{ImageType: image.Type}, 0, "")
+			pdf.Ln(4)
+		}
+	}
+
+	heading := func(text string) {
+		pdf.SetFont("Helvetica", "B", 14)
+		pdf.CellFormat(contentWidth, 10, text, "", 1, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 11)
+	}
+
+	heading("Ingredients")
+	for _, ingredient := range recipe.Ingredients {
+		pdf.CellFormat(6, 6, tr("•"), "", 0, "L", false, 0, "")
+		pdf.MultiCell(contentWidth-6, 6, tr(strings.TrimSpace(ingredient)), "", "L", false)
+	}
+	pdf.Ln(4)
+
+	heading("Instructions")
+	for i, step := range recipe.Instructions {
+		pdf.CellFormat(8, 6, fmt.Sprintf("%d.", i+1), "", 0, "L", false, 0, "")
+		pdf.MultiCell(contentWidth-8, 6, tr(strings.TrimSpace(step)), "", "L", false)
+		pdf.Ln(2)
+	}
+
+	return pdf.Output(w)
+}
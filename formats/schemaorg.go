@@ -0,0 +1,146 @@
+package formats
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"recipes-api/models"
+)
+
+var (
+	tagPattern    = regexp.MustCompile(`<[^>]*>`)
+	breakPattern  = regexp.MustCompile(`(?i)<br\s*/?>|</p>|</li>`)
+	numberPattern = regexp.MustCompile(`\d+`)
+	isoDuration   = regexp.MustCompile(`^P(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+)
+
+// SchemaOrgRecipe maps a schema.org/Recipe object, as decoded from JSON-LD,
+// onto a recipe: its name, ingredients, steps, keywords as tags, yield as
+// servings and times. Values may be plain or lists, and steps may be
+// text, HowToSteps or HowToSections of them.
+func SchemaOrgRecipe(object map[string]any) models.Recipe {
+	recipe := models.Recipe{Name: firstText(object["name"])}
+
+	ingredients := object["recipeIngredient"]
+	if ingredients == nil {
+		ingredients = object["ingredients"]
+	}
+	recipe.Ingredients = texts(ingredients)
+	recipe.Instructions = steps(object["recipeInstructions"])
+
+	for _, keywords := range texts(object["keywords"]) {
+		recipe.Tags = append(recipe.Tags, splitTags(keywords)...)
+	}
+	if yield := numberPattern.FindString(firstText(object["recipeYield"])); yield != "" {
+		recipe.Servings, _ = strconv.Atoi(yield)
+	}
+	recipe.PrepMinutes = minutes(firstText(object["prepTime"]))
+	recipe.CookMinutes = minutes(firstText(object["cookTime"]))
+	recipe.TotalTimeMinutes = minutes(firstText(object["totalTime"]))
+	return recipe
+}
+
+// findSchemaRecipe returns the first schema.org/Recipe in a decoded JSON-LD
+// document, looking through lists and @graph.
+func findSchemaRecipe(doc any) (map[string]any, bool) {
+	switch value := doc.(type) {
+	case []any:
+		for _, item := range value {
+			if recipe, ok := findSchemaRecipe(item); ok {
+				return recipe, true
+			}
+		}
+	case map[string]any:
+		for _, typ := range texts(value["@type"]) {
+			if typ == "Recipe" || strings.HasSuffix(typ, "/Recipe") {
+				return value, true
+			}
+		}
+		return findSchemaRecipe(value["@graph"])
+	}
+	return nil, false
+}
+
+// steps reads recipeInstructions. A single text is split into its lines.
+func steps(value any) []string {
+	switch value := value.(type) {
+	case string:
+		var lines []string
+		for _, line := range strings.Split(breakPattern.ReplaceAllString(value, "\n"), "\n") {
+			if line = clean(line); line != "" {
+				lines = append(lines, line)
+			}
+		}
+		return lines
+	case []any:
+		var all []string
+		for _, item := range value {
+			all = append(all, steps(item)...)
+		}
+		return all
+	case map[string]any:
+		if items, ok := value["itemListElement"]; ok {
+			return steps(items)
+		}
+		if text := firstText(value["text"]); text != "" {
+			return []string{text}
+		}
+		if name := firstText(value["name"]); name != "" {
+			return []string{name}
+		}
+	}
+	return nil
+}
+
+// texts returns the non-blank texts of a value or list of values, cleaned
+// of markup.
+func texts(value any) []string {
+	switch value := value.(type) {
+	case string:
+		if text := clean(value); text != "" {
+			return []string{text}
+		}
+	case float64:
+		return []string{strconv.FormatFloat(value, 'f', -1, 64)}
+	case []any:
+		var all []string
+		for _, item := range value {
+			all = append(all, texts(item)...)
+		}
+		return all
+	case map[string]any:
+		return texts(value["name"])
+	}
+	return nil
+}
+
+func firstText(value any) string {
+	if all := texts(value); len(all) > 0 {
+		return all[0]
+	}
+	return ""
+}
+
+// clean strips tags and entities from text and collapses its whitespace.
+func clean(text string) string {
+	return strings.Join(strings.Fields(html.UnescapeString(tagPattern.ReplaceAllString(text, " "))), " ")
+}
+
+// minutes reads an ISO 8601 duration such as PT1H30M, or zero when it
+// isn't one.
+func minutes(value string) int {
+	m := isoDuration.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(value)))
+	if m == nil {
+		return 0
+	}
+	var total float64
+	for i, scale := range []float64{24 * 60, 60, 1, 1.0 / 60} {
+		if m[i+1] != "" {
+			n, _ := strconv.ParseFloat(m[i+1], 64)
+			total += n * scale
+		}
+	}
+	return int(total + 0.5)
+}
@@ -0,0 +1,274 @@
+// Package gql exposes the recipe service as a GraphQL schema.
+package gql
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"recipes-api/authz"
+	"recipes-api/middleware"
+	"recipes-api/models"
+	"recipes-api/service"
+
+	"github.com/graphql-go/graphql"
+)
+
+var nutritionType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Nutrition",
+	Fields: graphql.Fields{
+		"calories": &graphql.Field{Type: graphql.Float},
+		"protein":  &graphql.Field{Type: graphql.Float},
+		"fat":      &graphql.Field{Type: graphql.Float},
+		"carbs":    &graphql.Field{Type: graphql.Float},
+	},
+})
+
+var imageVariantType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "ImageVariant",
+	Fields: graphql.Fields{
+		"name":   &graphql.Field{Type: graphql.String},
+		"url":    &graphql.Field{Type: graphql.String},
+		"width":  &graphql.Field{Type: graphql.Int},
+		"height": &graphql.Field{Type: graphql.Int},
+	},
+})
+
+type imageVariant struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+var imageType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Image",
+	Fields: graphql.Fields{
+		"url":         &graphql.Field{Type: graphql.String},
+		"contentType": &graphql.Field{Type: graphql.String},
+		"size":        &graphql.Field{Type: graphql.Int},
+		"variants": &graphql.Field{
+			Type: graphql.NewList(imageVariantType),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				image, _ := p.Source.(*models.Image)
+				if image == nil {
+					return nil, nil
+				}
+				variants := make([]imageVariant, 0, len(image.Variants))
+				for name, v := range image.Variants {
+					variants = append(variants, imageVariant{Name: name, URL: v.URL, Width: v.Width, Height: v.Height})
+				}
+				sort.Slice(variants, func(i, j int) bool { return variants[i].Width < variants[j].Width })
+				return variants, nil
+			},
+		},
+	},
+})
+
+var recipeType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Recipe",
+	Fields: graphql.Fields{
+		"id":                    &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+		"name":                  &graphql.Field{Type: graphql.String},
+		"slug":                  &graphql.Field{Type: graphql.String},
+		"tags":                  &graphql.Field{Type: graphql.NewList(graphql.String)},
+		"ingredients":           &graphql.Field{Type: graphql.NewList(graphql.String)},
+		"instructions":          &graphql.Field{Type: graphql.NewList(graphql.String)},
+		"instructionsOffloaded": &graphql.Field{Type: graphql.Boolean},
+		"nutrition":             &graphql.Field{Type: nutritionType},
+		"image":                 &graphql.Field{Type: imageType},
+		"publishedAt":           &graphql.Field{Type: graphql.DateTime},
+		"totalTimeMinutes":      &graphql.Field{Type: graphql.Int},
+		"totalTimeEstimated":    &graphql.Field{Type: graphql.Boolean},
+		"prepMinutes":           &graphql.Field{Type: graphql.Int},
+		"cookMinutes":           &graphql.Field{Type: graphql.Int},
+		"difficulty":            &graphql.Field{Type: graphql.String},
+		"cuisineId":             &graphql.Field{Type: graphql.ID},
+		"categoryId":            &graphql.Field{Type: graphql.ID},
+		"servings":              &graphql.Field{Type: graphql.Int},
+		"version":               &graphql.Field{Type: graphql.Int},
+	},
+})
+
+var recipeInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "RecipeInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"name":             &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"tags":             &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.String)},
+		"ingredients":      &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.String)},
+		"instructions":     &graphql.InputObjectFieldConfig{Type: graphql.NewList(graphql.String)},
+		"totalTimeMinutes": &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		"prepMinutes":      &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		"cookMinutes":      &graphql.InputObjectFieldConfig{Type: graphql.Int},
+		"difficulty":       &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"cuisineId":        &graphql.InputObjectFieldConfig{Type: graphql.ID},
+		"categoryId":       &graphql.InputObjectFieldConfig{Type: graphql.ID},
+		"servings":         &graphql.InputObjectFieldConfig{Type: graphql.Int},
+	},
+})
+
+// NewSchema builds the GraphQL schema on top of the recipe service, so
+// GraphQL and REST share caching, revisions and events. Mutations are
+// checked against the route policy as the REST routes they stand for.
+func NewSchema(recipes *service.RecipeService, policy func() []authz.Rule) (graphql.Schema, error) {
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"recipes": &graphql.Field{
+				Type: graphql.NewList(recipeType),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return recipes.List(p.Context)
+				},
+			},
+			"recipe": &graphql.Field{
+				Type: recipeType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					recipe, err := recipes.Get(p.Context, p.Args["id"].(string))
+					if errors.Is(err, service.ErrNotFound) {
+						return nil, nil
+					}
+					if err != nil {
+						return nil, err
+					}
+					// previews are only served by the REST API
+					if !recipe.IsPublished(time.Now()) {
+						return nil, nil
+					}
+					return recipe, nil
+				},
+			},
+			"search": &graphql.Field{
+				Type: graphql.NewList(recipeType),
+				Args: graphql.FieldConfigArgument{
+					"tag": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					tag := p.Args["tag"].(string)
+					if tag == "" {
+						return nil, errors.New("tag is required")
+					}
+					return recipes.Search(p.Context, tag)
+				},
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createRecipe": &graphql.Field{
+				Type: recipeType,
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(recipeInputType)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					if err := authorize(p, policy, "POST", "/recipes"); err != nil {
+						return nil, err
+					}
+					recipe, err := recipes.Create(p.Context, recipeFromInput(p.Args["input"]))
+					if err != nil {
+						return nil, mutationError(err)
+					}
+					return recipe, nil
+				},
+			},
+			"updateRecipe": &graphql.Field{
+				Type: recipeType,
+				Args: graphql.FieldConfigArgument{
+					"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(recipeInputType)},
+					// the version the change was made from, refused when stale
+					"version": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					if err := authorize(p, policy, "PUT", "/recipes/:id"); err != nil {
+						return nil, err
+					}
+					recipe := recipeFromInput(p.Args["input"])
+					recipe.Version = int64(p.Args["version"].(int))
+					recipe, err := recipes.Update(p.Context, p.Args["id"].(string), recipe)
+					if err != nil {
+						return nil, mutationError(err)
+					}
+					return recipe, nil
+				},
+			},
+			"deleteRecipe": &graphql.Field{
+				Type: graphql.Boolean,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					if err := authorize(p, policy, "DELETE", "/recipes/:id"); err != nil {
+						return false, err
+					}
+					if err := recipes.Delete(p.Context, p.Args["id"].(string)); err != nil {
+						return false, err
+					}
+					return true, nil
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query, Mutation: mutation})
+}
+
+// authorize refuses a mutation the caller's role may not make over REST,
+// the role being the one the API's authorization put in the context.
+func authorize(p graphql.ResolveParams, policy func() []authz.Rule, method, route string) error {
+	role := authz.RoleFrom(p.Context)
+	if !authz.Allowed(policy(), role, method, route) {
+		return fmt.Errorf("forbidden: not allowed for role %s", role)
+	}
+	return nil
+}
+
+// mutationError turns a recipe the service refused as invalid into an
+// error naming the field at fault, as the REST API's 422 does.
+func mutationError(err error) error {
+	if message, ok := middleware.DescribeValidation(err); ok {
+		return errors.New(message)
+	}
+	return err
+}
+
+func recipeFromInput(arg any) models.Recipe {
+	input, _ := arg.(map[string]any)
+	name, _ := input["name"].(string)
+	totalTime, _ := input["totalTimeMinutes"].(int)
+	prepTime, _ := input["prepMinutes"].(int)
+	cookTime, _ := input["cookMinutes"].(int)
+	difficulty, _ := input["difficulty"].(string)
+	cuisine, _ := input["cuisineId"].(string)
+	category, _ := input["categoryId"].(string)
+	servings, _ := input["servings"].(int)
+	return models.Recipe{
+		Name:             name,
+		Tags:             stringList(input["tags"]),
+		Ingredients:      stringList(input["ingredients"]),
+		Instructions:     stringList(input["instructions"]),
+		TotalTimeMinutes: totalTime,
+		PrepMinutes:      prepTime,
+		CookMinutes:      cookTime,
+		Difficulty:       difficulty,
+		CuisineID:        cuisine,
+		CategoryID:       category,
+		Servings:         servings,
+	}
+}
+
+func stringList(v any) []string {
+	items, _ := v.([]any)
+	list := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			list = append(list, s)
+		}
+	}
+	return list
+}
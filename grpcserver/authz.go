@@ -0,0 +1,57 @@
+package grpcserver
+
+import (
+	"context"
+	"strings"
+
+	"recipes-api/authz"
+	"recipes-api/recipespb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// route is the REST route a gRPC method stands for, which the policy is
+// checked against.
+type route struct {
+	method string
+	path   string
+}
+
+var routes = map[string]route{
+	recipespb.RecipeService_List_FullMethodName:   {"GET", "/recipes"},
+	recipespb.RecipeService_Get_FullMethodName:    {"GET", "/recipes/:id"},
+	recipespb.RecipeService_Create_FullMethodName: {"POST", "/recipes"},
+	recipespb.RecipeService_Update_FullMethodName: {"PUT", "/recipes/:id"},
+	recipespb.RecipeService_Delete_FullMethodName: {"DELETE", "/recipes/:id"},
+	recipespb.RecipeService_Search_FullMethodName: {"GET", "/recipes/search"},
+}
+
+// Authorize applies the route policy of the REST API to gRPC calls, each
+// method checked as the route it stands for. The role comes from the
+// "authorization: Bearer <token>" metadata like the REST API's header;
+// unknown tokens and anonymous callers turned away are Unauthenticated,
+// other roles PermissionDenied. Methods without a route are denied.
+func Authorize(tokens authz.Tokens, policy func() []authz.Rule) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		role := authz.RoleAnonymous
+		if values := metadata.ValueFromIncomingContext(ctx, "authorization"); len(values) > 0 {
+			r, ok := tokens.Role(strings.TrimPrefix(values[0], "Bearer "))
+			if !ok {
+				return nil, status.Error(codes.Unauthenticated, "invalid token")
+			}
+			role = r
+		}
+
+		r, ok := routes[info.FullMethod]
+		if !ok || !authz.Allowed(policy(), role, r.method, r.path) {
+			if role == authz.RoleAnonymous {
+				return nil, status.Error(codes.Unauthenticated, "authorization required")
+			}
+			return nil, status.Errorf(codes.PermissionDenied, "not allowed for role %s", role)
+		}
+		return handler(authz.WithRole(ctx, role), req)
+	}
+}
@@ -0,0 +1,22 @@
+package grpcserver
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// PrimaryWrites refuses the writes of a deployment outside the primary
+// region, named by primary. Unlike REST writes they aren't forwarded:
+// gRPC clients send them to the primary region's listener instead, and
+// FailedPrecondition tells them to rather than to retry here.
+func PrimaryWrites(primary string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if r, ok := routes[info.FullMethod]; !ok || r.method != "GET" {
+			return nil, status.Errorf(codes.FailedPrecondition, "writes are served in the primary region %s", primary)
+		}
+		return handler(ctx, req)
+	}
+}
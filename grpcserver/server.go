@@ -0,0 +1,157 @@
+// Package grpcserver serves the recipe service over gRPC for internal callers.
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"recipes-api/authz"
+	"recipes-api/middleware"
+	"recipes-api/models"
+	"recipes-api/recipespb"
+	"recipes-api/service"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Server implements recipespb.RecipeServiceServer on top of the same
+// service layer as the REST and GraphQL APIs.
+type Server struct {
+	recipespb.UnimplementedRecipeServiceServer
+	recipes *service.RecipeService
+}
+
+func NewServer(recipes *service.RecipeService) *Server {
+	return &Server{recipes: recipes}
+}
+
+func (s *Server) List(ctx context.Context, _ *recipespb.ListRecipesRequest) (*recipespb.ListRecipesResponse, error) {
+	recipes, err := s.recipes.List(ctx)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &recipespb.ListRecipesResponse{Recipes: toProtoList(recipes)}, nil
+}
+
+func (s *Server) Get(ctx context.Context, req *recipespb.GetRecipeRequest) (*recipespb.Recipe, error) {
+	recipe, err := s.recipes.Get(ctx, req.GetId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	// drafts and scheduled recipes are for admins; previews are only
+	// served by the REST API
+	if !recipe.IsPublished(time.Now()) && authz.RoleFrom(ctx) != authz.RoleAdmin {
+		return nil, toStatus(service.ErrNotFound)
+	}
+	return toProto(recipe), nil
+}
+
+func (s *Server) Create(ctx context.Context, req *recipespb.CreateRecipeRequest) (*recipespb.Recipe, error) {
+	if req.GetRecipe() == nil {
+		return nil, status.Error(codes.InvalidArgument, "recipe is required")
+	}
+	recipe, err := s.recipes.Create(ctx, fromProto(req.GetRecipe()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProto(recipe), nil
+}
+
+func (s *Server) Update(ctx context.Context, req *recipespb.UpdateRecipeRequest) (*recipespb.Recipe, error) {
+	if req.GetRecipe() == nil {
+		return nil, status.Error(codes.InvalidArgument, "recipe is required")
+	}
+	recipe, err := s.recipes.Update(ctx, req.GetId(), fromProto(req.GetRecipe()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toProto(recipe), nil
+}
+
+func (s *Server) Delete(ctx context.Context, req *recipespb.DeleteRecipeRequest) (*recipespb.DeleteRecipeResponse, error) {
+	if err := s.recipes.Delete(ctx, req.GetId()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &recipespb.DeleteRecipeResponse{}, nil
+}
+
+func (s *Server) Search(ctx context.Context, req *recipespb.SearchRecipesRequest) (*recipespb.SearchRecipesResponse, error) {
+	if req.GetTag() == "" {
+		return nil, status.Error(codes.InvalidArgument, "tag is required")
+	}
+	recipes, err := s.recipes.Search(ctx, req.GetTag())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &recipespb.SearchRecipesResponse{Recipes: toProtoList(recipes)}, nil
+}
+
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		return status.Error(codes.NotFound, "recipe not found")
+	case errors.Is(err, service.ErrInvalid):
+		if message, ok := middleware.DescribeValidation(err); ok {
+			return status.Error(codes.InvalidArgument, message)
+		}
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, service.ErrConflict):
+		return status.Error(codes.Aborted, err.Error())
+	case errors.Is(err, service.ErrVersionRequired):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, service.ErrReadOnly):
+		return status.Error(codes.Unavailable, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+func toProto(recipe models.Recipe) *recipespb.Recipe {
+	pb := &recipespb.Recipe{
+		Id:           recipe.ID,
+		Name:         recipe.Name,
+		Tags:         recipe.Tags,
+		Ingredients:  recipe.Ingredients,
+		Instructions: recipe.Instructions,
+		PublishedAt:  timestamppb.New(recipe.PublishedAt),
+		Version:      recipe.Version,
+	}
+	if recipe.Nutrition != nil {
+		pb.Nutrition = &recipespb.Nutrition{
+			Calories: recipe.Nutrition.Calories,
+			Protein:  recipe.Nutrition.Protein,
+			Fat:      recipe.Nutrition.Fat,
+			Carbs:    recipe.Nutrition.Carbs,
+		}
+	}
+	if recipe.Image != nil {
+		pb.ImageUrl = recipe.Image.URL
+	}
+	return pb
+}
+
+func toProtoList(recipes []models.Recipe) []*recipespb.Recipe {
+	list := make([]*recipespb.Recipe, 0, len(recipes))
+	for _, recipe := range recipes {
+		list = append(list, toProto(recipe))
+	}
+	return list
+}
+
+// fromProto keeps only the client-editable fields; the service fills in the rest.
+func fromProto(pb *recipespb.Recipe) models.Recipe {
+	return models.Recipe{
+		Name:         pb.GetName(),
+		Tags:         pb.GetTags(),
+		Ingredients:  pb.GetIngredients(),
+		Instructions: pb.GetInstructions(),
+		Version:      pb.GetVersion(),
+	}
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"recipes-api/apierrors"
+	"recipes-api/backup"
+	"recipes-api/cache"
+	"recipes-api/projections"
+	"recipes-api/search"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis"
+	"gorm.io/gorm"
+)
+
+type BackupController struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+	recipeList  *projections.RecipeList
+	// searcher is reindexed after a restore, or nil when search runs on
+	// the database and has no index.
+	searcher search.Searcher
+}
+
+func NewBackupController(db *gorm.DB, redisClient *redis.Client, recipeList *projections.RecipeList, searcher search.Searcher) *BackupController {
+	return &BackupController{db: db, redisClient: redisClient, recipeList: recipeList, searcher: searcher}
+}
+
+// @Summary Export a full backup
+// @Description Stream a dump of the catalog, for restoring with POST /admin/import: the recipes with their instructions, the tags, cuisines and categories and the metadata of the photos, which stay in storage. Trashed recipes are left out.
+// @Tags admin
+// @Produce json
+// @Produce application/gzip
+// @Param format query string false "json (default), or tar.gz for an archive with a file per batch of rows"
+// @Success 200 {string} string
+// @Failure 400 {object} apierrors.Error
+// @Router /admin/export [get]
+func (b *BackupController) ExportHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var write func(context.Context, *gorm.DB, io.Writer) error
+	var contentType, ext string
+	switch c.DefaultQuery("format", "json") {
+	case "json":
+		write, contentType, ext = backup.WriteJSON, "application/json", "json"
+	case "tar.gz", "tgz":
+		write, contentType, ext = backup.WriteTarGz, "application/gzip", "tar.gz"
+	default:
+		apierrors.Write(c, apierrors.BadRequest("Unsupported export format"))
+		return
+	}
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="recipes-backup-%s.%s"`, time.Now().UTC().Format("20060102-150405"), ext))
+	c.Status(http.StatusOK)
+
+	if err := write(ctx, b.db, c.Writer); err != nil {
+		// headers are already sent, so all we can do is cut the stream short
+		slog.ErrorContext(ctx, "Error exporting backup", "error", err)
+	}
+}
+
+// @Summary Restore a full backup
+// @Description Load a dump made by GET /admin/export, JSON or tar.gz, as the raw body or the multipart "file" field. JSON bodies are held to the request size limit, so send large JSON dumps as a file. Recipes and terms are overwritten by ID and nothing is removed; the whole dump is loaded or, on error, none of it.
+// @Tags admin
+// @Accept json
+// @Accept application/gzip
+// @Accept multipart/form-data
+// @Produce json
+// @Success 200 {object} backup.Summary
+// @Failure 400 {object} apierrors.Error
+// @Router /admin/import [post]
+func (b *BackupController) ImportHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var body io.Reader = c.Request.Body
+	if c.ContentType() == "multipart/form-data" {
+		header, err := c.FormFile("file")
+		if err != nil {
+			apierrors.Write(c, apierrors.BadRequest("Missing file field"))
+			return
+		}
+		file, err := header.Open()
+		if err != nil {
+			apierrors.Write(c, apierrors.BadRequest("Failed to read backup"))
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	summary, err := backup.Restore(ctx, b.db, body)
+	switch {
+	case errors.Is(err, backup.ErrInvalid):
+		apierrors.Write(c, apierrors.BadRequest("Invalid backup: {reason}").With("reason", err.Error()))
+		return
+	case err != nil:
+		slog.ErrorContext(ctx, "Error restoring backup", "error", err)
+		apierrors.Write(c, apierrors.Internal("Failed to restore backup"))
+		return
+	}
+
+	// the restore went around the event bus, so the read models are
+	// brought up to date here
+	ctx = context.WithoutCancel(ctx)
+	cache.FlushRecipes(ctx, b.redisClient)
+	if err := b.recipeList.Rebuild(ctx); err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to rebuild recipe list"))
+		return
+	}
+	if b.searcher != nil {
+		if _, err := search.Reindex(ctx, b.db, b.searcher); err != nil {
+			apierrors.Write(c, apierrors.Internal("Failed to reindex recipes"))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
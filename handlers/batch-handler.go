@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"net/http"
+	"recipes-api/apierrors"
+	"recipes-api/middleware"
+	"recipes-api/models"
+	"recipes-api/serializer"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxBatchRecipes caps how many recipes /recipes/batch fetches at once.
+const maxBatchRecipes = 100
+
+// BatchRequest is the body of POST /recipes/batch.
+type BatchRequest struct {
+	IDs []string `json:"ids"`
+}
+
+// BatchResponse holds the recipes of a batch fetch in the order their IDs
+// were asked for, and the IDs that have no recipe.
+type BatchResponse struct {
+	Recipes  []models.Recipe `json:"recipes"`
+	NotFound []string        `json:"notFound"`
+}
+
+// @Summary Get recipes by ID
+// @Description Get the recipes with the given IDs in one request, in the order asked for. IDs without a recipe, or of drafts for anyone but admins, are listed in notFound. Offloaded instructions are left out, as in listings. POST the IDs instead when there are too many for a URL.
+// @Tags recipes
+// @Produce json
+// @Param ids query string true "Recipe IDs, comma separated, at most 100"
+// @Success 200 {object} BatchResponse
+// @Failure 400 {object} apierrors.Error
+// @Router /recipes/batch [get]
+func (r *RecipeController) BatchRecipesHandler(c *gin.Context) {
+	r.batch(c, strings.Split(c.Query("ids"), ","))
+}
+
+// @Summary Get recipes by ID
+// @Description Get the recipes with the IDs in the body, as GET /recipes/batch does. It only reads, so it is served while the API is read-only.
+// @Tags recipes
+// @Accept json
+// @Produce json
+// @Param request body BatchRequest true "Recipe IDs, at most 100"
+// @Success 200 {object} BatchResponse
+// @Failure 400 {object} apierrors.Error
+// @Router /recipes/batch [post]
+func (r *RecipeController) BatchRecipesPostHandler(c *gin.Context) {
+	var req BatchRequest
+	if err := middleware.BindJSON(c, &req); err != nil {
+		bindFailed(c, err)
+		return
+	}
+	r.batch(c, req.IDs)
+}
+
+func (r *RecipeController) batch(c *gin.Context, requested []string) {
+	ctx := c.Request.Context()
+
+	var ids []string
+	for _, id := range requested {
+		if id = strings.TrimSpace(id); id != "" && !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		apierrors.Write(c, apierrors.BadRequest("ids is required"))
+		return
+	}
+	if len(ids) > maxBatchRecipes {
+		apierrors.Write(c, apierrors.BadRequest("At most {max} recipes can be fetched at once").With("max", strconv.Itoa(maxBatchRecipes)))
+		return
+	}
+
+	recipes, err := r.recipes.GetMany(ctx, ids)
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to fetch recipes"))
+		return
+	}
+	recipes = visible(c, recipes)
+
+	response := BatchResponse{Recipes: recipes, NotFound: []string{}}
+	for _, id := range ids {
+		if !slices.ContainsFunc(recipes, func(recipe models.Recipe) bool { return recipe.ID == id }) {
+			response.NotFound = append(response.NotFound, id)
+		}
+	}
+	serializer.JSON(c, http.StatusOK, response)
+}
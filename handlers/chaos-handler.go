@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+	"recipes-api/apierrors"
+	"recipes-api/chaos"
+	"recipes-api/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Get fault injection settings
+// @Description Get the current chaos configuration
+// @Tags admin
+// @Produce json
+// @Success 200 {object} chaos.Config
+// @Router /admin/chaos [get]
+func GetChaosHandler(injector *chaos.Injector) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, injector.Config())
+	}
+}
+
+// @Summary Update fault injection settings
+// @Description Replace the chaos configuration: extra latency, random 500s and Redis/DB errors at the given rates
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param config body chaos.Config true "Fault rates"
+// @Success 200 {object} chaos.Config
+// @Failure 400 {object} apierrors.Error
+// @Router /admin/chaos [put]
+func UpdateChaosHandler(injector *chaos.Injector) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var cfg chaos.Config
+		if err := middleware.BindJSON(c, &cfg); err != nil {
+			bindFailed(c, err)
+			return
+		}
+		if err := injector.SetConfig(cfg); err != nil {
+			apierrors.Write(c, apierrors.BadRequest(err.Error()))
+			return
+		}
+		c.JSON(http.StatusOK, cfg)
+	}
+}
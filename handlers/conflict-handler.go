@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"recipes-api/apierrors"
+	"recipes-api/conflicts"
+	"recipes-api/middleware"
+	"recipes-api/models"
+	"recipes-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// conflictRequest carries the two versions to merge. Each should include
+// fieldsUpdatedAt; without a remote version the stored recipe is used.
+type conflictRequest struct {
+	Local  models.Recipe  `json:"local" binding:"required"`
+	Remote *models.Recipe `json:"remote"`
+	// Policy overrides the configured conflict policy.
+	Policy string `json:"policy"`
+}
+
+type ConflictResponse struct {
+	Policy      string                 `json:"policy"`
+	Recipe      models.Recipe          `json:"recipe"`
+	Resolutions []conflicts.Resolution `json:"resolutions"`
+}
+
+// @Summary Resolve an edit conflict
+// @Description Merge a locally edited copy of a recipe with the remote one, field by field. With last-writer-wins the more recently changed value of each field is kept, judged by fieldsUpdatedAt; remote-wins and local-wins always pick one side. The merged recipe is returned, not saved; PUT it to store it.
+// @Tags recipes
+// @Accept json
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Param versions body conflictRequest true "Versions to merge"
+// @Success 200 {object} ConflictResponse
+// @Failure 400 {object} apierrors.Error
+// @Failure 404 {object} apierrors.Error
+// @Router /recipes/{id}/conflicts [post]
+func ResolveConflictHandler(recipes *service.RecipeService, defaultPolicy func() string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		var req conflictRequest
+		if err := middleware.BindJSON(c, &req); err != nil {
+			bindFailed(c, err)
+			return
+		}
+		if req.Policy == "" {
+			req.Policy = defaultPolicy()
+		}
+		if !conflicts.IsPolicy(req.Policy) {
+			apierrors.Write(c, apierrors.BadRequest("Unknown conflict policy {policy}").With("policy", req.Policy))
+			return
+		}
+
+		remote := req.Remote
+		if remote == nil {
+			stored, err := recipes.Get(ctx, c.Param("id"))
+			if errors.Is(err, service.ErrNotFound) {
+				apierrors.Write(c, apierrors.NotFound("Recipe not found"))
+				return
+			}
+			if err != nil {
+				apierrors.Write(c, apierrors.Internal("Failed to fetch recipe"))
+				return
+			}
+			remote = &stored
+		}
+
+		merged, resolutions, err := conflicts.Resolve(req.Local, *remote, req.Policy)
+		if err != nil {
+			apierrors.Write(c, apierrors.BadRequest(err.Error()))
+			return
+		}
+		merged.ID = c.Param("id")
+
+		c.JSON(http.StatusOK, ConflictResponse{Policy: req.Policy, Recipe: merged, Resolutions: resolutions})
+	}
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"recipes-api/apierrors"
+	"recipes-api/conversions"
+	"recipes-api/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ConversionResponse struct {
+	Amount float64 `json:"amount"`
+	From   string  `json:"from"`
+	To     string  `json:"to"`
+	Result float64 `json:"result"`
+}
+
+// @Summary Convert an amount between units
+// @Description Convert a cooking measure between units of volume (ml, l, tsp, tbsp, fl oz, cup, pint, quart, gallon) or of mass (mg, g, kg, oz, lb). Imperial volumes are US customary measures.
+// @Tags ingredients
+// @Produce json
+// @Param from query string true "Unit to convert from, e.g. cup"
+// @Param to query string true "Unit to convert to, e.g. ml"
+// @Param amount query number false "Amount to convert, 1 by default"
+// @Success 200 {object} ConversionResponse
+// @Failure 400 {object} apierrors.Error
+// @Router /convert [get]
+func ConvertHandler(c *gin.Context) {
+	amount := 1.0
+	if s := c.Query("amount"); s != "" {
+		var err error
+		amount, err = strconv.ParseFloat(s, 64)
+		if err != nil || amount < 0 {
+			apierrors.Write(c, apierrors.BadRequest("amount must be a number not below 0"))
+			return
+		}
+	}
+
+	from, err := conversions.Unit(c.Query("from"))
+	if err != nil {
+		apierrors.Write(c, apierrors.BadRequest("Unknown unit {unit}").With("unit", c.Query("from")))
+		return
+	}
+	to, err := conversions.Unit(c.Query("to"))
+	if err != nil {
+		apierrors.Write(c, apierrors.BadRequest("Unknown unit {unit}").With("unit", c.Query("to")))
+		return
+	}
+
+	result, err := conversions.Convert(amount, from, to)
+	if errors.Is(err, conversions.ErrIncompatible) {
+		apierrors.Write(c, apierrors.BadRequest("Can't convert {from} to {to}, one measures volume and the other mass").With("from", from).With("to", to))
+		return
+	}
+	if err != nil {
+		apierrors.Write(c, apierrors.BadRequest("Unknown unit {unit}").With("unit", from))
+		return
+	}
+	c.JSON(http.StatusOK, ConversionResponse{Amount: amount, From: from, To: to, Result: result})
+}
+
+// convertUnits converts the recipe's ingredients into the system asked for
+// with ?units=, metric or imperial. It responds with 400 and returns false
+// for other systems.
+func convertUnits(c *gin.Context, recipe *models.Recipe) bool {
+	switch system := c.Query("units"); system {
+	case "":
+	case conversions.Metric, conversions.Imperial:
+		recipe.Ingredients = conversions.Ingredients(recipe.Ingredients, system)
+	default:
+		apierrors.Write(c, apierrors.BadRequest("units must be metric or imperial"))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"errors"
+	"recipes-api/apierrors"
+	"recipes-api/middleware"
+	"recipes-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondError responds with err, turning the errors services return into
+// the API errors they stand for. Anything else is a 500.
+func respondError(c *gin.Context, err error) {
+	var unknownField *service.UnknownFieldError
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		apierrors.Write(c, apierrors.NotFound("Recipe not found"))
+	case isValidation(err):
+		fields, _ := middleware.FieldErrors(err)
+		apierrors.Write(c, apierrors.Validation(fields))
+	case errors.Is(err, service.ErrInvalid):
+		apierrors.Write(c, apierrors.BadRequest(err.Error()))
+	case errors.Is(err, service.ErrVersionRequired):
+		writeVersionRequired(c)
+	case errors.Is(err, service.ErrReadOnly):
+		apierrors.Write(c, apierrors.ReadOnly("The API is temporarily read-only"))
+	case errors.As(err, &unknownField):
+		apierrors.Write(c, apierrors.BadRequest("Unknown field {field} in fields").With("field", unknownField.Field))
+	default:
+		apierrors.Write(c, err)
+	}
+}
+
+func isValidation(err error) bool {
+	_, ok := middleware.FieldErrors(err)
+	return ok
+}
+
+// bindFailed responds to a body BindJSON rejected: 422 with the field
+// errors when it failed validation, 400 when it couldn't be decoded.
+func bindFailed(c *gin.Context, err error) {
+	if fields, ok := middleware.FieldErrors(err); ok {
+		apierrors.Write(c, apierrors.Validation(fields))
+		return
+	}
+	apierrors.Write(c, apierrors.BadRequest(err.Error()))
+}
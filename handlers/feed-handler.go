@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"recipes-api/apierrors"
+	"recipes-api/apiversion"
+	"recipes-api/cache"
+	"recipes-api/formats"
+	"recipes-api/models"
+	"recipes-api/service"
+	"recipes-api/tracing"
+
+	"github.com/gin-gonic/gin"
+)
+
+const feedSize = 20
+
+// @Summary RSS feed of newest recipes
+// @Description RSS 2.0 feed of the most recently published recipes
+// @Tags recipes
+// @Produce application/rss+xml
+// @Success 200 {string} string
+// @Router /recipes/feed.rss [get]
+func (r *RecipeController) RSSFeedHandler(c *gin.Context) {
+	r.serveFeed(c, cache.FeedRSSKey, "application/rss+xml; charset=utf-8", formats.WriteRSS)
+}
+
+// @Summary Atom feed of newest recipes
+// @Description Atom 1.0 feed of the most recently published recipes
+// @Tags recipes
+// @Produce application/atom+xml
+// @Success 200 {string} string
+// @Router /recipes/feed.atom [get]
+func (r *RecipeController) AtomFeedHandler(c *gin.Context) {
+	r.serveFeed(c, cache.FeedAtomKey, "application/atom+xml; charset=utf-8", formats.WriteAtom)
+}
+
+func (r *RecipeController) serveFeed(c *gin.Context, cacheKey, contentType string, write func(io.Writer, formats.FeedInfo, []models.Recipe) error) {
+	ctx := c.Request.Context()
+
+	if cached, err := tracing.Redis(ctx, r.redisClient).Get(cacheKey).Bytes(); err == nil {
+		c.Data(http.StatusOK, contentType, cached)
+		return
+	}
+
+	var recipes []models.Recipe
+	if err := service.Published(r.db.WithContext(ctx)).Order("published_at DESC").Limit(feedSize).Find(&recipes).Error; err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to fetch recipes"))
+		return
+	}
+
+	// links point at /v1 whichever path the feed was read from, since
+	// both share the cached copy
+	base := baseURL(c) + apiversion.V1
+	info := formats.FeedInfo{Title: "Recipes", BaseURL: base, SelfURL: base + apiversion.Unversioned(c.Request.URL.Path)}
+
+	var buf bytes.Buffer
+	if err := write(&buf, info, recipes); err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to render feed"))
+		return
+	}
+
+	tracing.Redis(ctx, r.redisClient).Set(cacheKey, buf.Bytes(), r.listTTL)
+
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+// baseURL is the externally visible scheme and host of the request,
+// honouring the usual reverse proxy headers.
+func baseURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+
+	host := c.Request.Host
+	if forwarded := c.GetHeader("X-Forwarded-Host"); forwarded != "" {
+		host = forwarded
+	}
+
+	return scheme + "://" + host
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"net/http"
+	"recipes-api/apierrors"
+	"recipes-api/events"
+	"recipes-api/fixtures"
+	"recipes-api/serializer"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary List fixture recipes
+// @Description Deterministic recipes for contract tests (test and sandbox mode only)
+// @Tags fixtures
+// @Produce json
+// @Success 200 {array} models.Recipe
+// @Router /fixtures/recipes [get]
+func ListFixtureRecipesHandler(c *gin.Context) {
+	serializer.List(c, fixtures.Recipes(), serializer.Meta{})
+}
+
+// @Summary Get a fixture recipe
+// @Description Deterministic recipe for contract tests (test and sandbox mode only)
+// @Tags fixtures
+// @Produce json
+// @Param id path string true "Fixture recipe ID"
+// @Success 200 {object} models.Recipe
+// @Failure 404 {object} apierrors.Error
+// @Router /fixtures/recipes/{id} [get]
+func GetFixtureRecipeHandler(c *gin.Context) {
+	recipe, ok := fixtures.Recipe(c.Param("id"))
+	if !ok {
+		apierrors.Write(c, apierrors.NotFound("Recipe not found"))
+		return
+	}
+
+	serializer.JSON(c, http.StatusOK, recipe)
+}
+
+// @Summary Get a fixture event
+// @Description Deterministic event payload for contract tests (test and sandbox mode only)
+// @Tags fixtures
+// @Produce json
+// @Param type path string true "Event type, e.g. recipe.updated"
+// @Param version query string false "Schema version, defaults to the current one"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} apierrors.Error
+// @Router /fixtures/events/{type} [get]
+func GetFixtureEventHandler(c *gin.Context) {
+	event, ok := fixtures.Event(c.Param("type"))
+	if !ok {
+		apierrors.Write(c, apierrors.NotFound("Event type not found"))
+		return
+	}
+
+	payload, err := event.Versioned(c.DefaultQuery("version", events.CurrentSchemaVersion))
+	if err != nil {
+		apierrors.Write(c, apierrors.NotFound("Schema version not found"))
+		return
+	}
+
+	c.JSON(http.StatusOK, payload)
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"recipes-api/apierrors"
+	"recipes-api/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+)
+
+type graphQLRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// @Summary GraphQL endpoint
+// @Description Run a GraphQL query or mutation against the recipes schema. GET accepts query, operationName and variables as query parameters, and only runs queries.
+// @Tags graphql
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} apierrors.Error
+// @Failure 405 {object} apierrors.Error
+// @Router /graphql [post]
+func GraphQLHandler(schema graphql.Schema) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req graphQLRequest
+		if c.Request.Method == http.MethodGet {
+			req.Query = c.Query("query")
+			req.OperationName = c.Query("operationName")
+			if vars := c.Query("variables"); vars != "" {
+				if err := json.Unmarshal([]byte(vars), &req.Variables); err != nil {
+					apierrors.Write(c, apierrors.BadRequest("Invalid variables"))
+					return
+				}
+			}
+		} else if err := middleware.BindJSON(c, &req); err != nil {
+			bindFailed(c, err)
+			return
+		}
+
+		if req.Query == "" {
+			apierrors.Write(c, apierrors.BadRequest("Query is required"))
+			return
+		}
+		// GET is for reads that may be cached or prefetched, so mutations
+		// have to be POSTed
+		if c.Request.Method == http.MethodGet && middleware.IsGraphQLWrite(req.Query, req.OperationName) {
+			c.Header("Allow", http.MethodPost)
+			apierrors.Write(c, apierrors.MethodNotAllowed("Mutations must be sent with POST"))
+			return
+		}
+
+		result := graphql.Do(graphql.Params{
+			Schema:         schema,
+			RequestString:  req.Query,
+			VariableValues: req.Variables,
+			OperationName:  req.OperationName,
+			Context:        c.Request.Context(),
+		})
+
+		c.JSON(http.StatusOK, result)
+	}
+}
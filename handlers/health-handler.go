@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"recipes-api/tracing"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis"
+	"gorm.io/gorm"
+)
+
+type HealthController struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+}
+
+func NewHealthController(db *gorm.DB, redisClient *redis.Client) *HealthController {
+	return &HealthController{db: db, redisClient: redisClient}
+}
+
+// ComponentStatus is the result of checking one dependency.
+type ComponentStatus struct {
+	Status    string `json:"status"`
+	LatencyMS int64  `json:"latencyMs"`
+	Error     string `json:"error,omitempty"`
+}
+
+type ReadinessResponse struct {
+	Status     string                     `json:"status"`
+	Components map[string]ComponentStatus `json:"components"`
+}
+
+// @Summary Liveness probe
+// @Description Report that the process is up. It doesn't check any dependency.
+// @Tags health
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Router /healthz [get]
+func (h *HealthController) LivenessHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// @Summary Readiness probe
+// @Description Ping Postgres and Redis. Returns 503 with the status of each component when one of them is down.
+// @Tags health
+// @Produce json
+// @Success 200 {object} ReadinessResponse
+// @Failure 503 {object} ReadinessResponse
+// @Router /readyz [get]
+func (h *HealthController) ReadinessHandler(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+
+	checks := map[string]func(context.Context) error{
+		"postgres": func(ctx context.Context) error {
+			sqlDB, err := h.db.DB()
+			if err != nil {
+				return err
+			}
+			return sqlDB.PingContext(ctx)
+		},
+		"redis": func(ctx context.Context) error {
+			return tracing.Redis(ctx, h.redisClient).Ping().Err()
+		},
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	resp := ReadinessResponse{Status: "ok", Components: map[string]ComponentStatus{}}
+	for name, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			err := check(ctx)
+
+			status := ComponentStatus{Status: "up", LatencyMS: time.Since(start).Milliseconds()}
+			if err != nil {
+				status.Status = "down"
+				status.Error = err.Error()
+			}
+			mu.Lock()
+			resp.Components[name] = status
+			if err != nil {
+				resp.Status = "unavailable"
+			}
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	code := http.StatusOK
+	if resp.Status != "ok" {
+		code = http.StatusServiceUnavailable
+	}
+	c.JSON(code, resp)
+}
@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"recipes-api/apierrors"
+	"recipes-api/events"
+	"recipes-api/models"
+	"recipes-api/serializer"
+	"recipes-api/service"
+	"recipes-api/storage"
+	"recipes-api/thumbnails"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis"
+	"github.com/rs/xid"
+	"gorm.io/gorm"
+)
+
+var allowedImageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+var errImageTooLarge = errors.New("image exceeds the maximum allowed size")
+
+type ImageController struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+	store       storage.Store
+	thumbnails  *thumbnails.Service
+	events      *events.Bus
+	maxBytes    int64
+}
+
+// NewImageController returns a controller for recipe images. A nil store
+// disables uploads.
+func NewImageController(db *gorm.DB, redisClient *redis.Client, store storage.Store, thumbnailService *thumbnails.Service, bus *events.Bus, maxBytes int64) *ImageController {
+	return &ImageController{db: db, redisClient: redisClient, store: store, thumbnails: thumbnailService, events: bus, maxBytes: maxBytes}
+}
+
+// @Summary Upload a recipe image
+// @Description Upload a JPEG, PNG or WebP photo for a recipe, replacing any existing one
+// @Tags recipes
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Param image formData file true "Image file"
+// @Success 200 {object} models.Recipe
+// @Failure 400 {object} apierrors.Error
+// @Failure 404 {object} apierrors.Error
+// @Failure 413 {object} apierrors.Error
+// @Failure 415 {object} apierrors.Error
+// @Router /recipes/{id}/image [post]
+func (i *ImageController) UploadImageHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	if i.store == nil {
+		apierrors.Write(c, apierrors.Unavailable("Image storage is not configured"))
+		return
+	}
+
+	id := c.Param("id")
+
+	var recipe models.Recipe
+	if err := i.db.WithContext(ctx).Where("id = ?", id).First(&recipe).Error; err != nil {
+		apierrors.Write(c, apierrors.NotFound("Recipe not found"))
+		return
+	}
+
+	// leave room for the multipart framing around the file itself
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, i.maxBytes+1<<20)
+
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		apierrors.Write(c, apierrors.BadRequest("Expected a multipart/form-data request"))
+		return
+	}
+
+	var part io.Reader
+	for {
+		p, err := reader.NextPart()
+		if err != nil {
+			apierrors.Write(c, apierrors.BadRequest("Missing image field"))
+			return
+		}
+		if p.FormName() == "image" {
+			part = p
+			break
+		}
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(part, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		apierrors.Write(c, apierrors.BadRequest("Failed to read image"))
+		return
+	}
+
+	contentType := http.DetectContentType(head[:n])
+	ext, ok := allowedImageTypes[contentType]
+	if !ok {
+		apierrors.Write(c, apierrors.UnsupportedMediaType("Unsupported image type {type}").With("type", contentType))
+		return
+	}
+
+	body := &sizeLimitedReader{r: io.MultiReader(bytes.NewReader(head[:n]), part), max: i.maxBytes}
+	key := fmt.Sprintf("recipes/%s/%s%s", recipe.ID, xid.New().String(), ext)
+
+	url, err := i.store.Put(ctx, key, body, contentType)
+	if err != nil {
+		if body.exceeded {
+			apierrors.Write(c, apierrors.TooLarge(errImageTooLarge.Error()))
+			return
+		}
+		apierrors.Write(c, apierrors.Internal("Failed to store image"))
+		return
+	}
+
+	before := recipe
+	previous := recipe.Image
+	image := &models.Image{Key: key, URL: url, ContentType: contentType, Size: body.read}
+
+	if err := i.db.WithContext(ctx).Model(&recipe).Select("image").Updates(models.Recipe{Image: image}).Error; err != nil {
+		i.store.Delete(ctx, key)
+		apierrors.Write(c, apierrors.Internal("Failed to save image"))
+		return
+	}
+
+	if previous != nil {
+		i.deleteObjects(context.WithoutCancel(ctx), previous)
+	}
+	service.ClearCache(ctx, i.redisClient, recipe.ID)
+	i.thumbnails.Enqueue(ctx, recipe.ID, key)
+	recipe.Image = image
+	i.publishUpdate(ctx, before, recipe)
+
+	serializer.JSON(c, http.StatusOK, recipe)
+}
+
+// @Summary Delete a recipe image
+// @Description Remove the photo of a recipe from storage
+// @Tags recipes
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} apierrors.Error
+// @Router /recipes/{id}/image [delete]
+func (i *ImageController) DeleteImageHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	if i.store == nil {
+		apierrors.Write(c, apierrors.Unavailable("Image storage is not configured"))
+		return
+	}
+
+	id := c.Param("id")
+
+	var recipe models.Recipe
+	if err := i.db.WithContext(ctx).Where("id = ?", id).First(&recipe).Error; err != nil {
+		apierrors.Write(c, apierrors.NotFound("Recipe not found"))
+		return
+	}
+
+	if recipe.Image == nil {
+		apierrors.Write(c, apierrors.NotFound("Recipe has no image"))
+		return
+	}
+
+	if err := i.deleteObjects(ctx, recipe.Image); err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to delete image"))
+		return
+	}
+
+	before := recipe
+	if err := i.db.WithContext(ctx).Model(&recipe).Update("image", nil).Error; err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to delete image"))
+		return
+	}
+	service.ClearCache(ctx, i.redisClient, recipe.ID)
+	recipe.Image = nil
+	i.publishUpdate(ctx, before, recipe)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Image has been deleted"})
+}
+
+// Attach stores data as the photo of a recipe that has none yet, such as
+// one just imported. The caller clears the cache and publishes the event.
+func (i *ImageController) Attach(ctx context.Context, recipe *models.Recipe, data []byte) error {
+	if i.store == nil {
+		return errors.New("image storage is not configured")
+	}
+	if int64(len(data)) > i.maxBytes {
+		return errImageTooLarge
+	}
+	contentType := http.DetectContentType(data)
+	ext, ok := allowedImageTypes[contentType]
+	if !ok {
+		return fmt.Errorf("unsupported image type %s", contentType)
+	}
+
+	key := fmt.Sprintf("recipes/%s/%s%s", recipe.ID, xid.New().String(), ext)
+	url, err := i.store.Put(ctx, key, bytes.NewReader(data), contentType)
+	if err != nil {
+		return err
+	}
+	image := &models.Image{Key: key, URL: url, ContentType: contentType, Size: int64(len(data))}
+	if err := i.db.WithContext(ctx).Model(recipe).Select("image").Updates(models.Recipe{Image: image}).Error; err != nil {
+		i.store.Delete(ctx, key)
+		return err
+	}
+	i.thumbnails.Enqueue(ctx, recipe.ID, key)
+	recipe.Image = image
+	return nil
+}
+
+func (i *ImageController) publishUpdate(ctx context.Context, before, after models.Recipe) {
+	event := events.NewEvent(events.RecipeUpdated, after)
+	event.Changes = events.Diff(before, after)
+	i.events.Publish(ctx, event)
+}
+
+// deleteObjects removes the original image and its resized variants from storage.
+func (i *ImageController) deleteObjects(ctx context.Context, image *models.Image) error {
+	var firstErr error
+	for _, key := range image.Keys() {
+		if err := i.store.Delete(ctx, key); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// sizeLimitedReader fails once more than max bytes have been read.
+type sizeLimitedReader struct {
+	r        io.Reader
+	max      int64
+	read     int64
+	exceeded bool
+}
+
+func (s *sizeLimitedReader) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	s.read += int64(n)
+	if s.read > s.max {
+		s.exceeded = true
+		return n, errImageTooLarge
+	}
+	return n, err
+}
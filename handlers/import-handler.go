@@ -0,0 +1,404 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"recipes-api/apierrors"
+	"recipes-api/events"
+	"recipes-api/formats"
+	"recipes-api/metrics"
+	"recipes-api/middleware"
+	"recipes-api/models"
+	"recipes-api/outbound"
+	"recipes-api/serializer"
+	"recipes-api/service"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	importBatchSize = 100
+	maxImportBytes  = 10 << 20
+	// maxArchiveImportBytes bounds uploads of app exports, which carry
+	// their photos.
+	maxArchiveImportBytes = 200 << 20
+	// maxImportedTags is as many tags as a recipe may have.
+	maxImportedTags = 30
+)
+
+type importResult struct {
+	Row    int    `json:"row"`
+	Status string `json:"status"`
+	ID     string `json:"id,omitempty"`
+	Name   string `json:"name,omitempty"`
+	Error  string `json:"error,omitempty"`
+	// Unmapped lists the fields of the source recipe that were left out,
+	// such as ratings, or a photo that couldn't be stored.
+	Unmapped []string `json:"unmapped,omitempty"`
+}
+
+type importReport struct {
+	Created int            `json:"created"`
+	Skipped int            `json:"skipped"`
+	Failed  int            `json:"failed"`
+	Results []importResult `json:"results"`
+}
+
+// @Summary Import recipes
+// @Description Bulk import recipes from a JSON array, CSV or Markdown, or from a Paprika, Mealie or Nextcloud Cookbook export (raw body or multipart "file" field). Exports bring their yield, nutrition and photos along; the fields of each recipe that have no place here are listed as unmapped. Recipes whose name already exists are skipped; invalid rows are reported without failing the others.
+// @Tags recipes
+// @Accept json
+// @Accept text/csv
+// @Accept text/markdown
+// @Accept application/zip
+// @Accept multipart/form-data
+// @Produce json
+// @Param format query string false "json, csv, markdown, paprika, mealie, nextcloud or zip for any of those exports, detected from the content type or file extension when omitted"
+// @Success 200 {object} importReport
+// @Failure 400 {object} apierrors.Error
+// @Router /recipes/import [post]
+func (r *RecipeController) ImportRecipesHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	limit := int64(maxImportBytes)
+	if c.ContentType() == "multipart/form-data" || c.ContentType() == "application/zip" || slices.Contains(formats.ArchiveFormats, strings.ToLower(c.Query("format"))) {
+		limit = maxArchiveImportBytes
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+
+	rows, err := readImport(c)
+	if err != nil {
+		apierrors.Write(c, apierrors.BadRequest(err.Error()))
+		return
+	}
+
+	results := make([]importResult, len(rows))
+	var pending []int
+
+	var existing []string
+	if err := r.db.WithContext(ctx).Model(&models.Recipe{}).Pluck("LOWER(name)", &existing).Error; err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to import recipes"))
+		return
+	}
+	seen := map[string]bool{}
+	for _, name := range existing {
+		seen[name] = true
+	}
+
+	for i, row := range rows {
+		results[i] = importResult{Row: i + 1, Name: row.Recipe.Name, Unmapped: row.Unmapped}
+
+		if row.Err == nil {
+			row.Err = validateImportedRecipe(row.Recipe)
+		}
+		if row.Err != nil {
+			results[i].Status = "error"
+			results[i].Error = row.Err.Error()
+			continue
+		}
+
+		key := strings.ToLower(strings.TrimSpace(row.Recipe.Name))
+		if seen[key] {
+			results[i].Status = "skipped"
+			results[i].Error = "A recipe with this name already exists"
+			continue
+		}
+
+		// server-managed fields are set here, as Create sets them
+		rows[i].Recipe.ID = r.ids.NewID()
+		rows[i].Recipe.PublishedAt = time.Now().UTC()
+		rows[i].Recipe.Nutrition = row.Nutrition
+		rows[i].Recipe.Image = nil
+		rows[i].Recipe.Slug = ""
+		rows[i].Recipe.Version = 1
+		rows[i].Recipe.FieldsUpdatedAt = nil
+		rows[i].Recipe.InstructionsOffloaded = false
+		service.ApplyTotalTime(&rows[i].Recipe)
+		if err := service.Check(r.db.WithContext(ctx), rows[i].Recipe); err != nil {
+			if !errors.Is(err, service.ErrInvalid) {
+				apierrors.Write(c, apierrors.Internal("Failed to import recipes"))
+				return
+			}
+			results[i].Status = "error"
+			results[i].Error = err.Error()
+			continue
+		}
+		seen[key] = true
+		pending = append(pending, i)
+	}
+
+	var created []int
+	// slugs picked for recipes not inserted yet
+	claimed := map[string]bool{}
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for start := 0; start < len(pending); start += importBatchSize {
+			end := min(start+importBatchSize, len(pending))
+
+			if err := tx.SavePoint("import_batch").Error; err != nil {
+				return err
+			}
+
+			batch := make([]models.Recipe, 0, end-start)
+			for _, i := range pending[start:end] {
+				if err := service.AssignSlug(tx, &rows[i].Recipe, claimed); err != nil {
+					return err
+				}
+				row, err := service.OffloadInstructions(tx, rows[i].Recipe)
+				if err != nil {
+					return err
+				}
+				rows[i].Recipe.InstructionsOffloaded = row.InstructionsOffloaded
+				batch = append(batch, row)
+			}
+
+			err := tx.Create(&batch).Error
+			if err == nil {
+				err = service.LinkTags(tx, batch...)
+			}
+			if err != nil {
+				if err := tx.RollbackTo("import_batch").Error; err != nil {
+					return err
+				}
+				// the driver's error may describe the schema, so it is
+				// logged rather than reported
+				slog.ErrorContext(ctx, "Error inserting imported recipes", "rows", end-start, "error", err)
+				for _, i := range pending[start:end] {
+					results[i].Status = "error"
+					results[i].Error = "Failed to store recipe"
+				}
+				continue
+			}
+
+			for k, i := range pending[start:end] {
+				results[i].Status = "created"
+				results[i].ID = rows[i].Recipe.ID
+				rows[i].Recipe = batch[k]
+			}
+			created = append(created, pending[start:end]...)
+		}
+		return nil
+	})
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to import recipes"))
+		return
+	}
+
+	for _, i := range created {
+		if len(rows[i].Image) == 0 {
+			continue
+		}
+		if err := r.images.Attach(ctx, &rows[i].Recipe, rows[i].Image); err != nil {
+			slog.WarnContext(ctx, "Error storing imported photo", "recipe_id", rows[i].Recipe.ID, "error", err)
+			results[i].Unmapped = append(results[i].Unmapped, "image")
+		}
+	}
+
+	if len(created) > 0 {
+		r.clearRecipeCache(ctx)
+	}
+	for _, i := range created {
+		recipe := rows[i].Recipe
+		if recipe.Nutrition == nil {
+			r.nutrition.Enqueue(ctx, recipe.ID)
+		}
+		r.events.Publish(ctx, events.NewEvent(events.RecipeCreated, recipe))
+	}
+
+	report := importReport{Results: results}
+	for _, result := range results {
+		switch result.Status {
+		case "created":
+			report.Created++
+		case "skipped":
+			report.Skipped++
+		default:
+			report.Failed++
+		}
+	}
+	metrics.ImportsFailed.Add(float64(report.Failed))
+
+	c.JSON(http.StatusOK, report)
+}
+
+// readImport picks the parser from the format query parameter, falling back
+// to the uploaded file's extension or the request content type. Exports of
+// other apps are zip archives, read whole as they can't be streamed.
+func readImport(c *gin.Context) ([]formats.Row, error) {
+	format := strings.ToLower(c.Query("format"))
+	var body io.Reader = c.Request.Body
+
+	if c.ContentType() == "multipart/form-data" {
+		header, err := c.FormFile("file")
+		if err != nil {
+			return nil, errors.New("missing file field")
+		}
+		file, err := header.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		body = file
+
+		if format == "" {
+			format = strings.TrimPrefix(strings.ToLower(filepath.Ext(header.Filename)), ".")
+			if format == "paprikarecipes" {
+				format = "paprika"
+			}
+		}
+	}
+
+	if format == "" {
+		switch c.ContentType() {
+		case "text/csv":
+			format = "csv"
+		case "text/markdown":
+			format = "markdown"
+		case "application/zip":
+			format = "zip"
+		default:
+			format = "json"
+		}
+	}
+
+	archive := format == "zip" || slices.Contains(formats.ArchiveFormats, format)
+	var data []byte
+	if archive {
+		var err error
+		if data, err = io.ReadAll(body); err != nil {
+			return nil, err
+		}
+	}
+
+	switch format {
+	case "json":
+		return formats.ReadJSON(body)
+	case "csv":
+		return formats.ReadCSV(body)
+	case "markdown", "md":
+		return formats.ReadMarkdown(body)
+	case "zip":
+		return formats.ReadArchive(bytes.NewReader(data), int64(len(data)))
+	case "paprika":
+		return formats.ReadPaprika(bytes.NewReader(data), int64(len(data)))
+	case "mealie":
+		return formats.ReadMealie(bytes.NewReader(data), int64(len(data)))
+	case "nextcloud":
+		return formats.ReadNextcloud(bytes.NewReader(data), int64(len(data)))
+	default:
+		return nil, fmt.Errorf("unsupported import format %q", format)
+	}
+}
+
+func validateImportedRecipe(recipe models.Recipe) error {
+	message, ok := middleware.DescribeValidation(middleware.Validate(recipe))
+	if !ok {
+		return nil
+	}
+	return errors.New(message)
+}
+
+// importURLRequest is the body of POST /recipes/import-url.
+type importURLRequest struct {
+	URL string `json:"url" binding:"required"`
+}
+
+// @Summary Import a recipe from a web page
+// @Description Fetch a web page and make a draft of the recipe on it, read from its schema.org JSON-LD or microdata or, failing those, from lists marked as ingredients and instructions. Publish the draft once it has been checked. Like creating a recipe, one that looks the same as an existing recipe is refused with 409 unless force is true.
+// @Tags recipes
+// @Accept json
+// @Produce json
+// @Param request body importURLRequest true "URL of the page"
+// @Param force query bool false "Create the draft even if it looks like a duplicate"
+// @Success 200 {object} models.Recipe
+// @Failure 400 {object} apierrors.Error
+// @Failure 409 {object} apierrors.Error
+// @Failure 422 {object} apierrors.Error
+// @Failure 502 {object} apierrors.Error
+// @Router /recipes/import-url [post]
+func ImportURLHandler(recipes *service.RecipeService, policy outbound.Policy) gin.HandlerFunc {
+	client := outbound.NewGuarded(policy, 15*time.Second, 1)
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		var req importURLRequest
+		if err := middleware.BindJSON(c, &req); err != nil {
+			bindFailed(c, err)
+			return
+		}
+		if err := policy.CheckURL(req.URL); err != nil {
+			apierrors.Write(c, apierrors.BadRequest("url must be an http or https URL the API may fetch"))
+			return
+		}
+
+		page, err := http.NewRequestWithContext(ctx, http.MethodGet, req.URL, nil)
+		if err != nil {
+			apierrors.Write(c, apierrors.BadRequest("url must be an http or https URL the API may fetch"))
+			return
+		}
+		page.Header.Set("Accept", "text/html,application/xhtml+xml")
+		resp, err := client.Do(page)
+		if err != nil {
+			slog.WarnContext(ctx, "Error fetching recipe page", "url", req.URL, "error", err)
+			apierrors.Write(c, apierrors.BadGateway("Failed to fetch the page"))
+			return
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			apierrors.Write(c, apierrors.BadGateway("The page responded with {status}").With("status", strconv.Itoa(resp.StatusCode)))
+			return
+		}
+
+		recipe, err := formats.ReadHTML(resp.Body)
+		switch {
+		case errors.Is(err, outbound.ErrTooLarge):
+			apierrors.Write(c, apierrors.BadGateway("The page is too large"))
+			return
+		case err != nil:
+			apierrors.Write(c, apierrors.New(http.StatusUnprocessableEntity, apierrors.CodeValidationFailed, "No recipe found on the page"))
+			return
+		}
+
+		// keywords are often too many or too long to be tags
+		recipe.Tags = slices.DeleteFunc(recipe.Tags, func(tag string) bool {
+			return utf8.RuneCountInString(tag) > middleware.MaxTagLength
+		})
+		recipe.Tags = recipe.Tags[:min(len(recipe.Tags), maxImportedTags)]
+		recipe.Draft = true
+		if fields, ok := middleware.FieldErrors(middleware.Validate(recipe)); ok {
+			apierrors.Write(c, apierrors.Validation(fields))
+			return
+		}
+
+		if c.Query("force") != "true" {
+			duplicate, found, err := recipes.FindDuplicate(ctx, recipe)
+			if err != nil {
+				apierrors.Write(c, apierrors.Internal("Failed to check for duplicates"))
+				return
+			}
+			if found {
+				apierrors.Write(c, apierrors.Conflict("Recipe {id} looks the same, create it with force=true if it isn't").
+					With("id", duplicate.ID).WithDetails(gin.H{"recipeId": duplicate.ID}))
+				return
+			}
+		}
+
+		recipe, err = recipes.Create(ctx, recipe)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		serializer.JSON(c, http.StatusOK, recipe)
+	}
+}
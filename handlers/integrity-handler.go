@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"net/http"
+	"recipes-api/integrity"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Run the integrity checks
+// @Description Find and remove orphaned images, instructions, previews, revisions, subscriptions and list entries, redirects to purged recipes, blank or repeated tags and cached copies of deleted recipes. With dryRun=true nothing is changed.
+// @Tags admin
+// @Produce json
+// @Param dryRun query bool false "Only report what would be fixed"
+// @Success 200 {object} integrity.Report
+// @Router /admin/integrity [post]
+func IntegrityHandler(checker *integrity.Checker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report := checker.Run(c.Request.Context(), c.Query("dryRun") == "true")
+		c.JSON(http.StatusOK, report)
+	}
+}
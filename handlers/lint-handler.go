@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+	"recipes-api/apierrors"
+	"recipes-api/lint"
+	"recipes-api/middleware"
+	"recipes-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LintResponse struct {
+	Warnings []lint.Warning `json:"warnings"`
+}
+
+// @Summary Lint a draft recipe
+// @Description Check a draft for common problems: ingredients used in the steps but not listed, listed ingredients never used, oven steps without a temperature, empty and overly long steps. Nothing is saved.
+// @Tags recipes
+// @Accept json
+// @Produce json
+// @Param recipe body models.Recipe true "Draft recipe"
+// @Success 200 {object} LintResponse
+// @Failure 400 {object} apierrors.Error
+// @Router /lint/recipe [post]
+func LintRecipeHandler(c *gin.Context) {
+	var recipe models.Recipe
+	// drafts are linted before they would pass validation
+	if err := middleware.DecodeJSON(c, &recipe); err != nil {
+		apierrors.Write(c, apierrors.BadRequest(err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, LintResponse{Warnings: lint.Recipe(recipe.Ingredients, recipe.Instructions)})
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"recipes-api/live"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// @Summary Live recipe updates
+// @Description Open a WebSocket that streams change events for subscribed recipe IDs or tags. Send {"action":"subscribe","ids":[...],"tags":[...]}, {"action":"unsubscribe",...} or {"action":"list"}.
+// @Tags recipes
+// @Success 101 {string} string "Switching Protocols"
+// @Router /ws [get]
+func LiveUpdatesHandler(hub *live.Hub, origins func() []string) gin.HandlerFunc {
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		// same-origin pages and the configured CORS origins may connect
+		CheckOrigin: func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				return true
+			}
+			if u, err := url.Parse(origin); err == nil && u.Host == r.Host {
+				return true
+			}
+			allowed := origins()
+			return slices.Contains(allowed, origin) || slices.Contains(allowed, "*")
+		},
+	}
+
+	return func(c *gin.Context) {
+		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			// the upgrader has already written an error response
+			return
+		}
+		hub.Serve(conn)
+	}
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"recipes-api/apierrors"
+	"recipes-api/mealplans"
+	"recipes-api/middleware"
+	"recipes-api/models"
+	"recipes-api/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxMealPlanEntries caps the meals planned in one request, a week of four
+// meals with a few recipes each.
+const maxMealPlanEntries = 100
+
+type MealPlanController struct {
+	recipes   *service.RecipeService
+	mealPlans *mealplans.Service
+}
+
+func NewMealPlanController(recipeService *service.RecipeService, mealPlanService *mealplans.Service) *MealPlanController {
+	return &MealPlanController{recipes: recipeService, mealPlans: mealPlanService}
+}
+
+type mealPlanEntryRequest struct {
+	Day      string `json:"day" binding:"required"`
+	Slot     string `json:"slot" binding:"required,oneof=breakfast lunch dinner snack"`
+	RecipeID string `json:"recipeId" binding:"required"`
+	Servings int    `json:"servings" binding:"min=0"`
+	Note     string `json:"note" binding:"max=500"`
+}
+
+type mealPlanRequest struct {
+	Entries []mealPlanEntryRequest `json:"entries" binding:"required,min=1,dive"`
+}
+
+// @Summary Plan meals
+// @Description Put recipes on the meal plan for meals of given days. A meal can have several recipes.
+// @Tags mealplans
+// @Accept json
+// @Produce json
+// @Param plan body mealPlanRequest true "Meals to plan"
+// @Success 201 {array} models.MealPlanEntry
+// @Failure 400 {object} apierrors.Error
+// @Failure 422 {object} apierrors.Error
+// @Router /mealplans [post]
+func (m *MealPlanController) CreateMealPlanHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req mealPlanRequest
+	if err := middleware.BindJSON(c, &req); err != nil {
+		bindFailed(c, err)
+		return
+	}
+	if len(req.Entries) > maxMealPlanEntries {
+		apierrors.Write(c, apierrors.BadRequest("At most {max} meals can be planned at once").With("max", strconv.Itoa(maxMealPlanEntries)))
+		return
+	}
+
+	entries := make([]models.MealPlanEntry, 0, len(req.Entries))
+	ids := make([]string, 0, len(req.Entries))
+	for _, e := range req.Entries {
+		if _, err := mealplans.ParseDay(e.Day); err != nil {
+			apierrors.Write(c, apierrors.BadRequest("Invalid day {day}, expected YYYY-MM-DD").With("day", e.Day))
+			return
+		}
+		entries = append(entries, models.MealPlanEntry{Day: e.Day, Slot: e.Slot, RecipeID: e.RecipeID, Servings: e.Servings, Note: e.Note})
+		ids = append(ids, e.RecipeID)
+	}
+
+	recipes, err := m.recipes.GetMany(ctx, ids)
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to fetch recipes"))
+		return
+	}
+	found := map[string]bool{}
+	for _, recipe := range visible(c, recipes) {
+		found[recipe.ID] = true
+	}
+	for _, id := range ids {
+		if !found[id] {
+			apierrors.Write(c, apierrors.NotFound("Recipe {id} not found").With("id", id))
+			return
+		}
+	}
+
+	entries, err = m.mealPlans.Add(ctx, entries)
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to save meal plan"))
+		return
+	}
+	c.JSON(http.StatusCreated, entries)
+}
+
+// @Summary Get a week's meal plan
+// @Description Get the meals planned for every day of an ISO week, Monday first, with the recipes' summaries
+// @Tags mealplans
+// @Produce json
+// @Param week path string true "ISO week, e.g. 2026-W07"
+// @Success 200 {object} mealplans.Plan
+// @Failure 400 {object} apierrors.Error
+// @Router /mealplans/{week} [get]
+func (m *MealPlanController) GetMealPlanHandler(c *gin.Context) {
+	week, ok := parseWeek(c)
+	if !ok {
+		return
+	}
+
+	plan, err := m.mealPlans.Week(c.Request.Context(), week)
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to fetch meal plan"))
+		return
+	}
+	c.JSON(http.StatusOK, plan)
+}
+
+// @Summary Copy last week's meal plan
+// @Description Plan the meals of the week before into the week, on the same weekdays. Meals the week already has are not copied again.
+// @Tags mealplans
+// @Produce json
+// @Param week path string true "ISO week, e.g. 2026-W07"
+// @Success 200 {object} mealplans.Plan
+// @Failure 400 {object} apierrors.Error
+// @Router /mealplans/{week}/copy-last-week [post]
+func (m *MealPlanController) CopyLastWeekHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	week, ok := parseWeek(c)
+	if !ok {
+		return
+	}
+
+	if _, err := m.mealPlans.CopyPreviousWeek(ctx, week); err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to copy meal plan"))
+		return
+	}
+	plan, err := m.mealPlans.Week(ctx, week)
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to fetch meal plan"))
+		return
+	}
+	c.JSON(http.StatusOK, plan)
+}
+
+// @Summary Clear a day of the meal plan
+// @Tags mealplans
+// @Produce json
+// @Param day path string true "Date, YYYY-MM-DD"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} apierrors.Error
+// @Router /mealplans/days/{day} [delete]
+func (m *MealPlanController) ClearDayHandler(c *gin.Context) {
+	day := c.Param("day")
+	if _, err := mealplans.ParseDay(day); err != nil {
+		apierrors.Write(c, apierrors.BadRequest("Invalid day {day}, expected YYYY-MM-DD").With("day", day))
+		return
+	}
+
+	deleted, err := m.mealPlans.ClearDay(c.Request.Context(), day)
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to clear meal plan"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Day has been cleared", "deleted": deleted})
+}
+
+// @Summary Remove a meal from the meal plan
+// @Tags mealplans
+// @Produce json
+// @Param id path string true "Meal plan entry ID"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} apierrors.Error
+// @Router /mealplans/entries/{id} [delete]
+func (m *MealPlanController) DeleteEntryHandler(c *gin.Context) {
+	err := m.mealPlans.Delete(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, mealplans.ErrNotFound) {
+		apierrors.Write(c, apierrors.NotFound("Meal plan entry not found"))
+		return
+	}
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to delete meal plan entry"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Meal plan entry has been deleted"})
+}
+
+func parseWeek(c *gin.Context) (mealplans.Week, bool) {
+	week, err := mealplans.ParseWeek(c.Param("week"))
+	if err != nil {
+		apierrors.Write(c, apierrors.BadRequest("Invalid week {week}, expected an ISO week such as 2026-W07").With("week", c.Param("week")))
+		return week, false
+	}
+	return week, true
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"recipes-api/apierrors"
+	"recipes-api/middleware"
+	"recipes-api/serializer"
+	"recipes-api/service"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type mergeRequest struct {
+	// Into is the id of the recipe to keep.
+	Into string `json:"into" binding:"required"`
+}
+
+// @Summary Merge a recipe into another
+// @Description Fold a duplicate into the recipe to keep: its tags and subscribers are added to that recipe, it goes to the trash, and links to it redirect to the kept recipe from then on.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "ID of the recipe to merge away"
+// @Param merge body mergeRequest true "Recipe to keep"
+// @Success 200 {object} Recipe
+// @Failure 400 {object} apierrors.Error
+// @Failure 404 {object} apierrors.Error
+// @Router /admin/recipes/{id}/merge [post]
+func (r *RecipeController) MergeRecipeHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req mergeRequest
+	if err := middleware.BindJSON(c, &req); err != nil {
+		bindFailed(c, err)
+		return
+	}
+
+	recipe, err := r.recipes.Merge(ctx, c.Param("id"), req.Into)
+	if errors.Is(err, service.ErrNotFound) {
+		apierrors.Write(c, apierrors.NotFound("Recipe not found"))
+		return
+	}
+	if errors.Is(err, service.ErrInvalid) {
+		apierrors.Write(c, apierrors.BadRequest(err.Error()))
+		return
+	}
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to merge recipes"))
+		return
+	}
+
+	serializer.JSON(c, http.StatusOK, recipe)
+}
+
+// redirectMoved answers a request for a recipe that was merged away, or
+// for an old slug, with a permanent redirect to the same path of its
+// replacement, and reports whether it did.
+func redirectMoved(c *gin.Context, db *gorm.DB, id string) bool {
+	ctx := c.Request.Context()
+
+	to, err := service.FindRedirect(db.WithContext(ctx), id)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error looking up redirect", "recipe_id", id, "error", err)
+		return false
+	}
+	if to == "" {
+		return false
+	}
+
+	// the last match, since a slug can be the same as a path segment
+	// before it, like /recipes/slug/slug
+	path := c.Request.URL.Path
+	i := strings.LastIndex(path, "/"+id)
+	if i < 0 {
+		return false
+	}
+	location := path[:i] + "/" + to + path[i+len(id)+1:]
+	if c.Request.URL.RawQuery != "" {
+		location += "?" + c.Request.URL.RawQuery
+	}
+	c.Redirect(http.StatusMovedPermanently, location)
+	return true
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+	"recipes-api/apierrors"
+	"recipes-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Get recipe nutrition
+// @Description Get the nutrition totals computed for a recipe
+// @Tags recipes
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Success 200 {object} models.Nutrition
+// @Failure 404 {object} apierrors.Error
+// @Router /recipes/{id}/nutrition [get]
+func (r *RecipeController) GetNutritionHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := c.Param("id")
+
+	var recipe models.Recipe
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&recipe).Error; err != nil {
+		apierrors.Write(c, apierrors.NotFound("Recipe not found"))
+		return
+	}
+	if !canView(c, r.previews, recipe) {
+		return
+	}
+
+	if recipe.Nutrition == nil {
+		apierrors.Write(c, apierrors.NotFound("Nutrition facts are not available for this recipe yet"))
+		return
+	}
+
+	c.JSON(http.StatusOK, recipe.Nutrition)
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net/http"
+	"recipes-api/apierrors"
+	"recipes-api/ingredients"
+	"recipes-api/middleware"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const maxParseLines = 200
+
+type parseIngredientsRequest struct {
+	Lines []string `json:"lines" binding:"required"`
+}
+
+type ParseIngredientsResponse struct {
+	Ingredients []ingredients.Line `json:"ingredients"`
+}
+
+// @Summary Parse ingredient lines
+// @Description Split free-text ingredient lines into quantity, unit, item and comment, with a confidence score per line. Nothing is saved; clients can use it to preview how lines will be read.
+// @Tags ingredients
+// @Accept json
+// @Produce json
+// @Param request body parseIngredientsRequest true "Ingredient lines, at most 200"
+// @Success 200 {object} ParseIngredientsResponse
+// @Failure 400 {object} apierrors.Error
+// @Router /parse/ingredients [post]
+func ParseIngredientsHandler(c *gin.Context) {
+	var request parseIngredientsRequest
+	if err := middleware.BindJSON(c, &request); err != nil {
+		bindFailed(c, err)
+		return
+	}
+	if len(request.Lines) > maxParseLines {
+		apierrors.Write(c, apierrors.BadRequest("At most {max} lines can be parsed at once").With("max", strconv.Itoa(maxParseLines)))
+		return
+	}
+
+	response := ParseIngredientsResponse{Ingredients: make([]ingredients.Line, 0, len(request.Lines))}
+	for _, line := range request.Lines {
+		response.Ingredients = append(response.Ingredients, ingredients.Parse(line))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"recipes-api/apierrors"
+	"recipes-api/formats"
+	"recipes-api/models"
+	"recipes-api/previews"
+	"recipes-api/service"
+	"recipes-api/storage"
+	"recipes-api/translations"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+var pdfImageTypes = map[string]string{
+	"image/jpeg": "JPG",
+	"image/png":  "PNG",
+}
+
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+type PDFController struct {
+	db           *gorm.DB
+	store        storage.Store
+	previews     *previews.Service
+	translations *translations.Service
+}
+
+func NewPDFController(db *gorm.DB, store storage.Store, previewService *previews.Service, translationService *translations.Service) *PDFController {
+	return &PDFController{db: db, store: store, previews: previewService, translations: translationService}
+}
+
+// @Summary Download a recipe as PDF
+// @Description Render a printable PDF with the title, photo, ingredients and numbered instructions
+// @Tags recipes
+// @Produce application/pdf
+// @Param id path string true "Recipe ID"
+// @Param lang query string false "Language to read the recipe in, instead of Accept-Language"
+// @Success 200 {file} file
+// @Failure 404 {object} apierrors.Error
+// @Router /recipes/{id}/pdf [get]
+func (p *PDFController) RecipePDFHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := c.Param("id")
+
+	var recipe models.Recipe
+	if err := p.db.WithContext(ctx).Where("id = ?", id).First(&recipe).Error; err != nil {
+		if !redirectMoved(c, p.db, id) {
+			apierrors.Write(c, apierrors.NotFound("Recipe not found"))
+		}
+		return
+	}
+	if !canView(c, p.previews, recipe) {
+		return
+	}
+	if err := service.LoadInstructions(p.db.WithContext(ctx), &recipe); err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to fetch recipe"))
+		return
+	}
+	localize(c, p.translations, &recipe)
+
+	var buf bytes.Buffer
+	if err := formats.WritePDF(&buf, recipe, p.loadImage(c, recipe.Image)); err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to render PDF"))
+		return
+	}
+
+	filename := unsafeFilenameChars.ReplaceAllString(recipe.Name, "-") + ".pdf"
+	c.Header("Content-Disposition", fmt.Sprintf(`inline; filename="%s"`, filename))
+	c.Data(http.StatusOK, "application/pdf", buf.Bytes())
+}
+
+// loadImage fetches the photo to print, preferring the medium JPEG variant.
+// A missing or unreadable image only leaves the photo out of the PDF.
+func (p *PDFController) loadImage(c *gin.Context, image *models.Image) *formats.PDFImage {
+	if p.store == nil || image == nil {
+		return nil
+	}
+
+	key, contentType := image.Key, image.ContentType
+	if variant, ok := image.Variants["medium"]; ok {
+		key, contentType = variant.Key, "image/jpeg"
+	}
+
+	imageType, ok := pdfImageTypes[contentType]
+	if !ok {
+		return nil
+	}
+
+	obj, err := p.store.Get(c.Request.Context(), key)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Error loading image for PDF", "key", key, "error", err)
+		return nil
+	}
+	defer obj.Close()
+
+	data, err := io.ReadAll(obj)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Error loading image for PDF", "key", key, "error", err)
+		return nil
+	}
+
+	return &formats.PDFImage{Reader: bytes.NewReader(data), Type: imageType}
+}
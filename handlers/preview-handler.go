@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"recipes-api/apierrors"
+	"recipes-api/apiversion"
+	"recipes-api/authz"
+	"recipes-api/middleware"
+	"recipes-api/models"
+	"recipes-api/previews"
+	"recipes-api/service"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPreviewTTL = 7 * 24 * time.Hour
+	maxPreviewTTL     = 30 * 24 * time.Hour
+)
+
+type PreviewController struct {
+	recipes  *service.RecipeService
+	previews *previews.Service
+}
+
+func NewPreviewController(recipeService *service.RecipeService, previewService *previews.Service) *PreviewController {
+	return &PreviewController{recipes: recipeService, previews: previewService}
+}
+
+type previewRequest struct {
+	// ExpiresIn is a duration such as "72h"; a week when left out.
+	ExpiresIn string `json:"expiresIn"`
+}
+
+type PreviewResponse struct {
+	models.RecipePreview
+	Token string `json:"token"`
+	URL   string `json:"url"`
+}
+
+// @Summary Create a preview link
+// @Description Create a link reviewers can read a draft or scheduled recipe with before it is published. It works until it expires, at most 30 days, or is revoked.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Param preview body previewRequest false "Expiry"
+// @Success 201 {object} PreviewResponse
+// @Failure 400 {object} apierrors.Error
+// @Failure 404 {object} apierrors.Error
+// @Failure 409 {object} apierrors.Error
+// @Router /admin/recipes/{id}/previews [post]
+func (p *PreviewController) CreatePreviewHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req previewRequest
+	if c.Request.ContentLength != 0 {
+		if err := middleware.BindJSON(c, &req); err != nil {
+			bindFailed(c, err)
+			return
+		}
+	}
+	ttl := defaultPreviewTTL
+	if req.ExpiresIn != "" {
+		d, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil || d <= 0 || d > maxPreviewTTL {
+			apierrors.Write(c, apierrors.BadRequest("expiresIn must be a duration between 0 and 720h"))
+			return
+		}
+		ttl = d
+	}
+
+	recipe, ok := p.find(c)
+	if !ok {
+		return
+	}
+	if recipe.IsPublished(time.Now()) {
+		apierrors.Write(c, apierrors.Conflict("Recipe is already published"))
+		return
+	}
+
+	preview, token, err := p.previews.Create(ctx, recipe.ID, ttl)
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to create preview"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, PreviewResponse{
+		RecipePreview: preview,
+		Token:         token,
+		URL:           baseURL(c) + apiversion.V1 + "/recipes/" + recipe.ID + "?preview=" + token,
+	})
+}
+
+// @Summary List preview links
+// @Description List the preview links of a recipe, newest first, including expired and revoked ones. Tokens are only shown when a link is created.
+// @Tags admin
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Success 200 {array} models.RecipePreview
+// @Failure 404 {object} apierrors.Error
+// @Router /admin/recipes/{id}/previews [get]
+func (p *PreviewController) ListPreviewsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	recipe, ok := p.find(c)
+	if !ok {
+		return
+	}
+
+	list, err := p.previews.List(ctx, recipe.ID)
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to fetch previews"))
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// @Summary Revoke a preview link
+// @Tags admin
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Param previewId path string true "Preview ID"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} apierrors.Error
+// @Router /admin/recipes/{id}/previews/{previewId} [delete]
+func (p *PreviewController) RevokePreviewHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	err := p.previews.Revoke(ctx, c.Param("id"), c.Param("previewId"))
+	if errors.Is(err, previews.ErrNotFound) {
+		apierrors.Write(c, apierrors.NotFound("Preview not found"))
+		return
+	}
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to revoke preview"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Preview has been revoked"})
+}
+
+func (p *PreviewController) find(c *gin.Context) (models.Recipe, bool) {
+	recipe, err := p.recipes.Get(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, service.ErrNotFound) {
+		apierrors.Write(c, apierrors.NotFound("Recipe not found"))
+		return recipe, false
+	}
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to fetch recipe"))
+		return recipe, false
+	}
+	return recipe, true
+}
+
+// canView reports whether the caller may read the recipe: anyone once it
+// is published, before that admins and holders of a preview token. Others
+// get a 404, so unpublished recipes can't be discovered.
+func canView(c *gin.Context, previewService *previews.Service, recipe models.Recipe) bool {
+	if recipe.IsPublished(time.Now()) {
+		return true
+	}
+	c.Header("Cache-Control", "private, no-store")
+
+	if c.GetString(middleware.RoleKey) == authz.RoleAdmin {
+		return true
+	}
+	if token := c.Query("preview"); token != "" {
+		ok, err := previewService.Allows(c.Request.Context(), token, recipe.ID)
+		if err != nil {
+			apierrors.Write(c, apierrors.Internal("Failed to check preview"))
+			return false
+		}
+		if ok {
+			return true
+		}
+	}
+
+	apierrors.Write(c, apierrors.NotFound("Recipe not found"))
+	return false
+}
+
+// visible leaves out the recipes the caller may not read, the way canView
+// would, so fetching several by ID treats unpublished ones as missing.
+// Preview tokens are for a single recipe and don't apply here.
+func visible(c *gin.Context, recipes []models.Recipe) []models.Recipe {
+	if c.GetString(middleware.RoleKey) == authz.RoleAdmin {
+		return recipes
+	}
+	now := time.Now()
+	return slices.DeleteFunc(recipes, func(recipe models.Recipe) bool {
+		return !recipe.IsPublished(now)
+	})
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"recipes-api/apierrors"
+	"recipes-api/models"
+	"recipes-api/quality"
+	"recipes-api/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type QualityResponse struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	quality.Report
+}
+
+type LowQualityRecipe struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Score int    `json:"score"`
+}
+
+// @Summary Get a recipe's quality score
+// @Description Score a recipe from 0 to 100 on having an image, nutrition, cleanly parsed ingredients, an explicit total time and at least four steps
+// @Tags admin
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Success 200 {object} QualityResponse
+// @Failure 404 {object} apierrors.Error
+// @Router /admin/recipes/{id}/quality [get]
+func (r *RecipeController) RecipeQualityHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	recipe, err := r.recipes.Get(ctx, c.Param("id"))
+	if errors.Is(err, service.ErrNotFound) {
+		apierrors.Write(c, apierrors.NotFound("Recipe not found"))
+		return
+	}
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to fetch recipe"))
+		return
+	}
+
+	c.JSON(http.StatusOK, QualityResponse{ID: recipe.ID, Name: recipe.Name, Report: quality.Score(recipe)})
+}
+
+// @Summary List low quality recipes
+// @Description List the recipes scoring below a threshold, worst first, for cleanup
+// @Tags admin
+// @Produce json
+// @Param below query int false "Score threshold (default 60)"
+// @Param limit query int false "Maximum number of recipes to return (default 100)"
+// @Success 200 {array} LowQualityRecipe
+// @Router /admin/recipes/quality [get]
+func (r *RecipeController) ListLowQualityHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	below, err := strconv.Atoi(c.DefaultQuery("below", strconv.Itoa(quality.LowScore)))
+	if err != nil || below <= 0 || below > 100 {
+		apierrors.Write(c, apierrors.BadRequest("below must be a score between 1 and 100"))
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 {
+		limit = 100
+	}
+
+	var summaries []models.RecipeSummary
+	if err := r.db.WithContext(ctx).Where("quality < ?", below).Order("quality, published_at").Limit(limit).Find(&summaries).Error; err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to fetch recipes"))
+		return
+	}
+
+	recipes := make([]LowQualityRecipe, 0, len(summaries))
+	for _, summary := range summaries {
+		recipes = append(recipes, LowQualityRecipe{ID: summary.ID, Name: summary.Name, Score: summary.Quality})
+	}
+
+	c.JSON(http.StatusOK, recipes)
+}
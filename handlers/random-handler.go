@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"recipes-api/apierrors"
+	"recipes-api/models"
+	"recipes-api/serializer"
+	"recipes-api/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxRandomRecipes caps how many recipes GET /recipes/random returns.
+const maxRandomRecipes = 50
+
+// @Summary Get random recipes
+// @Description Pick published recipes at random, optionally among those matching the filters. Tags must all be present.
+// @Tags recipes
+// @Produce json
+// @Param count query int false "How many recipes to pick, 1 by default and at most 50"
+// @Param tag query []string false "Only recipes with these tags" collectionFormat(multi)
+// @Param maxTotalTime query int false "Only recipes that take at most this many minutes"
+// @Param difficulty query string false "Only recipes of these difficulties, comma separated: easy, medium, hard"
+// @Param cuisine query string false "Only recipes of this cuisine ID"
+// @Param category query string false "Only recipes of this category ID"
+// @Success 200 {array} models.Recipe
+// @Failure 400 {object} apierrors.Error
+// @Router /recipes/random [get]
+func (r *RecipeController) RandomRecipesHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	count := 1
+	if param := c.Query("count"); param != "" {
+		n, err := strconv.Atoi(param)
+		if err != nil || n < 1 || n > maxRandomRecipes {
+			apierrors.Write(c, apierrors.BadRequest("count must be between 1 and {max}").With("max", strconv.Itoa(maxRandomRecipes)))
+			return
+		}
+		count = n
+	}
+	filter, ok := parseListFilter(c)
+	if !ok {
+		return
+	}
+
+	// only the IDs are picked in the database, from the compact list
+	// projection, and the recipes read through the cache
+	query := filter.where(service.Published(service.ReadReplica(r.db.WithContext(ctx))).Model(&models.RecipeSummary{}))
+	for _, tag := range c.QueryArray("tag") {
+		query = query.Where("id IN (SELECT recipe_tags.recipe_id FROM recipe_tags JOIN tags ON tags.id = recipe_tags.tag_id WHERE tags.name = ?)", models.TagName(tag))
+	}
+	var ids []string
+	if err := query.Order("random()").Limit(count).Pluck("id", &ids).Error; err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to fetch recipes"))
+		return
+	}
+
+	recipes, err := r.recipes.GetMany(ctx, ids)
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to fetch recipes"))
+		return
+	}
+	serializer.List(c, recipes, serializer.Meta{})
+}
@@ -1,130 +1,678 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
 	"net/http"
+	"recipes-api/apierrors"
+	"recipes-api/cache"
+	"recipes-api/events"
+	"recipes-api/formats"
+	"recipes-api/ids"
+	"recipes-api/ingredients"
+	"recipes-api/middleware"
 	"recipes-api/models"
+	"recipes-api/nutrition"
+	"recipes-api/previews"
+	"recipes-api/search"
+	"recipes-api/serializer"
+	"recipes-api/service"
+	"recipes-api/tracing"
+	"recipes-api/translations"
+	"recipes-api/views"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-redis/redis"
-	"github.com/rs/xid"
 	"gorm.io/gorm"
 )
 
 type RecipeController struct {
 	db          *gorm.DB
 	redisClient *redis.Client
+	recipes     *service.RecipeService
+	nutrition   *nutrition.Service
+	events      *events.Bus
+	listTTL     time.Duration
+	// rankByQuality reports whether summaries list better recipes first.
+	rankByQuality func() bool
+	searcher      search.Searcher
+	previews      *previews.Service
+	translations  *translations.Service
+	views         *views.Tracker
+	// ids makes the IDs of imported recipes, like the service does for
+	// created ones.
+	ids ids.Generator
+	// images stores the photos that come with imported recipes.
+	images *ImageController
 }
 
-func NewRecipeController(db *gorm.DB, redisClient *redis.Client) *RecipeController {
-	return &RecipeController{db: db, redisClient: redisClient}
+// NewRecipeController creates the controller. Listings and feeds are
+// cached for listTTL. generator should be the one the recipe service uses.
+func NewRecipeController(db *gorm.DB, redisClient *redis.Client, recipeService *service.RecipeService, nutritionService *nutrition.Service, bus *events.Bus, listTTL time.Duration, rankByQuality func() bool, searcher search.Searcher, previewService *previews.Service, translationService *translations.Service, viewTracker *views.Tracker, generator ids.Generator, images *ImageController) *RecipeController {
+	return &RecipeController{db: db, redisClient: redisClient, recipes: recipeService, nutrition: nutritionService, events: bus, listTTL: listTTL, rankByQuality: rankByQuality, searcher: searcher, previews: previewService, translations: translationService, views: viewTracker, ids: generator, images: images}
 }
 
-func (r *RecipeController) clearRecipeCache() {
-	keys := []string{"recipes:all"}
-	for _, k := range keys {
-		r.redisClient.Del(k)
-	}
+func (r *RecipeController) clearRecipeCache(ctx context.Context, ids ...string) {
+	service.ClearCache(ctx, r.redisClient, ids...)
 }
 
 // @summary Create a recipe
-// @Description Create a new recipe
+// @Description Create a new recipe. A recipe with the same name, but for case and punctuation, and mostly the same ingredients is refused as a duplicate with 409, its ID in details.recipeId, unless force is true.
 // @Tags recipes
 // @Accept json
 // @Produce json
 // @Param recipe body Recipe true "Recipe object"
+// @Param force query bool false "Create the recipe even if it looks like a duplicate"
+// @Param Idempotency-Key header string false "Key to retry the request under; a retry gets the first response back instead of creating the recipe again"
 // @Success 200 {object} Recipe
+// @Failure 409 {object} apierrors.Error
+// @Failure 422 {object} apierrors.Error
 // @Router /recipes [post]
 func (r *RecipeController) NewRecipeHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
 	var recipe models.Recipe
-	if err := c.ShouldBindJSON(&recipe); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := middleware.BindJSON(c, &recipe); err != nil {
+		bindFailed(c, err)
 		return
 	}
 
-	recipe.ID = xid.New().String()
-	recipe.PublishedAt = time.Now()
+	if c.Query("force") != "true" {
+		duplicate, found, err := r.recipes.FindDuplicate(ctx, recipe)
+		if err != nil {
+			apierrors.Write(c, apierrors.Internal("Failed to check for duplicates"))
+			return
+		}
+		if found {
+			apierrors.Write(c, apierrors.Conflict("Recipe {id} looks the same, create it with force=true if it isn't").
+				With("id", duplicate.ID).WithDetails(gin.H{"recipeId": duplicate.ID}))
+			return
+		}
+	}
 
-	if err := r.db.Create(&recipe).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	recipe, err := r.recipes.Create(ctx, recipe)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
-	r.clearRecipeCache()
-
-	c.JSON(http.StatusOK, recipe)
+	serializer.JSON(c, http.StatusOK, recipe)
 }
 
 // @Summary List Recipes
-// @Description Get all recipes
+// @Description Get all recipes. With view=summary only the compact list fields (id, name, tags, thumb, publishedAt, totalTimeMinutes, difficulty, cuisineId, categoryId) are returned, newest first; with the rankByQuality setting on, more complete recipes come first.
 // @Tags recipes
 // @Produce json
+// @Param view query string false "Set to summary for the compact list projection"
+// @Param maxTotalTime query int false "Only recipes that take at most this many minutes"
+// @Param difficulty query string false "Only recipes of these difficulties, comma separated: easy, medium, hard"
+// @Param cuisine query string false "Only recipes of this cuisine ID"
+// @Param category query string false "Only recipes of this category ID"
+// @Param fields query string false "Only these fields, and id, of each recipe, comma separated, e.g. id,name,tags"
 // @Success 200 {array} Recipe
+// @Failure 400 {object} apierrors.Error
 // @Router /recipes [get]
 func (r *RecipeController) ListRecipesHandler(c *gin.Context) {
-	cacheKey := "recipes:all"
+	ctx := c.Request.Context()
+
+	filter, ok := parseListFilter(c)
+	if !ok {
+		return
+	}
+
+	if c.Query("view") == "summary" {
+		r.listSummaries(c, filter)
+		return
+	}
+	if fields := serializer.RequestedFields(c); len(fields) > 0 {
+		r.listColumns(c, filter, fields)
+		return
+	}
+
+	recipes, err := r.recipes.List(ctx)
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to fetch recipes"))
+		return
+	}
+	if filter.active() {
+		recipes = slices.DeleteFunc(recipes, func(recipe models.Recipe) bool {
+			return !filter.matches(models.NewRecipeSummary(recipe))
+		})
+	}
+
+	serializer.List(c, recipes, serializer.Meta{})
+}
+
+// listColumns serves the recipes with only the columns of the requested
+// fields loaded. The filter is applied in the query, since the fields it
+// looks at may not be loaded.
+func (r *RecipeController) listColumns(c *gin.Context, filter listFilter, fields []string) {
+	columns, err := service.Columns(r.db, &models.Recipe{}, fields)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	recipes, err := r.recipes.ListColumns(c.Request.Context(), columns, filter.where)
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to fetch recipes"))
+		return
+	}
+	serializer.List(c, recipes, serializer.Meta{})
+}
+
+// listFilter narrows recipe listings down by total time, difficulty,
+// cuisine and category. It is applied to the cached listings, so they are
+// shared by all filters.
+type listFilter struct {
+	maxTotalTime int
+	difficulties []string
+	cuisine      string
+	category     string
+}
+
+// parseListFilter reads ?maxTotalTime=, ?difficulty=, ?cuisine= and
+// ?category=. It responds with 400 and returns false when one is invalid.
+func parseListFilter(c *gin.Context) (listFilter, bool) {
+	filter := listFilter{cuisine: c.Query("cuisine"), category: c.Query("category")}
+	maxTotalTime, ok := maxTotalTimeParam(c)
+	if !ok {
+		return filter, false
+	}
+	filter.maxTotalTime = maxTotalTime
+	if param := c.Query("difficulty"); param != "" {
+		for _, difficulty := range strings.Split(param, ",") {
+			difficulty = strings.ToLower(strings.TrimSpace(difficulty))
+			if !slices.Contains(models.Difficulties, difficulty) {
+				apierrors.Write(c, apierrors.BadRequest("Unknown difficulty {difficulty}, expected easy, medium or hard").With("difficulty", difficulty))
+				return filter, false
+			}
+			filter.difficulties = append(filter.difficulties, difficulty)
+		}
+	}
+	return filter, true
+}
+
+// where applies the filter to a query of recipes_list.
+func (f listFilter) where(query *gorm.DB) *gorm.DB {
+	if f.maxTotalTime > 0 {
+		query = query.Where("total_time_minutes > 0 AND total_time_minutes <= ?", f.maxTotalTime)
+	}
+	if len(f.difficulties) > 0 {
+		query = query.Where("difficulty IN ?", f.difficulties)
+	}
+	if f.cuisine != "" {
+		query = query.Where("cuisine_id = ?", f.cuisine)
+	}
+	if f.category != "" {
+		query = query.Where("category_id = ?", f.category)
+	}
+	return query
+}
+
+func (f listFilter) active() bool {
+	return f.maxTotalTime > 0 || len(f.difficulties) > 0 || f.cuisine != "" || f.category != ""
+}
+
+// matches reports whether a recipe passes the filter. Recipes without a
+// time or a difficulty don't pass filters on them.
+func (f listFilter) matches(summary models.RecipeSummary) bool {
+	if f.maxTotalTime > 0 && (summary.TotalTimeMinutes <= 0 || summary.TotalTimeMinutes > f.maxTotalTime) {
+		return false
+	}
+	if len(f.difficulties) > 0 && !slices.Contains(f.difficulties, summary.Difficulty) {
+		return false
+	}
+	return (f.cuisine == "" || summary.CuisineID == f.cuisine) && (f.category == "" || summary.CategoryID == f.category)
+}
+
+// listSummaries serves the recipes_list projection, newest first, or by
+// quality score and then newest first when ranking by quality. Requests
+// for some of the fields skip the cache and load only their columns.
+func (r *RecipeController) listSummaries(c *gin.Context, filter listFilter) {
+	ctx := c.Request.Context()
+
+	key, order := cache.RecipeSummariesKey, "published_at DESC"
+	if r.rankByQuality() {
+		key, order = cache.RankedSummariesKey, "quality DESC, published_at DESC"
+	}
+
+	if fields := serializer.RequestedFields(c); len(fields) > 0 {
+		columns, err := service.Columns(r.db, &models.RecipeSummary{}, fields)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		summaries := []models.RecipeSummary{}
+		query := service.Published(service.ReadReplica(r.db.WithContext(ctx))).Scopes(filter.where)
+		if err := query.Select(columns).Order(order).Find(&summaries).Error; err != nil {
+			apierrors.Write(c, apierrors.Internal("Failed to fetch recipes"))
+			return
+		}
+		serializer.List(c, summaries, serializer.Meta{})
+		return
+	}
+
+	var summaries []models.RecipeSummary
+	cached, err := tracing.Redis(ctx, r.redisClient).Get(key).Result()
+	if err != nil || json.Unmarshal([]byte(cached), &summaries) != nil {
+		summaries = nil
+		if err := service.Published(service.ReadReplica(r.db.WithContext(ctx))).Order(order).Find(&summaries).Error; err != nil {
+			apierrors.Write(c, apierrors.Internal("Failed to fetch recipes"))
+			return
+		}
+
+		data, _ := json.Marshal(summaries)
+		tracing.Redis(ctx, r.redisClient).Set(key, data, r.listTTL)
+	}
+
+	if filter.active() {
+		summaries = slices.DeleteFunc(summaries, func(summary models.RecipeSummary) bool {
+			return !filter.matches(summary)
+		})
+	}
+	serializer.List(c, summaries, serializer.Meta{})
+}
+
+// @Summary Get a recipe
+// @Description Get a recipe by id. Drafts and scheduled recipes are only shown to admins and with a preview token; recipes merged into another redirect to it. Append .md to the id to get it as Markdown, or send Accept: application/ld+json for schema.org JSON-LD.
+// @Tags recipes
+// @Produce json
+// @Produce text/markdown
+// @Produce application/ld+json
+// @Param id path string true "Recipe ID, optionally suffixed with .md"
+// @Param lang query string false "Language to read the recipe in, instead of Accept-Language"
+// @Param units query string false "Convert ingredient quantities to metric or imperial"
+// @Param servings query int false "Scale the ingredients to serve this many"
+// @Success 200 {object} models.Recipe
+// @Failure 400 {object} apierrors.Error
+// @Failure 404 {object} apierrors.Error
+// @Router /recipes/{id} [get]
+func (r *RecipeController) GetRecipeHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := c.Param("id")
+	markdown := strings.HasSuffix(id, ".md")
+	id = strings.TrimSuffix(id, ".md")
+
+	recipe, err := r.recipes.Get(ctx, id)
+	if errors.Is(err, service.ErrNotFound) {
+		if !redirectMoved(c, r.db, id) {
+			apierrors.Write(c, apierrors.NotFound("Recipe not found"))
+		}
+		return
+	}
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to fetch recipe"))
+		return
+	}
+	if !canView(c, r.previews, recipe) {
+		return
+	}
+	// previews of drafts aren't views
+	if recipe.IsPublished(time.Now()) {
+		r.views.Record(context.WithoutCancel(ctx), recipe.ID)
+	}
+	// the flushed count only changes every so often, unlike the live one,
+	// so responses can still be cached
+	if views, err := r.views.Flushed(ctx, recipe.ID); err == nil {
+		recipe.ViewCount = views
+	}
+	localize(c, r.translations, &recipe)
+	if !scaleServings(c, &recipe) || !convertUnits(c, &recipe) {
+		return
+	}
+
+	if markdown {
+		var buf bytes.Buffer
+		formats.WriteMarkdown(&buf, recipe)
+		c.Data(http.StatusOK, "text/markdown; charset=utf-8", buf.Bytes())
+		return
+	}
+
+	if c.NegotiateFormat(gin.MIMEJSON, formats.JSONLDContentType) == formats.JSONLDContentType {
+		writeJSONLD(c, formats.JSONLD(recipe))
+		return
+	}
+
+	serializer.JSON(c, http.StatusOK, recipe)
+}
+
+// @Summary Get a recipe by slug
+// @Description Get a recipe by its slug, with the same options and formats as getting it by id. Old slugs of renamed recipes redirect to the current one.
+// @Tags recipes
+// @Produce json
+// @Produce text/markdown
+// @Produce application/ld+json
+// @Param slug path string true "Recipe slug, optionally suffixed with .md"
+// @Success 200 {object} models.Recipe
+// @Failure 404 {object} apierrors.Error
+// @Router /recipes/slug/{slug} [get]
+func (r *RecipeController) GetRecipeBySlugHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	slug := c.Param("slug")
+	suffix := ""
+	if strings.HasSuffix(slug, ".md") {
+		slug, suffix = strings.TrimSuffix(slug, ".md"), ".md"
+	}
+
+	id, err := r.recipes.IDForSlug(ctx, slug)
+	if errors.Is(err, service.ErrNotFound) {
+		if !redirectMoved(c, r.db, slug) {
+			apierrors.Write(c, apierrors.NotFound("Recipe not found"))
+		}
+		return
+	}
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to fetch recipe"))
+		return
+	}
+
+	c.Params = append(c.Params, gin.Param{Key: "id", Value: id + suffix})
+	r.GetRecipeHandler(c)
+}
 
-	// check cache
-	cached, err := r.redisClient.Get(cacheKey).Result()
-	if err == nil {
-		var recipes []models.Recipe
-		json.Unmarshal([]byte(cached), &recipes)
-		c.JSON(http.StatusOK, recipes)
+// maxServings is the most servings a recipe can be scaled to, as many as
+// it can have.
+const maxServings = 1000
+
+// scaleServings scales the recipe's ingredients to the servings asked for
+// with ?servings=. It responds with 400 and returns false when the number
+// is invalid or the recipe doesn't say how many it serves.
+func scaleServings(c *gin.Context, recipe *models.Recipe) bool {
+	param := c.Query("servings")
+	if param == "" {
+		return true
 	}
+	servings, err := strconv.Atoi(param)
+	if err != nil || servings < 1 || servings > maxServings {
+		apierrors.Write(c, apierrors.BadRequest("servings must be between 1 and {max}").With("max", strconv.Itoa(maxServings)))
+		return false
+	}
+	if recipe.Servings == 0 {
+		apierrors.Write(c, apierrors.BadRequest("The recipe doesn't say how many it serves, so it can't be scaled"))
+		return false
+	}
+
+	recipe.Ingredients = ingredients.ScaleLines(recipe.Ingredients, float64(servings)/float64(recipe.Servings))
+	recipe.Servings = servings
+	return true
+}
+
+// @Summary Get a recipe as schema.org JSON-LD
+// @Description Get a schema.org/Recipe document suitable for embedding in web pages
+// @Tags recipes
+// @Produce application/ld+json
+// @Param id path string true "Recipe ID"
+// @Param lang query string false "Language to read the recipe in, instead of Accept-Language"
+// @Success 200 {object} formats.JSONLDRecipe
+// @Failure 404 {object} apierrors.Error
+// @Router /recipes/{id}/jsonld [get]
+func (r *RecipeController) RecipeJSONLDHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := c.Param("id")
 
-	// load from DB
-	var recipes []models.Recipe
-	if err := r.db.Find(&recipes).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recipes"})
+	recipe, err := r.recipes.Get(ctx, id)
+	if errors.Is(err, service.ErrNotFound) {
+		if !redirectMoved(c, r.db, id) {
+			apierrors.Write(c, apierrors.NotFound("Recipe not found"))
+		}
 		return
 	}
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to fetch recipe"))
+		return
+	}
+	if !canView(c, r.previews, recipe) {
+		return
+	}
+	localize(c, r.translations, &recipe)
 
-	// save to cache
-	data, _ := json.Marshal(recipes)
-	r.redisClient.Set(cacheKey, data, 5*time.Minute)
+	writeJSONLD(c, formats.JSONLD(recipe))
+}
 
-	c.JSON(http.StatusOK, recipes)
+func writeJSONLD(c *gin.Context, doc formats.JSONLDRecipe) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to encode response"))
+		return
+	}
+	c.Data(http.StatusOK, formats.JSONLDContentType, data)
 }
 
 // @Summary Update an existing Recipe
-// @Description Get an existing recipe and update it
+// @Description Get an existing recipe and update it. The version it was edited from goes in If-Match, e.g. "3", or in the body; the update is refused with 409 when the recipe has changed since, and with 428 when neither is sent. If-Match: * updates whatever the version.
 // @Tags recipes
 // @Accept json
 // @produce json
 // @Param id path string true "Recipe ID"
+// @Param If-Match header string false "Version the update was made from"
 // @Param recipe body Recipe true "Recipe object"
 // @Success 200 {object} Recipe
-// @Failure 400 {object} map[string]string
-// @Failure 404 {object} map[string]string
+// @Failure 400 {object} apierrors.Error
+// @Failure 404 {object} apierrors.Error
+// @Failure 409 {object} apierrors.Error
+// @Failure 422 {object} apierrors.Error
+// @Failure 428 {object} apierrors.Error
 // @Router /recipes/{id} [put]
 func (r *RecipeController) UpdateRecipeHandler(c *gin.Context) {
+	ctx := c.Request.Context()
 	id := c.Param("id")
 
 	var recipe models.Recipe
-	if err := c.ShouldBindJSON(&recipe); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	if err := middleware.BindJSON(c, &recipe); err != nil {
+		bindFailed(c, err)
+		return
+	}
+	version, ok := expectedVersion(c, recipe.Version)
+	if !ok {
 		return
 	}
+	recipe.Version = version
 
-	var existingRecipe models.Recipe
-	if err := r.db.Where("id = ?", id).First(&existingRecipe).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Recipe not found"})
+	recipe, err := r.recipes.Update(ctx, id, recipe)
+	if errors.Is(err, service.ErrConflict) {
+		writeVersionConflict(c, version)
+		return
+	}
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 
-	recipe.ID = existingRecipe.ID
-	recipe.PublishedAt = existingRecipe.PublishedAt
+	serializer.JSON(c, http.StatusOK, recipe)
+}
 
-	if err := r.db.Model(&existingRecipe).Updates(&recipe).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update recipe"})
+// @Summary Partially update a recipe
+// @Description Change only the fields in the body, a JSON Merge Patch (RFC 7396) of name, tags, ingredients, instructions, totalTimeMinutes, prepMinutes, cookMinutes, difficulty, cuisineId, categoryId and servings. null clears a field; a cleared totalTimeMinutes is estimated again. The previous state is saved as a revision. The version the patch was made from goes in If-Match or in the patch's version, as for PUT.
+// @Tags recipes
+// @Accept json
+// @Accept application/merge-patch+json
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Param If-Match header string false "Version the patch was made from"
+// @Param patch body object true "Fields to change"
+// @Success 200 {object} Recipe
+// @Failure 400 {object} apierrors.Error
+// @Failure 404 {object} apierrors.Error
+// @Failure 409 {object} apierrors.Error
+// @Failure 422 {object} apierrors.Error
+// @Failure 428 {object} apierrors.Error
+// @Router /recipes/{id} [patch]
+func (r *RecipeController) PatchRecipeHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := c.Param("id")
+
+	var patch map[string]json.RawMessage
+	if err := middleware.BindJSON(c, &patch); err != nil {
+		bindFailed(c, err)
+		return
+	}
+	// the version says what the patch applies to, it isn't a change
+	var bodyVersion int64
+	if value, ok := patch["version"]; ok {
+		if err := json.Unmarshal(value, &bodyVersion); err != nil {
+			apierrors.Write(c, apierrors.BadRequest("version must be a number"))
+			return
+		}
+		delete(patch, "version")
+	}
+	version, ok := expectedVersion(c, bodyVersion)
+	if !ok {
 		return
 	}
 
-	r.clearRecipeCache()
+	recipe, err := r.recipes.Patch(ctx, id, version, func(recipe *models.Recipe) error {
+		return mergePatch(recipe, patch)
+	})
+	if errors.Is(err, service.ErrConflict) {
+		writeVersionConflict(c, version)
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	serializer.JSON(c, http.StatusOK, recipe)
+}
+
+// expectedVersion returns the version of the recipe a write was made
+// from: the one in If-Match, e.g. "3", or else body, the one sent in the
+// body. If-Match: * gives service.AnyVersion, which skips the check.
+// Without either it responds with 428 and reports false.
+func expectedVersion(c *gin.Context, body int64) (int64, bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "*" {
+		return service.AnyVersion, true
+	}
+	if header != "" {
+		version, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(header, "W/"), `"`), 10, 64)
+		if err != nil || version < 1 {
+			apierrors.Write(c, apierrors.BadRequest(`If-Match must be the recipe's version, e.g. "3"`))
+			return 0, false
+		}
+		return version, true
+	}
+	if body > 0 {
+		return body, true
+	}
+	writeVersionRequired(c)
+	return 0, false
+}
+
+func writeVersionRequired(c *gin.Context) {
+	apierrors.Write(c, apierrors.PreconditionRequired("Send the version of the recipe the change was made from in If-Match or the body"))
+}
+
+func writeVersionConflict(c *gin.Context, version int64) {
+	apierrors.Write(c, apierrors.Conflict("Recipe has changed since version {version}, fetch it again and retry").With("version", strconv.FormatInt(version, 10)))
+}
+
+// mergePatch applies the fields of a merge patch to recipe. Lists are
+// replaced as a whole and null clears a field.
+func mergePatch(recipe *models.Recipe, patch map[string]json.RawMessage) error {
+	for _, field := range slices.Sorted(maps.Keys(patch)) {
+		value := patch[field]
+		var err error
+		switch field {
+		case "name":
+			recipe.Name = ""
+			err = json.Unmarshal(value, &recipe.Name)
+		case "tags":
+			recipe.Tags = nil
+			err = json.Unmarshal(value, &recipe.Tags)
+		case "ingredients":
+			recipe.Ingredients = nil
+			err = json.Unmarshal(value, &recipe.Ingredients)
+		case "instructions":
+			recipe.Instructions = nil
+			err = json.Unmarshal(value, &recipe.Instructions)
+		case "totalTimeMinutes":
+			recipe.TotalTimeMinutes, recipe.TotalTimeEstimated = 0, false
+			err = json.Unmarshal(value, &recipe.TotalTimeMinutes)
+		case "prepMinutes":
+			recipe.PrepMinutes = 0
+			err = json.Unmarshal(value, &recipe.PrepMinutes)
+		case "cookMinutes":
+			recipe.CookMinutes = 0
+			err = json.Unmarshal(value, &recipe.CookMinutes)
+		case "difficulty":
+			recipe.Difficulty = ""
+			err = json.Unmarshal(value, &recipe.Difficulty)
+		case "cuisineId":
+			recipe.CuisineID = ""
+			err = json.Unmarshal(value, &recipe.CuisineID)
+		case "categoryId":
+			recipe.CategoryID = ""
+			err = json.Unmarshal(value, &recipe.CategoryID)
+		case "servings":
+			recipe.Servings = 0
+			err = json.Unmarshal(value, &recipe.Servings)
+		default:
+			return fmt.Errorf("field %q can't be patched", field)
+		}
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return fmt.Errorf("field %q must be %s, got %s", field, typeErr.Type, typeErr.Value)
+		}
+		if err != nil {
+			return fmt.Errorf("field %q: %v", field, err)
+		}
+	}
+	return nil
+}
 
-	c.JSON(http.StatusOK, existingRecipe)
+type publishRequest struct {
+	// At schedules the recipe; it is published right away when left out.
+	At *time.Time `json:"at"`
+}
+
+// @Summary Publish a recipe
+// @Description Publish a draft or scheduled recipe now, or schedule it for the given time.
+// @Tags recipes
+// @Accept json
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Param publish body publishRequest false "Publication time"
+// @Success 200 {object} Recipe
+// @Failure 400 {object} apierrors.Error
+// @Failure 404 {object} apierrors.Error
+// @Router /recipes/{id}/publish [post]
+func (r *RecipeController) PublishRecipeHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := c.Param("id")
+
+	var req publishRequest
+	if c.Request.ContentLength != 0 {
+		if err := middleware.BindJSON(c, &req); err != nil {
+			bindFailed(c, err)
+			return
+		}
+	}
+	at := time.Now()
+	if req.At != nil {
+		at = *req.At
+	}
+
+	recipe, err := r.recipes.Publish(ctx, id, at)
+	if errors.Is(err, service.ErrNotFound) {
+		apierrors.Write(c, apierrors.NotFound("Recipe not found"))
+		return
+	}
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to publish recipe"))
+		return
+	}
+
+	serializer.JSON(c, http.StatusOK, recipe)
 }
 
 // @Summary Delete a recipe
@@ -133,68 +681,111 @@ func (r *RecipeController) UpdateRecipeHandler(c *gin.Context) {
 // @Produce json
 // @Param id path string true "Recipe ID"
 // @Success 200 {object} map[string]string
-// @Failure 404 {object} map[string]string
+// @Failure 404 {object} apierrors.Error
 // @Router /recipes/{id} [delete]
 func (r *RecipeController) DeleteRecipeHandler(c *gin.Context) {
+	ctx := c.Request.Context()
 	id := c.Param("id")
 
-	var recipe models.Recipe
-	if err := r.db.Where("id = ?", id).First(&recipe).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Recipe not found"})
+	err := r.recipes.Delete(ctx, id)
+	if errors.Is(err, service.ErrNotFound) {
+		apierrors.Write(c, apierrors.NotFound("Recipe not found"))
 		return
 	}
-
-	if err := r.db.Delete(&recipe).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete the recipe"})
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to delete the recipe"))
 		return
 	}
-	r.clearRecipeCache()
 
 	c.JSON(http.StatusOK, gin.H{"message": "Recipe has been deleted"})
 }
 
+// maxSearchLimit caps the limit of searches.
+const maxSearchLimit = 200
+
+type SearchResponse struct {
+	Total   int                        `json:"total"`
+	Recipes []models.Recipe            `json:"recipes"`
+	Facets  map[string][]search.Bucket `json:"facets"`
+}
+
 // @Summary Search recipes
-// @Description Search recipes by tag
+// @Description Search recipes by text in names, tags, ingredients and steps and by tags, which must all match whole, ignoring case. Text matches are ranked by relevance where the search backend supports it; otherwise the newest come first. facets=true adds tag and total time counts.
 // @Tags recipes
 // @Produce json
-// @Param tag query string true "Tag to search for"
+// @Param q query string false "Text to search for"
+// @Param tag query []string false "Tags to search for" collectionFormat(multi)
+// @Param maxTotalTime query int false "Only recipes that take at most this many minutes, estimated times included"
+// @Param limit query int false "Maximum number of results"
+// @Param facets query bool false "Return a SearchResponse with facet counts"
 // @Success 200 {array} Recipe
+// @Failure 400 {object} apierrors.Error
 // @Router /recipes/search [get]
 func (r *RecipeController) SearchRecipesHandler(c *gin.Context) {
-	tag := c.Query("tag")
-	if tag == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Tag is required"})
-	}
-
-	cacheKey := "recipes:search:" + strings.ToLower(tag)
+	ctx := c.Request.Context()
 
-	cached, err := r.redisClient.Get(cacheKey).Result()
-	if err == nil {
-		var cachedRecipes []models.Recipe
-		json.Unmarshal([]byte(cached), &cachedRecipes)
-		c.JSON(http.StatusOK, cachedRecipes)
+	query := search.Query{Text: strings.TrimSpace(c.Query("q")), Tags: c.QueryArray("tag")}
+	if query.Text == "" && len(query.Tags) == 0 {
+		apierrors.Write(c, apierrors.BadRequest("q or tag is required"))
 		return
 	}
 
-	var recipes []models.Recipe
-	if err := r.db.Find(&recipes).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search recipes"})
+	maxTotalTime, ok := maxTotalTimeParam(c)
+	if !ok {
 		return
 	}
+	query.MaxTotalTime = maxTotalTime
 
-	var listOfRecipes []models.Recipe
-	lowerTag := strings.ToLower(tag)
-
-	for _, recipe := range recipes {
-		for _, t := range recipe.Tags {
-			if strings.Contains(strings.ToLower(t), lowerTag) {
-				listOfRecipes = append(listOfRecipes, recipe)
-			}
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxSearchLimit {
+			apierrors.Write(c, apierrors.BadRequest("limit must be between 1 and {max}").With("max", strconv.Itoa(maxSearchLimit)))
+			return
 		}
+		query.Limit = n
 	}
 
-	data, _ := json.Marshal(listOfRecipes)
-	r.redisClient.Set(cacheKey, data, 5*time.Minute)
+	result, err := r.searcher.Query(ctx, query)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error searching recipes", "error", err)
+		apierrors.Write(c, apierrors.Internal("Failed to search recipes"))
+		return
+	}
 
-	c.JSON(http.StatusOK, listOfRecipes)
+	recipes, err := r.recipes.GetMany(ctx, result.IDs)
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to search recipes"))
+		return
+	}
+	// the backends leave out drafts and scheduled recipes, but an index
+	// may not have caught up with a recipe going back to draft yet
+	now := time.Now()
+	recipes = slices.DeleteFunc(recipes, func(recipe models.Recipe) bool {
+		return !recipe.IsPublished(now)
+	})
+
+	meta := serializer.Meta{Total: result.Total}
+	if c.Query("facets") == "true" {
+		if !serializer.Enveloped(c) {
+			serializer.JSON(c, http.StatusOK, SearchResponse{Total: result.Total, Recipes: recipes, Facets: result.Facets})
+			return
+		}
+		meta.Facets = result.Facets
+	}
+	serializer.List(c, recipes, meta)
+}
+
+// maxTotalTimeParam reads the maxTotalTime query parameter, zero when
+// absent. It responds with 400 when the value is invalid.
+func maxTotalTimeParam(c *gin.Context) (int, bool) {
+	v := c.Query("maxTotalTime")
+	if v == "" {
+		return 0, true
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		apierrors.Write(c, apierrors.BadRequest("maxTotalTime must be a positive number of minutes"))
+		return 0, false
+	}
+	return n, true
 }
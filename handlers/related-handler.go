@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"recipes-api/apierrors"
+	"recipes-api/related"
+	"recipes-api/service"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary List related recipes
+// @Description List published recipes like the recipe, for a "You might also like" section: the ones sharing the most tags, and then ingredients, with it
+// @Tags recipes
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Param limit query int false "How many recipes to list, 6 by default and at most 20"
+// @Success 200 {array} related.Recipe
+// @Failure 400 {object} apierrors.Error
+// @Failure 404 {object} apierrors.Error
+// @Router /recipes/{id}/related [get]
+func RelatedRecipesHandler(recipeService *service.RecipeService, relatedService *related.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		limit := 6
+		if param := c.Query("limit"); param != "" {
+			n, err := strconv.Atoi(param)
+			if err != nil || n < 1 || n > related.MaxRecipes {
+				apierrors.Write(c, apierrors.BadRequest("limit must be between 1 and {max}").With("max", strconv.Itoa(related.MaxRecipes)))
+				return
+			}
+			limit = n
+		}
+
+		recipe, err := recipeService.Get(ctx, c.Param("id"))
+		// drafts and scheduled recipes have nothing related yet
+		if errors.Is(err, service.ErrNotFound) || err == nil && !recipe.IsPublished(time.Now().UTC()) {
+			apierrors.Write(c, apierrors.NotFound("Recipe not found"))
+			return
+		}
+		if err != nil {
+			apierrors.Write(c, apierrors.Internal("Failed to fetch recipe"))
+			return
+		}
+
+		recipes, err := relatedService.Related(ctx, recipe, limit)
+		if err != nil {
+			apierrors.Write(c, apierrors.Internal("Failed to fetch related recipes"))
+			return
+		}
+		c.JSON(http.StatusOK, recipes)
+	}
+}
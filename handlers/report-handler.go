@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"net/http"
+	"recipes-api/apierrors"
+	"recipes-api/reports"
+	"recipes-api/stats"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// @Summary Preview the weekly report
+// @Description Build the admin report from the analytics tables. Defaults to the last 7 days including today.
+// @Tags admin
+// @Produce json
+// @Param from query string false "First day (YYYY-MM-DD)"
+// @Param to query string false "Last day (YYYY-MM-DD)"
+// @Success 200 {object} reports.Report
+// @Failure 400 {object} apierrors.Error
+// @Router /admin/reports/weekly [get]
+func WeeklyReportHandler(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		to := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+		if v := c.Query("to"); v != "" {
+			day, err := time.Parse(time.DateOnly, v)
+			if err != nil {
+				apierrors.Write(c, apierrors.BadRequest("Invalid to date, expected YYYY-MM-DD"))
+				return
+			}
+			to = day.AddDate(0, 0, 1)
+		}
+		from := to.AddDate(0, 0, -7)
+		if v := c.Query("from"); v != "" {
+			day, err := time.Parse(time.DateOnly, v)
+			if err != nil {
+				apierrors.Write(c, apierrors.BadRequest("Invalid from date, expected YYYY-MM-DD"))
+				return
+			}
+			from = day
+		}
+		if !from.Before(to) {
+			apierrors.Write(c, apierrors.BadRequest("from must not be after to"))
+			return
+		}
+
+		report, err := reports.Build(ctx, db, from, to)
+		if err != nil {
+			apierrors.Write(c, apierrors.Internal("Failed to build report"))
+			return
+		}
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+// @Summary Show catalog statistics
+// @Description Count recipes by state and by tag, list how many were published each of the last 30 days and which are read most, and sum up the storage their photos take. The figures are cached for a few minutes; refresh=true works them out anew.
+// @Tags admin
+// @Produce json
+// @Param refresh query bool false "Skip the cached figures"
+// @Success 200 {object} stats.Stats
+// @Router /admin/stats [get]
+func StatsHandler(statsService *stats.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		s, err := statsService.Get(ctx, c.Query("refresh") == "true")
+		if err != nil {
+			apierrors.Write(c, apierrors.Internal("Failed to compute stats"))
+			return
+		}
+		c.JSON(http.StatusOK, s)
+	}
+}
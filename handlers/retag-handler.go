@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"recipes-api/apierrors"
+	"recipes-api/middleware"
+	"recipes-api/models"
+	"recipes-api/retag"
+
+	"github.com/gin-gonic/gin"
+)
+
+type retagRequest struct {
+	Action string `json:"action" binding:"required,oneof=add remove"`
+	Tag    string `json:"tag" binding:"recipetag"`
+	// Q, Tags and MaxTotalTime select the recipes like /recipes/search
+	// does, drafts included.
+	Q            string   `json:"q"`
+	Tags         []string `json:"tags"`
+	MaxTotalTime int      `json:"maxTotalTime" binding:"min=0"`
+}
+
+type RetagPreview struct {
+	Matched int `json:"matched"`
+	Changed int `json:"changed"`
+}
+
+// @Summary Retag the recipes matching a search
+// @Description Add a tag to, or remove it from, every recipe matching q, tags and maxTotalTime, in the background. Follow the returned job at /admin/retag/{id}. With dryRun=true only the recipes that match and would change are counted.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param dryRun query bool false "Only count the recipes that would change"
+// @Param job body retagRequest true "Change and search"
+// @Success 200 {object} RetagPreview
+// @Success 202 {object} models.RetagJob
+// @Failure 400 {object} apierrors.Error
+// @Failure 422 {object} apierrors.Error
+// @Router /admin/retag [post]
+func RetagHandler(runner *retag.Runner) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		var req retagRequest
+		if err := middleware.BindJSON(c, &req); err != nil {
+			bindFailed(c, err)
+			return
+		}
+		// retagging every recipe is more likely a mistake than intended
+		if req.Q == "" && len(req.Tags) == 0 {
+			apierrors.Write(c, apierrors.BadRequest("q or tags is required"))
+			return
+		}
+		job := models.RetagJob{Action: req.Action, Tag: req.Tag, Q: req.Q, Tags: req.Tags, MaxTotalTime: req.MaxTotalTime}
+
+		if c.Query("dryRun") == "true" {
+			matched, changed, err := runner.Preview(ctx, job)
+			if err != nil {
+				apierrors.Write(c, apierrors.Internal("Failed to search recipes"))
+				return
+			}
+			c.JSON(http.StatusOK, RetagPreview{Matched: matched, Changed: changed})
+			return
+		}
+
+		job, err := runner.Start(ctx, job)
+		if err != nil {
+			apierrors.Write(c, apierrors.Internal("Failed to start retag job"))
+			return
+		}
+		c.JSON(http.StatusAccepted, job)
+	}
+}
+
+// @Summary Get a retag job
+// @Tags admin
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 200 {object} models.RetagJob
+// @Failure 404 {object} apierrors.Error
+// @Router /admin/retag/{id} [get]
+func RetagJobHandler(runner *retag.Runner) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		job, err := runner.Get(c.Request.Context(), c.Param("id"))
+		if errors.Is(err, retag.ErrNotFound) {
+			apierrors.Write(c, apierrors.NotFound("Retag job not found"))
+			return
+		}
+		if err != nil {
+			apierrors.Write(c, apierrors.Internal("Failed to fetch retag job"))
+			return
+		}
+		c.JSON(http.StatusOK, job)
+	}
+}
+
+// @Summary Undo a retag job
+// @Description Put back, in the background, the tags the job changed, from the revisions saved before each change. Recipes whose tags were edited since are skipped.
+// @Tags admin
+// @Produce json
+// @Param id path string true "Job ID"
+// @Success 202 {object} models.RetagJob
+// @Failure 404 {object} apierrors.Error
+// @Failure 409 {object} apierrors.Error
+// @Router /admin/retag/{id}/undo [post]
+func UndoRetagHandler(runner *retag.Runner) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		job, err := runner.Undo(c.Request.Context(), c.Param("id"))
+		if errors.Is(err, retag.ErrNotFound) {
+			apierrors.Write(c, apierrors.NotFound("Retag job not found"))
+			return
+		}
+		if errors.Is(err, retag.ErrNotUndoable) {
+			apierrors.Write(c, apierrors.Conflict("Only finished retag jobs can be undone"))
+			return
+		}
+		if err != nil {
+			apierrors.Write(c, apierrors.Internal("Failed to undo retag job"))
+			return
+		}
+		c.JSON(http.StatusAccepted, job)
+	}
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"recipes-api/apierrors"
+	"recipes-api/middleware"
+	"recipes-api/models"
+	"recipes-api/serializer"
+	"recipes-api/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary List recipe revisions
+// @Description List the snapshots recorded before each update of a recipe, newest first
+// @Tags recipes
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Success 200 {array} models.RecipeRevision
+// @Failure 404 {object} apierrors.Error
+// @Router /recipes/{id}/revisions [get]
+func (r *RecipeController) ListRevisionsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := c.Param("id")
+
+	var recipe models.Recipe
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&recipe).Error; err != nil {
+		apierrors.Write(c, apierrors.NotFound("Recipe not found"))
+		return
+	}
+	if !canView(c, r.previews, recipe) {
+		return
+	}
+
+	var revisions []models.RecipeRevision
+	if err := r.db.WithContext(ctx).Where("recipe_id = ?", id).Order("revision DESC").Find(&revisions).Error; err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to fetch revisions"))
+		return
+	}
+
+	serializer.List(c, revisions, serializer.Meta{})
+}
+
+// @Summary Restore a recipe revision
+// @Description Roll a recipe back to the name, tags, ingredients and instructions of an earlier revision. The current state is saved as a new revision first, and the slug follows the restored name, with links to the old slug redirected. The version the restore was made from goes in If-Match or in the body's version, as for PUT.
+// @Tags recipes
+// @Accept json
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Param rev path int true "Revision number"
+// @Param If-Match header string false "Version the restore was made from"
+// @Success 200 {object} models.Recipe
+// @Failure 400 {object} apierrors.Error
+// @Failure 404 {object} apierrors.Error
+// @Failure 409 {object} apierrors.Error
+// @Failure 422 {object} apierrors.Error
+// @Failure 428 {object} apierrors.Error
+// @Router /recipes/{id}/revisions/{rev}/restore [post]
+func (r *RecipeController) RestoreRevisionHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := c.Param("id")
+
+	rev, err := strconv.Atoi(c.Param("rev"))
+	if err != nil {
+		apierrors.Write(c, apierrors.BadRequest("Invalid revision number"))
+		return
+	}
+
+	var body struct {
+		Version int64 `json:"version"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := middleware.BindJSON(c, &body); err != nil {
+			bindFailed(c, err)
+			return
+		}
+	}
+	version, ok := expectedVersion(c, body.Version)
+	if !ok {
+		return
+	}
+
+	recipe, err := r.recipes.Restore(ctx, id, rev, version)
+	if errors.Is(err, service.ErrRevisionNotFound) {
+		apierrors.Write(c, apierrors.NotFound("Revision not found"))
+		return
+	}
+	if errors.Is(err, service.ErrConflict) {
+		writeVersionConflict(c, version)
+		return
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	serializer.JSON(c, http.StatusOK, recipe)
+}
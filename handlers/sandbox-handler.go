@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"recipes-api/apierrors"
+	"recipes-api/cache"
+	"recipes-api/ids"
+	"recipes-api/models"
+	"recipes-api/projections"
+	"recipes-api/sandbox"
+	"recipes-api/seed"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis"
+	"gorm.io/gorm"
+)
+
+type SandboxController struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+	recorder    *sandbox.Recorder
+	recipeList  *projections.RecipeList
+	seedFile    string
+	ids         ids.Generator
+}
+
+func NewSandboxController(db *gorm.DB, redisClient *redis.Client, recorder *sandbox.Recorder, recipeList *projections.RecipeList, seedFile string, generator ids.Generator) *SandboxController {
+	return &SandboxController{db: db, redisClient: redisClient, recorder: recorder, recipeList: recipeList, seedFile: seedFile, ids: generator}
+}
+
+// @Summary Reset sandbox data
+// @Description Restore the seed dataset, dropping all changes, revisions, events, webhook deliveries and captured messages
+// @Tags admin
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/sandbox/reset [post]
+func (s *SandboxController) ResetHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&models.RecipeRevision{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("1 = 1").Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("1 = 1").Delete(&models.OutboxEvent{}).Error
+	})
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to reset sandbox"))
+		return
+	}
+
+	count, err := seed.Reset(s.db.WithContext(ctx), s.seedFile, s.ids)
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to reset sandbox"))
+		return
+	}
+
+	cache.FlushRecipes(context.WithoutCancel(ctx), s.redisClient)
+	if err := s.recipeList.Rebuild(context.WithoutCancel(ctx)); err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to rebuild recipe list"))
+		return
+	}
+	s.recorder.Clear()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Sandbox has been reset", "recipes": count})
+}
+
+// @Summary List captured messages
+// @Description List emails and webhooks captured in sandbox mode, newest first
+// @Tags admin
+// @Produce json
+// @Success 200 {array} sandbox.Message
+// @Router /admin/sandbox/messages [get]
+func (s *SandboxController) ListMessagesHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, s.recorder.List())
+}
+
+// @Summary Clear captured messages
+// @Description Empty the sandbox message log
+// @Tags admin
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Router /admin/sandbox/messages [delete]
+func (s *SandboxController) ClearMessagesHandler(c *gin.Context) {
+	s.recorder.Clear()
+	c.JSON(http.StatusOK, gin.H{"message": "Captured messages have been cleared"})
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+	"recipes-api/apierrors"
+	"recipes-api/events"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary List event schemas
+// @Description List the event payload schema versions and the event types they cover
+// @Tags schemas
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /schemas/events [get]
+func ListEventSchemasHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"current":  events.CurrentSchemaVersion,
+		"versions": events.SchemaVersions,
+		"types":    events.Types,
+	})
+}
+
+// @Summary Get an event schema
+// @Description Get the JSON Schema document for an event payload version
+// @Tags schemas
+// @Produce json
+// @Param version path string true "Schema version, e.g. v2"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} apierrors.Error
+// @Router /schemas/events/{version} [get]
+func GetEventSchemaHandler(c *gin.Context) {
+	schema, err := events.Schema(c.Param("version"))
+	if err != nil {
+		apierrors.Write(c, apierrors.NotFound("Schema version not found"))
+		return
+	}
+
+	c.Data(http.StatusOK, "application/schema+json", schema)
+}
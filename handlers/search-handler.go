@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+	"recipes-api/apierrors"
+	"recipes-api/search"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// @Summary Rebuild the search index
+// @Description Index every recipe again, e.g. after switching search backends. Only available with the redisearch and elasticsearch backends.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/search/reindex [post]
+func ReindexHandler(db *gorm.DB, searcher search.Searcher) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		count, err := search.Reindex(c.Request.Context(), db, searcher)
+		if err != nil {
+			apierrors.Write(c, apierrors.Internal("Failed to reindex recipes").WithDetails(gin.H{"indexed": count}))
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Recipes have been reindexed", "indexed": count})
+	}
+}
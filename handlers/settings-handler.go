@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"net/http"
+	"recipes-api/apierrors"
+	"recipes-api/settings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Get runtime settings
+// @Description Get the settings currently in effect: log level, rate limit, feature flags, CORS origins and JSON limits
+// @Tags admin
+// @Produce json
+// @Success 200 {object} settings.Settings
+// @Router /admin/settings [get]
+func GetSettingsHandler(store *settings.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, store.Get())
+	}
+}
+
+// @Summary Reload runtime settings
+// @Description Re-read the settings file, like sending SIGHUP. Invalid files are rejected and the current settings kept.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} settings.Settings
+// @Failure 400 {object} apierrors.Error
+// @Router /admin/settings/reload [post]
+func ReloadSettingsHandler(store *settings.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := store.Reload(); err != nil {
+			apierrors.Write(c, apierrors.BadRequest(err.Error()))
+			return
+		}
+		c.JSON(http.StatusOK, store.Get())
+	}
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"recipes-api/apierrors"
+	"recipes-api/ingredients"
+	"recipes-api/mealplans"
+	"recipes-api/middleware"
+	"recipes-api/models"
+	"recipes-api/service"
+	"recipes-api/shopping"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxShoppingRecipes caps the recipes one list is built from.
+const maxShoppingRecipes = 100
+
+type ShoppingController struct {
+	recipes   *service.RecipeService
+	mealPlans *mealplans.Service
+	shopping  *shopping.Service
+}
+
+func NewShoppingController(recipeService *service.RecipeService, mealPlanService *mealplans.Service, shoppingService *shopping.Service) *ShoppingController {
+	return &ShoppingController{recipes: recipeService, mealPlans: mealPlanService, shopping: shoppingService}
+}
+
+// shoppingListRequest names the recipes to shop for, or the week of the
+// meal plan whose recipes to shop for. A recipe given twice is bought for
+// twice; planned meals are bought for as many servings as planned.
+type shoppingListRequest struct {
+	Name      string   `json:"name" binding:"max=200"`
+	RecipeIDs []string `json:"recipeIds" binding:"dive,required"`
+	MealPlan  string   `json:"mealPlanWeek"`
+}
+
+type checkItemRequest struct {
+	Checked *bool `json:"checked" binding:"required"`
+}
+
+// @Summary Create a shopping list
+// @Description Build a shopping list from recipes or from a week of the meal plan. Ingredients are merged by item, with quantities summed where the units match, and grouped by aisle.
+// @Tags shopping
+// @Accept json
+// @Produce json
+// @Param list body shoppingListRequest true "Recipes or meal plan week"
+// @Success 201 {object} shopping.List
+// @Failure 400 {object} apierrors.Error
+// @Failure 404 {object} apierrors.Error
+// @Router /shopping-lists [post]
+func (s *ShoppingController) CreateShoppingListHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req shoppingListRequest
+	if err := middleware.BindJSON(c, &req); err != nil {
+		bindFailed(c, err)
+		return
+	}
+	if (len(req.RecipeIDs) == 0) == (req.MealPlan == "") {
+		apierrors.Write(c, apierrors.BadRequest("Give either recipeIds or mealPlanWeek"))
+		return
+	}
+
+	// servings are how many each recipe is cooked for, zero for as written
+	ids, servings := req.RecipeIDs, make([]int, len(req.RecipeIDs))
+	if req.MealPlan != "" {
+		week, err := mealplans.ParseWeek(req.MealPlan)
+		if err != nil {
+			apierrors.Write(c, apierrors.BadRequest("Invalid week {week}, expected an ISO week such as 2026-W07").With("week", req.MealPlan))
+			return
+		}
+		entries, err := s.mealPlans.Entries(ctx, week)
+		if err != nil {
+			apierrors.Write(c, apierrors.Internal("Failed to fetch meal plan"))
+			return
+		}
+		for _, entry := range entries {
+			ids = append(ids, entry.RecipeID)
+			servings = append(servings, entry.Servings)
+		}
+		if len(ids) == 0 {
+			apierrors.Write(c, apierrors.BadRequest("No meals are planned for {week}").With("week", req.MealPlan))
+			return
+		}
+	}
+	if len(ids) > maxShoppingRecipes {
+		apierrors.Write(c, apierrors.BadRequest("A shopping list can be built from at most {max} recipes").With("max", strconv.Itoa(maxShoppingRecipes)))
+		return
+	}
+
+	recipes, err := s.recipes.GetMany(ctx, ids)
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to fetch recipes"))
+		return
+	}
+	found := map[string]models.Recipe{}
+	for _, recipe := range visible(c, recipes) {
+		found[recipe.ID] = recipe
+	}
+
+	sources := make([]shopping.Source, 0, len(ids))
+	for i, id := range ids {
+		recipe, ok := found[id]
+		// recipes deleted since they were planned are skipped, but asking
+		// for one that doesn't exist is a mistake
+		if !ok && req.MealPlan == "" {
+			apierrors.Write(c, apierrors.NotFound("Recipe {id} not found").With("id", id))
+			return
+		}
+		if !ok {
+			continue
+		}
+		lines := recipe.Ingredients
+		if servings[i] > 0 && recipe.Servings > 0 {
+			lines = ingredients.ScaleLines(lines, float64(servings[i])/float64(recipe.Servings))
+		}
+		sources = append(sources, shopping.Source{RecipeID: recipe.ID, Ingredients: lines})
+	}
+	name := req.Name
+	if name == "" && req.MealPlan != "" {
+		name = req.MealPlan
+	}
+
+	list, err := s.shopping.Create(ctx, name, sources)
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to save shopping list"))
+		return
+	}
+	c.JSON(http.StatusCreated, list)
+}
+
+// @Summary Get a shopping list
+// @Tags shopping
+// @Produce json
+// @Param id path string true "Shopping list ID"
+// @Success 200 {object} shopping.List
+// @Failure 404 {object} apierrors.Error
+// @Router /shopping-lists/{id} [get]
+func (s *ShoppingController) GetShoppingListHandler(c *gin.Context) {
+	list, err := s.shopping.Get(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, shopping.ErrNotFound) {
+		apierrors.Write(c, apierrors.NotFound("Shopping list not found"))
+		return
+	}
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to fetch shopping list"))
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// @Summary Check off a shopping list item
+// @Description Mark an item as bought, or as not bought with checked false
+// @Tags shopping
+// @Accept json
+// @Produce json
+// @Param id path string true "Shopping list ID"
+// @Param itemId path string true "Item ID"
+// @Param item body checkItemRequest true "Checked"
+// @Success 200 {object} models.ShoppingListItem
+// @Failure 400 {object} apierrors.Error
+// @Failure 404 {object} apierrors.Error
+// @Router /shopping-lists/{id}/items/{itemId} [patch]
+func (s *ShoppingController) CheckItemHandler(c *gin.Context) {
+	var req checkItemRequest
+	if err := middleware.BindJSON(c, &req); err != nil {
+		bindFailed(c, err)
+		return
+	}
+
+	item, err := s.shopping.Check(c.Request.Context(), c.Param("id"), c.Param("itemId"), *req.Checked)
+	if errors.Is(err, shopping.ErrNotFound) {
+		apierrors.Write(c, apierrors.NotFound("Shopping list item not found"))
+		return
+	}
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to update shopping list item"))
+		return
+	}
+	c.JSON(http.StatusOK, item)
+}
+
+// @Summary Delete a shopping list
+// @Tags shopping
+// @Produce json
+// @Param id path string true "Shopping list ID"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} apierrors.Error
+// @Router /shopping-lists/{id} [delete]
+func (s *ShoppingController) DeleteShoppingListHandler(c *gin.Context) {
+	err := s.shopping.Delete(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, shopping.ErrNotFound) {
+		apierrors.Write(c, apierrors.NotFound("Shopping list not found"))
+		return
+	}
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to delete shopping list"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Shopping list has been deleted"})
+}
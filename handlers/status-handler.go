@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"recipes-api/apierrors"
+	"recipes-api/middleware"
+	"recipes-api/models"
+	"recipes-api/statuspage"
+
+	"github.com/gin-gonic/gin"
+)
+
+type StatusController struct {
+	monitor *statuspage.Monitor
+}
+
+func NewStatusController(monitor *statuspage.Monitor) *StatusController {
+	return &StatusController{monitor: monitor}
+}
+
+type incidentRequest struct {
+	Title      string   `json:"title" binding:"notblank,max=200"`
+	Impact     string   `json:"impact" binding:"required,oneof=minor major critical"`
+	Status     string   `json:"status" binding:"omitempty,oneof=investigating identified monitoring resolved"`
+	Message    string   `json:"message" binding:"max=5000"`
+	Components []string `json:"components" binding:"dive,oneof=db redis search jobs storage"`
+}
+
+// @Summary API status
+// @Description Report whether the database, Redis, search, background jobs and image storage work, their uptime over the last 1, 7, 30 and 90 days, and the open and recently resolved incidents. Components are checked every minute.
+// @Tags health
+// @Produce json
+// @Success 200 {object} statuspage.Report
+// @Router /status [get]
+func (s *StatusController) StatusHandler(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=30")
+	c.JSON(http.StatusOK, s.monitor.Report(c.Request.Context()))
+}
+
+// @Summary Report an incident
+// @Description Add an incident to the status page. It is being investigated unless status says otherwise.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param incident body incidentRequest true "Incident"
+// @Success 201 {object} models.Incident
+// @Failure 400 {object} apierrors.Error
+// @Failure 422 {object} apierrors.Error
+// @Router /admin/incidents [post]
+func (s *StatusController) CreateIncidentHandler(c *gin.Context) {
+	var req incidentRequest
+	if err := middleware.BindJSON(c, &req); err != nil {
+		bindFailed(c, err)
+		return
+	}
+
+	incident, err := s.monitor.CreateIncident(c.Request.Context(), req.incident())
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to create incident"))
+		return
+	}
+	c.JSON(http.StatusCreated, incident)
+}
+
+// @Summary Update an incident
+// @Description Replace an incident's details, e.g. to post progress. Setting status to resolved records when it was resolved.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "Incident ID"
+// @Param incident body incidentRequest true "Incident"
+// @Success 200 {object} models.Incident
+// @Failure 400 {object} apierrors.Error
+// @Failure 404 {object} apierrors.Error
+// @Failure 422 {object} apierrors.Error
+// @Router /admin/incidents/{id} [put]
+func (s *StatusController) UpdateIncidentHandler(c *gin.Context) {
+	var req incidentRequest
+	if err := middleware.BindJSON(c, &req); err != nil {
+		bindFailed(c, err)
+		return
+	}
+
+	incident, err := s.monitor.UpdateIncident(c.Request.Context(), c.Param("id"), func(incident *models.Incident) {
+		update := req.incident()
+		if update.Status == "" {
+			update.Status = incident.Status
+		}
+		incident.Title, incident.Impact, incident.Status = update.Title, update.Impact, update.Status
+		incident.Message, incident.Components = update.Message, update.Components
+	})
+	if errors.Is(err, statuspage.ErrIncidentNotFound) {
+		apierrors.Write(c, apierrors.NotFound("Incident not found"))
+		return
+	}
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to update incident"))
+		return
+	}
+	c.JSON(http.StatusOK, incident)
+}
+
+func (req incidentRequest) incident() models.Incident {
+	return models.Incident{
+		Title:      req.Title,
+		Impact:     req.Impact,
+		Status:     req.Status,
+		Message:    req.Message,
+		Components: req.Components,
+	}
+}
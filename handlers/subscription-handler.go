@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/mail"
+	"recipes-api/apierrors"
+	"recipes-api/events"
+	"recipes-api/middleware"
+	"recipes-api/models"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/xid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SubscriptionController struct {
+	db *gorm.DB
+}
+
+func NewSubscriptionController(db *gorm.DB) *SubscriptionController {
+	return &SubscriptionController{db: db}
+}
+
+type subscriptionRequest struct {
+	Email string `json:"email" binding:"required"`
+	// Fields limits notifications to these recipe fields; empty means any change.
+	Fields []string `json:"fields"`
+}
+
+// @Summary Subscribe to recipe updates
+// @Description Get an email when the recipe is updated, optionally only for some fields. Edits made in quick succession are sent as one digest. Subscribing again with the same email replaces the field list.
+// @Tags recipes
+// @Accept json
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Param subscription body subscriptionRequest true "Subscriber"
+// @Success 201 {object} models.RecipeSubscription
+// @Failure 400 {object} apierrors.Error
+// @Failure 404 {object} apierrors.Error
+// @Router /recipes/{id}/subscriptions [post]
+func (s *SubscriptionController) SubscribeHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := c.Param("id")
+
+	var req subscriptionRequest
+	if err := middleware.BindJSON(c, &req); err != nil {
+		bindFailed(c, err)
+		return
+	}
+	address, err := mail.ParseAddress(req.Email)
+	if err != nil {
+		apierrors.Write(c, apierrors.BadRequest("Invalid email address"))
+		return
+	}
+	fields := events.Fields()
+	for _, f := range req.Fields {
+		if !slices.Contains(fields, f) {
+			apierrors.Write(c, apierrors.BadRequest("Unknown field {field}").With("field", f))
+			return
+		}
+	}
+
+	if err := s.db.WithContext(ctx).Select("id").Where("id = ?", id).First(&models.Recipe{}).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			apierrors.Write(c, apierrors.NotFound("Recipe not found"))
+			return
+		}
+		apierrors.Write(c, apierrors.Internal("Failed to load recipe"))
+		return
+	}
+
+	sub := models.RecipeSubscription{
+		ID:       xid.New().String(),
+		RecipeID: id,
+		Email:    strings.ToLower(address.Address),
+		Fields:   req.Fields,
+	}
+	err = s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "recipe_id"}, {Name: "email"}},
+		DoUpdates: clause.AssignmentColumns([]string{"fields"}),
+	}).Create(&sub).Error
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to subscribe"))
+		return
+	}
+
+	// on conflict the existing row keeps its ID
+	if err := s.db.WithContext(ctx).Where("recipe_id = ? AND email = ?", id, sub.Email).First(&sub).Error; err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to subscribe"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, sub)
+}
+
+// @Summary Unsubscribe from recipe updates
+// @Description Remove a subscription created with POST /recipes/{id}/subscriptions
+// @Tags recipes
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Param subscriptionId path string true "Subscription ID"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} apierrors.Error
+// @Router /recipes/{id}/subscriptions/{subscriptionId} [delete]
+func (s *SubscriptionController) UnsubscribeHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	result := s.db.WithContext(ctx).Where("id = ? AND recipe_id = ?", c.Param("subscriptionId"), c.Param("id")).Delete(&models.RecipeSubscription{})
+	if result.Error != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to unsubscribe"))
+		return
+	}
+	if result.RowsAffected == 0 {
+		apierrors.Write(c, apierrors.NotFound("Subscription not found"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Subscription has been removed"})
+}
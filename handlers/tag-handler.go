@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http"
+	"recipes-api/apierrors"
+	"recipes-api/tags"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxPopularTags caps how many tags GET /tags/popular returns.
+const maxPopularTags = 100
+
+// @Summary List tags
+// @Description List the tags of published recipes with how many recipes have each, by name. With prefix only the tags starting with it are listed, for autocompletion.
+// @Tags tags
+// @Produce json
+// @Param prefix query string false "Only tags starting with this, ignoring case"
+// @Success 200 {array} tags.Count
+// @Router /tags [get]
+func ListTagsHandler(tagService *tags.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		counts, err := tagService.Search(c.Request.Context(), c.Query("prefix"))
+		if err != nil {
+			apierrors.Write(c, apierrors.Internal("Failed to fetch tags"))
+			return
+		}
+		c.JSON(http.StatusOK, counts)
+	}
+}
+
+// @Summary List popular tags
+// @Description List the most used tags of published recipes, most used first
+// @Tags tags
+// @Produce json
+// @Param limit query int false "How many tags to list, 20 by default and at most 100"
+// @Success 200 {array} tags.Count
+// @Failure 400 {object} apierrors.Error
+// @Router /tags/popular [get]
+func PopularTagsHandler(tagService *tags.Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := 20
+		if param := c.Query("limit"); param != "" {
+			n, err := strconv.Atoi(param)
+			if err != nil || n < 1 || n > maxPopularTags {
+				apierrors.Write(c, apierrors.BadRequest("limit must be between 1 and {max}").With("max", strconv.Itoa(maxPopularTags)))
+				return
+			}
+			limit = n
+		}
+
+		counts, err := tagService.Popular(c.Request.Context(), limit)
+		if err != nil {
+			apierrors.Write(c, apierrors.Internal("Failed to fetch tags"))
+			return
+		}
+		c.JSON(http.StatusOK, counts)
+	}
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"recipes-api/apierrors"
+	"recipes-api/middleware"
+	"recipes-api/taxonomy"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TaxonomyController serves the cuisines or the categories, whichever its
+// service manages. Its error messages name the kind of term.
+type TaxonomyController struct {
+	terms    *taxonomy.Service
+	messages termMessages
+}
+
+type termMessages struct {
+	notFound, nameTaken, inUse, deleted   string
+	fetchFailed, saveFailed, deleteFailed string
+}
+
+func NewCuisineController(cuisines *taxonomy.Service) *TaxonomyController {
+	return &TaxonomyController{terms: cuisines, messages: termMessages{
+		notFound:     "Cuisine not found",
+		nameTaken:    "A cuisine with this name already exists",
+		inUse:        "Cuisine is used by {count} recipes",
+		deleted:      "Cuisine has been deleted",
+		fetchFailed:  "Failed to fetch cuisines",
+		saveFailed:   "Failed to save cuisine",
+		deleteFailed: "Failed to delete cuisine",
+	}}
+}
+
+func NewCategoryController(categories *taxonomy.Service) *TaxonomyController {
+	return &TaxonomyController{terms: categories, messages: termMessages{
+		notFound:     "Category not found",
+		nameTaken:    "A category with this name already exists",
+		inUse:        "Category is used by {count} recipes",
+		deleted:      "Category has been deleted",
+		fetchFailed:  "Failed to fetch categories",
+		saveFailed:   "Failed to save category",
+		deleteFailed: "Failed to delete category",
+	}}
+}
+
+type termRequest struct {
+	Name        string `json:"name" binding:"notblank,max=100"`
+	Description string `json:"description" binding:"max=1000"`
+}
+
+// @Summary List cuisines or categories
+// @Description List the cuisines or categories by name. Recipes are filed under them with cuisineId and categoryId.
+// @Tags taxonomy
+// @Produce json
+// @Success 200 {array} models.Term
+// @Router /cuisines [get]
+// @Router /categories [get]
+func (t *TaxonomyController) ListTermsHandler(c *gin.Context) {
+	terms, err := t.terms.List(c.Request.Context())
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal(t.messages.fetchFailed))
+		return
+	}
+	c.JSON(http.StatusOK, terms)
+}
+
+// @Summary Get a cuisine or category
+// @Tags taxonomy
+// @Produce json
+// @Param id path string true "Cuisine or category ID"
+// @Success 200 {object} models.Term
+// @Failure 404 {object} apierrors.Error
+// @Router /cuisines/{id} [get]
+// @Router /categories/{id} [get]
+func (t *TaxonomyController) GetTermHandler(c *gin.Context) {
+	term, err := t.terms.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		t.respondError(c, err, t.messages.fetchFailed)
+		return
+	}
+	c.JSON(http.StatusOK, term)
+}
+
+// @Summary Create a cuisine or category
+// @Tags taxonomy
+// @Accept json
+// @Produce json
+// @Param term body termRequest true "Name and description"
+// @Success 201 {object} models.Term
+// @Failure 409 {object} apierrors.Error
+// @Failure 422 {object} apierrors.Error
+// @Router /admin/cuisines [post]
+// @Router /admin/categories [post]
+func (t *TaxonomyController) CreateTermHandler(c *gin.Context) {
+	var req termRequest
+	if err := middleware.BindJSON(c, &req); err != nil {
+		bindFailed(c, err)
+		return
+	}
+
+	term, err := t.terms.Create(c.Request.Context(), req.Name, req.Description)
+	if err != nil {
+		t.respondError(c, err, t.messages.saveFailed)
+		return
+	}
+	c.JSON(http.StatusCreated, term)
+}
+
+// @Summary Update a cuisine or category
+// @Description Rename a cuisine or category and replace its description. The recipes filed under it stay there.
+// @Tags taxonomy
+// @Accept json
+// @Produce json
+// @Param id path string true "Cuisine or category ID"
+// @Param term body termRequest true "Name and description"
+// @Success 200 {object} models.Term
+// @Failure 404 {object} apierrors.Error
+// @Failure 409 {object} apierrors.Error
+// @Failure 422 {object} apierrors.Error
+// @Router /admin/cuisines/{id} [put]
+// @Router /admin/categories/{id} [put]
+func (t *TaxonomyController) UpdateTermHandler(c *gin.Context) {
+	var req termRequest
+	if err := middleware.BindJSON(c, &req); err != nil {
+		bindFailed(c, err)
+		return
+	}
+
+	term, err := t.terms.Update(c.Request.Context(), c.Param("id"), req.Name, req.Description)
+	if err != nil {
+		t.respondError(c, err, t.messages.saveFailed)
+		return
+	}
+	c.JSON(http.StatusOK, term)
+}
+
+// @Summary Delete a cuisine or category
+// @Description Delete a cuisine or category no recipe is filed under, including recipes in the trash
+// @Tags taxonomy
+// @Produce json
+// @Param id path string true "Cuisine or category ID"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} apierrors.Error
+// @Failure 409 {object} apierrors.Error
+// @Router /admin/cuisines/{id} [delete]
+// @Router /admin/categories/{id} [delete]
+func (t *TaxonomyController) DeleteTermHandler(c *gin.Context) {
+	count, err := t.terms.Delete(c.Request.Context(), c.Param("id"))
+	if errors.Is(err, taxonomy.ErrInUse) {
+		apierrors.Write(c, apierrors.Conflict(t.messages.inUse).With("count", strconv.FormatInt(count, 10)))
+		return
+	}
+	if err != nil {
+		t.respondError(c, err, t.messages.deleteFailed)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": t.messages.deleted})
+}
+
+// respondError responds with err from the service, or with a 500 saying
+// failed for errors it doesn't know.
+func (t *TaxonomyController) respondError(c *gin.Context, err error, failed string) {
+	switch {
+	case errors.Is(err, taxonomy.ErrNotFound):
+		apierrors.Write(c, apierrors.NotFound(t.messages.notFound))
+	case errors.Is(err, taxonomy.ErrNameTaken):
+		apierrors.Write(c, apierrors.Conflict(t.messages.nameTaken))
+	default:
+		apierrors.Write(c, apierrors.Internal(failed))
+	}
+}
@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"net/http"
+	"recipes-api/apierrors"
+	"recipes-api/middleware"
+	"recipes-api/models"
+	"recipes-api/serializer"
+	"recipes-api/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/xid"
+	"gorm.io/gorm"
+)
+
+type TemplateController struct {
+	db      *gorm.DB
+	recipes *service.RecipeService
+}
+
+func NewTemplateController(db *gorm.DB, recipeService *service.RecipeService) *TemplateController {
+	return &TemplateController{db: db, recipes: recipeService}
+}
+
+type templateRequest struct {
+	Name             string   `json:"name" binding:"required"`
+	Description      string   `json:"description"`
+	Tags             []string `json:"tags"`
+	Ingredients      []string `json:"ingredients"`
+	Instructions     []string `json:"instructions"`
+	TotalTimeMinutes int      `json:"totalTimeMinutes" binding:"min=0"`
+}
+
+// fromTemplateRequest names the new recipe. Lists that are given replace
+// the template's instead of being merged with them.
+type fromTemplateRequest struct {
+	Name         string   `json:"name" binding:"required"`
+	Tags         []string `json:"tags"`
+	Ingredients  []string `json:"ingredients"`
+	Instructions []string `json:"instructions"`
+}
+
+// @Summary Create a recipe template
+// @Description Save a template new recipes can be started from
+// @Tags templates
+// @Accept json
+// @Produce json
+// @Param template body templateRequest true "Template"
+// @Success 201 {object} models.RecipeTemplate
+// @Failure 400 {object} apierrors.Error
+// @Failure 409 {object} apierrors.Error
+// @Router /templates [post]
+func (t *TemplateController) CreateTemplateHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req templateRequest
+	if err := middleware.BindJSON(c, &req); err != nil {
+		bindFailed(c, err)
+		return
+	}
+
+	template := models.RecipeTemplate{ID: xid.New().String()}
+	req.apply(&template)
+
+	if t.nameTaken(c, template) {
+		return
+	}
+	if err := t.db.WithContext(ctx).Create(&template).Error; err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to create template"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, template)
+}
+
+// @Summary List recipe templates
+// @Description List the recipe templates by name
+// @Tags templates
+// @Produce json
+// @Success 200 {array} models.RecipeTemplate
+// @Router /templates [get]
+func (t *TemplateController) ListTemplatesHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	templates := []models.RecipeTemplate{}
+	if err := t.db.WithContext(ctx).Order("name").Find(&templates).Error; err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to fetch templates"))
+		return
+	}
+
+	c.JSON(http.StatusOK, templates)
+}
+
+// @Summary Get a recipe template
+// @Tags templates
+// @Produce json
+// @Param id path string true "Template ID"
+// @Success 200 {object} models.RecipeTemplate
+// @Failure 404 {object} apierrors.Error
+// @Router /templates/{id} [get]
+func (t *TemplateController) GetTemplateHandler(c *gin.Context) {
+	template, ok := t.find(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// @Summary Update a recipe template
+// @Description Replace a template. Recipes already created from it are not changed.
+// @Tags templates
+// @Accept json
+// @Produce json
+// @Param id path string true "Template ID"
+// @Param template body templateRequest true "Template"
+// @Success 200 {object} models.RecipeTemplate
+// @Failure 400 {object} apierrors.Error
+// @Failure 404 {object} apierrors.Error
+// @Failure 409 {object} apierrors.Error
+// @Router /templates/{id} [put]
+func (t *TemplateController) UpdateTemplateHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req templateRequest
+	if err := middleware.BindJSON(c, &req); err != nil {
+		bindFailed(c, err)
+		return
+	}
+
+	template, ok := t.find(c)
+	if !ok {
+		return
+	}
+	req.apply(&template)
+
+	if t.nameTaken(c, template) {
+		return
+	}
+	if err := t.db.WithContext(ctx).Save(&template).Error; err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to update template"))
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// @Summary Delete a recipe template
+// @Tags templates
+// @Produce json
+// @Param id path string true "Template ID"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} apierrors.Error
+// @Router /templates/{id} [delete]
+func (t *TemplateController) DeleteTemplateHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	template, ok := t.find(c)
+	if !ok {
+		return
+	}
+
+	if err := t.db.WithContext(ctx).Delete(&template).Error; err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to delete template"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Template has been deleted"})
+}
+
+// @Summary Create a recipe from a template
+// @Description Create a recipe with the template's tags, ingredients and steps. Lists given in the body replace the template's.
+// @Tags recipes
+// @Accept json
+// @Produce json
+// @Param id path string true "Template ID"
+// @Param recipe body fromTemplateRequest true "Name and overrides"
+// @Success 201 {object} models.Recipe
+// @Failure 400 {object} apierrors.Error
+// @Failure 404 {object} apierrors.Error
+// @Router /recipes/from-template/{id} [post]
+func (t *TemplateController) NewRecipeFromTemplateHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req fromTemplateRequest
+	if err := middleware.BindJSON(c, &req); err != nil {
+		bindFailed(c, err)
+		return
+	}
+
+	template, ok := t.find(c)
+	if !ok {
+		return
+	}
+
+	recipe := template.Recipe(req.Name)
+	if req.Tags != nil {
+		recipe.Tags = req.Tags
+	}
+	if req.Ingredients != nil {
+		recipe.Ingredients = req.Ingredients
+	}
+	if req.Instructions != nil {
+		recipe.Instructions = req.Instructions
+		// the template's time was for its own steps
+		recipe.TotalTimeMinutes = 0
+	}
+
+	recipe, err := t.recipes.Create(ctx, recipe)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	serializer.JSON(c, http.StatusCreated, recipe)
+}
+
+func (t *TemplateController) find(c *gin.Context) (models.RecipeTemplate, bool) {
+	var template models.RecipeTemplate
+	if err := t.db.WithContext(c.Request.Context()).Where("id = ?", c.Param("id")).First(&template).Error; err != nil {
+		apierrors.Write(c, apierrors.NotFound("Template not found"))
+		return template, false
+	}
+	return template, true
+}
+
+// nameTaken responds with 409 when another template already has the name.
+func (t *TemplateController) nameTaken(c *gin.Context, template models.RecipeTemplate) bool {
+	var count int64
+	if err := t.db.WithContext(c.Request.Context()).Model(&models.RecipeTemplate{}).Where("name = ? AND id <> ?", template.Name, template.ID).Count(&count).Error; err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to check template name"))
+		return true
+	}
+	if count > 0 {
+		apierrors.Write(c, apierrors.Conflict("A template with this name already exists"))
+		return true
+	}
+	return false
+}
+
+func (req templateRequest) apply(template *models.RecipeTemplate) {
+	template.Name = req.Name
+	template.Description = req.Description
+	template.Tags = req.Tags
+	template.Ingredients = req.Ingredients
+	template.Instructions = req.Instructions
+	template.TotalTimeMinutes = req.TotalTimeMinutes
+}
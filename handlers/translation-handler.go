@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"recipes-api/apierrors"
+	"recipes-api/middleware"
+	"recipes-api/models"
+	"recipes-api/previews"
+	"recipes-api/service"
+	"recipes-api/translations"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TranslationController struct {
+	recipes      *service.RecipeService
+	previews     *previews.Service
+	translations *translations.Service
+}
+
+func NewTranslationController(recipeService *service.RecipeService, previewService *previews.Service, translationService *translations.Service) *TranslationController {
+	return &TranslationController{recipes: recipeService, previews: previewService, translations: translationService}
+}
+
+// translationRequest holds the translated fields. Lists are translated
+// item by item, so they must be as long as the recipe's.
+type translationRequest struct {
+	Name         string   `json:"name" binding:"omitempty,notblank,max=200"`
+	Ingredients  []string `json:"ingredients" binding:"dive,notblank"`
+	Instructions []string `json:"instructions" binding:"dive,notblank"`
+}
+
+// @Summary Add or update a translation
+// @Description Store a recipe's name, ingredients and steps in another language, replacing an earlier translation into it. Fields left out are read in the original language.
+// @Tags recipes
+// @Accept json
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Param locale path string true "BCP 47 language tag, e.g. sw or pt-BR"
+// @Param translation body translationRequest true "Translated fields"
+// @Success 200 {object} models.RecipeTranslation
+// @Failure 400 {object} apierrors.Error
+// @Failure 404 {object} apierrors.Error
+// @Failure 422 {object} apierrors.Error
+// @Router /recipes/{id}/translations/{locale} [put]
+func (t *TranslationController) PutTranslationHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req translationRequest
+	if err := middleware.BindJSON(c, &req); err != nil {
+		bindFailed(c, err)
+		return
+	}
+	if req.Name == "" && len(req.Ingredients) == 0 && len(req.Instructions) == 0 {
+		apierrors.Write(c, apierrors.BadRequest("A translation needs a name, ingredients or instructions"))
+		return
+	}
+	locale, err := translations.Locale(c.Param("locale"))
+	if err != nil {
+		apierrors.Write(c, apierrors.BadRequest("Unknown language {locale}").With("locale", c.Param("locale")))
+		return
+	}
+
+	recipe, err := t.recipes.Get(ctx, c.Param("id"))
+	if errors.Is(err, service.ErrNotFound) {
+		apierrors.Write(c, apierrors.NotFound("Recipe not found"))
+		return
+	}
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to fetch recipe"))
+		return
+	}
+	if req.Ingredients != nil && len(req.Ingredients) != len(recipe.Ingredients) {
+		apierrors.Write(c, apierrors.BadRequest("ingredients must have as many items as the recipe's"))
+		return
+	}
+	if req.Instructions != nil && len(req.Instructions) != len(recipe.Instructions) {
+		apierrors.Write(c, apierrors.BadRequest("instructions must have as many items as the recipe's"))
+		return
+	}
+
+	translation, err := t.translations.Put(ctx, models.RecipeTranslation{
+		RecipeID:     recipe.ID,
+		Locale:       locale,
+		Name:         req.Name,
+		Ingredients:  req.Ingredients,
+		Instructions: req.Instructions,
+	})
+	if errors.Is(err, translations.ErrOriginal) {
+		apierrors.Write(c, apierrors.BadRequest("Recipes are already written in {locale}").With("locale", locale))
+		return
+	}
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to save translation"))
+		return
+	}
+	c.JSON(http.StatusOK, translation)
+}
+
+// @Summary List a recipe's translations
+// @Tags recipes
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Success 200 {array} models.RecipeTranslation
+// @Failure 404 {object} apierrors.Error
+// @Router /recipes/{id}/translations [get]
+func (t *TranslationController) ListTranslationsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	recipe, err := t.recipes.Get(ctx, c.Param("id"))
+	if errors.Is(err, service.ErrNotFound) {
+		apierrors.Write(c, apierrors.NotFound("Recipe not found"))
+		return
+	}
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to fetch recipe"))
+		return
+	}
+	if !canView(c, t.previews, recipe) {
+		return
+	}
+
+	list, err := t.translations.List(ctx, recipe.ID)
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to fetch translations"))
+		return
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// @Summary Delete a translation
+// @Tags recipes
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Param locale path string true "BCP 47 language tag"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} apierrors.Error
+// @Router /recipes/{id}/translations/{locale} [delete]
+func (t *TranslationController) DeleteTranslationHandler(c *gin.Context) {
+	locale, err := translations.Locale(c.Param("locale"))
+	if err == nil {
+		err = t.translations.Delete(c.Request.Context(), c.Param("id"), locale)
+	}
+	if locale == "" || errors.Is(err, translations.ErrNotFound) {
+		apierrors.Write(c, apierrors.NotFound("Translation not found"))
+		return
+	}
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to delete translation"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Translation has been deleted"})
+}
+
+// localize translates the recipe into the language asked for with ?lang=,
+// or else with Accept-Language, and says which one it is in. Failing to
+// load the translations leaves the recipe as written.
+func localize(c *gin.Context, translationService *translations.Service, recipe *models.Recipe) {
+	wanted := c.Query("lang")
+	if wanted == "" {
+		wanted = c.GetHeader("Accept-Language")
+	}
+	tag, err := translationService.Localize(c.Request.Context(), recipe, wanted)
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Error loading translations", "recipe_id", recipe.ID, "error", err)
+	}
+	c.Header("Content-Language", tag.String())
+}
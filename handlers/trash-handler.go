@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"net/http"
+	"recipes-api/apierrors"
+	"recipes-api/events"
+	"recipes-api/models"
+	"recipes-api/serializer"
+	"recipes-api/service"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type trashedRecipe struct {
+	models.Recipe
+	DeletedAt time.Time `json:"deletedAt"`
+}
+
+// @Summary Restore a deleted recipe
+// @Description Bring back a recipe that was moved to the trash
+// @Tags recipes
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Success 200 {object} models.Recipe
+// @Failure 404 {object} apierrors.Error
+// @Router /recipes/{id}/restore [post]
+func (r *RecipeController) RestoreRecipeHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := c.Param("id")
+
+	var recipe models.Recipe
+	if err := r.db.WithContext(ctx).Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&recipe).Error; err != nil {
+		apierrors.Write(c, apierrors.NotFound("Deleted recipe not found"))
+		return
+	}
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&recipe).Update("deleted_at", nil).Error; err != nil {
+			return err
+		}
+		if err := service.LinkTags(tx, recipe); err != nil {
+			return err
+		}
+		// a recipe merged away is reachable again, by id and by slug
+		if err := service.RemoveRedirect(tx, recipe.Slug); err != nil {
+			return err
+		}
+		return service.RemoveRedirect(tx, recipe.ID)
+	})
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to restore recipe"))
+		return
+	}
+
+	r.clearRecipeCache(ctx, recipe.ID)
+	r.events.Publish(ctx, events.NewEvent(events.RecipeRestored, recipe))
+
+	serializer.JSON(c, http.StatusOK, recipe)
+}
+
+// @Summary List trashed recipes
+// @Description List soft-deleted recipes, most recently deleted first
+// @Tags admin
+// @Produce json
+// @Success 200 {array} trashedRecipe
+// @Router /admin/recipes/trash [get]
+func (r *RecipeController) ListTrashHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var recipes []models.Recipe
+	if err := r.db.WithContext(ctx).Unscoped().Where("deleted_at IS NOT NULL").Order("deleted_at DESC").Find(&recipes).Error; err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to fetch trashed recipes"))
+		return
+	}
+
+	trashed := make([]trashedRecipe, 0, len(recipes))
+	for _, recipe := range recipes {
+		trashed = append(trashed, trashedRecipe{Recipe: recipe, DeletedAt: recipe.DeletedAt.Time})
+	}
+
+	serializer.List(c, trashed, serializer.Meta{})
+}
+
+// @Summary Purge a trashed recipe
+// @Description Permanently delete a soft-deleted recipe and its revisions
+// @Tags admin
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} apierrors.Error
+// @Router /admin/recipes/trash/{id} [delete]
+func (r *RecipeController) PurgeRecipeHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := c.Param("id")
+
+	var recipe models.Recipe
+	if err := r.db.WithContext(ctx).Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&recipe).Error; err != nil {
+		apierrors.Write(c, apierrors.NotFound("Deleted recipe not found"))
+		return
+	}
+
+	if err := purgeRecipes(r.db.WithContext(ctx), []string{recipe.ID}); err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to purge recipe"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Recipe has been purged"})
+}
+
+// @Summary Empty the trash
+// @Description Permanently delete all soft-deleted recipes and their revisions
+// @Tags admin
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/recipes/trash [delete]
+func (r *RecipeController) EmptyTrashHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var ids []string
+	if err := r.db.WithContext(ctx).Unscoped().Model(&models.Recipe{}).Where("deleted_at IS NOT NULL").Pluck("id", &ids).Error; err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to purge recipes"))
+		return
+	}
+
+	if err := purgeRecipes(r.db.WithContext(ctx), ids); err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to purge recipes"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Trash has been emptied", "purged": len(ids)})
+}
+
+func purgeRecipes(db *gorm.DB, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("recipe_id IN ?", ids).Delete(&models.RecipeRevision{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("recipe_id IN ?", ids).Delete(&models.RecipeInstructions{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("id IN ?", ids).Delete(&models.Recipe{}).Error
+	})
+}
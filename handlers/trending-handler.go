@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"recipes-api/apierrors"
+	"recipes-api/models"
+	"recipes-api/service"
+	"recipes-api/views"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxTrendingRecipes caps how many recipes GET /recipes/trending returns.
+const maxTrendingRecipes = 50
+
+// TrendingRecipe is a published recipe with how many times it was read in
+// the window.
+type TrendingRecipe struct {
+	models.RecipeSummary
+	Views int `json:"views"`
+}
+
+// @Summary List trending recipes
+// @Description List the published recipes read the most over the last 24 hours or 7 days, most read first. Views are counted by the hour, so the window moves an hour at a time.
+// @Tags recipes
+// @Produce json
+// @Param window query string false "24h, the default, or 7d"
+// @Param limit query int false "How many recipes to list, 10 by default and at most 50"
+// @Success 200 {array} TrendingRecipe
+// @Failure 400 {object} apierrors.Error
+// @Router /recipes/trending [get]
+func (r *RecipeController) TrendingRecipesHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	window, ok := views.Windows[c.DefaultQuery("window", "24h")]
+	if !ok {
+		apierrors.Write(c, apierrors.BadRequest("window must be 24h or 7d"))
+		return
+	}
+	limit := 10
+	if param := c.Query("limit"); param != "" {
+		n, err := strconv.Atoi(param)
+		if err != nil || n < 1 || n > maxTrendingRecipes {
+			apierrors.Write(c, apierrors.BadRequest("limit must be between 1 and {max}").With("max", strconv.Itoa(maxTrendingRecipes)))
+			return
+		}
+		limit = n
+	}
+
+	// twice as many are ranked, as some may have been deleted or
+	// unpublished since they were read
+	entries, err := r.views.Trending(ctx, window, 2*limit)
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to fetch trending recipes"))
+		return
+	}
+	ids := make([]string, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry.RecipeID
+	}
+	recipes, err := r.recipes.GetMany(ctx, ids)
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to fetch trending recipes"))
+		return
+	}
+
+	published := make(map[string]models.Recipe, len(recipes))
+	now := time.Now()
+	for _, recipe := range recipes {
+		if recipe.IsPublished(now) {
+			published[recipe.ID] = recipe
+		}
+	}
+	trending := make([]TrendingRecipe, 0, limit)
+	for _, entry := range entries {
+		recipe, ok := published[entry.RecipeID]
+		if !ok || len(trending) == limit {
+			continue
+		}
+		trending = append(trending, TrendingRecipe{RecipeSummary: models.NewRecipeSummary(recipe), Views: entry.Views})
+	}
+	c.JSON(http.StatusOK, trending)
+}
+
+// RecipeStats are the view counts of a recipe.
+type RecipeStats struct {
+	RecipeID     string `json:"recipeId"`
+	Views        int64  `json:"views"`
+	ViewsLast24h int64  `json:"viewsLast24h"`
+	ViewsLast7d  int64  `json:"viewsLast7d"`
+}
+
+// @Summary Get recipe stats
+// @Description Get how many times a published recipe has been read, in total and over the last 24 hours and 7 days. Windows move an hour at a time.
+// @Tags recipes
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Success 200 {object} RecipeStats
+// @Failure 404 {object} apierrors.Error
+// @Router /recipes/{id}/stats [get]
+func (r *RecipeController) RecipeStatsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	recipe, err := r.recipes.Get(ctx, c.Param("id"))
+	if errors.Is(err, service.ErrNotFound) || err == nil && !recipe.IsPublished(time.Now()) {
+		apierrors.Write(c, apierrors.NotFound("Recipe not found"))
+		return
+	}
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to fetch recipe"))
+		return
+	}
+
+	stats := RecipeStats{RecipeID: recipe.ID}
+	if stats.Views, err = r.views.Count(ctx, recipe.ID); err == nil {
+		if stats.ViewsLast24h, err = r.views.Since(ctx, recipe.ID, views.Windows["24h"]); err == nil {
+			stats.ViewsLast7d, err = r.views.Since(ctx, recipe.ID, views.Windows["7d"])
+		}
+	}
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to fetch recipe stats"))
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"recipes-api/apierrors"
+	"recipes-api/events"
+	"recipes-api/middleware"
+	"recipes-api/models"
+	"recipes-api/webhooks"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/xid"
+	"gorm.io/gorm"
+)
+
+// replayPageSize caps the events one replay request reads from the outbox.
+const replayPageSize = 1000
+
+// replayResponse says how many events a replay queued and, when it didn't
+// get to all of them, the since to replay the rest from.
+type replayResponse struct {
+	Message string `json:"message"`
+	Queued  int    `json:"queued"`
+	Next    string `json:"next,omitempty"`
+}
+
+type WebhookController struct {
+	db         *gorm.DB
+	dispatcher *webhooks.Dispatcher
+	outbox     *events.Outbox
+}
+
+func NewWebhookController(db *gorm.DB, dispatcher *webhooks.Dispatcher, outbox *events.Outbox) *WebhookController {
+	return &WebhookController{db: db, dispatcher: dispatcher, outbox: outbox}
+}
+
+type webhookRequest struct {
+	URL           string   `json:"url" binding:"required"`
+	Secret        string   `json:"secret"`
+	Events        []string `json:"events"`
+	SchemaVersion string   `json:"schemaVersion"`
+}
+
+// createdWebhook is only returned on registration, the one time the secret is shown.
+type createdWebhook struct {
+	models.Webhook
+	Secret string `json:"secret"`
+}
+
+// @Summary Register a webhook
+// @Description Register a target URL for signed recipe events. A secret is generated when none is given; it is only returned in this response.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param webhook body webhookRequest true "Webhook"
+// @Success 201 {object} createdWebhook
+// @Failure 400 {object} apierrors.Error
+// @Router /admin/webhooks [post]
+func (w *WebhookController) CreateWebhookHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req webhookRequest
+	if err := middleware.BindJSON(c, &req); err != nil {
+		bindFailed(c, err)
+		return
+	}
+
+	if err := w.dispatcher.CheckURL(req.URL); err != nil {
+		apierrors.Write(c, apierrors.BadRequest(err.Error()))
+		return
+	}
+	for _, t := range req.Events {
+		if !slices.Contains(events.Types, t) {
+			apierrors.Write(c, apierrors.BadRequest("Unknown event type {type}").With("type", t))
+			return
+		}
+	}
+	if req.SchemaVersion == "" {
+		req.SchemaVersion = events.CurrentSchemaVersion
+	}
+	if !events.IsSchemaVersion(req.SchemaVersion) {
+		apierrors.Write(c, apierrors.BadRequest("Unknown schema version"))
+		return
+	}
+	if req.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			apierrors.Write(c, apierrors.Internal("Failed to generate secret"))
+			return
+		}
+		req.Secret = hex.EncodeToString(secret)
+	}
+
+	hook := models.Webhook{
+		ID:            xid.New().String(),
+		URL:           req.URL,
+		Secret:        req.Secret,
+		Events:        req.Events,
+		SchemaVersion: req.SchemaVersion,
+		Active:        true,
+	}
+	if err := w.db.WithContext(ctx).Create(&hook).Error; err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to register webhook"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, createdWebhook{Webhook: hook, Secret: hook.Secret})
+}
+
+// @Summary List webhooks
+// @Description List registered webhooks
+// @Tags admin
+// @Produce json
+// @Success 200 {array} models.Webhook
+// @Router /admin/webhooks [get]
+func (w *WebhookController) ListWebhooksHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var hooks []models.Webhook
+	if err := w.db.WithContext(ctx).Order("created_at DESC").Find(&hooks).Error; err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to fetch webhooks"))
+		return
+	}
+
+	c.JSON(http.StatusOK, hooks)
+}
+
+// @Summary Delete a webhook
+// @Description Stop delivering events to a webhook and remove its delivery log
+// @Tags admin
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} apierrors.Error
+// @Router /admin/webhooks/{id} [delete]
+func (w *WebhookController) DeleteWebhookHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := c.Param("id")
+
+	var hook models.Webhook
+	if err := w.db.WithContext(ctx).Where("id = ?", id).First(&hook).Error; err != nil {
+		apierrors.Write(c, apierrors.NotFound("Webhook not found"))
+		return
+	}
+
+	err := w.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", id).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&hook).Error
+	})
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to delete webhook"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook has been deleted"})
+}
+
+// @Summary List webhook deliveries
+// @Description List delivery attempts for a webhook, newest first
+// @Tags admin
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Param limit query int false "Maximum number of attempts to return (default 100)"
+// @Success 200 {array} models.WebhookDelivery
+// @Failure 404 {object} apierrors.Error
+// @Router /admin/webhooks/{id}/deliveries [get]
+func (w *WebhookController) ListDeliveriesHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := c.Param("id")
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if err != nil || limit <= 0 {
+		limit = 100
+	}
+
+	var hook models.Webhook
+	if err := w.db.WithContext(ctx).Where("id = ?", id).First(&hook).Error; err != nil {
+		apierrors.Write(c, apierrors.NotFound("Webhook not found"))
+		return
+	}
+
+	var deliveries []models.WebhookDelivery
+	if err := w.db.WithContext(ctx).Where("webhook_id = ?", id).Order("created_at DESC").Limit(limit).Find(&deliveries).Error; err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to fetch deliveries"))
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// @Summary Replay webhook events
+// @Description Re-deliver events from the outbox that occurred in [since, until) to a webhook, up to 1000 at a time. When more are left, or the delivery queue filled up, the response's next is the since to replay the rest from; events at that very time may be delivered twice.
+// @Tags admin
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Param since query string true "RFC 3339 start time"
+// @Param until query string false "RFC 3339 end time, defaults to now"
+// @Success 202 {object} replayResponse
+// @Failure 400 {object} apierrors.Error
+// @Failure 404 {object} apierrors.Error
+// @Failure 503 {object} apierrors.Error
+// @Router /admin/webhooks/{id}/replay [post]
+// @Router /webhooks/{id}/replay [post]
+func (w *WebhookController) ReplayHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+	id := c.Param("id")
+
+	since, err := time.Parse(time.RFC3339, c.Query("since"))
+	if err != nil {
+		apierrors.Write(c, apierrors.BadRequest("since must be an RFC 3339 time"))
+		return
+	}
+	var until time.Time
+	if v := c.Query("until"); v != "" {
+		if until, err = time.Parse(time.RFC3339, v); err != nil {
+			apierrors.Write(c, apierrors.BadRequest("until must be an RFC 3339 time"))
+			return
+		}
+	}
+
+	var hook models.Webhook
+	if err := w.db.WithContext(ctx).Where("id = ?", id).First(&hook).Error; err != nil {
+		apierrors.Write(c, apierrors.NotFound("Webhook not found"))
+		return
+	}
+
+	// one more than a page, so the next page starts at an event not replayed
+	list, err := w.outbox.Since(ctx, since, until, replayPageSize+1)
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to read events"))
+		return
+	}
+	page := list[:min(len(list), replayPageSize)]
+
+	queued, done := w.dispatcher.Replay(hook, page)
+	if queued == 0 && done < len(page) {
+		c.Header("Retry-After", "30")
+		apierrors.Write(c, apierrors.Unavailable("The webhook delivery queue is full"))
+		return
+	}
+
+	response := replayResponse{Message: "Events queued for delivery", Queued: queued}
+	if done < len(list) {
+		response.Next = list[done].OccurredAt.UTC().Format(time.RFC3339Nano)
+	}
+	c.JSON(http.StatusAccepted, response)
+}
@@ -0,0 +1,182 @@
+// Package i18n translates the messages the API responds with into the
+// language asked for with Accept-Language.
+//
+// Messages are written in English and keyed by their English text, so a
+// message without a translation falls back to the text itself. A bundle
+// holds the translations of one language, as a JSON object named after its
+// tag, e.g. sw.json. Placeholders such as {limit} are filled in after
+// translating and must be kept by translations.
+package i18n
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// Default is the language messages are written in.
+var Default = language.English
+
+//go:embed locales/*.json
+var builtin embed.FS
+
+var placeholder = regexp.MustCompile(`\{[a-zA-Z]+\}`)
+
+// Catalog holds the bundles of every supported language.
+type Catalog struct {
+	bundles map[language.Tag]map[string]string
+	// tags are the supported languages, the default first, for matching.
+	tags    []language.Tag
+	matcher language.Matcher
+}
+
+// Load reads the built-in bundles and then those in dir, if not empty,
+// whose translations take precedence.
+func Load(dir string) (*Catalog, error) {
+	c := &Catalog{bundles: map[language.Tag]map[string]string{}}
+	if err := c.add(builtin, "locales"); err != nil {
+		return nil, err
+	}
+	if dir != "" {
+		if err := c.add(os.DirFS(dir), "."); err != nil {
+			return nil, err
+		}
+	}
+
+	c.tags = []language.Tag{Default}
+	for tag := range c.bundles {
+		if tag != Default {
+			c.tags = append(c.tags, tag)
+		}
+	}
+	// map order would make matching between equally good tags random
+	slices.SortFunc(c.tags[1:], func(a, b language.Tag) int {
+		return strings.Compare(a.String(), b.String())
+	})
+	c.matcher = language.NewMatcher(c.tags)
+	return c, nil
+}
+
+// add reads every bundle in dir of fsys. A translation that drops or adds
+// a placeholder is an error, as the filled in message would be wrong.
+func (c *Catalog) add(fsys fs.FS, dir string) error {
+	files, err := fs.Glob(fsys, path.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		tag, err := language.Parse(strings.TrimSuffix(path.Base(file), ".json"))
+		if err != nil {
+			return fmt.Errorf("bundle %s: %w", file, err)
+		}
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return fmt.Errorf("bundle %s: %w", file, err)
+		}
+
+		bundle := c.bundles[tag]
+		if bundle == nil {
+			bundle = map[string]string{}
+			c.bundles[tag] = bundle
+		}
+		for message, translation := range messages {
+			if !samePlaceholders(message, translation) {
+				return fmt.Errorf("bundle %s: translation of %q must keep its placeholders", file, message)
+			}
+			bundle[message] = translation
+		}
+	}
+	return nil
+}
+
+func samePlaceholders(a, b string) bool {
+	pa, pb := placeholder.FindAllString(a, -1), placeholder.FindAllString(b, -1)
+	slices.Sort(pa)
+	slices.Sort(pb)
+	return slices.Equal(pa, pb)
+}
+
+// Localizer translates into one language. Messages missing from its
+// bundle are looked up in the bundles of its parent languages, e.g. sw for
+// sw-KE, and are left in English if none has them.
+type Localizer struct {
+	tag   language.Tag
+	chain []map[string]string
+}
+
+// Localizer returns the localizer for the best supported match of an
+// Accept-Language header, English when nothing matches.
+func (c *Catalog) Localizer(acceptLanguage string) *Localizer {
+	wanted, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(wanted) == 0 {
+		return &Localizer{tag: Default}
+	}
+	_, i, _ := c.matcher.Match(wanted...)
+
+	l := &Localizer{tag: c.tags[i]}
+	for tag := l.tag; !tag.IsRoot(); tag = tag.Parent() {
+		if bundle, ok := c.bundles[tag]; ok {
+			l.chain = append(l.chain, bundle)
+		}
+	}
+	return l
+}
+
+// Language is the language translated into, for Content-Language.
+func (l *Localizer) Language() language.Tag {
+	if l == nil {
+		return Default
+	}
+	return l.tag
+}
+
+// Translate returns message in the localizer's language with params
+// filled in. A nil localizer leaves it in English.
+func (l *Localizer) Translate(message string, params map[string]string) string {
+	if l != nil {
+		for _, bundle := range l.chain {
+			if translation, ok := bundle[message]; ok {
+				message = translation
+				break
+			}
+		}
+	}
+	return Fill(message, params)
+}
+
+// Fill replaces the {name} placeholders in message with params.
+func Fill(message string, params map[string]string) string {
+	if len(params) == 0 {
+		return message
+	}
+	pairs := make([]string, 0, 2*len(params))
+	for name, value := range params {
+		pairs = append(pairs, "{"+name+"}", value)
+	}
+	return strings.NewReplacer(pairs...).Replace(message)
+}
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, l *Localizer) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the localizer stored in ctx, or nil.
+func FromContext(ctx context.Context) *Localizer {
+	l, _ := ctx.Value(contextKey{}).(*Localizer)
+	return l
+}
@@ -0,0 +1,117 @@
+// Package ids generates the IDs of new recipes. Deployments that move
+// recipes over from another system can pick the kind of ID it used, so
+// the IDs look alike; other records keep their xid IDs.
+package ids
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/xid"
+)
+
+// Generator strategies.
+const (
+	XID       = "xid"
+	UUIDv7    = "uuidv7"
+	Snowflake = "snowflake"
+)
+
+// Generator makes IDs that are unique across the deployment.
+type Generator interface {
+	NewID() string
+}
+
+// Config selects the generator. Node tells the servers of a deployment
+// apart for snowflake IDs, and must differ between them.
+type Config struct {
+	Strategy string
+	Node     int64
+}
+
+// New returns the generator cfg selects.
+func New(cfg Config) (Generator, error) {
+	switch cfg.Strategy {
+	case XID:
+		return XIDGenerator{}, nil
+	case UUIDv7:
+		return UUIDv7Generator{}, nil
+	case Snowflake:
+		return NewSnowflakeGenerator(cfg.Node)
+	default:
+		return nil, fmt.Errorf("%q is not xid, uuidv7 or snowflake", cfg.Strategy)
+	}
+}
+
+// XIDGenerator makes 20 character xid IDs, sortable by creation time.
+type XIDGenerator struct{}
+
+func (XIDGenerator) NewID() string {
+	return xid.New().String()
+}
+
+// UUIDv7Generator makes time-ordered UUIDs as in RFC 9562.
+type UUIDv7Generator struct{}
+
+func (UUIDv7Generator) NewID() string {
+	// NewV7 only fails when the system's random source does
+	return uuid.Must(uuid.NewV7()).String()
+}
+
+const (
+	nodeBits     = 10
+	sequenceBits = 12
+	// MaxNode is the largest node a snowflake generator can have.
+	MaxNode = 1<<nodeBits - 1
+)
+
+// snowflakeEpoch is when snowflake timestamps start, leaving them room for
+// about 69 years.
+var snowflakeEpoch = time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// SnowflakeGenerator makes Twitter style snowflake IDs in decimal: 41 bits
+// of milliseconds since 2020, 10 bits of node and a 12 bit sequence within
+// the millisecond.
+type SnowflakeGenerator struct {
+	node int64
+
+	mu       sync.Mutex
+	last     int64
+	sequence int64
+}
+
+// NewSnowflakeGenerator returns a generator for node, between 0 and
+// MaxNode.
+func NewSnowflakeGenerator(node int64) (*SnowflakeGenerator, error) {
+	if node < 0 || node > MaxNode {
+		return nil, fmt.Errorf("snowflake node %d is not between 0 and %d", node, MaxNode)
+	}
+	return &SnowflakeGenerator{node: node}, nil
+}
+
+func (g *SnowflakeGenerator) NewID() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	// a clock set back keeps counting from the last time used, so IDs
+	// never repeat
+	now := max(time.Since(snowflakeEpoch).Milliseconds(), g.last)
+	if now == g.last {
+		g.sequence = (g.sequence + 1) & (1<<sequenceBits - 1)
+		if g.sequence == 0 {
+			// the millisecond is used up
+			for now <= g.last {
+				time.Sleep(100 * time.Microsecond)
+				now = time.Since(snowflakeEpoch).Milliseconds()
+			}
+		}
+	} else {
+		g.sequence = 0
+	}
+	g.last = now
+
+	return strconv.FormatInt(now<<(nodeBits+sequenceBits)|g.node<<sequenceBits|g.sequence, 10)
+}
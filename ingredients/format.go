@@ -0,0 +1,118 @@
+package ingredients
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// fractions are the ones cooks measure with, as written in recipes.
+var fractions = []struct {
+	value float64
+	text  string
+}{{0, ""}, {0.125, "1/8"}, {0.25, "1/4"}, {1.0 / 3, "1/3"}, {0.5, "1/2"}, {2.0 / 3, "2/3"}, {0.75, "3/4"}, {1, ""}}
+
+// abbreviations are how units are written back; units missing from it are
+// written out and pluralized.
+var abbreviations = map[string]string{
+	"tablespoon": "tbsp", "teaspoon": "tsp", "ounce": "oz", "fluid ounce": "fl oz",
+	"pound": "lb", "gram": "g", "kilogram": "kg", "milligram": "mg",
+	"milliliter": "ml", "liter": "l",
+}
+
+// FormatAmount writes an amount the way a recipe would: whole numbers and
+// the nearest common fraction ("1 1/2", "1/3") when fractions is set, as
+// for cups and spoons, or else a decimal with at most as many places as
+// the amount's size calls for ("250", "1.5").
+func FormatAmount(v float64, fractions bool) string {
+	if v <= 0 {
+		return "0"
+	}
+	if fractions && v < 20 {
+		return fraction(v)
+	}
+	switch {
+	case v >= 100:
+		v = math.Round(v)
+	case v >= 10:
+		v = math.Round(v*2) / 2
+	default:
+		v = math.Round(v*10) / 10
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func fraction(v float64) string {
+	whole := math.Floor(v)
+	best := fractions[0]
+	for _, f := range fractions {
+		if math.Abs(v-whole-f.value) < math.Abs(v-whole-best.value) {
+			best = f
+		}
+	}
+	if best.value == 1 {
+		whole++
+	}
+	switch {
+	case whole == 0 && best.text == "":
+		// less than an eighth is still some
+		return "1/8"
+	case whole == 0:
+		return best.text
+	case best.text == "":
+		return strconv.FormatFloat(whole, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(whole, 'f', -1, 64) + " " + best.text
+}
+
+// String writes the line back out from its parts, as
+// "quantity unit item, comment". Lines without a quantity are returned as
+// they were written.
+func (l Line) String() string {
+	if l.Quantity == 0 {
+		return l.Input
+	}
+	useFractions := l.Unit == "" || abbreviations[l.Unit] == "" || l.Unit == "tablespoon" || l.Unit == "teaspoon"
+
+	amount := FormatAmount(l.Quantity, useFractions)
+	parts := []string{amount}
+	if l.QuantityMax > 0 {
+		amount = FormatAmount(l.QuantityMax, useFractions)
+		parts[0] += "-" + amount
+	}
+	if l.Unit != "" {
+		parts = append(parts, unitLabel(l.Unit, amount))
+	}
+	// sizes in parentheses go back before the item: "1 can (14 oz) tomatoes"
+	var comments []string
+	for _, comment := range strings.Split(l.Comment, ", ") {
+		if strings.HasPrefix(comment, "(") {
+			parts = append(parts, comment)
+		} else if comment != "" {
+			comments = append(comments, comment)
+		}
+	}
+	parts = append(parts, l.Item)
+
+	s := strings.Join(parts, " ")
+	if len(comments) > 0 {
+		s += ", " + strings.Join(comments, ", ")
+	}
+	return s
+}
+
+// unitLabel writes unit for an amount as formatted, "1 cup" but "1 1/2
+// cups".
+func unitLabel(unit, amount string) string {
+	if abbreviation, ok := abbreviations[unit]; ok {
+		return abbreviation
+	}
+	if amount == "1" || !strings.Contains(amount, " ") && strings.Contains(amount, "/") {
+		return unit
+	}
+	switch {
+	case strings.HasSuffix(unit, "ch"), strings.HasSuffix(unit, "sh"):
+		return unit + "es"
+	}
+	return unit + "s"
+}
@@ -0,0 +1,234 @@
+// Package ingredients parses free-text ingredient lines such as
+// "1 1/2 cups packed brown sugar" into quantity, unit and item.
+package ingredients
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Line is a parsed ingredient line. Quantity is zero when the line has
+// none; QuantityMax is set for ranges like "2-3 cloves garlic".
+type Line struct {
+	Input       string  `json:"input"`
+	Quantity    float64 `json:"quantity,omitempty"`
+	QuantityMax float64 `json:"quantityMax,omitempty"`
+	Unit        string  `json:"unit,omitempty"`
+	Item        string  `json:"item"`
+	// Comment holds preparation notes and asides: "finely chopped",
+	// "(14 oz)", "to taste".
+	Comment string `json:"comment,omitempty"`
+	// Confidence between 0 and 1 of how well the line fit the expected
+	// "quantity unit item, comment" shape.
+	Confidence float64 `json:"confidence"`
+}
+
+var unicodeFractions = strings.NewReplacer(
+	"½", " 1/2", "⅓", " 1/3", "⅔", " 2/3", "¼", " 1/4", "¾", " 3/4",
+	"⅕", " 1/5", "⅖", " 2/5", "⅗", " 3/5", "⅘", " 4/5", "⅙", " 1/6",
+	"⅚", " 5/6", "⅛", " 1/8", "⅜", " 3/8", "⅝", " 5/8", "⅞", " 7/8",
+	"⁄", "/",
+)
+
+const number = `\d+\s+\d+/\d+|\d+/\d+|\d+(?:[.,]\d+)?`
+
+var (
+	// "1 1/2", "1/2", "1.5", optionally a range "2-3" or "2 to 3"
+	quantityPattern = regexp.MustCompile(`^(` + number + `)(?:\s*(?:-|–|to|or)\s*(` + number + `))?\s*`)
+	parensPattern   = regexp.MustCompile(`\s*\(([^)]*)\)`)
+	spacePattern    = regexp.MustCompile(`\s+`)
+	digitPattern    = regexp.MustCompile(`\d`)
+)
+
+var numberWords = map[string]float64{
+	"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5,
+	"six": 6, "seven": 7, "eight": 8, "nine": 9, "ten": 10, "eleven": 11,
+	"twelve": 12, "dozen": 12, "half": 0.5,
+}
+
+// units maps spellings to the unit reported. Single letter abbreviations
+// are case sensitive ("T" is a tablespoon, "t" a teaspoon) and looked up
+// as written; everything else is lowercased first.
+var units = map[string]string{
+	"T": "tablespoon", "t": "teaspoon", "c": "cup", "g": "gram", "l": "liter",
+
+	"cup": "cup", "cups": "cup",
+	"tablespoon": "tablespoon", "tablespoons": "tablespoon", "tbsp": "tablespoon", "tbsps": "tablespoon", "tbs": "tablespoon", "tbl": "tablespoon",
+	"teaspoon": "teaspoon", "teaspoons": "teaspoon", "tsp": "teaspoon", "tsps": "teaspoon",
+	"ounce": "ounce", "ounces": "ounce", "oz": "ounce",
+	"fl oz": "fluid ounce", "fluid ounce": "fluid ounce", "fluid ounces": "fluid ounce",
+	"pound": "pound", "pounds": "pound", "lb": "pound", "lbs": "pound",
+	"gram": "gram", "grams": "gram", "gr": "gram",
+	"kilogram": "kilogram", "kilograms": "kilogram", "kg": "kilogram", "kgs": "kilogram",
+	"milligram": "milligram", "milligrams": "milligram", "mg": "milligram",
+	"milliliter": "milliliter", "milliliters": "milliliter", "millilitre": "milliliter", "millilitres": "milliliter", "ml": "milliliter",
+	"liter": "liter", "liters": "liter", "litre": "liter", "litres": "liter",
+	"pint": "pint", "pints": "pint", "pt": "pint",
+	"quart": "quart", "quarts": "quart", "qt": "quart",
+	"gallon": "gallon", "gallons": "gallon", "gal": "gallon",
+	"pinch": "pinch", "pinches": "pinch",
+	"dash": "dash", "dashes": "dash",
+	"clove": "clove", "cloves": "clove",
+	"can": "can", "cans": "can", "tin": "can", "tins": "can",
+	"jar": "jar", "jars": "jar",
+	"package": "package", "packages": "package", "pkg": "package", "packet": "package", "packets": "package",
+	"slice": "slice", "slices": "slice",
+	"stick": "stick", "sticks": "stick",
+	"bunch": "bunch", "bunches": "bunch",
+	"handful": "handful", "handfuls": "handful",
+	"sprig": "sprig", "sprigs": "sprig",
+	"piece": "piece", "pieces": "piece",
+	"head": "head", "heads": "head",
+	"stalk": "stalk", "stalks": "stalk",
+}
+
+// phrases that stand in for a quantity
+var unmeasured = []string{"to taste", "as needed", "for serving", "for garnish", "optional"}
+
+// Parse splits an ingredient line into its parts. It never fails; lines
+// that don't fit get a low confidence instead.
+func Parse(input string) Line {
+	line := Line{Input: input}
+	rest := strings.TrimSpace(spacePattern.ReplaceAllString(unicodeFractions.Replace(input), " "))
+	rest = strings.TrimPrefix(strings.TrimPrefix(rest, "-"), "•")
+	rest = strings.TrimSpace(rest)
+	if rest == "" {
+		return line
+	}
+
+	var comments []string
+	confidence := 1.0
+
+	if m := quantityPattern.FindStringSubmatch(rest); m != nil {
+		line.Quantity = parseNumber(m[1])
+		if m[2] != "" {
+			line.QuantityMax = parseNumber(m[2])
+		}
+		rest = rest[len(m[0]):]
+	} else if word, after, _ := strings.Cut(rest, " "); numberWords[strings.ToLower(word)] > 0 && after != "" {
+		line.Quantity = numberWords[strings.ToLower(word)]
+		// "half a lemon"
+		rest = strings.TrimPrefix(strings.TrimPrefix(after, "a "), "an ")
+		confidence -= 0.1
+	}
+
+	// "2 (14 oz) cans": the package size belongs to the comment
+	if line.Quantity > 0 {
+		if loc := parensPattern.FindStringSubmatchIndex(rest); loc != nil && loc[0] == 0 {
+			comments = append(comments, "("+rest[loc[2]:loc[3]]+")")
+			rest = strings.TrimSpace(rest[loc[1]:])
+		}
+	}
+
+	if unit, n := lookupUnit(rest); n > 0 {
+		line.Unit = unit
+		rest = strings.TrimSpace(rest[n:])
+		rest = strings.TrimPrefix(rest, "of ")
+	}
+
+	for _, m := range parensPattern.FindAllStringSubmatch(rest, -1) {
+		comments = append(comments, "("+m[1]+")")
+	}
+	rest = parensPattern.ReplaceAllString(rest, "")
+
+	if item, comment, ok := strings.Cut(rest, ","); ok {
+		rest = item
+		comments = append(comments, strings.TrimSpace(comment))
+	}
+
+	measured := line.Quantity > 0
+	lower := strings.ToLower(rest)
+	for _, phrase := range unmeasured {
+		if i := strings.Index(lower, phrase); i > 0 {
+			comments = append(comments, rest[i:])
+			rest = rest[:i]
+			measured = true
+			break
+		}
+	}
+
+	line.Item = strings.Trim(strings.TrimSpace(rest), ",.;:")
+	line.Comment = strings.Join(nonEmpty(comments), ", ")
+
+	switch {
+	case line.Item == "":
+		confidence = 0
+	case !measured:
+		confidence -= 0.3
+	case line.Quantity > 0 && line.Unit == "":
+		// fine for countables ("2 eggs"), but often an unknown unit
+		confidence -= 0.1
+	}
+	if digitPattern.MatchString(line.Item) {
+		confidence -= 0.3
+	}
+	if len(strings.Fields(line.Item)) > 6 {
+		confidence -= 0.2
+	}
+	line.Confidence = math.Round(max(confidence, 0)*100) / 100
+
+	return line
+}
+
+// lookupUnit reports the unit at the start of s and how many bytes of s it
+// takes up, including a trailing period.
+func lookupUnit(s string) (string, int) {
+	words := strings.SplitN(s, " ", 3)
+	// try two word units like "fl oz" first
+	for n := min(len(words), 2); n > 0; n-- {
+		candidate := strings.Join(words[:n], " ")
+		length := len(candidate)
+		candidate = strings.TrimSuffix(candidate, ".")
+		// a unit needs something after it to apply to
+		if length == len(s) {
+			continue
+		}
+		if unit, ok := units[candidate]; ok && len(candidate) == 1 {
+			return unit, length
+		}
+		if unit, ok := units[strings.ToLower(candidate)]; ok && len(candidate) > 1 {
+			return unit, length
+		}
+	}
+	return "", 0
+}
+
+// parseNumber reads "1 1/2", "3/4", "1.5" and "1,5".
+func parseNumber(s string) float64 {
+	total := 0.0
+	for _, part := range strings.Fields(s) {
+		if num, den, ok := strings.Cut(part, "/"); ok {
+			n, _ := strconv.ParseFloat(num, 64)
+			d, _ := strconv.ParseFloat(den, 64)
+			if d != 0 {
+				total += n / d
+			}
+			continue
+		}
+		v, _ := strconv.ParseFloat(strings.Replace(part, ",", ".", 1), 64)
+		total += v
+	}
+	return math.Round(total*1000) / 1000
+}
+
+func nonEmpty(list []string) []string {
+	out := list[:0]
+	for _, s := range list {
+		if s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// CanonicalUnit returns the unit a spelling such as "tbsp" or "cups"
+// stands for, as Parse reports it.
+func CanonicalUnit(s string) (string, bool) {
+	if unit, ok := units[s]; ok {
+		return unit, true
+	}
+	unit, ok := units[strings.ToLower(strings.TrimSuffix(s, "."))]
+	return unit, ok
+}
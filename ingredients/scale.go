@@ -0,0 +1,44 @@
+package ingredients
+
+import "math"
+
+// counted are units of things bought and used whole or in halves.
+var counted = map[string]bool{
+	"": true, "clove": true, "can": true, "jar": true, "package": true, "slice": true,
+	"stick": true, "bunch": true, "sprig": true, "piece": true, "head": true, "stalk": true,
+}
+
+// Scale multiplies the quantity of line by factor. Counted items, such as
+// eggs or cans, are rounded to the nearest half and never drop below one
+// half; measures keep their precision for String to round.
+func (l Line) Scale(factor float64) Line {
+	if l.Quantity == 0 || factor == 1 {
+		return l
+	}
+	l.Quantity = scaleAmount(l.Quantity, factor, counted[l.Unit])
+	if l.QuantityMax > 0 {
+		l.QuantityMax = scaleAmount(l.QuantityMax, factor, counted[l.Unit])
+	}
+	return l
+}
+
+func scaleAmount(v, factor float64, whole bool) float64 {
+	v *= factor
+	if whole {
+		return max(math.Round(v*2)/2, 0.5)
+	}
+	return math.Round(v*1000) / 1000
+}
+
+// ScaleLines scales the quantities of ingredient lines by factor. Lines
+// without a quantity, such as "salt to taste", are kept as written.
+func ScaleLines(lines []string, factor float64) []string {
+	out := make([]string, len(lines))
+	for i, input := range lines {
+		out[i] = input
+		if line := Parse(input); line.Quantity > 0 && factor != 1 {
+			out[i] = line.Scale(factor).String()
+		}
+	}
+	return out
+}
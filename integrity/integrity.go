@@ -0,0 +1,295 @@
+// Package integrity finds data that no longer belongs to a recipe, such as
+// images and revisions of purged recipes, and removes it.
+package integrity
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"recipes-api/cache"
+	"recipes-api/models"
+	"recipes-api/storage"
+	"recipes-api/tracing"
+
+	"github.com/go-redis/redis"
+	"gorm.io/gorm"
+)
+
+// Kinds of problems the checker looks for.
+const (
+	OrphanedImages        = "orphaned-images"
+	OrphanedInstructions  = "orphaned-instructions"
+	OrphanedPreviews      = "orphaned-previews"
+	OrphanedRevisions     = "orphaned-revisions"
+	OrphanedSubscriptions = "orphaned-subscriptions"
+	OrphanedSummaries     = "orphaned-summaries"
+	OrphanedTagLinks      = "orphaned-tag-links"
+	OrphanedTranslations  = "orphaned-translations"
+	DanglingRedirects     = "dangling-redirects"
+	DanglingTags          = "dangling-tags"
+	StaleCacheKeys        = "stale-cache-keys"
+	UnusedTags            = "unused-tags"
+)
+
+const (
+	imagePrefix = "recipes/"
+	// imageGracePeriod keeps images that were just uploaded: they are
+	// stored before the recipe is updated to point at them.
+	imageGracePeriod = time.Hour
+	// maxListed caps the items reported per finding.
+	maxListed = 100
+)
+
+// Finding is what a check found, and fixed unless the run was a dry run.
+// Items are the recipe ids, object keys or tag names concerned.
+type Finding struct {
+	Kind  string   `json:"kind"`
+	Count int      `json:"count"`
+	Items []string `json:"items,omitempty"`
+}
+
+type Report struct {
+	DryRun     bool      `json:"dryRun"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+	Findings   []Finding `json:"findings"`
+	// Errors are the checks that failed; the others still ran.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// Checker runs the integrity checks. Without a store images are not
+// checked.
+type Checker struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+	store       storage.Store
+}
+
+func NewChecker(db *gorm.DB, redisClient *redis.Client, store storage.Store) *Checker {
+	return &Checker{db: db, redisClient: redisClient, store: store}
+}
+
+// check looks for one kind of problem and returns the items concerned,
+// having fixed them unless dryRun is set.
+type check struct {
+	kind string
+	run  func(ctx context.Context, dryRun bool) ([]string, error)
+}
+
+// Run performs every check and, unless dryRun is set, repairs what they
+// find.
+func (c *Checker) Run(ctx context.Context, dryRun bool) Report {
+	report := Report{DryRun: dryRun, StartedAt: time.Now().UTC(), Findings: []Finding{}}
+
+	checks := []check{
+		{OrphanedInstructions, c.orphanedRows(&models.RecipeInstructions{}, "recipe_id", true)},
+		{OrphanedPreviews, c.orphanedRows(&models.RecipePreview{}, "recipe_id", true)},
+		{OrphanedRevisions, c.orphanedRows(&models.RecipeRevision{}, "recipe_id", true)},
+		{OrphanedSubscriptions, c.orphanedRows(&models.RecipeSubscription{}, "recipe_id", true)},
+		{OrphanedSummaries, c.orphanedRows(&models.RecipeSummary{}, "id", false)},
+		{OrphanedTagLinks, c.orphanedRows(&models.RecipeTag{}, "recipe_id", false)},
+		{OrphanedTranslations, c.orphanedRows(&models.RecipeTranslation{}, "recipe_id", true)},
+		{DanglingRedirects, c.orphanedRows(&models.Redirect{}, "to_id", true)},
+		{DanglingTags, c.danglingTags},
+		{UnusedTags, c.unusedTags},
+		{StaleCacheKeys, c.staleCacheKeys},
+	}
+	if c.store != nil {
+		checks = append(checks, check{OrphanedImages, c.orphanedImages})
+	}
+
+	for _, step := range checks {
+		items, err := step.run(ctx, dryRun)
+		if err != nil {
+			slog.ErrorContext(ctx, "Integrity check failed", "check", step.kind, "error", err)
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", step.kind, err))
+			continue
+		}
+		if len(items) == 0 {
+			continue
+		}
+		finding := Finding{Kind: step.kind, Count: len(items), Items: items[:min(len(items), maxListed)]}
+		report.Findings = append(report.Findings, finding)
+	}
+
+	report.FinishedAt = time.Now().UTC()
+	return report
+}
+
+// RunEvery repairs at the given interval. It never returns.
+func (c *Checker) RunEvery(interval time.Duration) {
+	for {
+		time.Sleep(interval)
+		report := c.Run(context.Background(), false)
+		for _, finding := range report.Findings {
+			slog.Info("Repaired integrity problem", "kind", finding.Kind, "count", finding.Count)
+		}
+	}
+}
+
+// orphanedRows returns a check for rows of model whose column refers to a
+// recipe that doesn't exist. With includeDeleted, rows of trashed recipes
+// are kept so restoring brings them back too.
+func (c *Checker) orphanedRows(model any, column string, includeDeleted bool) func(context.Context, bool) ([]string, error) {
+	return func(ctx context.Context, dryRun bool) ([]string, error) {
+		db := c.db.WithContext(ctx)
+		recipes := db.Model(&models.Recipe{}).Select("id")
+		if includeDeleted {
+			recipes = recipes.Unscoped()
+		}
+
+		var ids []string
+		if err := db.Model(model).Distinct(column).Where(column+" NOT IN (?)", recipes).Pluck(column, &ids).Error; err != nil {
+			return nil, err
+		}
+		if dryRun || len(ids) == 0 {
+			return ids, nil
+		}
+		return ids, db.Where(column+" IN ?", ids).Delete(model).Error
+	}
+}
+
+// danglingTags finds recipes with blank or repeated tags and drops them.
+func (c *Checker) danglingTags(ctx context.Context, dryRun bool) ([]string, error) {
+	db := c.db.WithContext(ctx)
+
+	var ids []string
+	var batch []models.Recipe
+	err := db.Select("id", "tags").FindInBatches(&batch, 500, func(_ *gorm.DB, _ int) error {
+		for _, recipe := range batch {
+			tags := cleanTags(recipe.Tags)
+			if len(tags) == len(recipe.Tags) {
+				continue
+			}
+			ids = append(ids, recipe.ID)
+			if dryRun {
+				continue
+			}
+			err := db.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Model(&models.Recipe{ID: recipe.ID}).Select("tags").Updates(models.Recipe{Tags: tags}).Error; err != nil {
+					return err
+				}
+				return tx.Model(&models.RecipeSummary{ID: recipe.ID}).Select("tags").Updates(models.RecipeSummary{Tags: tags}).Error
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return ids, err
+	}
+
+	if !dryRun && len(ids) > 0 {
+		cache.InvalidateRecipes(ctx, c.redisClient, ids...)
+	}
+	return ids, nil
+}
+
+// unusedTags finds tags no recipe is filed under and deletes them. It runs
+// after the orphaned links are gone, so tags only trashed or purged
+// recipes had go too.
+func (c *Checker) unusedTags(ctx context.Context, dryRun bool) ([]string, error) {
+	db := c.db.WithContext(ctx)
+	linked := db.Model(&models.RecipeTag{}).Select("1").Where("recipe_tags.tag_id = tags.id")
+
+	var names []string
+	if err := db.Model(&models.Tag{}).Where("NOT EXISTS (?)", linked).Order("name").Pluck("name", &names).Error; err != nil {
+		return nil, err
+	}
+	if dryRun || len(names) == 0 {
+		return names, nil
+	}
+	// checked again, in case a recipe was filed under one since
+	return names, db.Where("name IN ? AND NOT EXISTS (?)", names, linked).Delete(&models.Tag{}).Error
+}
+
+// cleanTags drops blank tags and repeats, keeping the first spelling.
+func cleanTags(tags []string) []string {
+	cleaned := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if strings.TrimSpace(tag) == "" || slices.Contains(cleaned, tag) {
+			continue
+		}
+		cleaned = append(cleaned, tag)
+	}
+	return cleaned
+}
+
+// staleCacheKeys finds cached recipes that are deleted or gone from the
+// database and drops them.
+func (c *Checker) staleCacheKeys(ctx context.Context, dryRun bool) ([]string, error) {
+	keys, err := cache.RecipeKeys(ctx, c.redisClient)
+	if err != nil || len(keys) == 0 {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(keys))
+	for id := range keys {
+		ids = append(ids, id)
+	}
+	var live []string
+	if err := c.db.WithContext(ctx).Model(&models.Recipe{}).Where("id IN ?", ids).Pluck("id", &live).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range live {
+		delete(keys, id)
+	}
+
+	stale := make([]string, 0, len(keys))
+	for _, key := range keys {
+		stale = append(stale, key)
+	}
+	slices.Sort(stale)
+	if dryRun || len(stale) == 0 {
+		return stale, nil
+	}
+	return stale, tracing.Redis(ctx, c.redisClient).Del(stale...).Err()
+}
+
+// orphanedImages finds stored images no recipe, trashed or not, refers to
+// and deletes them.
+func (c *Checker) orphanedImages(ctx context.Context, dryRun bool) ([]string, error) {
+	objects, err := c.store.List(ctx, imagePrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	referenced := map[string]bool{}
+	var batch []models.Recipe
+	err = c.db.WithContext(ctx).Unscoped().Select("id", "image").Where("image IS NOT NULL").FindInBatches(&batch, 500, func(_ *gorm.DB, _ int) error {
+		for _, recipe := range batch {
+			if recipe.Image == nil {
+				continue
+			}
+			for _, key := range recipe.Image.Keys() {
+				referenced[key] = true
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := time.Now().Add(-imageGracePeriod)
+	var orphaned []string
+	for _, object := range objects {
+		if referenced[object.Key] || object.LastModified.After(cutoff) {
+			continue
+		}
+		orphaned = append(orphaned, object.Key)
+		if dryRun {
+			continue
+		}
+		if err := c.store.Delete(ctx, object.Key); err != nil {
+			return orphaned, err
+		}
+	}
+	return orphaned, nil
+}
@@ -0,0 +1,206 @@
+// Package lint checks draft recipes for common authoring mistakes.
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"recipes-api/ingredients"
+)
+
+// Warning codes.
+const (
+	NoIngredients     = "no-ingredients"
+	NoInstructions    = "no-instructions"
+	EmptyStep         = "empty-step"
+	MissingIngredient = "missing-ingredient"
+	UnusedIngredient  = "unused-ingredient"
+	MissingTemp       = "missing-temperature"
+	LongStep          = "long-step"
+)
+
+const (
+	maxStepWords = 80
+	maxStepChars = 500
+)
+
+// Warning is a single finding. Step and Ingredient are 1-based positions
+// in the recipe, zero when the warning isn't about one of them.
+type Warning struct {
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	Step       int    `json:"step,omitempty"`
+	Ingredient int    `json:"ingredient,omitempty"`
+}
+
+var (
+	heatPattern        = regexp.MustCompile(`(?i)\b(oven|bake[ds]?|baking|roast(?:ed|ing|s)?|preheat(?:ed)?|broil(?:ed)?)\b`)
+	temperaturePattern = regexp.MustCompile(`(?i)\d+\s*(?:°|º|degrees?\b|[fc]\b)|\bgas mark\b|\b(?:low|medium|high|moderate) (?:heat|oven)\b`)
+	wordPattern        = regexp.MustCompile(`[\p{L}]+`)
+)
+
+// pantry lists common ingredients looked for in the steps. A step that
+// names one of them when the ingredient list doesn't likely lost a line.
+var pantry = []string{
+	"almond", "apple", "bacon", "baking powder", "baking soda", "banana",
+	"basil", "bean", "beef", "bread", "broccoli", "broth", "butter",
+	"buttermilk", "cabbage", "carrot", "celery", "cheese", "chicken",
+	"chili", "chocolate", "cilantro", "cinnamon", "cocoa", "coconut",
+	"cream", "cucumber", "cumin", "egg", "flour", "garlic", "ginger",
+	"honey", "lemon", "lime", "maple syrup", "mayonnaise", "milk", "mint",
+	"mushroom", "mustard", "noodle", "nutmeg", "oat", "oil", "olive",
+	"onion", "oregano", "paprika", "parsley", "pasta", "pepper", "pork",
+	"potato", "rice", "salmon", "salt", "sausage", "shallot", "shrimp",
+	"soy sauce", "spinach", "sugar", "thyme", "tofu", "tomato", "vanilla",
+	"vinegar", "walnut", "wine", "yeast", "yogurt", "zucchini",
+}
+
+// descriptors are words of ingredient names that don't identify the
+// ingredient, so a step using them doesn't count as using it
+var descriptors = map[string]bool{
+	"a": true, "and": true, "or": true, "of": true, "the": true, "fresh": true,
+	"freshly": true, "large": true, "small": true, "medium": true, "whole": true,
+	"chopped": true, "minced": true, "diced": true, "sliced": true, "grated": true,
+	"ground": true, "packed": true, "extra": true, "virgin": true, "finely": true,
+	"roughly": true, "cold": true, "warm": true, "hot": true, "room": true,
+	"temperature": true, "softened": true, "melted": true, "dried": true,
+}
+
+// Recipe checks the ingredients and instructions of a draft. Warnings about
+// the whole recipe come first, then those about steps in step order, then
+// unused ingredients.
+func Recipe(ingredientLines, instructions []string) []Warning {
+	warnings := []Warning{}
+	if len(ingredientLines) == 0 {
+		warnings = append(warnings, Warning{Code: NoIngredients, Message: "The recipe has no ingredients"})
+	}
+	if len(instructions) == 0 {
+		warnings = append(warnings, Warning{Code: NoInstructions, Message: "The recipe has no instructions"})
+	}
+
+	listed := map[string]bool{}
+	items := make([]string, len(ingredientLines))
+	for i, line := range ingredientLines {
+		items[i] = strings.ToLower(ingredients.Parse(line).Item)
+		for _, word := range words(items[i]) {
+			listed[word] = true
+		}
+	}
+
+	steps := make([][]string, len(instructions))
+	used := map[string]bool{}
+	temperatureGiven := false
+	for i, step := range instructions {
+		steps[i] = words(step)
+		for _, word := range steps[i] {
+			used[word] = true
+		}
+		if temperaturePattern.MatchString(step) {
+			temperatureGiven = true
+		}
+	}
+
+	reported := map[string]bool{}
+	for i, step := range instructions {
+		n := i + 1
+		if strings.TrimSpace(step) == "" {
+			warnings = append(warnings, Warning{Code: EmptyStep, Step: n, Message: fmt.Sprintf("Step %d is empty", n)})
+			continue
+		}
+
+		for _, name := range pantry {
+			if reported[name] || !mentions(steps[i], words(name)) || containsAll(listed, words(name)) {
+				continue
+			}
+			reported[name] = true
+			warnings = append(warnings, Warning{Code: MissingIngredient, Step: n, Message: fmt.Sprintf("Step %d uses %s, which is not in the ingredient list", n, name)})
+		}
+
+		if m := heatPattern.FindString(step); m != "" && !temperatureGiven && !reported[MissingTemp] {
+			reported[MissingTemp] = true
+			warnings = append(warnings, Warning{Code: MissingTemp, Step: n, Message: fmt.Sprintf("Step %d mentions %q but no temperature is given anywhere", n, strings.ToLower(m))})
+		}
+
+		if len(steps[i]) > maxStepWords || len(step) > maxStepChars {
+			warnings = append(warnings, Warning{Code: LongStep, Step: n, Message: fmt.Sprintf("Step %d is %d words long, consider splitting it", n, len(steps[i]))})
+		}
+	}
+
+	if len(instructions) > 0 {
+		for i, item := range items {
+			if keys := keyWords(item); len(keys) > 0 && !containsAny(used, keys) {
+				warnings = append(warnings, Warning{Code: UnusedIngredient, Ingredient: i + 1, Message: fmt.Sprintf("%q is never used in the instructions", ingredientLines[i])})
+			}
+		}
+	}
+
+	return warnings
+}
+
+// words returns the lowercased, singular words of s.
+func words(s string) []string {
+	list := wordPattern.FindAllString(strings.ToLower(s), -1)
+	for i, w := range list {
+		list[i] = singular(w)
+	}
+	return list
+}
+
+func singular(w string) string {
+	switch {
+	case len(w) > 4 && strings.HasSuffix(w, "oes"):
+		return strings.TrimSuffix(w, "es")
+	case len(w) > 3 && strings.HasSuffix(w, "ies"):
+		return strings.TrimSuffix(w, "ies") + "y"
+	case len(w) > 3 && strings.HasSuffix(w, "s") && !strings.HasSuffix(w, "ss"):
+		return strings.TrimSuffix(w, "s")
+	}
+	return w
+}
+
+// mentions reports whether phrase occurs in text as consecutive words.
+func mentions(text, phrase []string) bool {
+	for i := 0; i+len(phrase) <= len(text); i++ {
+		match := true
+		for j, w := range phrase {
+			if text[i+j] != w {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// keyWords returns the words of an ingredient name that identify it.
+func keyWords(item string) []string {
+	var keys []string
+	for _, w := range words(item) {
+		if !descriptors[w] {
+			keys = append(keys, w)
+		}
+	}
+	return keys
+}
+
+func containsAny(set map[string]bool, list []string) bool {
+	for _, w := range list {
+		if set[w] {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAll(set map[string]bool, list []string) bool {
+	for _, w := range list {
+		if !set[w] {
+			return false
+		}
+	}
+	return true
+}
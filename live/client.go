@@ -0,0 +1,199 @@
+package live
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"recipes-api/events"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	writeWait      = 10 * time.Second
+	pongWait       = 60 * time.Second
+	pingPeriod     = pongWait * 9 / 10
+	maxMessageSize = 4 << 10
+	// MaxSubscriptions caps the IDs plus tags a single connection may watch.
+	MaxSubscriptions = 100
+)
+
+// request is a message sent by the client.
+type request struct {
+	Action string   `json:"action"`
+	IDs    []string `json:"ids"`
+	Tags   []string `json:"tags"`
+}
+
+// message is a message sent to the client.
+type message struct {
+	Type  string        `json:"type"`
+	Event *events.Event `json:"event,omitempty"`
+	IDs   []string      `json:"ids,omitempty"`
+	Tags  []string      `json:"tags,omitempty"`
+	Error string        `json:"error,omitempty"`
+}
+
+// Client is a single WebSocket connection and its subscriptions.
+type Client struct {
+	hub  *Hub
+	conn *websocket.Conn
+	send chan message
+
+	mu   sync.RWMutex
+	ids  map[string]bool
+	tags map[string]bool
+
+	closeOnce sync.Once
+}
+
+// Serve runs the connection until the client goes away. Clients send
+// {"action":"subscribe"|"unsubscribe","ids":[...],"tags":[...]} or
+// {"action":"list"} and receive {"type":"event","event":{...}} for every
+// matching change.
+func (h *Hub) Serve(conn *websocket.Conn) {
+	c := &Client{
+		hub:  h,
+		conn: conn,
+		send: make(chan message, 64),
+		ids:  map[string]bool{},
+		tags: map[string]bool{},
+	}
+	h.register(c)
+
+	go c.writePump()
+	c.readPump()
+}
+
+func (c *Client) close() {
+	c.closeOnce.Do(func() {
+		c.hub.unregister(c)
+		close(c.send)
+	})
+}
+
+func (c *Client) matches(id string, tags []string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.ids[id] {
+		return true
+	}
+	for _, t := range tags {
+		if c.tags[t] {
+			return true
+		}
+	}
+	return false
+}
+
+// notify queues a message without blocking the publisher. A client that
+// can't keep up is disconnected. It is only called by the hub while the
+// client is registered or by readPump, so send is never closed here.
+func (c *Client) notify(m message) {
+	select {
+	case c.send <- m:
+	default:
+		go c.conn.Close()
+	}
+}
+
+func (c *Client) readPump() {
+	defer func() {
+		c.close()
+		c.conn.Close()
+	}()
+
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var req request
+		if err := json.Unmarshal(data, &req); err != nil {
+			c.notify(message{Type: "error", Error: "Invalid message"})
+			continue
+		}
+		c.handle(req)
+	}
+}
+
+func (c *Client) handle(req request) {
+	c.mu.Lock()
+	switch req.Action {
+	case "subscribe":
+		if len(c.ids)+len(c.tags)+len(req.IDs)+len(req.Tags) > MaxSubscriptions {
+			c.mu.Unlock()
+			c.notify(message{Type: "error", Error: "Too many subscriptions"})
+			return
+		}
+		for _, id := range req.IDs {
+			c.ids[id] = true
+		}
+		for _, t := range req.Tags {
+			c.tags[strings.ToLower(t)] = true
+		}
+	case "unsubscribe":
+		for _, id := range req.IDs {
+			delete(c.ids, id)
+		}
+		for _, t := range req.Tags {
+			delete(c.tags, strings.ToLower(t))
+		}
+	case "list":
+	default:
+		c.mu.Unlock()
+		c.notify(message{Type: "error", Error: "Unknown action"})
+		return
+	}
+	reply := message{Type: "subscriptions", IDs: keys(c.ids), Tags: keys(c.tags)}
+	c.mu.Unlock()
+
+	c.notify(reply)
+}
+
+func (c *Client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case m, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				c.conn.WriteMessage(websocket.CloseMessage, nil)
+				return
+			}
+			if err := c.conn.WriteJSON(m); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func keys(m map[string]bool) []string {
+	list := make([]string, 0, len(m))
+	for k := range m {
+		list = append(list, k)
+	}
+	slices.Sort(list)
+	return list
+}
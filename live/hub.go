@@ -0,0 +1,88 @@
+// Package live pushes recipe change notifications to WebSocket clients
+// subscribed to specific recipe IDs or tags.
+package live
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"recipes-api/events"
+
+	"github.com/gorilla/websocket"
+)
+
+// Hub fans events out to connected clients. It is meant to be subscribed
+// to the bus.
+type Hub struct {
+	mu      sync.RWMutex
+	clients map[*Client]struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{clients: map[*Client]struct{}{}}
+}
+
+func (h *Hub) register(c *Client) {
+	h.mu.Lock()
+	h.clients[c] = struct{}{}
+	h.mu.Unlock()
+}
+
+func (h *Hub) unregister(c *Client) {
+	h.mu.Lock()
+	delete(h.clients, c)
+	h.mu.Unlock()
+}
+
+// Close disconnects every client with a going-away close frame, so they
+// reconnect to another instance.
+func (h *Hub) Close() {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for c := range h.clients {
+		c.conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"),
+			time.Now().Add(time.Second))
+		c.conn.Close()
+	}
+}
+
+// Handle notifies every client subscribed to the recipe's ID or one of its
+// tags. For updates the tags before the change count too, so a client
+// watching a tag learns that a recipe left it.
+func (h *Hub) Handle(e events.Event) {
+	tags := eventTags(e)
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for c := range h.clients {
+		if c.matches(e.Recipe.ID, tags) {
+			c.notify(message{Type: "event", Event: &e})
+		}
+	}
+}
+
+func eventTags(e events.Event) []string {
+	tags := append([]string(nil), e.Recipe.Tags...)
+
+	if change, ok := e.Changes["tags"]; ok {
+		switch old := change.Old.(type) {
+		case []string:
+			tags = append(tags, old...)
+		case []any:
+			for _, t := range old {
+				if s, ok := t.(string); ok {
+					tags = append(tags, s)
+				}
+			}
+		}
+	}
+
+	for i, t := range tags {
+		tags[i] = strings.ToLower(t)
+	}
+	return tags
+}
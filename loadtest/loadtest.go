@@ -0,0 +1,333 @@
+// Package loadtest drives a mix of API traffic against a running server and
+// reports latency percentiles per operation.
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Operations that can appear in a traffic mix.
+const (
+	OpGet    = "get"
+	OpList   = "list"
+	OpSearch = "search"
+	OpCreate = "create"
+	OpUpdate = "update"
+)
+
+const defaultMix = "get=60,list=10,search=20,create=5,update=5"
+
+type weightedOp struct {
+	name   string
+	weight int
+}
+
+type sample struct {
+	op       string
+	duration time.Duration
+	err      bool
+}
+
+type runner struct {
+	target string
+	client *http.Client
+
+	mu      sync.Mutex
+	ids     []string
+	tags    []string
+	created []string
+}
+
+// Run parses the loadtest flags from args, runs the test and prints the report to stdout.
+func Run(args []string) error {
+	fs := flag.NewFlagSet("loadtest", flag.ContinueOnError)
+	target := fs.String("target", "http://localhost:8080", "base URL of the API")
+	duration := fs.Duration("duration", 30*time.Second, "how long to generate traffic")
+	concurrency := fs.Int("concurrency", 10, "number of concurrent clients")
+	mix := fs.String("mix", defaultMix, "operation weights, e.g. get=60,search=30,create=10")
+	keep := fs.Bool("keep", false, "keep recipes created during the run instead of deleting them")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ops, err := parseMix(*mix)
+	if err != nil {
+		return err
+	}
+
+	r := &runner{target: strings.TrimRight(*target, "/"), client: &http.Client{Timeout: 30 * time.Second}}
+	if err := r.discover(); err != nil {
+		return fmt.Errorf("discovering recipes on %s: %w", r.target, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *duration)
+	defer cancel()
+
+	samples := make(chan sample, 1024)
+	var wg sync.WaitGroup
+	for i := 0; i < *concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				op := pick(ops)
+				start := time.Now()
+				err := r.do(op)
+				samples <- sample{op: op, duration: time.Since(start), err: err != nil}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(samples)
+	}()
+
+	results := map[string][]sample{}
+	started := time.Now()
+	for s := range samples {
+		results[s.op] = append(results[s.op], s)
+	}
+	elapsed := time.Since(started)
+
+	report(os.Stdout, results, elapsed)
+
+	if !*keep {
+		r.cleanup()
+	}
+	return nil
+}
+
+func parseMix(mix string) ([]weightedOp, error) {
+	var ops []weightedOp
+	for _, part := range strings.Split(mix, ",") {
+		name, weight, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid mix entry %q", part)
+		}
+		switch name {
+		case OpGet, OpList, OpSearch, OpCreate, OpUpdate:
+		default:
+			return nil, fmt.Errorf("unknown operation %q", name)
+		}
+		w, err := strconv.Atoi(weight)
+		if err != nil || w < 0 {
+			return nil, fmt.Errorf("invalid weight for %s: %q", name, weight)
+		}
+		if w > 0 {
+			ops = append(ops, weightedOp{name: name, weight: w})
+		}
+	}
+	if len(ops) == 0 {
+		return nil, fmt.Errorf("mix has no operations")
+	}
+	return ops, nil
+}
+
+func pick(ops []weightedOp) string {
+	total := 0
+	for _, op := range ops {
+		total += op.weight
+	}
+	n := rand.IntN(total)
+	for _, op := range ops {
+		if n < op.weight {
+			return op.name
+		}
+		n -= op.weight
+	}
+	return ops[len(ops)-1].name
+}
+
+// discover collects existing recipe IDs and tags to use in reads and searches.
+func (r *runner) discover() error {
+	var summaries []struct {
+		ID   string   `json:"id"`
+		Tags []string `json:"tags"`
+	}
+	if err := r.request(http.MethodGet, "/recipes?view=summary", nil, &summaries); err != nil {
+		return err
+	}
+
+	seen := map[string]bool{}
+	for _, s := range summaries {
+		r.ids = append(r.ids, s.ID)
+		for _, tag := range s.Tags {
+			if !seen[tag] {
+				seen[tag] = true
+				r.tags = append(r.tags, tag)
+			}
+		}
+	}
+	if len(r.tags) == 0 {
+		r.tags = []string{"vegetarian"}
+	}
+	return nil
+}
+
+func (r *runner) do(op string) error {
+	switch op {
+	case OpGet:
+		id := r.randomID()
+		if id == "" {
+			return r.request(http.MethodGet, "/recipes", nil, nil)
+		}
+		return r.request(http.MethodGet, "/recipes/"+id, nil, nil)
+	case OpList:
+		return r.request(http.MethodGet, "/recipes", nil, nil)
+	case OpSearch:
+		tag := r.tags[rand.IntN(len(r.tags))]
+		return r.request(http.MethodGet, "/recipes/search?tag="+url.QueryEscape(tag), nil, nil)
+	case OpCreate:
+		var created struct {
+			ID string `json:"id"`
+		}
+		if err := r.request(http.MethodPost, "/recipes", generateRecipe(r.tags), &created); err != nil {
+			return err
+		}
+		r.mu.Lock()
+		r.ids = append(r.ids, created.ID)
+		r.created = append(r.created, created.ID)
+		r.mu.Unlock()
+		return nil
+	case OpUpdate:
+		// only recipes created by this run are updated, so seed data is left alone
+		r.mu.Lock()
+		var id string
+		if len(r.created) > 0 {
+			id = r.created[rand.IntN(len(r.created))]
+		}
+		r.mu.Unlock()
+		if id == "" {
+			return r.do(OpCreate)
+		}
+		return r.request(http.MethodPut, "/recipes/"+id, generateRecipe(r.tags), nil)
+	}
+	return fmt.Errorf("unknown operation %q", op)
+}
+
+func (r *runner) randomID() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.ids) == 0 {
+		return ""
+	}
+	return r.ids[rand.IntN(len(r.ids))]
+}
+
+func (r *runner) cleanup() {
+	for _, id := range r.created {
+		r.request(http.MethodDelete, "/recipes/"+id, nil, nil)
+	}
+	if len(r.created) > 0 {
+		fmt.Printf("\nDeleted %d recipes created during the run\n", len(r.created))
+	}
+}
+
+func (r *runner) request(method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, r.target+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if method == http.MethodPut {
+		// updates overwrite whatever version the recipe is at
+		req.Header.Set("If-Match", "*")
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		io.Copy(io.Discard, resp.Body)
+		return fmt.Errorf("%s %s returned %s", method, path, resp.Status)
+	}
+	if out == nil {
+		_, err = io.Copy(io.Discard, resp.Body)
+		return err
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func generateRecipe(tags []string) map[string]any {
+	n := rand.IntN(1_000_000)
+	return map[string]any{
+		"name":         fmt.Sprintf("Load test recipe %d", n),
+		"tags":         []string{tags[rand.IntN(len(tags))], "loadtest"},
+		"ingredients":  []string{"1 cup flour", "2 eggs", "1 cup milk"},
+		"instructions": []string{"Mix everything.", "Cook for 10 minutes."},
+	}
+}
+
+func report(w io.Writer, results map[string][]sample, elapsed time.Duration) {
+	ops := make([]string, 0, len(results))
+	for op := range results {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "op\trequests\terrors\treq/s\tp50\tp90\tp95\tp99\tmax\t")
+
+	var all []sample
+	for _, op := range ops {
+		writeRow(tw, op, results[op], elapsed)
+		all = append(all, results[op]...)
+	}
+	writeRow(tw, "total", all, elapsed)
+	tw.Flush()
+}
+
+func writeRow(w io.Writer, op string, samples []sample, elapsed time.Duration) {
+	durations := make([]time.Duration, 0, len(samples))
+	errors := 0
+	for _, s := range samples {
+		durations = append(durations, s.duration)
+		if s.err {
+			errors++
+		}
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+	fmt.Fprintf(w, "%s\t%d\t%d\t%.1f\t%s\t%s\t%s\t%s\t%s\t\n",
+		op, len(samples), errors, float64(len(samples))/elapsed.Seconds(),
+		percentile(durations, 50), percentile(durations, 90), percentile(durations, 95),
+		percentile(durations, 99), percentile(durations, 100))
+}
+
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	i := (len(sorted)*p+99)/100 - 1
+	i = max(0, min(i, len(sorted)-1))
+	return sorted[i].Round(10 * time.Microsecond)
+}
@@ -0,0 +1,68 @@
+// Package logging configures the process-wide structured logger. Lines are
+// JSON, or text with LOG_FORMAT=text, filtered by the runtime log level and
+// tagged with the request ID of the context they are logged with.
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"os"
+
+	"recipes-api/reqid"
+)
+
+// Setup installs the logger as the slog default, which also routes the
+// standard log package through it. level is read on every call so level
+// changes apply immediately.
+func Setup(level func() string) {
+	options := &slog.HandlerOptions{Level: levelFunc(level)}
+
+	var handler slog.Handler
+	if os.Getenv("LOG_FORMAT") == "text" {
+		handler = slog.NewTextHandler(os.Stderr, options)
+	} else {
+		handler = slog.NewJSONHandler(os.Stderr, options)
+	}
+	slog.SetDefault(slog.New(contextHandler{handler}))
+}
+
+// Fatal logs at error level and exits.
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+type levelFunc func() string
+
+func (f levelFunc) Level() slog.Level {
+	switch f() {
+	case "debug":
+		return slog.LevelDebug
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// contextHandler adds the request ID from the context to every record.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := reqid.FromContext(ctx).RequestID; id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
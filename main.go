@@ -4,27 +4,90 @@
 // @contact.name Alex N. Kinuthia
 // @contact.email alexkienjeku@gmail.com
 // @host localhost:8080
-// @BasePath /
+// @BasePath /v1
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"flag"
 	"fmt"
-	"log"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/http"
+	_ "net/http/pprof"
+	"net/url"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-redis/redis"
 	"github.com/joho/godotenv"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"golang.org/x/text/language"
+	"google.golang.org/grpc"
 
-	"github.com/rs/xid"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
 
-	_ "recipes-api/docs"
+	"recipes-api/analytics"
+	"recipes-api/apiversion"
+	"recipes-api/authz"
+	"recipes-api/cache"
+	"recipes-api/chaos"
+	"recipes-api/config"
+	"recipes-api/docs"
+	"recipes-api/email"
+	"recipes-api/events"
+	"recipes-api/gql"
+	"recipes-api/grpcserver"
 	"recipes-api/handlers"
-	"recipes-api/models"
+	"recipes-api/i18n"
+	"recipes-api/ids"
+	"recipes-api/integrity"
+	"recipes-api/live"
+	"recipes-api/loadtest"
+	"recipes-api/logging"
+	"recipes-api/mealplans"
+	"recipes-api/metrics"
+	"recipes-api/middleware"
+	"recipes-api/migrations"
+	"recipes-api/nutrition"
+	"recipes-api/previews"
+	"recipes-api/projections"
+	"recipes-api/recipespb"
+	"recipes-api/region"
+	"recipes-api/related"
+	"recipes-api/reports"
+	"recipes-api/retag"
+	"recipes-api/sandbox"
+	"recipes-api/search"
+	"recipes-api/seed"
+	"recipes-api/serializer"
+	"recipes-api/service"
+	"recipes-api/settings"
+	"recipes-api/shopping"
+	"recipes-api/startup"
+	"recipes-api/stats"
+	"recipes-api/statuspage"
+	"recipes-api/storage"
+	"recipes-api/subscriptions"
+	"recipes-api/tags"
+	"recipes-api/taxonomy"
+	"recipes-api/thumbnails"
+	"recipes-api/tracing"
+	"recipes-api/translations"
+	"recipes-api/views"
+	"recipes-api/webhooks"
 
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -32,88 +95,818 @@ import (
 
 var db *gorm.DB
 var redisClient *redis.Client
+var nutritionService *nutrition.Service
+var recipeService *service.RecipeService
+var eventBus = events.NewBus()
+var imageStore storage.Store
+var integrityChecker *integrity.Checker
+var searcher search.Searcher
+var previewService *previews.Service
+var translationService *translations.Service
+var retagRunner *retag.Runner
+var statusMonitor *statuspage.Monitor
+var thumbnailService *thumbnails.Service
+var sandboxRecorder *sandbox.Recorder
+var recipeList *projections.RecipeList
+var chaosInjector *chaos.Injector
+var outbox *events.Outbox
+var webhookDispatcher *webhooks.Dispatcher
+var emailSender *email.Sender
+var analyticsRecorder *analytics.Recorder
+var kpis *analytics.KPIs
+var viewTracker *views.Tracker
+var idGenerator ids.Generator
+var shutdownTracing func(context.Context) error
+var redisMonitor *startup.RedisMonitor
+var settingsStore *settings.Store
+var cfg config.Config
+var liveHub = live.NewHub()
 
-func init() {
+const usage = `Usage: recipes-api [command] [flags]
+
+Commands:
+  serve     run the API server (the default)
+  migrate   apply (up), roll back (down) or list (status) schema migrations
+  seed      add the recipes of a seed file that are missing
+  loadtest  generate traffic against a running server
+
+Run "recipes-api <command> -h" to list the flags of a command.
+`
+
+// loadConfig reads the configuration and the runtime settings and sets up
+// logging. Every command but loadtest starts with it.
+func loadConfig(flags *flag.FlagSet, args []string) {
 	var err error
 
-	if err = godotenv.Load(); err != nil {
-		log.Fatal("Failed to load environment variables")
+	// containers usually get their configuration from the environment
+	// directly, so a missing .env file is fine; a broken one is not
+	envErr := godotenv.Load()
+	if envErr != nil && !errors.Is(envErr, fs.ErrNotExist) {
+		logging.Fatal("Failed to load environment variables", "error", envErr)
 	}
 
-	host := os.Getenv("HOST")
-	dbUser := os.Getenv("DBUSER")
-	password := os.Getenv("PASSWORD")
-	dbName := os.Getenv("DBNAME")
-	port := os.Getenv("PORT")
+	cfg, err = config.Load(flags, args)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		logging.Fatal("Invalid configuration", "error", err)
+	}
 
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=Africa/Nairobi", host, dbUser, password, dbName, port)
-	db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	settingsStore, err = settings.Load(cfg.SettingsFile)
+	if err != nil {
+		logging.Fatal("Error loading settings", "error", err)
+	}
 
+	idGenerator, err = ids.New(cfg.IDs)
 	if err != nil {
-		log.Fatalf("Error opening database connection: %v", err)
+		logging.Fatal("Error configuring recipe IDs", "error", err)
 	}
 
-	if err := db.AutoMigrate(&models.Recipe{}); err != nil {
-		log.Fatalf("Error migrating tables")
+	logging.Setup(settingsStore.LogLevel)
+	if envErr != nil {
+		slog.Info("No .env file found, using the process environment")
 	}
+}
 
-	fmt.Println("Database connection established...")
+// openDatabase connects to postgres, retrying while it starts up.
+func openDatabase() {
+	err := startup.Retry("Database", cfg.Startup, func() error {
+		var err error
+		db, err = gorm.Open(postgres.Open(cfg.Database.ConnString()), &gorm.Config{
+			NowFunc: func() time.Time { return time.Now().UTC() },
+			// pinged below, with a timeout
+			DisableAutomaticPing: true,
+		})
+		if err != nil {
+			return err
+		}
 
-	redisClient = redis.NewClient(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "",
-		DB:       0,
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Database.PingTimeout)
+		defer cancel()
+		return databasePool().PingContext(ctx)
 	})
-	status := redisClient.Ping()
-	fmt.Println(status)
+	if err != nil {
+		logging.Fatal("Error opening database connection", "error", err)
+	}
+	if err := tracing.InstrumentGORM(db); err != nil {
+		logging.Fatal("Error instrumenting database queries", "error", err)
+	}
+
+	pool := databasePool()
+	pool.SetMaxOpenConns(cfg.Database.MaxOpenConns)
+	pool.SetMaxIdleConns(cfg.Database.MaxIdleConns)
+	pool.SetConnMaxLifetime(cfg.Database.ConnMaxLifetime)
+
+	dsns := cfg.Database.Replicas
+	if len(cfg.Database.LocalReplicas) > 0 {
+		dsns = cfg.Database.LocalReplicas
+	}
+	if len(dsns) > 0 {
+		replicas := make([]gorm.Dialector, len(dsns))
+		for i, dsn := range dsns {
+			replicas[i] = postgres.Open(dsn)
+		}
+		resolver := dbresolver.Register(dbresolver.Config{Replicas: replicas, Policy: dbresolver.RandomPolicy{}}, service.ReplicaResolver).
+			SetMaxOpenConns(cfg.Database.MaxOpenConns).
+			SetMaxIdleConns(cfg.Database.MaxIdleConns).
+			SetConnMaxLifetime(cfg.Database.ConnMaxLifetime)
+		if err := db.Use(resolver); err != nil {
+			logging.Fatal("Error configuring read replicas", "error", err)
+		}
+	}
+
+	slog.Info("Database connection established", "replicas", len(dsns), "max_open_conns", cfg.Database.MaxOpenConns, "max_idle_conns", cfg.Database.MaxIdleConns)
+}
+
+// previewSecret returns the secret preview links are signed with, a random
+// one when none is configured.
+func previewSecret() []byte {
+	if cfg.Server.PreviewSecret != "" {
+		return []byte(cfg.Server.PreviewSecret)
+	}
+	slog.Warn("No preview-secret set, preview links will stop working on restart")
+	secret := make([]byte, 32)
+	rand.Read(secret)
+	return secret
+}
 
-	loadInitialData()
+// statusChecks returns the checks of the components on the status page.
+// Searching is the database's job unless an index is configured.
+func statusChecks(indexer *search.Indexer) map[string]statuspage.Check {
+	checks := map[string]statuspage.Check{
+		statuspage.ComponentDB:     statuspage.PingDB(db),
+		statuspage.ComponentRedis:  statuspage.PingRedis(redisClient),
+		statuspage.ComponentSearch: statuspage.PingDB(db),
+	}
+	queues := map[string]statuspage.Queue{
+		"webhooks":   webhookDispatcher.Backlog,
+		"outbox":     outbox.Backlog,
+		"thumbnails": thumbnailService.Backlog,
+	}
+	if cfg.Search.Backend != config.SearchPostgres {
+		checks[statuspage.ComponentSearch] = statuspage.SearchIndex(searcher)
+	}
+	if indexer != nil {
+		queues["search-index"] = indexer.Backlog
+	}
+	checks[statuspage.ComponentJobs] = statuspage.Queues(queues)
+	if imageStore != nil {
+		checks[statuspage.ComponentStorage] = statuspage.Storage(imageStore)
+	}
+	return checks
 }
 
-func loadInitialData() {
-	file, err := os.ReadFile("recipes.json")
+// apiTokens returns the roles granted by the configured tokens.
+func apiTokens() authz.Tokens {
+	tokens, err := authz.ParseTokens(cfg.Server.APITokens)
 	if err != nil {
-		log.Fatalf("Error reading recipes.json: %v", err)
+		logging.Fatal("Error reading API tokens", "error", err)
 	}
+	if cfg.Server.AdminToken != "" {
+		tokens[cfg.Server.AdminToken] = authz.RoleAdmin
+	}
+	return tokens
+}
 
-	var recipes []models.Recipe
-	if err := json.Unmarshal(file, &recipes); err != nil {
-		log.Fatalf("Error parsing recipes.json: %v", err)
+// migrateUp applies the pending schema migrations and rebuilds the
+// projections if there were any.
+func migrateUp() {
+	results, err := migrations.Up(context.Background(), databasePool())
+	for _, r := range results {
+		slog.Info("Applied migration", "version", r.Source.Version, "file", r.Source.Path, "duration_ms", r.Duration.Milliseconds())
+	}
+	if err != nil {
+		logging.Fatal("Error migrating the database", "error", err)
 	}
 
-	if err := db.Exec("DELETE FROM recipes").Error; err != nil {
-		log.Fatalf("Error clearing recipes table: %v", err)
+	// migrations can change what the projections hold
+	if len(results) > 0 {
+		if err := projections.NewRecipeList(db, newRedisClient()).Rebuild(context.Background()); err != nil {
+			logging.Fatal("Error rebuilding recipes list projection", "error", err)
+		}
 	}
 
-	for _, recipe := range recipes {
-		if recipe.ID == "" {
-			recipe.ID = xid.New().String()
+	slog.Info("Database schema is up to date")
+}
+
+func databasePool() *sql.DB {
+	sqlDB, err := db.DB()
+	if err != nil {
+		logging.Fatal("Error getting the database pool", "error", err)
+	}
+	return sqlDB
+}
+
+func newRedisClient() *redis.Client {
+	options := &redis.Options{
+		Addr:     cfg.Redis.Addr,
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
+	}
+	if chaosInjector != nil {
+		options.Dialer = chaosInjector.RedisDialer(options.Addr)
+	}
+	return redis.NewClient(options)
+}
+
+// setup connects to the database and Redis and starts the background
+// workers. Unless turned off it also migrates the schema and loads the
+// seed data. Only the server needs it.
+func setup(args []string) {
+	var err error
+
+	loadConfig(flag.NewFlagSet("recipes-api serve", flag.ContinueOnError), args)
+	settingsStore.ReloadOnSignal()
+
+	shutdownTracing, err = tracing.Setup(context.Background(), cfg.Region.Name)
+	if err != nil {
+		logging.Fatal("Error setting up tracing", "error", err)
+	}
+
+	openDatabase()
+
+	if cfg.ChaosMode {
+		chaosInjector = chaos.NewInjector()
+		if err := chaosInjector.RegisterGORM(db); err != nil {
+			logging.Fatal("Error registering fault injection", "error", err)
 		}
-		if recipe.PublishedAt.IsZero() {
-			recipe.PublishedAt = time.Now()
+		slog.Info("Running in chaos mode, faults can be injected via /admin/chaos")
+	}
+
+	if cfg.AutoMigrate {
+		migrateUp()
+	}
+	if err := migrations.Check(context.Background(), databasePool()); err != nil {
+		logging.Fatal("Refusing to start, run \"recipes-api migrate up\" first", "error", err)
+	}
+
+	redisClient = newRedisClient()
+	err = startup.Retry("Redis", cfg.Startup, func() error {
+		return redisClient.Ping().Err()
+	})
+	if err != nil {
+		slog.Warn("Redis unavailable, starting in read-only mode", "error", err)
+	} else {
+		slog.Info("Redis connection established")
+	}
+	redisMonitor = startup.NewRedisMonitor(redisClient, err == nil)
+	go redisMonitor.Watch(15 * time.Second)
+
+	provider, err := nutrition.NewProvider(cfg.Nutrition.Provider, cfg.Nutrition.AppID, cfg.Nutrition.APIKey)
+	if err != nil {
+		logging.Fatal("Error configuring nutrition provider", "error", err)
+	}
+	nutritionService = nutrition.NewService(db, redisClient, provider, eventBus, 2)
+	recipeService = service.NewRecipeService(db, redisClient, nutritionService, eventBus, cfg.Cache.RecipeTTL, redisMonitor.ReadOnly, idGenerator)
+
+	if cfg.S3.Endpoint != "" {
+		imageStore, err = storage.NewS3Store(cfg.S3)
+		if err != nil {
+			logging.Fatal("Error configuring image storage", "error", err)
 		}
+	}
+	thumbnailService = thumbnails.NewService(db, redisClient, imageStore, eventBus, 2)
+
+	outbox = events.NewOutbox(db, cfg.OutboxRetention)
+	eventBus.Subscribe(outbox.Record)
+
+	recipeList = projections.NewRecipeList(db, redisClient)
+	eventBus.Subscribe(recipeList.Handle)
+
+	if cfg.SandboxMode {
+		sandboxRecorder = sandbox.NewRecorder(500)
+		slog.Info("Running in sandbox mode, outgoing emails and webhooks will be captured")
+	}
+
+	webhookDispatcher = webhooks.NewDispatcher(db, sandboxRecorder, cfg.Outbound, 4)
+	eventBus.Subscribe(webhookDispatcher.Handle)
+	eventBus.Subscribe(liveHub.Handle)
 
-		if err := db.Create(&recipe).Error; err != nil {
-			log.Fatalf("Error inserting recipe %s: %v", recipe.Name, err)
+	emailSender = email.NewSender(cfg.SMTP, sandboxRecorder)
+	eventBus.Subscribe(subscriptions.NewNotifier(db, emailSender, cfg.DigestWindow).Handle)
+
+	analyticsRecorder = analytics.NewRecorder(db)
+	go analyticsRecorder.Run(time.Minute)
+	kpis = analytics.NewKPIs(db, redisClient)
+	go kpis.Run(time.Minute)
+	viewTracker = views.NewTracker(db, redisClient)
+	go viewTracker.RunFlush(time.Minute)
+
+	// the primary region sends the reports and cleans up; the others would
+	// only repeat it
+	if cfg.Region.IsPrimary() && (len(cfg.Reports.Emails) > 0 || cfg.Reports.WebhookURL != "") {
+		scheduler := reports.NewScheduler(db, emailSender, cfg.Outbound, sandboxRecorder, cfg.Reports.Emails, cfg.Reports.WebhookURL)
+		go scheduler.Run(time.Hour)
+	}
+
+	searcher = newSearcher(cfg.Search.Backend)
+	if cfg.Search.Shadow != "" {
+		searcher = search.NewShadow(searcher, newSearcher(cfg.Search.Shadow), cfg.Search.ShadowPercent)
+		slog.Info("Comparing searches with a shadow backend", "backend", cfg.Search.Shadow, "percent", cfg.Search.ShadowPercent)
+	}
+	var indexer *search.Indexer
+	if cfg.Search.Indexed() {
+		indexer = search.NewIndexer(searcher)
+		eventBus.Subscribe(indexer.Handle)
+	}
+
+	previewService = previews.NewService(db, previewSecret())
+	translationService = translations.NewService(db, language.Make(cfg.ContentLanguage))
+	retagRunner = retag.NewRunner(db, redisClient, eventBus)
+
+	statusMonitor = statuspage.NewMonitor(db, statusChecks(indexer))
+	go statusMonitor.Run(time.Minute)
+
+	integrityChecker = integrity.NewChecker(db, redisClient, imageStore)
+	if cfg.Region.IsPrimary() && cfg.IntegrityInterval > 0 {
+		go integrityChecker.RunEvery(cfg.IntegrityInterval)
+	}
+	if cfg.Region.IsPrimary() {
+		go outbox.RunPrune(time.Hour)
+	}
+
+	// writes clear the primary region's Redis as they are made, which the
+	// instances there share; other regions hear of them through the outbox
+	if !cfg.Region.IsPrimary() {
+		go region.NewInvalidator(db, redisClient).Run(5 * time.Second)
+		slog.Info("Forwarding writes to the primary region", "region", cfg.Region.Name, "primary", cfg.Region.Primary)
+	}
+
+	if cfg.SeedOnStart {
+		// a bad seed file shouldn't keep the server from serving the
+		// recipes it already has
+		if _, err := loadInitialData(); err != nil {
+			slog.Error("Error loading seed recipes", "file", cfg.SeedFile, "error", err)
 		}
 	}
+}
 
-	log.Printf("Successfully loaded %d recipes from recipes.json into database", len(recipes))
+// newSearcher returns the search backend named, creating its index if it
+// keeps one.
+func newSearcher(backend string) search.Searcher {
+	switch backend {
+	case config.SearchElasticsearch:
+		index := search.NewElasticsearch(cfg.Search.ElasticsearchURL, cfg.Search.Index)
+		if err := index.EnsureIndex(context.Background()); err != nil {
+			slog.Error("Error creating search index", "backend", backend, "index", cfg.Search.Index, "error", err)
+		}
+		return index
+	case config.SearchRediSearch:
+		index := search.NewRediSearch(redisClient, cfg.Search.Index)
+		if err := index.EnsureIndex(context.Background()); err != nil {
+			slog.Error("Error creating search index", "backend", backend, "index", cfg.Search.Index, "error", err)
+		}
+		return index
+	}
+	return search.NewPostgres(recipeService)
+}
+
+// loadInitialData adds the seed recipes missing from the database and logs
+// what it did.
+func loadInitialData() (seed.Summary, error) {
+	ctx := context.Background()
+
+	summary, err := seed.Load(ctx, db, cfg.SeedFile, idGenerator)
+	if err != nil {
+		return summary, err
+	}
+	slog.Info("Seeded recipes", "file", cfg.SeedFile, "inserted", summary.Inserted, "skipped", summary.Skipped, "failed", summary.Failed)
+
+	// seeding bypasses the event bus
+	if summary.Inserted > 0 {
+		if err := recipeList.Rebuild(ctx); err != nil {
+			return summary, fmt.Errorf("rebuilding recipes list projection: %w", err)
+		}
+	}
+	return summary, nil
 }
 
 func main() {
-	router := gin.Default()
+	command, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "serve":
+		serve(args)
+	case "migrate":
+		runMigrate(args)
+	case "seed":
+		runSeed(args)
+	case "loadtest":
+		if err := loadtest.Run(args); err != nil && !errors.Is(err, flag.ErrHelp) {
+			logging.Fatal("Load test failed", "error", err)
+		}
+	case "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n%s", command, usage)
+		os.Exit(2)
+	}
+}
+
+// runMigrate applies (up), rolls back (down) or lists (status) schema
+// migrations without starting the server, e.g. as a deployment step before
+// new instances come up.
+func runMigrate(args []string) {
+	action := "up"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		action, args = args[0], args[1:]
+	}
+	if action != "up" && action != "down" && action != "status" {
+		fmt.Fprintf(os.Stderr, "Unknown migrate action %q, expected up, down or status\n", action)
+		os.Exit(2)
+	}
+
+	loadConfig(flag.NewFlagSet("recipes-api migrate "+action, flag.ContinueOnError), args)
+	openDatabase()
+	ctx := context.Background()
+
+	switch action {
+	case "up":
+		migrateUp()
+	case "down":
+		result, err := migrations.Down(ctx, databasePool())
+		if err != nil {
+			logging.Fatal("Error rolling back migration", "error", err)
+		}
+		slog.Info("Rolled back migration", "version", result.Source.Version, "file", result.Source.Path)
+	case "status":
+		list, err := migrations.List(ctx, databasePool())
+		if err != nil {
+			logging.Fatal("Error reading migration status", "error", err)
+		}
+		for _, m := range list {
+			applied := "pending"
+			if !m.AppliedAt.IsZero() {
+				applied = m.AppliedAt.UTC().Format(time.RFC3339)
+			}
+			fmt.Printf("%5d  %-25s  %s\n", m.Source.Version, applied, m.Source.Path)
+		}
+	}
+}
+
+// runSeed adds the recipes of the seed file that are missing, or with
+// -reset replaces all recipes with them. Cached recipes are dropped so
+// running servers pick up the changes.
+func runSeed(args []string) {
+	fs := flag.NewFlagSet("recipes-api seed", flag.ContinueOnError)
+	file := fs.String("file", "", "seed file to load (default -seed-file)")
+	reset := fs.Bool("reset", false, "delete all recipes first; never use on real data")
+	loadConfig(fs, args)
+	if *file != "" {
+		cfg.SeedFile = *file
+	}
+
+	openDatabase()
+	redisClient = newRedisClient()
+	recipeList = projections.NewRecipeList(db, redisClient)
+
+	failed := false
+	if *reset {
+		count, err := seed.Reset(db, cfg.SeedFile, idGenerator)
+		if err != nil {
+			logging.Fatal("Error resetting recipes", "error", err)
+		}
+		slog.Info("Replaced all recipes", "file", cfg.SeedFile, "count", count)
+		if err := recipeList.Rebuild(context.Background()); err != nil {
+			logging.Fatal("Error building recipes list projection", "error", err)
+		}
+	} else {
+		summary, err := loadInitialData()
+		if err != nil {
+			logging.Fatal("Error loading seed recipes", "error", err)
+		}
+		failed = summary.Failed > 0
+	}
+
+	if err := cache.FlushRecipes(context.Background(), redisClient); err != nil {
+		slog.Warn("Error clearing cached recipes, they expire on their own", "error", err, "ttl", cfg.Cache.RecipeTTL.String())
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func serve(args []string) {
+	setup(args)
+	middleware.RegisterValidations()
+	catalog, err := i18n.Load(cfg.LocalesDir)
+	if err != nil {
+		logging.Fatal("Error loading translations", "error", err)
+	}
+
+	router := gin.New()
+	router.Use(serializer.Timing(), otelgin.Middleware(tracing.ServiceName), middleware.Localize(catalog))
+	router.Use(middleware.RequestID(), middleware.Logger(), middleware.Recovery())
+	// the live updates socket and exports stream for as long as they need
+	router.Use(middleware.Timeout(cfg.Server.RequestTimeout, "/ws", "/recipes/export"))
+	router.Use(analyticsRecorder.Middleware())
+	router.Use(kpis.Middleware())
+	router.Use(middleware.CORS(settingsStore.CORSOrigins, settingsStore.CORS))
+	authorize := middleware.Authorize(apiTokens(), settingsStore.Policy)
+	router.Use(authorize)
+	router.Use(middleware.RateLimit(settingsStore.RateLimit))
+	router.Use(middleware.JSONLimits(settingsStore.JSONLimits))
+	if chaosInjector != nil {
+		router.Use(chaosInjector.Middleware())
+	}
+	if !cfg.Region.IsPrimary() {
+		primary, _ := url.Parse(cfg.Region.PrimaryURL)
+		router.Use(middleware.ForwardWrites(primary, cfg.Region.Name, cfg.Region.Secret))
+	}
+	router.Use(middleware.ReadOnly(redisMonitor.ReadOnly))
+
+	// the API lives under /v1, and where it was before versioning until
+	// clients have moved
+	v1 := router.Group(apiversion.V1)
+	api := apiversion.Routes{v1, router.Group("", apiversion.Deprecated(apiversion.V1, time.Time{}))}
+
+	ih := handlers.NewImageController(db, redisClient, imageStore, thumbnailService, eventBus, cfg.ImageMaxBytes)
+	rh := handlers.NewRecipeController(db, redisClient, recipeService, nutritionService, eventBus, cfg.Cache.ListTTL, settingsStore.RankByQuality, searcher, previewService, translationService, viewTracker, idGenerator, ih)
+
+	ph := handlers.NewPDFController(db, imageStore, previewService, translationService)
+	prh := handlers.NewPreviewController(recipeService, previewService)
+
+	api.POST("/recipes", middleware.Idempotency(redisClient, 24*time.Hour), rh.NewRecipeHandler)
+	api.GET("/recipes", middleware.ETag(), rh.ListRecipesHandler)
+	api.GET("/recipes/:id", middleware.ETag(), rh.GetRecipeHandler)
+	api.PUT("/recipes/:id", rh.UpdateRecipeHandler)
+	api.PATCH("/recipes/:id", rh.PatchRecipeHandler)
+	api.POST("/recipes/:id/publish", rh.PublishRecipeHandler)
+	api.DELETE("/recipes/:id", rh.DeleteRecipeHandler)
+	api.GET("/recipes/search", rh.SearchRecipesHandler)
+	api.GET("/recipes/random", rh.RandomRecipesHandler)
+	api.GET("/recipes/batch", rh.BatchRecipesHandler)
+	api.POST("/recipes/batch", rh.BatchRecipesPostHandler)
+	api.GET("/recipes/slug/:slug", middleware.ETag(), rh.GetRecipeBySlugHandler)
+	api.GET("/recipes/trending", rh.TrendingRecipesHandler)
+	api.POST("/recipes/import", rh.ImportRecipesHandler)
+	api.POST("/recipes/import-url", handlers.ImportURLHandler(recipeService, cfg.Outbound))
+	api.GET("/recipes/export", rh.ExportRecipesHandler)
+	feeds := middleware.RequireFeature(settingsStore.Enabled, settings.FeatureFeeds)
+	api.GET("/recipes/feed.rss", feeds, rh.RSSFeedHandler)
+	api.GET("/recipes/feed.atom", feeds, rh.AtomFeedHandler)
+	api.GET("/recipes/:id/nutrition", rh.GetNutritionHandler)
+	api.GET("/recipes/:id/jsonld", rh.RecipeJSONLDHandler)
+	api.GET("/recipes/:id/stats", rh.RecipeStatsHandler)
+	api.GET("/recipes/:id/related", handlers.RelatedRecipesHandler(recipeService, related.NewService(db, redisClient, recipeService, cfg.Cache.ListTTL)))
+	api.GET("/recipes/:id/revisions", rh.ListRevisionsHandler)
+	api.POST("/recipes/:id/revisions/:rev/restore", rh.RestoreRevisionHandler)
+	api.POST("/recipes/:id/restore", rh.RestoreRecipeHandler)
+	api.POST("/recipes/:id/conflicts", handlers.ResolveConflictHandler(recipeService, settingsStore.ConflictPolicy))
+	api.POST("/recipes/:id/image", ih.UploadImageHandler)
+	api.DELETE("/recipes/:id/image", ih.DeleteImageHandler)
+	api.GET("/recipes/:id/pdf", ph.RecipePDFHandler)
+
+	trh := handlers.NewTranslationController(recipeService, previewService, translationService)
+	api.GET("/recipes/:id/translations", trh.ListTranslationsHandler)
+	api.PUT("/recipes/:id/translations/:locale", trh.PutTranslationHandler)
+	api.DELETE("/recipes/:id/translations/:locale", trh.DeleteTranslationHandler)
+
+	subh := handlers.NewSubscriptionController(db)
+	api.POST("/recipes/:id/subscriptions", subh.SubscribeHandler)
+	api.DELETE("/recipes/:id/subscriptions/:subscriptionId", subh.UnsubscribeHandler)
+
+	th := handlers.NewTemplateController(db, recipeService)
+	api.GET("/templates", th.ListTemplatesHandler)
+	api.POST("/templates", th.CreateTemplateHandler)
+	api.GET("/templates/:id", th.GetTemplateHandler)
+	api.PUT("/templates/:id", th.UpdateTemplateHandler)
+	api.DELETE("/templates/:id", th.DeleteTemplateHandler)
+	api.POST("/recipes/from-template/:id", th.NewRecipeFromTemplateHandler)
+
+	mealPlanService := mealplans.NewService(db)
+	mph := handlers.NewMealPlanController(recipeService, mealPlanService)
+	api.POST("/mealplans", mph.CreateMealPlanHandler)
+	api.GET("/mealplans/:week", mph.GetMealPlanHandler)
+	api.POST("/mealplans/:week/copy-last-week", mph.CopyLastWeekHandler)
+	api.DELETE("/mealplans/days/:day", mph.ClearDayHandler)
+	api.DELETE("/mealplans/entries/:id", mph.DeleteEntryHandler)
 
-	rh := handlers.NewRecipeController(db, redisClient)
+	shh := handlers.NewShoppingController(recipeService, mealPlanService, shopping.NewService(db))
+	api.POST("/shopping-lists", shh.CreateShoppingListHandler)
+	api.GET("/shopping-lists/:id", shh.GetShoppingListHandler)
+	api.PATCH("/shopping-lists/:id/items/:itemId", shh.CheckItemHandler)
+	api.DELETE("/shopping-lists/:id", shh.DeleteShoppingListHandler)
 
-	router.POST("/recipes", rh.NewRecipeHandler)
-	router.GET("/recipes", rh.ListRecipesHandler)
-	router.PUT("/recipes/:id", rh.UpdateRecipeHandler)
-	router.DELETE("/recipes/:id", rh.DeleteRecipeHandler)
-	router.GET("/recipes/search", rh.SearchRecipesHandler)
+	cuisines := taxonomy.NewService(db, taxonomy.Cuisines)
+	categories := taxonomy.NewService(db, taxonomy.Categories)
+	serializer.RegisterIncluder("cuisines", func(ctx context.Context, ids []string) (any, error) {
+		return cuisines.Find(ctx, ids)
+	})
+	serializer.RegisterIncluder("categories", func(ctx context.Context, ids []string) (any, error) {
+		return categories.Find(ctx, ids)
+	})
+	cuh := handlers.NewCuisineController(cuisines)
+	cah := handlers.NewCategoryController(categories)
+	api.GET("/cuisines", cuh.ListTermsHandler)
+	api.GET("/cuisines/:id", cuh.GetTermHandler)
+	api.GET("/categories", cah.ListTermsHandler)
+	api.GET("/categories/:id", cah.GetTermHandler)
+
+	tagService := tags.NewService(db, redisClient, cfg.Cache.ListTTL)
+	api.GET("/tags", handlers.ListTagsHandler(tagService))
+	api.GET("/tags/popular", handlers.PopularTagsHandler(tagService))
+
+	api.POST("/parse/ingredients", handlers.ParseIngredientsHandler)
+	api.GET("/convert", handlers.ConvertHandler)
+	api.POST("/lint/recipe", handlers.LintRecipeHandler)
+
+	api.GET("/schemas/events", handlers.ListEventSchemasHandler)
+	api.GET("/schemas/events/:version", handlers.GetEventSchemaHandler)
+
+	if sandboxRecorder != nil || cfg.AppEnv == "test" {
+		api.GET("/fixtures/recipes", handlers.ListFixtureRecipesHandler)
+		api.GET("/fixtures/recipes/:id", handlers.GetFixtureRecipeHandler)
+		api.GET("/fixtures/events/:type", handlers.GetFixtureEventHandler)
+	}
+
+	// admin and debug endpoints get their own listener so they can be
+	// firewalled separately from the public API
+	adminRouter := gin.New()
+	adminRouter.Use(serializer.Timing(), middleware.Localize(catalog))
+	adminRouter.Use(middleware.RequestID(), middleware.Logger(), middleware.Recovery())
+	adminRouter.Use(middleware.JSONLimits(settingsStore.JSONLimits))
+
+	adminRouter.Use(authorize)
+	if !cfg.Region.IsPrimary() {
+		// settings and chaos are per deployment, everything else is
+		// changed in the primary region
+		primaryAdmin, _ := url.Parse(cfg.Region.PrimaryAdminURL)
+		adminRouter.Use(middleware.ForwardWrites(primaryAdmin, cfg.Region.Name, cfg.Region.Secret, "/admin/settings/reload", "/admin/chaos"))
+	}
+	adminRouter.Use(middleware.ReadOnly(redisMonitor.ReadOnly))
+	admin := adminRouter.Group("/admin")
+	admin.GET("/recipes/trash", rh.ListTrashHandler)
+	admin.DELETE("/recipes/trash", rh.EmptyTrashHandler)
+	admin.DELETE("/recipes/trash/:id", rh.PurgeRecipeHandler)
+	admin.GET("/recipes/quality", rh.ListLowQualityHandler)
+	admin.GET("/recipes/:id/quality", rh.RecipeQualityHandler)
+	admin.POST("/recipes/:id/merge", rh.MergeRecipeHandler)
+	admin.POST("/recipes/:id/previews", prh.CreatePreviewHandler)
+	admin.GET("/recipes/:id/previews", prh.ListPreviewsHandler)
+	admin.DELETE("/recipes/:id/previews/:previewId", prh.RevokePreviewHandler)
+	admin.GET("/settings", handlers.GetSettingsHandler(settingsStore))
+	admin.GET("/reports/weekly", handlers.WeeklyReportHandler(db))
+	admin.GET("/stats", handlers.StatsHandler(stats.NewService(db, redisClient, cfg.Cache.ListTTL)))
+	admin.POST("/integrity", handlers.IntegrityHandler(integrityChecker))
+	admin.POST("/retag", handlers.RetagHandler(retagRunner))
+	admin.GET("/retag/:id", handlers.RetagJobHandler(retagRunner))
+	admin.POST("/retag/:id/undo", handlers.UndoRetagHandler(retagRunner))
+	if cfg.Search.Indexed() {
+		admin.POST("/search/reindex", handlers.ReindexHandler(db, searcher))
+	}
+	admin.POST("/settings/reload", handlers.ReloadSettingsHandler(settingsStore))
+	var indexed search.Searcher
+	if cfg.Search.Indexed() {
+		indexed = searcher
+	}
+	bh := handlers.NewBackupController(db, redisClient, recipeList, indexed)
+	admin.GET("/export", bh.ExportHandler)
+	admin.POST("/import", bh.ImportHandler)
+	wh := handlers.NewWebhookController(db, webhookDispatcher, outbox)
+	admin.POST("/webhooks", wh.CreateWebhookHandler)
+	admin.GET("/webhooks", wh.ListWebhooksHandler)
+	admin.DELETE("/webhooks/:id", wh.DeleteWebhookHandler)
+	admin.GET("/webhooks/:id/deliveries", wh.ListDeliveriesHandler)
+	admin.POST("/webhooks/:id/replay", wh.ReplayHandler)
+	// also on the public API for integrators redelivering to their own
+	// webhooks; the default policy keeps it to admins
+	api.POST("/webhooks/:id/replay", wh.ReplayHandler)
+	stc := handlers.NewStatusController(statusMonitor)
+	admin.POST("/incidents", stc.CreateIncidentHandler)
+	admin.PUT("/incidents/:id", stc.UpdateIncidentHandler)
+	admin.POST("/cuisines", cuh.CreateTermHandler)
+	admin.PUT("/cuisines/:id", cuh.UpdateTermHandler)
+	admin.DELETE("/cuisines/:id", cuh.DeleteTermHandler)
+	admin.POST("/categories", cah.CreateTermHandler)
+	admin.PUT("/categories/:id", cah.UpdateTermHandler)
+	admin.DELETE("/categories/:id", cah.DeleteTermHandler)
+	if chaosInjector != nil {
+		admin.GET("/chaos", handlers.GetChaosHandler(chaosInjector))
+		admin.PUT("/chaos", handlers.UpdateChaosHandler(chaosInjector))
+	}
+	if sandboxRecorder != nil {
+		sh := handlers.NewSandboxController(db, redisClient, sandboxRecorder, recipeList, cfg.SeedFile, idGenerator)
+		admin.POST("/sandbox/reset", sh.ResetHandler)
+		admin.GET("/sandbox/messages", sh.ListMessagesHandler)
+		admin.DELETE("/sandbox/messages", sh.ClearMessagesHandler)
+	}
+
+	schema, err := gql.NewSchema(recipeService, settingsStore.Policy)
+	if err != nil {
+		logging.Fatal("Error building GraphQL schema", "error", err)
+	}
+	graphqlEnabled := middleware.RequireFeature(settingsStore.Enabled, settings.FeatureGraphQL)
+	api.POST("/graphql", graphqlEnabled, handlers.GraphQLHandler(schema))
+	api.GET("/graphql", graphqlEnabled, handlers.GraphQLHandler(schema))
+
+	api.GET("/ws", handlers.LiveUpdatesHandler(liveHub, settingsStore.CORSOrigins))
+
+	hc := handlers.NewHealthController(db, redisClient)
+	router.GET("/healthz", hc.LivenessHandler)
+	router.GET("/readyz", hc.ReadinessHandler)
+	api.GET("/status", stc.StatusHandler)
+
+	var gatherer prometheus.Gatherer = prometheus.DefaultGatherer
+	if cfg.Region.Name != "" {
+		gatherer = metrics.WithLabel(gatherer, "region", cfg.Region.Name)
+	}
+	adminRouter.GET("/metrics", gin.WrapH(promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer, promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{}))))
+	// net/http/pprof registers its handlers on the default mux
+	adminRouter.GET("/debug/pprof/*any", gin.WrapH(http.DefaultServeMux))
 
 	// swagger endpoint
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	// each version serves its own docs, generated with swag init
+	// --instanceName for versions after the first
+	v1.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler, ginSwagger.InstanceName(docs.SwaggerInfo.InstanceName())))
 
-	router.Run(":8080")
+	lis, err := net.Listen("tcp", ":"+cfg.Server.GRPCPort)
+	if err != nil {
+		logging.Fatal("Error listening for gRPC", "error", err)
+	}
+	// gRPC calls go through the same route policy as the REST API
+	interceptors := []grpc.UnaryServerInterceptor{grpcserver.Authorize(apiTokens(), settingsStore.Policy)}
+	if !cfg.Region.IsPrimary() {
+		interceptors = append(interceptors, grpcserver.PrimaryWrites(cfg.Region.Primary))
+	}
+	grpcServer := grpc.NewServer(grpc.ChainUnaryInterceptor(interceptors...))
+	recipespb.RegisterRecipeServiceServer(grpcServer, grpcserver.NewServer(recipeService))
+	go func() {
+		if err := grpcServer.Serve(lis); err != nil {
+			slog.Error("gRPC server stopped", "error", err)
+		}
+	}()
+
+	adminServer := &http.Server{Addr: cfg.Server.AdminAddr, Handler: adminRouter}
+	go func() {
+		if err := adminServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logging.Fatal("Error serving admin endpoints", "error", err)
+		}
+	}()
+
+	server := &http.Server{Addr: cfg.Server.Addr, Handler: router}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logging.Fatal("Error serving API", "error", err)
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	<-ctx.Done()
+	stop()
+
+	shutdown(server, adminServer, grpcServer)
+}
+
+// shutdown stops accepting connections, lets in-flight requests finish
+// within the shutdown timeout and then closes Redis and the
+// database pool. A second signal during shutdown kills the process.
+func shutdown(server, adminServer *http.Server, grpcServer *grpc.Server) {
+	timeout := cfg.Server.ShutdownTimeout
+	slog.Info("Shutting down, waiting for in-flight requests", "timeout", timeout.String())
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// hijacked WebSocket connections aren't tracked by Shutdown
+	liveHub.Close()
+	if err := server.Shutdown(ctx); err != nil {
+		slog.Error("Error shutting down API server", "error", err)
+	}
+	if err := adminServer.Shutdown(ctx); err != nil {
+		slog.Error("Error shutting down admin server", "error", err)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		grpcServer.Stop()
+	}
+
+	if err := analyticsRecorder.Flush(); err != nil {
+		slog.Error("Error flushing analytics", "error", err)
+	}
+	if err := redisClient.Close(); err != nil {
+		slog.Error("Error closing Redis client", "error", err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			slog.Error("Error closing database pool", "error", err)
+		}
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("Error flushing traces", "error", err)
+	}
+	slog.Info("Shutdown complete")
 }
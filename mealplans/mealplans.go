@@ -0,0 +1,167 @@
+// Package mealplans keeps the weekly meal plan: which recipes are cooked
+// for which meal of which day.
+package mealplans
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"recipes-api/models"
+
+	"github.com/rs/xid"
+	"gorm.io/gorm"
+)
+
+// Slots are the meals of a day, in the order they are eaten.
+var Slots = []string{"breakfast", "lunch", "dinner", "snack"}
+
+var ErrNotFound = errors.New("meal plan entry not found")
+
+// Plan is the meal plan of a week.
+type Plan struct {
+	Week string `json:"week"`
+	Days []Day  `json:"days"`
+}
+
+// Day holds the meals of one day by slot.
+type Day struct {
+	Day   string `json:"day"`
+	Meals []Meal `json:"meals"`
+}
+
+// Meal is an entry with the recipe it plans. Recipe is missing once the
+// recipe has been deleted.
+type Meal struct {
+	models.MealPlanEntry
+	Recipe *models.RecipeSummary `json:"recipe,omitempty"`
+}
+
+type Service struct {
+	db *gorm.DB
+}
+
+func NewService(db *gorm.DB) *Service {
+	return &Service{db: db}
+}
+
+// Add puts the entries on the plan.
+func (s *Service) Add(ctx context.Context, entries []models.MealPlanEntry) ([]models.MealPlanEntry, error) {
+	now := time.Now().UTC()
+	for i := range entries {
+		entries[i].ID = xid.New().String()
+		entries[i].CreatedAt = now
+	}
+	if err := s.db.WithContext(ctx).Create(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Entries returns the entries of the week, by day and slot.
+func (s *Service) Entries(ctx context.Context, week Week) ([]models.MealPlanEntry, error) {
+	days := week.Days()
+	entries := []models.MealPlanEntry{}
+	err := s.db.WithContext(ctx).Where("day BETWEEN ? AND ?", days[0], days[6]).Order("created_at").Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(entries, func(a, b models.MealPlanEntry) int {
+		if c := strings.Compare(a.Day, b.Day); c != 0 {
+			return c
+		}
+		return slices.Index(Slots, a.Slot) - slices.Index(Slots, b.Slot)
+	})
+	return entries, nil
+}
+
+// Week returns the plan of the week with every day, planned or not.
+func (s *Service) Week(ctx context.Context, week Week) (Plan, error) {
+	entries, err := s.Entries(ctx, week)
+	if err != nil {
+		return Plan{}, err
+	}
+
+	ids := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		ids = append(ids, entry.RecipeID)
+	}
+	var summaries []models.RecipeSummary
+	if err := s.db.WithContext(ctx).Where("id IN ?", ids).Find(&summaries).Error; err != nil {
+		return Plan{}, err
+	}
+	recipes := map[string]*models.RecipeSummary{}
+	for i := range summaries {
+		recipes[summaries[i].ID] = &summaries[i]
+	}
+
+	plan := Plan{Week: week.String()}
+	for _, day := range week.Days() {
+		d := Day{Day: day, Meals: []Meal{}}
+		for _, entry := range entries {
+			if entry.Day == day {
+				d.Meals = append(d.Meals, Meal{MealPlanEntry: entry, Recipe: recipes[entry.RecipeID]})
+			}
+		}
+		plan.Days = append(plan.Days, d)
+	}
+	return plan, nil
+}
+
+// CopyPreviousWeek plans the meals of the week before into week, on the
+// same weekdays. Meals already planned the same way are not copied again,
+// so copying twice doesn't double them. It returns how many were copied.
+func (s *Service) CopyPreviousWeek(ctx context.Context, week Week) (int, error) {
+	previous, err := s.Entries(ctx, week.Previous())
+	if err != nil {
+		return 0, err
+	}
+	current, err := s.Entries(ctx, week)
+	if err != nil {
+		return 0, err
+	}
+
+	type meal struct{ day, slot, recipeID string }
+	planned := map[meal]bool{}
+	for _, entry := range current {
+		planned[meal{entry.Day, entry.Slot, entry.RecipeID}] = true
+	}
+
+	var copies []models.MealPlanEntry
+	for _, entry := range previous {
+		day, err := ParseDay(entry.Day)
+		if err != nil {
+			continue
+		}
+		entry.Day = day.AddDate(0, 0, 7).Format(dayLayout)
+		if planned[meal{entry.Day, entry.Slot, entry.RecipeID}] {
+			continue
+		}
+		copies = append(copies, entry)
+	}
+	if len(copies) == 0 {
+		return 0, nil
+	}
+	_, err = s.Add(ctx, copies)
+	return len(copies), err
+}
+
+// ClearDay removes every meal planned for day and returns how many there
+// were.
+func (s *Service) ClearDay(ctx context.Context, day string) (int64, error) {
+	result := s.db.WithContext(ctx).Where("day = ?", day).Delete(&models.MealPlanEntry{})
+	return result.RowsAffected, result.Error
+}
+
+func (s *Service) Delete(ctx context.Context, id string) error {
+	result := s.db.WithContext(ctx).Where("id = ?", id).Delete(&models.MealPlanEntry{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package mealplans
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const dayLayout = "2006-01-02"
+
+var ErrInvalidWeek = errors.New("week must be an ISO week such as 2026-W07")
+
+// Week is an ISO 8601 week, Monday to Sunday.
+type Week struct {
+	Year   int
+	Number int
+}
+
+// ParseWeek reads a week written as 2026-W07.
+func ParseWeek(s string) (Week, error) {
+	var w Week
+	if _, err := fmt.Sscanf(s, "%4d-W%2d", &w.Year, &w.Number); err != nil || w.String() != s {
+		return Week{}, ErrInvalidWeek
+	}
+	// years have 52 or 53 weeks; a week 53 that doesn't exist would start
+	// in the next year's week 1
+	if year, number := w.Start().ISOWeek(); year != w.Year || number != w.Number {
+		return Week{}, ErrInvalidWeek
+	}
+	return w, nil
+}
+
+// WeekOf returns the week t falls in.
+func WeekOf(t time.Time) Week {
+	year, number := t.ISOWeek()
+	return Week{Year: year, Number: number}
+}
+
+func (w Week) String() string {
+	return fmt.Sprintf("%04d-W%02d", w.Year, w.Number)
+}
+
+// Start returns the Monday of the week, in UTC.
+func (w Week) Start() time.Time {
+	// January 4th is always in week 1
+	jan4 := time.Date(w.Year, time.January, 4, 0, 0, 0, 0, time.UTC)
+	monday := jan4.AddDate(0, 0, -(int(jan4.Weekday())+6)%7)
+	return monday.AddDate(0, 0, 7*(w.Number-1))
+}
+
+// Days returns the dates of the week, Monday first.
+func (w Week) Days() []string {
+	start := w.Start()
+	days := make([]string, 7)
+	for i := range days {
+		days[i] = start.AddDate(0, 0, i).Format(dayLayout)
+	}
+	return days
+}
+
+func (w Week) Previous() Week {
+	return WeekOf(w.Start().AddDate(0, 0, -7))
+}
+
+// ParseDay checks a date written as YYYY-MM-DD.
+func ParseDay(s string) (time.Time, error) {
+	return time.Parse(dayLayout, s)
+}
@@ -0,0 +1,30 @@
+package metrics
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// WithLabel returns a gatherer adding name="value" to every metric g
+// gathers, e.g. the region, so series of deployments scraped together stay
+// apart. Metrics that already have the label keep theirs.
+func WithLabel(g prometheus.Gatherer, name, value string) prometheus.Gatherer {
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		families, err := g.Gather()
+		for _, family := range families {
+			for _, metric := range family.Metric {
+				if slices.ContainsFunc(metric.Label, func(l *dto.LabelPair) bool { return l.GetName() == name }) {
+					continue
+				}
+				metric.Label = append(metric.Label, &dto.LabelPair{Name: &name, Value: &value})
+				slices.SortFunc(metric.Label, func(a, b *dto.LabelPair) int {
+					return strings.Compare(a.GetName(), b.GetName())
+				})
+			}
+		}
+		return families, err
+	})
+}
@@ -0,0 +1,48 @@
+// Package metrics holds the Prometheus collectors exported at /metrics.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	// CacheBatchSize observes how many keys each batched cache operation carries.
+	CacheBatchSize = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "recipes_cache_batch_size",
+		Help:    "Number of keys per batched Redis operation.",
+		Buckets: []float64{1, 2, 5, 10, 25, 50, 100, 250},
+	}, []string{"op"})
+
+	// CacheLookups counts per-key cache reads by result (hit or miss).
+	CacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "recipes_cache_lookups_total",
+		Help: "Recipe cache lookups by result.",
+	}, []string{"result"})
+
+	// SearchShadowReads counts searches replayed against the shadow search
+	// backend by outcome: match, diverged or error.
+	SearchShadowReads = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "recipes_search_shadow_reads_total",
+		Help: "Searches replayed against the shadow search backend, by outcome.",
+	}, []string{"outcome"})
+
+	// The business KPIs below are refreshed by analytics.KPIs, except
+	// ImportsFailed which the import handler counts.
+	RecipesTotal = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "recipes_total",
+		Help: "Recipes that are not deleted.",
+	})
+	RecipesPublished24h = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "recipes_published_24h",
+		Help: "Recipes published in the last 24 hours and not deleted.",
+	})
+	ActiveUsers24h = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "recipes_active_users_24h",
+		Help: "Estimated distinct clients, by IP, seen in the last 24 hours.",
+	})
+	ImportsFailed = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "recipes_imports_failed_total",
+		Help: "Imported recipes that were invalid or failed to insert.",
+	})
+)
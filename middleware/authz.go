@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"strings"
+
+	"recipes-api/apierrors"
+	"recipes-api/apiversion"
+	"recipes-api/authz"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RoleKey is the context key holding the role of the caller.
+const RoleKey = "role"
+
+// Authorize resolves the role of the caller from "Authorization: Bearer
+// <token>" and checks the route, without its version prefix, against the
+// policy. Unknown tokens get a
+// 401, as do anonymous callers the policy turns away; other roles get a
+// 403. Requests that matched no route pass on to the 404 handler.
+func Authorize(tokens authz.Tokens, policy func() []authz.Rule) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := authz.RoleAnonymous
+		if header := c.GetHeader("Authorization"); header != "" {
+			r, ok := tokens.Role(strings.TrimPrefix(header, "Bearer "))
+			if !ok {
+				apierrors.Abort(c, apierrors.Unauthorized("Invalid token"))
+				return
+			}
+			role = r
+		}
+		c.Set(RoleKey, role)
+		c.Request = c.Request.WithContext(authz.WithRole(c.Request.Context(), role))
+
+		route := c.FullPath()
+		if route == "" || authz.Allowed(policy(), role, c.Request.Method, apiversion.Unversioned(route)) {
+			c.Next()
+			return
+		}
+
+		if role == authz.RoleAnonymous {
+			apierrors.Abort(c, apierrors.Unauthorized("Authorization required"))
+			return
+		}
+		apierrors.Abort(c, apierrors.Forbidden("Not allowed for role {role}").With("role", role))
+	}
+}
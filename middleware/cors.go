@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"recipes-api/settings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CORS allows cross-origin requests from the given origins, answering
+// preflight requests itself. Both the origins and what their requests may
+// do are read on every request so they can change at runtime. "*" allows
+// any origin.
+func CORS(origins func() []string, options func() settings.CORS) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		allowed := origins()
+		c.Writer.Header().Add("Vary", "Origin")
+		if !slices.Contains(allowed, origin) && !slices.Contains(allowed, "*") {
+			c.Next()
+			return
+		}
+
+		current := options()
+		c.Header("Access-Control-Allow-Origin", origin)
+		if current.AllowCredentials {
+			c.Header("Access-Control-Allow-Credentials", "true")
+		}
+
+		if c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != "" {
+			c.Header("Access-Control-Allow-Methods", strings.Join(current.Methods, ", "))
+			if len(current.Headers) > 0 {
+				c.Header("Access-Control-Allow-Headers", strings.Join(current.Headers, ", "))
+			} else if headers := c.GetHeader("Access-Control-Request-Headers"); headers != "" {
+				c.Writer.Header().Add("Vary", "Access-Control-Request-Headers")
+				c.Header("Access-Control-Allow-Headers", headers)
+			}
+			c.Header("Access-Control-Max-Age", strconv.Itoa(current.MaxAge))
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		if len(current.ExposeHeaders) > 0 {
+			c.Header("Access-Control-Expose-Headers", strings.Join(current.ExposeHeaders, ", "))
+		}
+		c.Next()
+	}
+}
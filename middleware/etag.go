@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type bufferedWriter struct {
+	gin.ResponseWriter
+	body   bytes.Buffer
+	status int
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	w.status = code
+}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	return w.body.Len() > 0
+}
+
+// ETag sets a weak ETag computed from the response body on successful GET
+// responses and answers 304 Not Modified when it matches If-None-Match.
+// The hash covers the final bytes, so every representation (version, case,
+// timezone, format) gets its own tag.
+func ETag() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			c.Next()
+			return
+		}
+
+		original := c.Writer
+		w := &bufferedWriter{ResponseWriter: original, status: http.StatusOK}
+		c.Writer = w
+		c.Next()
+		c.Writer = original
+
+		if w.status == http.StatusOK {
+			sum := sha256.Sum256(w.body.Bytes())
+			tag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+			original.Header().Set("ETag", tag)
+
+			if etagMatches(c.GetHeader("If-None-Match"), tag) {
+				original.Header().Del("Content-Type")
+				original.Header().Del("Content-Length")
+				original.WriteHeader(http.StatusNotModified)
+				original.WriteHeaderNow()
+				return
+			}
+		}
+
+		original.WriteHeader(w.status)
+		original.Write(w.body.Bytes())
+	}
+}
+
+// etagMatches uses the weak comparison If-None-Match calls for.
+func etagMatches(header, tag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(tag, "W/") {
+			return true
+		}
+	}
+	return false
+}
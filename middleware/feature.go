@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"recipes-api/apierrors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireFeature answers 404 while the named feature is switched off.
+func RequireFeature(enabled func(string) bool, feature string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !enabled(feature) {
+			apierrors.Abort(c, apierrors.NotFound("Not found"))
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"recipes-api/apierrors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis"
+)
+
+const (
+	// IdempotencyKeyHeader names the key clients retry a request under.
+	IdempotencyKeyHeader = "Idempotency-Key"
+	maxIdempotencyKey    = 255
+	// claimTTL bounds how long a request holds its key while it runs, in
+	// case the server dies before storing the response.
+	claimTTL = time.Minute
+)
+
+// storedResponse is what is kept under an idempotency key: the request it
+// answered, and the response once there is one. A zero Status means the
+// first request is still running.
+type storedResponse struct {
+	Fingerprint string `json:"fingerprint"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"contentType,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+// Idempotency lets clients retry a request safely by sending the same
+// Idempotency-Key header: the response to the first request with a key is
+// kept for ttl and replayed to the later ones, marked with an
+// Idempotent-Replayed header, instead of running them again. Server errors
+// aren't kept, so those requests can be retried for real. Reusing a key
+// for a different request, or while the first one still runs, is a
+// conflict. Keys are the caller's own: the same key sent with another
+// Authorization header is another key. Requests without the header, and
+// all requests while Redis is unreachable, run as usual.
+func Idempotency(redisClient *redis.Client, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKey {
+			apierrors.Abort(c, apierrors.BadRequest("Idempotency-Key must be at most {max} characters").With("max", strconv.Itoa(maxIdempotencyKey)))
+			return
+		}
+
+		var body []byte
+		if c.Request.Body != nil {
+			var err error
+			body, err = io.ReadAll(c.Request.Body)
+			if err != nil {
+				apierrors.Abort(c, apierrors.BadRequest("Failed to read request body"))
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		// the query is part of the request: a retry with force=true isn't
+		// the request that was refused
+		sum := sha256.Sum256([]byte(c.Request.Method + " " + c.Request.URL.Path + "?" + c.Request.URL.RawQuery + "\n" + string(body)))
+		fingerprint := hex.EncodeToString(sum[:])
+		caller := sha256.Sum256([]byte(c.GetHeader("Authorization")))
+		redisKey := "idempotency:" + hex.EncodeToString(caller[:8]) + ":" + key
+
+		claim, _ := json.Marshal(storedResponse{Fingerprint: fingerprint})
+		claimed, err := redisClient.SetNX(redisKey, claim, claimTTL).Result()
+		if err != nil {
+			slog.WarnContext(ctx, "Error claiming idempotency key, running the request anyway", "error", err)
+			c.Next()
+			return
+		}
+		if !claimed {
+			replay(c, redisClient, redisKey, fingerprint)
+			return
+		}
+
+		original := c.Writer
+		w := &bufferedWriter{ResponseWriter: original, status: http.StatusOK}
+		c.Writer = w
+		c.Next()
+		c.Writer = original
+
+		if w.status >= http.StatusInternalServerError {
+			if err := redisClient.Del(redisKey).Err(); err != nil {
+				slog.WarnContext(ctx, "Error releasing idempotency key", "error", err)
+			}
+		} else {
+			stored, _ := json.Marshal(storedResponse{
+				Fingerprint: fingerprint,
+				Status:      w.status,
+				ContentType: original.Header().Get("Content-Type"),
+				Body:        w.body.Bytes(),
+			})
+			if err := redisClient.Set(redisKey, stored, ttl).Err(); err != nil {
+				slog.WarnContext(ctx, "Error storing idempotent response", "error", err)
+			}
+		}
+
+		original.WriteHeader(w.status)
+		original.Write(w.body.Bytes())
+	}
+}
+
+// replay answers a request whose key is taken with the response kept
+// under it.
+func replay(c *gin.Context, redisClient *redis.Client, redisKey, fingerprint string) {
+	data, err := redisClient.Get(redisKey).Bytes()
+	if err == redis.Nil {
+		// the first request failed or its claim ran out just now
+		apierrors.Abort(c, apierrors.Conflict("A request with this Idempotency-Key is still in progress"))
+		return
+	}
+	var stored storedResponse
+	if err == nil {
+		err = json.Unmarshal(data, &stored)
+	}
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "Error reading idempotent response", "error", err)
+		apierrors.Abort(c, apierrors.Internal("Failed to replay the response for this Idempotency-Key"))
+		return
+	}
+
+	switch {
+	case stored.Fingerprint != fingerprint:
+		apierrors.Abort(c, apierrors.Conflict("Idempotency-Key was already used for a different request"))
+	case stored.Status == 0:
+		apierrors.Abort(c, apierrors.Conflict("A request with this Idempotency-Key is still in progress"))
+	default:
+		c.Header("Idempotent-Replayed", "true")
+		c.Data(stored.Status, stored.ContentType, stored.Body)
+		c.Abort()
+	}
+}
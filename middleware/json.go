@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"recipes-api/apierrors"
+	"recipes-api/settings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const strictJSONKey = "strictJSON"
+
+// JSONLimits rejects JSON request bodies that are larger or nested deeper
+// than the current limits, and marks the request for strict decoding in
+// BindJSON. Other content types pass through untouched.
+func JSONLimits(limits func() settings.JSONLimits) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Body == nil || c.Request.Body == http.NoBody || !isJSON(c.ContentType()) {
+			c.Next()
+			return
+		}
+
+		current := limits()
+		body := c.Request.Body
+		if current.MaxBytes > 0 {
+			body = http.MaxBytesReader(c.Writer, body, current.MaxBytes)
+		}
+		data, err := io.ReadAll(body)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				apierrors.Abort(c, apierrors.TooLarge("Request body exceeds {limit} bytes").With("limit", strconv.FormatInt(tooLarge.Limit, 10)))
+				return
+			}
+			apierrors.Abort(c, apierrors.BadRequest("Failed to read request body"))
+			return
+		}
+
+		if current.MaxDepth > 0 {
+			if offset := exceedsDepth(data, current.MaxDepth); offset >= 0 {
+				apierrors.Abort(c, apierrors.BadRequest("JSON nesting exceeds {depth} levels at offset {offset}").With("depth", strconv.Itoa(current.MaxDepth)).With("offset", strconv.Itoa(offset)))
+				return
+			}
+		}
+
+		c.Request.Body = io.NopCloser(bytes.NewReader(data))
+		c.Set(strictJSONKey, current.Strict)
+		c.Next()
+	}
+}
+
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
+
+// exceedsDepth returns the offset where data first nests deeper than max,
+// or -1. Malformed JSON is left for the decoder to report.
+func exceedsDepth(data []byte, max int) int {
+	depth := 0
+	inString, escaped := false, false
+	for i, b := range data {
+		switch {
+		case escaped:
+			escaped = false
+		case inString && b == '\\':
+			escaped = true
+		case b == '"':
+			inString = !inString
+		case inString:
+		case b == '{' || b == '[':
+			depth++
+			if depth > max {
+				return i
+			}
+		case b == '}' || b == ']':
+			depth--
+		}
+	}
+	return -1
+}
+
+// BindJSON works like ShouldBindJSON, but when strict JSON is on it rejects
+// fields the target doesn't have, so typos like "ingredents" aren't
+// silently dropped. Errors name the offending field or offset; failed
+// validations can be broken down with FieldErrors.
+func BindJSON(c *gin.Context, obj any) error {
+	if !c.GetBool(strictJSONKey) {
+		return c.ShouldBindJSON(obj)
+	}
+	if err := DecodeJSON(c, obj); err != nil {
+		return err
+	}
+	return Validate(obj)
+}
+
+// DecodeJSON is BindJSON without the validation, for bodies that may be
+// incomplete, like drafts to lint.
+func DecodeJSON(c *gin.Context, obj any) error {
+	if c.Request.Body == nil {
+		return errors.New("request body is empty")
+	}
+	decoder := json.NewDecoder(c.Request.Body)
+	if c.GetBool(strictJSONKey) {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(obj); err != nil {
+		return describeJSONError(err)
+	}
+	if decoder.More() {
+		return errors.New("request body must contain a single JSON value")
+	}
+	return nil
+}
+
+func describeJSONError(err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		return fmt.Errorf("malformed JSON at offset %d: %v", syntaxErr.Offset, syntaxErr)
+	case errors.As(err, &typeErr):
+		return fmt.Errorf("field %q must be %s, got %s", typeErr.Field, typeErr.Type, typeErr.Value)
+	case errors.Is(err, io.EOF):
+		return errors.New("request body is empty")
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return errors.New("malformed JSON: body ends in the middle of a value")
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		return fmt.Errorf("unknown field %s", strings.TrimPrefix(err.Error(), "json: unknown field "))
+	}
+	return err
+}
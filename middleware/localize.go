@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"recipes-api/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Localize picks the language of the request's messages from its
+// Accept-Language header. It should run before any middleware that may
+// respond with an error.
+func Localize(catalog *i18n.Catalog) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		l := catalog.Localizer(c.GetHeader("Accept-Language"))
+		c.Request = c.Request.WithContext(i18n.NewContext(c.Request.Context(), l))
+		c.Writer.Header().Add("Vary", "Accept-Language")
+		c.Next()
+	}
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"recipes-api/apierrors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Logger logs one line per request: info for successful requests, warn for
+// 4xx and error for 5xx, so the current log level decides which show up.
+// Must run after RequestID so the line carries the request ID.
+func Logger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		attrs := []any{
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"route", c.FullPath(),
+			"status", status,
+			"duration_ms", float64(time.Since(start).Microseconds()) / 1000,
+			"bytes", c.Writer.Size(),
+			"client_ip", c.ClientIP(),
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, "errors", c.Errors.String())
+		}
+		slog.Log(c.Request.Context(), level, "Request", attrs...)
+	}
+}
+
+// Recovery turns panics into 500 responses and logs them with the stack.
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, rec any) {
+		slog.ErrorContext(c.Request.Context(), "Panic while handling request", "panic", rec, "stack", string(debug.Stack()))
+		apierrors.Abort(c, apierrors.Internal("Internal server error"))
+	})
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"recipes-api/apierrors"
+	"recipes-api/settings"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// RateLimit limits requests per client IP using a token bucket. The limit is
+// read on every request so it can change at runtime; a zero rate turns the
+// limiter off.
+func RateLimit(limit func() settings.RateLimit) gin.HandlerFunc {
+	var mu sync.Mutex
+	clients := map[string]*clientLimiter{}
+	lastSweep := time.Now()
+
+	return func(c *gin.Context) {
+		current := limit()
+		perSecond, burst := current.RequestsPerSecond, current.Burst
+		if perSecond <= 0 {
+			c.Next()
+			return
+		}
+		burst = max(burst, 1)
+
+		now := time.Now()
+		mu.Lock()
+		client, ok := clients[c.ClientIP()]
+		if !ok {
+			client = &clientLimiter{limiter: rate.NewLimiter(rate.Limit(perSecond), burst)}
+			clients[c.ClientIP()] = client
+		}
+		if client.limiter.Limit() != rate.Limit(perSecond) || client.limiter.Burst() != burst {
+			client.limiter.SetLimitAt(now, rate.Limit(perSecond))
+			client.limiter.SetBurstAt(now, burst)
+		}
+		client.lastSeen = now
+		allowed := client.limiter.AllowN(now, 1)
+
+		// forget clients that have been idle for a while
+		if now.Sub(lastSweep) > time.Minute {
+			for ip, cl := range clients {
+				if now.Sub(cl.lastSeen) > 10*time.Minute {
+					delete(clients, ip)
+				}
+			}
+			lastSweep = now
+		}
+		mu.Unlock()
+
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(max(1, int(1/perSecond))))
+			apierrors.Abort(c, apierrors.RateLimited("Too many requests"))
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+
+	"recipes-api/apierrors"
+	"recipes-api/apiversion"
+
+	"github.com/gin-gonic/gin"
+)
+
+var readOnlyExempt = []string{"/graphql", "/recipes/batch"}
+
+// ReadOnly refuses writes with 503 while readOnly reports true. /graphql is
+// left to the service layer since queries are POSTed too, and
+// /recipes/batch only reads what is POSTed to it.
+func ReadOnly(readOnly func() bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		if readOnly() && !slices.Contains(readOnlyExempt, apiversion.Unversioned(c.Request.URL.Path)) {
+			c.Header("Retry-After", "30")
+			apierrors.Abort(c, apierrors.ReadOnly("The API is temporarily read-only"))
+			return
+		}
+		c.Next()
+	}
+}
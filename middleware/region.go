@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"slices"
+
+	"recipes-api/apierrors"
+	"recipes-api/apiversion"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+const (
+	// ForwardedRegionHeader names the region a forwarded write came from.
+	ForwardedRegionHeader = "X-Forwarded-Region"
+	// RegionSecretHeader carries the secret the regions share, vouching
+	// for ForwardedRegionHeader.
+	RegionSecretHeader = "X-Region-Secret"
+)
+
+// readOnlyPosts are the routes POSTed to that only read.
+var readOnlyPosts = []string{"/recipes/batch"}
+
+// ForwardWrites sends writes to the API of the primary region and relays
+// its response, so a deployment outside it only serves reads. POSTs that
+// only read, /recipes/batch and GraphQL queries, are served here, as are
+// the local routes, writes that only change the deployment itself, while
+// GraphQL mutations are forwarded. Requests another region forwarded
+// already, vouched for by the shared secret, are served here too, so
+// misconfigured regions can't pass a write around in a loop; clients
+// can't skip forwarding by sending the header themselves.
+func ForwardWrites(primary *url.URL, region, secret string, local ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		route := apiversion.Unversioned(c.FullPath())
+		if slices.Contains(readOnlyPosts, route) || slices.Contains(local, route) || route == "/graphql" && isGraphQLQuery(c) {
+			c.Next()
+			return
+		}
+		if c.GetHeader(ForwardedRegionHeader) != "" && subtle.ConstantTimeCompare([]byte(c.GetHeader(RegionSecretHeader)), []byte(secret)) == 1 {
+			c.Next()
+			return
+		}
+
+		proxy := &httputil.ReverseProxy{
+			Rewrite: func(r *httputil.ProxyRequest) {
+				r.SetURL(primary)
+				r.SetXForwarded()
+				r.Out.Header.Set(ForwardedRegionHeader, region)
+				r.Out.Header.Set(RegionSecretHeader, secret)
+			},
+			ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+				slog.ErrorContext(r.Context(), "Error forwarding write to the primary region", "path", r.URL.Path, "error", err)
+				c.Header("Retry-After", "30")
+				apierrors.Write(c, apierrors.Unavailable("The primary region is unavailable"))
+			},
+		}
+		proxy.ServeHTTP(c.Writer, c.Request)
+		c.Abort()
+	}
+}
+
+// isGraphQLQuery reports whether a GraphQL request runs a query rather
+// than a mutation, reading the body and putting it back for the handler.
+func isGraphQLQuery(c *gin.Context) bool {
+	body, err := io.ReadAll(c.Request.Body)
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+	var req struct {
+		Query         string `json:"query"`
+		OperationName string `json:"operationName"`
+	}
+	if json.Unmarshal(body, &req) != nil {
+		return false
+	}
+	operations, err := graphQLOperations(req.Query, req.OperationName)
+	return err == nil && len(operations) > 0 && !slices.ContainsFunc(operations, isWrite)
+}
+
+// IsGraphQLWrite reports whether a GraphQL document runs anything but a
+// query, the operation named by operationName or, without one, any of
+// them. Documents that don't parse aren't writes; running them fails.
+func IsGraphQLWrite(query, operationName string) bool {
+	operations, _ := graphQLOperations(query, operationName)
+	return slices.ContainsFunc(operations, isWrite)
+}
+
+func isWrite(operation string) bool {
+	return operation != ast.OperationTypeQuery
+}
+
+// graphQLOperations returns the types of the operations of a document that
+// would run: the one named operationName, or all of them without a name.
+func graphQLOperations(query, operationName string) ([]string, error) {
+	doc, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return nil, err
+	}
+	var operations []string
+	for _, definition := range doc.Definitions {
+		op, ok := definition.(*ast.OperationDefinition)
+		if !ok || operationName != "" && (op.Name == nil || op.Name.Value != operationName) {
+			continue
+		}
+		operations = append(operations, op.Operation)
+	}
+	return operations, nil
+}
@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"recipes-api/reqid"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/xid"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestID makes sure every request has an X-Request-ID, keeping a sane one
+// sent by the client or a proxy, echoes it in the response and in JSON
+// error bodies, and stores it in the request context with the trace
+// context: that of the request's span when tracing is on, otherwise the
+// traceparent header as received.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(reqid.Header)
+		if !validRequestID(id) {
+			id = xid.New().String()
+		}
+		c.Header(reqid.Header, id)
+
+		ctx := c.Request.Context()
+		ids := reqid.IDs{RequestID: id, Traceparent: c.GetHeader(reqid.TraceparentHeader)}
+		if trace.SpanContextFromContext(ctx).IsValid() {
+			carrier := propagation.MapCarrier{}
+			propagation.TraceContext{}.Inject(ctx, carrier)
+			ids.Traceparent = carrier[reqid.TraceparentHeader]
+		}
+		c.Request = c.Request.WithContext(reqid.NewContext(ctx, ids))
+
+		original := c.Writer
+		w := &errorWriter{ResponseWriter: original}
+		c.Writer = w
+		c.Next()
+		c.Writer = original
+
+		if w.body.Len() > 0 {
+			original.Write(withRequestID(w.body.Bytes(), id))
+		}
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, r := range id {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// errorWriter passes responses through until an error status is set, then
+// holds the body back so the request ID can be added to it.
+type errorWriter struct {
+	gin.ResponseWriter
+	body      bytes.Buffer
+	buffering bool
+}
+
+func (w *errorWriter) WriteHeader(code int) {
+	w.buffering = code >= http.StatusBadRequest
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *errorWriter) Write(b []byte) (int, error) {
+	if w.buffering {
+		return w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *errorWriter) WriteString(s string) (int, error) {
+	if w.buffering {
+		return w.body.WriteString(s)
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *errorWriter) Size() int {
+	if w.buffering {
+		return w.body.Len()
+	}
+	return w.ResponseWriter.Size()
+}
+
+func (w *errorWriter) Written() bool {
+	return w.body.Len() > 0 || w.ResponseWriter.Written()
+}
+
+// withRequestID adds "requestId" to a JSON error object, or the id to the
+// objects of a JSON:API error document. Anything else is returned
+// unchanged.
+func withRequestID(body []byte, id string) []byte {
+	var doc map[string]any
+	if json.Unmarshal(body, &doc) != nil || doc == nil {
+		return body
+	}
+	if objects, ok := doc["errors"].([]any); ok {
+		for _, o := range objects {
+			if object, ok := o.(map[string]any); ok {
+				object["id"] = id
+			}
+		}
+	} else if _, ok := doc["error"]; ok {
+		doc["requestId"] = id
+	} else {
+		return body
+	}
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return body
+	}
+	return out
+}
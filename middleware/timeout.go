@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"recipes-api/apiversion"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Timeout bounds how long a request may take by cancelling its context,
+// which database queries and outgoing calls run under, after timeout.
+// Handlers that fail because of it answer 503, see apierrors.Write. The
+// routes in exempt, like streams and websockets, aren't bounded in any
+// version, and a zero timeout bounds none.
+func Timeout(timeout time.Duration, exempt ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if timeout <= 0 || slices.Contains(exempt, apiversion.Unversioned(c.FullPath())) {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
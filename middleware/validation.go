@@ -0,0 +1,146 @@
+package middleware
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"recipes-api/apierrors"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// MaxTagLength is the longest tag the recipetag validation accepts.
+const MaxTagLength = 40
+
+// RegisterValidations adds the custom validations to gin's validator and
+// makes its errors name fields by their JSON names. It must run before
+// requests are served.
+//
+//   - notblank: strings must have something other than whitespace, other
+//     values must not be empty
+//   - recipetag: a non-blank tag of at most MaxTagLength characters
+//     without commas, which search indexes use as a separator
+func RegisterValidations() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		panic("gin's validator is not go-playground/validator")
+	}
+
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+	v.RegisterValidation("notblank", notBlank)
+	v.RegisterValidation("recipetag", recipeTag)
+}
+
+func notBlank(fl validator.FieldLevel) bool {
+	field := fl.Field()
+	switch field.Kind() {
+	case reflect.String:
+		return strings.TrimSpace(field.String()) != ""
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return field.Len() > 0
+	}
+	return !field.IsZero()
+}
+
+func recipeTag(fl validator.FieldLevel) bool {
+	tag := fl.Field().String()
+	return strings.TrimSpace(tag) != "" && utf8.RuneCountInString(tag) <= MaxTagLength && !strings.Contains(tag, ",")
+}
+
+// Validate checks obj against its binding tags, as BindJSON does.
+func Validate(obj any) error {
+	return binding.Validator.ValidateStruct(obj)
+}
+
+// FieldErrors returns what is wrong with each field when err is a failed
+// validation.
+func FieldErrors(err error) ([]apierrors.FieldError, bool) {
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) {
+		return nil, false
+	}
+
+	fields := make([]apierrors.FieldError, 0, len(errs))
+	for _, fe := range errs {
+		// the namespace starts with the Go name of the validated struct
+		_, field, _ := strings.Cut(fe.Namespace(), ".")
+		message, params := fieldMessage(fe)
+		fields = append(fields, apierrors.NewFieldError(field, message, params))
+	}
+	return fields, true
+}
+
+// DescribeValidation describes a failed validation by its first field,
+// such as "name must not be blank", for callers that can't return field
+// errors.
+func DescribeValidation(err error) (string, bool) {
+	fields, ok := FieldErrors(err)
+	if !ok || len(fields) == 0 {
+		return "", false
+	}
+	return fields[0].Field + " " + fields[0].Message, true
+}
+
+// fieldMessage returns the message for a failed validation and the values
+// of its placeholders. Messages are whole sentences so they can be
+// translated.
+func fieldMessage(fe validator.FieldError) (string, map[string]string) {
+	params := map[string]string{"param": fe.Param()}
+	switch fe.Tag() {
+	case "required":
+		return "is required", nil
+	case "notblank":
+		return "must not be blank", nil
+	case "recipetag":
+		return "must be a tag of at most {param} characters without commas", map[string]string{"param": strconv.Itoa(MaxTagLength)}
+	case "min":
+		switch fe.Kind() {
+		case reflect.String:
+			return "must be at least {param} characters long", params
+		case reflect.Slice, reflect.Map, reflect.Array:
+			if fe.Param() == "1" {
+				return "must have at least 1 item", nil
+			}
+			return "must have at least {param} items", params
+		}
+		return "must be at least {param}", params
+	case "max":
+		switch fe.Kind() {
+		case reflect.String:
+			return "must be at most {param} characters long", params
+		case reflect.Slice, reflect.Map, reflect.Array:
+			if fe.Param() == "1" {
+				return "must have at most 1 item", nil
+			}
+			return "must have at most {param} items", params
+		}
+		return "must be at most {param}", params
+	case "len":
+		switch fe.Kind() {
+		case reflect.String:
+			return "must be exactly {param} characters long", params
+		case reflect.Slice, reflect.Map, reflect.Array:
+			if fe.Param() == "1" {
+				return "must have exactly 1 item", nil
+			}
+			return "must have exactly {param} items", params
+		}
+		return "must be exactly {param}", params
+	case "oneof":
+		return "must be one of {param}", map[string]string{"param": strings.ReplaceAll(fe.Param(), " ", ", ")}
+	}
+	return "is invalid ({param})", map[string]string{"param": fe.Tag()}
+}
@@ -0,0 +1,145 @@
+-- The schema as AutoMigrate left it. Everything is IF NOT EXISTS so this
+-- also applies cleanly to databases created before migrations were added.
+
+-- +goose Up
+CREATE TABLE IF NOT EXISTS recipes (
+    id text PRIMARY KEY,
+    name text,
+    tags text,
+    ingredients text,
+    instructions text,
+    nutrition text,
+    image text,
+    published_at timestamptz,
+    deleted_at timestamptz,
+    instructions_offloaded boolean,
+    total_time_minutes bigint,
+    total_time_estimated boolean
+);
+CREATE INDEX IF NOT EXISTS idx_recipes_deleted_at ON recipes (deleted_at);
+
+CREATE TABLE IF NOT EXISTS recipe_instructions (
+    recipe_id text PRIMARY KEY,
+    instructions text
+);
+
+CREATE TABLE IF NOT EXISTS recipe_revisions (
+    id bigserial PRIMARY KEY,
+    recipe_id text,
+    revision bigint,
+    snapshot text,
+    created_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_recipe_revision ON recipe_revisions (recipe_id, revision);
+
+CREATE TABLE IF NOT EXISTS recipes_list (
+    id text PRIMARY KEY,
+    name text,
+    tags text,
+    thumb text,
+    published_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_recipes_list_published_at ON recipes_list (published_at);
+
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id text PRIMARY KEY,
+    type text,
+    recipe_id text,
+    payload jsonb,
+    occurred_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_type ON outbox_events (type);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_recipe_id ON outbox_events (recipe_id);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_occurred_at ON outbox_events (occurred_at);
+
+CREATE TABLE IF NOT EXISTS webhooks (
+    id text PRIMARY KEY,
+    url text,
+    secret text,
+    events text,
+    schema_version text,
+    active boolean,
+    created_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id text PRIMARY KEY,
+    webhook_id text,
+    event_id text,
+    event_type text,
+    attempt bigint,
+    status_code bigint,
+    error text,
+    duration_ms bigint,
+    success boolean,
+    captured boolean,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries (webhook_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_event_id ON webhook_deliveries (event_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_created_at ON webhook_deliveries (created_at);
+
+CREATE TABLE IF NOT EXISTS recipe_subscriptions (
+    id text PRIMARY KEY,
+    recipe_id text,
+    email text,
+    fields text,
+    created_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_subscription_recipe_email ON recipe_subscriptions (recipe_id, email);
+
+CREATE TABLE IF NOT EXISTS request_stats (
+    day date,
+    route text,
+    requests bigint,
+    errors bigint,
+    total_ms bigint,
+    max_ms bigint,
+    PRIMARY KEY (day, route)
+);
+
+CREATE TABLE IF NOT EXISTS search_stats (
+    day date,
+    query text,
+    count bigint,
+    PRIMARY KEY (day, query)
+);
+
+CREATE TABLE IF NOT EXISTS cache_stats (
+    day date PRIMARY KEY,
+    hits bigint,
+    misses bigint
+);
+
+CREATE TABLE IF NOT EXISTS report_runs (
+    period_start timestamptz PRIMARY KEY,
+    created_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS recipe_templates (
+    id text PRIMARY KEY,
+    name text,
+    description text,
+    tags text,
+    ingredients text,
+    instructions text,
+    total_time_minutes bigint,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_recipe_templates_name ON recipe_templates (name);
+
+-- +goose Down
+DROP TABLE IF EXISTS recipe_templates;
+DROP TABLE IF EXISTS report_runs;
+DROP TABLE IF EXISTS cache_stats;
+DROP TABLE IF EXISTS search_stats;
+DROP TABLE IF EXISTS request_stats;
+DROP TABLE IF EXISTS recipe_subscriptions;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
+DROP TABLE IF EXISTS outbox_events;
+DROP TABLE IF EXISTS recipes_list;
+DROP TABLE IF EXISTS recipe_revisions;
+DROP TABLE IF EXISTS recipe_instructions;
+DROP TABLE IF EXISTS recipes;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE recipes ADD COLUMN IF NOT EXISTS fields_updated_at text;
+
+-- +goose Down
+ALTER TABLE recipes DROP COLUMN IF EXISTS fields_updated_at;
@@ -0,0 +1,9 @@
+-- +goose Up
+-- Existing rows score 0 until the projection is rebuilt, which
+-- "recipes-api migrate up" does after applying migrations.
+ALTER TABLE recipes_list ADD COLUMN IF NOT EXISTS quality integer NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_recipes_list_quality ON recipes_list (quality, published_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_recipes_list_quality;
+ALTER TABLE recipes_list DROP COLUMN IF EXISTS quality;
@@ -0,0 +1,17 @@
+-- +goose Up
+ALTER TABLE recipes ADD COLUMN IF NOT EXISTS draft boolean NOT NULL DEFAULT false;
+ALTER TABLE recipes_list ADD COLUMN IF NOT EXISTS draft boolean NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS recipe_previews (
+    id text PRIMARY KEY,
+    recipe_id text,
+    expires_at timestamptz,
+    revoked_at timestamptz,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_recipe_previews_recipe_id ON recipe_previews (recipe_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS recipe_previews;
+ALTER TABLE recipes_list DROP COLUMN IF EXISTS draft;
+ALTER TABLE recipes DROP COLUMN IF EXISTS draft;
@@ -0,0 +1,10 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS redirects (
+    from_id text PRIMARY KEY,
+    to_id text,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_redirects_to_id ON redirects (to_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS redirects;
@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS retag_jobs (
+    id text PRIMARY KEY,
+    action text,
+    tag text,
+    q text,
+    tags text,
+    max_total_time bigint,
+    status text,
+    matched bigint,
+    changed bigint,
+    revisions text,
+    skipped bigint,
+    error text,
+    created_at timestamptz,
+    finished_at timestamptz
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS retag_jobs;
@@ -0,0 +1,25 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS incidents (
+    id text PRIMARY KEY,
+    title text,
+    impact text,
+    status text,
+    message text,
+    components text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    resolved_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_incidents_created_at ON incidents (created_at);
+
+CREATE TABLE IF NOT EXISTS status_samples (
+    component text,
+    day date,
+    up bigint NOT NULL DEFAULT 0,
+    total bigint NOT NULL DEFAULT 0,
+    PRIMARY KEY (component, day)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS status_samples;
+DROP TABLE IF EXISTS incidents;
@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS recipe_translations (
+    recipe_id text,
+    locale text,
+    name text,
+    ingredients text,
+    instructions text,
+    updated_at timestamptz,
+    PRIMARY KEY (recipe_id, locale)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS recipe_translations;
@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS meal_plan_entries (
+    id text PRIMARY KEY,
+    day text NOT NULL,
+    slot text NOT NULL,
+    recipe_id text NOT NULL,
+    servings bigint NOT NULL DEFAULT 0,
+    note text,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_meal_plan_entries_day ON meal_plan_entries (day);
+CREATE INDEX IF NOT EXISTS idx_meal_plan_entries_recipe_id ON meal_plan_entries (recipe_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS meal_plan_entries;
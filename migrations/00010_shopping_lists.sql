@@ -0,0 +1,23 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS shopping_lists (
+    id text PRIMARY KEY,
+    name text,
+    recipe_ids text,
+    created_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS shopping_list_items (
+    id text PRIMARY KEY,
+    list_id text NOT NULL REFERENCES shopping_lists (id) ON DELETE CASCADE,
+    position bigint NOT NULL DEFAULT 0,
+    aisle text,
+    item text,
+    quantities text,
+    recipe_ids text,
+    checked boolean NOT NULL DEFAULT false
+);
+CREATE INDEX IF NOT EXISTS idx_shopping_list_items_list_id ON shopping_list_items (list_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS shopping_list_items;
+DROP TABLE IF EXISTS shopping_lists;
@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE recipes ADD COLUMN IF NOT EXISTS servings bigint NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE recipes DROP COLUMN IF EXISTS servings;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- recipes_list picks the new columns up when the projection is rebuilt,
+-- which "recipes-api migrate up" does after applying migrations.
+ALTER TABLE recipes ADD COLUMN IF NOT EXISTS prep_minutes bigint NOT NULL DEFAULT 0;
+ALTER TABLE recipes ADD COLUMN IF NOT EXISTS cook_minutes bigint NOT NULL DEFAULT 0;
+ALTER TABLE recipes ADD COLUMN IF NOT EXISTS difficulty text NOT NULL DEFAULT '';
+ALTER TABLE recipes_list ADD COLUMN IF NOT EXISTS total_time_minutes bigint NOT NULL DEFAULT 0;
+ALTER TABLE recipes_list ADD COLUMN IF NOT EXISTS difficulty text NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE recipes_list DROP COLUMN IF EXISTS difficulty;
+ALTER TABLE recipes_list DROP COLUMN IF EXISTS total_time_minutes;
+ALTER TABLE recipes DROP COLUMN IF EXISTS difficulty;
+ALTER TABLE recipes DROP COLUMN IF EXISTS cook_minutes;
+ALTER TABLE recipes DROP COLUMN IF EXISTS prep_minutes;
@@ -0,0 +1,34 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS cuisines (
+    id text PRIMARY KEY,
+    name text NOT NULL,
+    description text,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_cuisines_name ON cuisines (name);
+CREATE TABLE IF NOT EXISTS categories (
+    id text PRIMARY KEY,
+    name text NOT NULL,
+    description text,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_categories_name ON categories (name);
+
+ALTER TABLE recipes ADD COLUMN IF NOT EXISTS cuisine_id text NOT NULL DEFAULT '';
+ALTER TABLE recipes ADD COLUMN IF NOT EXISTS category_id text NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS idx_recipes_cuisine_id ON recipes (cuisine_id);
+CREATE INDEX IF NOT EXISTS idx_recipes_category_id ON recipes (category_id);
+ALTER TABLE recipes_list ADD COLUMN IF NOT EXISTS cuisine_id text NOT NULL DEFAULT '';
+ALTER TABLE recipes_list ADD COLUMN IF NOT EXISTS category_id text NOT NULL DEFAULT '';
+
+-- +goose Down
+ALTER TABLE recipes_list DROP COLUMN IF EXISTS category_id;
+ALTER TABLE recipes_list DROP COLUMN IF EXISTS cuisine_id;
+DROP INDEX IF EXISTS idx_recipes_category_id;
+DROP INDEX IF EXISTS idx_recipes_cuisine_id;
+ALTER TABLE recipes DROP COLUMN IF EXISTS category_id;
+ALTER TABLE recipes DROP COLUMN IF EXISTS cuisine_id;
+DROP TABLE IF EXISTS categories;
+DROP TABLE IF EXISTS cuisines;
@@ -0,0 +1,30 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS tags (
+    id bigserial PRIMARY KEY,
+    name text NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_name ON tags (name);
+CREATE TABLE IF NOT EXISTS recipe_tags (
+    recipe_id text NOT NULL,
+    tag_id bigint NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+    PRIMARY KEY (recipe_id, tag_id)
+);
+CREATE INDEX IF NOT EXISTS idx_recipe_tags_tag_id ON recipe_tags (tag_id);
+
+-- recipes without tags may have null for them, which isn't an array
+INSERT INTO tags (name)
+SELECT DISTINCT LOWER(TRIM(t))
+FROM recipes CROSS JOIN LATERAL jsonb_array_elements_text(CASE WHEN recipes.tags LIKE '[%' THEN recipes.tags::jsonb ELSE '[]' END) AS t
+WHERE recipes.deleted_at IS NULL
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO recipe_tags (recipe_id, tag_id)
+SELECT DISTINCT recipes.id, tags.id
+FROM recipes CROSS JOIN LATERAL jsonb_array_elements_text(CASE WHEN recipes.tags LIKE '[%' THEN recipes.tags::jsonb ELSE '[]' END) AS t
+JOIN tags ON tags.name = LOWER(TRIM(t))
+WHERE recipes.deleted_at IS NULL
+ON CONFLICT DO NOTHING;
+
+-- +goose Down
+DROP TABLE IF EXISTS recipe_tags;
+DROP TABLE IF EXISTS tags;
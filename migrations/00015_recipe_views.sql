@@ -0,0 +1,9 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS recipe_views (
+    recipe_id text PRIMARY KEY,
+    views bigint NOT NULL DEFAULT 0,
+    updated_at timestamptz
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS recipe_views;
@@ -0,0 +1,37 @@
+-- +goose Up
+-- Existing recipes get slugs from their names, oldest first, with -2, -3
+-- and so on for names that come out the same. Slugify in the service makes
+-- the same slugs for plain ASCII names; others may differ slightly, which
+-- is fine since slugs don't have to be derivable, only unique.
+ALTER TABLE recipes ADD COLUMN IF NOT EXISTS slug text NOT NULL DEFAULT '';
+ALTER TABLE recipes_list ADD COLUMN IF NOT EXISTS slug text NOT NULL DEFAULT '';
+
+WITH bases AS (
+    SELECT id, COALESCE(NULLIF(LEFT(TRIM(BOTH '-' FROM regexp_replace(LOWER(name), '[^a-z0-9]+', '-', 'g')), 80), ''), 'recipe') AS base,
+           published_at
+    FROM recipes
+    WHERE slug = ''
+), numbered AS (
+    SELECT id, base, ROW_NUMBER() OVER (PARTITION BY base ORDER BY published_at, id) AS n
+    FROM bases
+)
+UPDATE recipes SET slug = CASE WHEN numbered.n = 1 THEN numbered.base ELSE numbered.base || '-' || numbered.n END
+FROM numbered
+WHERE recipes.id = numbered.id;
+
+-- A numbered slug can still clash with a name that already ends in a
+-- number; those few fall back to the recipe id.
+UPDATE recipes SET slug = slug || '-' || id
+WHERE id IN (
+    SELECT id FROM (
+        SELECT id, ROW_NUMBER() OVER (PARTITION BY slug ORDER BY published_at, id) AS n FROM recipes
+    ) dupes
+    WHERE n > 1
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_recipes_slug ON recipes (slug) WHERE slug <> '';
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_recipes_slug;
+ALTER TABLE recipes_list DROP COLUMN IF EXISTS slug;
+ALTER TABLE recipes DROP COLUMN IF EXISTS slug;
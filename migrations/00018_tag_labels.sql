@@ -0,0 +1,22 @@
+-- +goose Up
+ALTER TABLE tags ADD COLUMN IF NOT EXISTS label text;
+
+-- a tag is shown as spelled on the oldest recipe filed under it
+UPDATE tags SET label = spellings.label
+FROM (
+    SELECT DISTINCT ON (LOWER(TRIM(t))) LOWER(TRIM(t)) AS name, TRIM(t) AS label
+    FROM recipes CROSS JOIN LATERAL jsonb_array_elements_text(CASE WHEN recipes.tags LIKE '[%' THEN recipes.tags::jsonb ELSE '[]' END) AS t
+    WHERE recipes.deleted_at IS NULL
+    ORDER BY LOWER(TRIM(t)), recipes.published_at
+) AS spellings
+WHERE tags.name = spellings.name;
+UPDATE tags SET label = name WHERE label IS NULL;
+ALTER TABLE tags ALTER COLUMN label SET NOT NULL;
+
+-- text_pattern_ops lets prefix LIKE searches use the index whatever the
+-- collation
+CREATE INDEX IF NOT EXISTS idx_tags_name_prefix ON tags (name text_pattern_ops);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_tags_name_prefix;
+ALTER TABLE tags DROP COLUMN IF EXISTS label;
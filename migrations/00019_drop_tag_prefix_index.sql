@@ -0,0 +1,7 @@
+-- +goose Up
+-- tag searches match anywhere in the name again, which the prefix index
+-- can't serve
+DROP INDEX IF EXISTS idx_tags_name_prefix;
+
+-- +goose Down
+CREATE INDEX IF NOT EXISTS idx_tags_name_prefix ON tags (name text_pattern_ops);
@@ -0,0 +1,76 @@
+// Package migrations holds the versioned database schema. Each change is a
+// SQL file named <version>_<description>.sql with goose Up and Down
+// sections; the files are embedded in the binary.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+
+	"github.com/pressly/goose/v3"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// ErrVersionMismatch is returned by Check when the database schema is not
+// the one this build was written for.
+var ErrVersionMismatch = errors.New("database schema version mismatch")
+
+// Status is the state of a single migration.
+type Status = goose.MigrationStatus
+
+// Result describes an applied or rolled back migration.
+type Result = goose.MigrationResult
+
+// provider must not be closed, that would close db.
+func provider(db *sql.DB) (*goose.Provider, error) {
+	return goose.NewProvider(goose.DialectPostgres, db, files)
+}
+
+// Up applies all pending migrations in order.
+func Up(ctx context.Context, db *sql.DB) ([]*Result, error) {
+	p, err := provider(db)
+	if err != nil {
+		return nil, err
+	}
+	return p.Up(ctx)
+}
+
+// Down rolls back the most recently applied migration.
+func Down(ctx context.Context, db *sql.DB) (*Result, error) {
+	p, err := provider(db)
+	if err != nil {
+		return nil, err
+	}
+	return p.Down(ctx)
+}
+
+// List returns every known migration and whether it has been applied.
+func List(ctx context.Context, db *sql.DB) ([]*Status, error) {
+	p, err := provider(db)
+	if err != nil {
+		return nil, err
+	}
+	return p.Status(ctx)
+}
+
+// Check returns an error wrapping ErrVersionMismatch unless every
+// migration, and nothing newer, has been applied.
+func Check(ctx context.Context, db *sql.DB) error {
+	p, err := provider(db)
+	if err != nil {
+		return err
+	}
+	current, target, err := p.GetVersions(ctx)
+	if err != nil {
+		return err
+	}
+	if current != target {
+		return fmt.Errorf("%w: database is at version %d, this build expects %d", ErrVersionMismatch, current, target)
+	}
+	return nil
+}
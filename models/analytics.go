@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// RequestStat aggregates the requests to one route on one day (UTC).
+type RequestStat struct {
+	Day      time.Time `json:"day" gorm:"primaryKey;type:date"`
+	Route    string    `json:"route" gorm:"primaryKey"`
+	Requests int64     `json:"requests"`
+	// Errors counts 5xx responses.
+	Errors  int64 `json:"errors"`
+	TotalMS int64 `json:"totalMs"`
+	MaxMS   int64 `json:"maxMs"`
+}
+
+// SearchStat counts how often a tag was searched on one day.
+type SearchStat struct {
+	Day   time.Time `json:"day" gorm:"primaryKey;type:date"`
+	Query string    `json:"query" gorm:"primaryKey"`
+	Count int64     `json:"count"`
+}
+
+// CacheStat counts recipe cache lookups on one day.
+type CacheStat struct {
+	Day    time.Time `json:"day" gorm:"primaryKey;type:date"`
+	Hits   int64     `json:"hits"`
+	Misses int64     `json:"misses"`
+}
+
+// ReportRun marks a weekly report as sent, so only one instance sends it.
+type ReportRun struct {
+	PeriodStart time.Time `json:"periodStart" gorm:"primaryKey"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
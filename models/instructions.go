@@ -0,0 +1,9 @@
+package models
+
+// RecipeInstructions holds the instructions of recipes too long to keep
+// inline in the recipes row. Recipes stored this way have
+// InstructionsOffloaded set and no instructions in their own row.
+type RecipeInstructions struct {
+	RecipeID     string   `gorm:"primaryKey"`
+	Instructions []string `gorm:"serializer:json"`
+}
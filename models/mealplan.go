@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// MealPlanEntry puts a recipe on the meal plan for one meal of a day.
+// A meal may have several recipes, e.g. a main and a side.
+type MealPlanEntry struct {
+	ID string `json:"id" gorm:"primaryKey"`
+	// Day is the date of the meal, as YYYY-MM-DD.
+	Day      string `json:"day" gorm:"index"`
+	Slot     string `json:"slot"`
+	RecipeID string `json:"recipeId" gorm:"index"`
+	// Servings is how many the recipe is cooked for, if not as written.
+	Servings  int       `json:"servings,omitempty"`
+	Note      string    `json:"note,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// OutboxEvent is a persisted copy of an emitted recipe event, kept so
+// deliveries can be replayed later.
+type OutboxEvent struct {
+	ID         string    `json:"id" gorm:"primaryKey"`
+	Type       string    `json:"type" gorm:"index"`
+	RecipeID   string    `json:"recipeId" gorm:"index"`
+	Payload    []byte    `json:"-" gorm:"type:jsonb"`
+	OccurredAt time.Time `json:"occurredAt" gorm:"index"`
+}
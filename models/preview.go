@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// RecipePreview lets whoever holds its token read a recipe before it is
+// published, until it expires or is revoked.
+type RecipePreview struct {
+	ID        string     `json:"id" gorm:"primaryKey"`
+	RecipeID  string     `json:"recipeId" gorm:"index"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	RevokedAt *time.Time `json:"revokedAt,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// Active reports whether the preview can still be used at now.
+func (p RecipePreview) Active(now time.Time) bool {
+	return p.RevokedAt == nil && now.Before(p.ExpiresAt)
+}
@@ -1,12 +1,130 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type Recipe struct {
-	ID           string    `json:"id" gorm:"primaryKey"`
-	Name         string    `json:"name"`
-	Tags         []string  `json:"tags" gorm:"serializer:json"`
-	Ingredients  []string  `json:"ingredients" gorm:"serializer:json"`
-	Instructions []string  `json:"instructions" gorm:"serializer:json"`
-	PublishedAt  time.Time `json:"publishedAt"`
-}
\ No newline at end of file
+	ID           string         `json:"id" gorm:"primaryKey"`
+	Name         string         `json:"name" binding:"notblank,max=200"`
+	Tags         []string       `json:"tags" gorm:"serializer:json" binding:"max=30,dive,recipetag"`
+	Ingredients  []string       `json:"ingredients" gorm:"serializer:json" binding:"min=1,dive,notblank"`
+	Instructions []string       `json:"instructions" gorm:"serializer:json" binding:"min=1,dive,notblank"`
+	Nutrition    *Nutrition     `json:"nutrition,omitempty" gorm:"serializer:json"`
+	Image        *Image         `json:"image,omitempty" gorm:"serializer:json"`
+	PublishedAt  time.Time      `json:"publishedAt"`
+	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Slug is the recipe's name made URL-safe, unique across recipes. It
+	// is set from the name on create and follows renames; the old slug
+	// redirects to the new one.
+	Slug string `json:"slug,omitempty"`
+
+	// Version counts the edits of the recipe's content, starting at 1.
+	// Updates send the version they were made from and are refused when
+	// the recipe has moved on since.
+	Version int64 `json:"version" gorm:"not null;default:1" diff:"-"`
+
+	// Draft recipes, and recipes scheduled with a PublishedAt in the
+	// future, are left out of listings and searches and can only be read
+	// by admins or with a preview token.
+	Draft bool `json:"draft,omitempty"`
+
+	// InstructionsOffloaded is set when the instructions are stored in
+	// recipe_instructions. List views leave them out; fetch the recipe by
+	// id to get them.
+	InstructionsOffloaded bool `json:"instructionsOffloaded,omitempty"`
+
+	// TotalTimeMinutes is how long the recipe takes. When the author leaves
+	// it out it is worked out, from the prep and cook times if given and
+	// otherwise from the steps, and TotalTimeEstimated is set.
+	TotalTimeMinutes   int  `json:"totalTimeMinutes,omitempty" binding:"min=0"`
+	TotalTimeEstimated bool `json:"totalTimeEstimated,omitempty"`
+
+	// PrepMinutes and CookMinutes split the time into hands-on preparation
+	// and cooking, up to a week each.
+	PrepMinutes int `json:"prepMinutes,omitempty" binding:"min=0,max=10080"`
+	CookMinutes int `json:"cookMinutes,omitempty" binding:"min=0,max=10080"`
+
+	// Difficulty is easy, medium or hard, or empty when not rated.
+	Difficulty string `json:"difficulty,omitempty" binding:"omitempty,oneof=easy medium hard"`
+
+	// CuisineID and CategoryID file the recipe under a cuisine and a
+	// category, or neither when empty.
+	CuisineID  string `json:"cuisineId,omitempty" gorm:"index"`
+	CategoryID string `json:"categoryId,omitempty" gorm:"index"`
+
+	// Servings is how many the ingredients serve, which scaling the
+	// recipe starts from. Zero means it isn't known.
+	Servings int `json:"servings,omitempty" binding:"min=0,max=1000"`
+
+	// ViewCount is how many times the recipe has been read, as of the last
+	// time views were flushed. It is counted apart from the recipe and
+	// filled in when one is read by id.
+	ViewCount int64 `json:"viewCount,omitempty" gorm:"-" diff:"-"`
+
+	// FieldsUpdatedAt records when each field was last changed, keyed by
+	// JSON name, so offline clients can resolve conflicts field by field.
+	// Fields missing from it haven't changed since PublishedAt.
+	FieldsUpdatedAt map[string]time.Time `json:"fieldsUpdatedAt,omitempty" gorm:"serializer:json" diff:"-"`
+}
+
+// IsPublished reports whether the recipe is public at now.
+func (r Recipe) IsPublished(now time.Time) bool {
+	return !r.Draft && !r.PublishedAt.After(now)
+}
+
+// Difficulties are the values Recipe.Difficulty takes, easiest first.
+var Difficulties = []string{"easy", "medium", "hard"}
+
+// FieldUpdatedAt returns when the field was last changed.
+func (r Recipe) FieldUpdatedAt(field string) time.Time {
+	if t, ok := r.FieldsUpdatedAt[field]; ok {
+		return t
+	}
+	return r.PublishedAt
+}
+
+// Nutrition holds the nutrition totals of a recipe, summed over its ingredients.
+type Nutrition struct {
+	Calories float64 `json:"calories"`
+	Protein  float64 `json:"protein"`
+	Fat      float64 `json:"fat"`
+	Carbs    float64 `json:"carbs"`
+}
+
+func (n *Nutrition) Add(other Nutrition) {
+	n.Calories += other.Calories
+	n.Protein += other.Protein
+	n.Fat += other.Fat
+	n.Carbs += other.Carbs
+}
+
+// Image describes an uploaded recipe photo stored in object storage.
+type Image struct {
+	Key         string `json:"key"`
+	URL         string `json:"url"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+	// Variants are resized copies keyed by name (thumb, medium, large),
+	// filled in by the thumbnail worker after upload.
+	Variants map[string]ImageVariant `json:"variants,omitempty"`
+}
+
+type ImageVariant struct {
+	Key    string `json:"key"`
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// Keys returns the storage keys of the original image and all its variants.
+func (i *Image) Keys() []string {
+	keys := []string{i.Key}
+	for _, v := range i.Variants {
+		keys = append(keys, v.Key)
+	}
+	return keys
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// Redirect sends links to a recipe that no longer exists, e.g. because it
+// was merged into another, on to its replacement. It also sends old slugs
+// of renamed recipes on to their current ones.
+type Redirect struct {
+	FromID    string    `json:"from" gorm:"primaryKey"`
+	ToID      string    `json:"to" gorm:"index"`
+	CreatedAt time.Time `json:"createdAt"`
+}
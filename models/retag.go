@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// RetagJob adds a tag to, or removes it from, every recipe matching a
+// search. Q, Tags and MaxTotalTime are the search, as for
+// /recipes/search.
+type RetagJob struct {
+	ID           string   `json:"id" gorm:"primaryKey"`
+	Action       string   `json:"action"`
+	Tag          string   `json:"tag"`
+	Q            string   `json:"q,omitempty"`
+	Tags         []string `json:"tags,omitempty" gorm:"serializer:json"`
+	MaxTotalTime int      `json:"maxTotalTime,omitempty"`
+
+	Status string `json:"status"`
+	// Matched counts the recipes the search found and Changed the ones
+	// that needed the change.
+	Matched int `json:"matched"`
+	Changed int `json:"changed"`
+	// Revisions holds, per changed recipe, the revision saved right
+	// before the change, which undoing the job goes back to.
+	Revisions map[string]int `json:"-" gorm:"serializer:json"`
+	// Skipped counts the recipes undoing left alone because their tags
+	// were edited after the job.
+	Skipped    int        `json:"skipped,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// RecipeRevision is a snapshot of a recipe taken right before it was changed.
+type RecipeRevision struct {
+	ID        uint      `json:"-" gorm:"primaryKey"`
+	RecipeID  string    `json:"recipeId" gorm:"uniqueIndex:idx_recipe_revision"`
+	Revision  int       `json:"revision" gorm:"uniqueIndex:idx_recipe_revision"`
+	Snapshot  Recipe    `json:"snapshot" gorm:"serializer:json"`
+	CreatedAt time.Time `json:"createdAt"`
+}
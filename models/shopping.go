@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// ShoppingList is a list of ingredients to buy, built from recipes.
+type ShoppingList struct {
+	ID        string             `json:"id" gorm:"primaryKey"`
+	Name      string             `json:"name,omitempty"`
+	RecipeIDs []string           `json:"recipeIds" gorm:"serializer:json"`
+	Items     []ShoppingListItem `json:"-" gorm:"foreignKey:ListID;constraint:OnDelete:CASCADE"`
+	CreatedAt time.Time          `json:"createdAt"`
+}
+
+// ShoppingListItem is one ingredient to buy, merged from every recipe
+// that needs it.
+type ShoppingListItem struct {
+	ID     string `json:"id" gorm:"primaryKey"`
+	ListID string `json:"-" gorm:"index"`
+	// Position keeps the items in the order they were listed.
+	Position int    `json:"-"`
+	Aisle    string `json:"aisle"`
+	Item     string `json:"item"`
+	// Quantities holds the summed amount per unit. Amounts in units that
+	// can't be added up are listed apart, and an item only used to taste
+	// has none.
+	Quantities []Quantity `json:"quantities,omitempty" gorm:"serializer:json"`
+	RecipeIDs  []string   `json:"recipeIds" gorm:"serializer:json"`
+	Checked    bool       `json:"checked"`
+}
+
+// Quantity is an amount of a unit; Unit is empty for counted items, as in
+// "2 eggs". AmountMax is set when a recipe gave a range.
+type Quantity struct {
+	Amount    float64 `json:"amount"`
+	AmountMax float64 `json:"amountMax,omitempty"`
+	Unit      string  `json:"unit,omitempty"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Incident is an outage or degradation admins report on the status page.
+type Incident struct {
+	ID    string `json:"id" gorm:"primaryKey"`
+	Title string `json:"title"`
+	// Impact is minor, major or critical.
+	Impact string `json:"impact"`
+	// Status is investigating, identified, monitoring or resolved.
+	Status  string `json:"status"`
+	Message string `json:"message,omitempty"`
+	// Components are the affected ones, named as on the status page.
+	Components []string   `json:"components" gorm:"serializer:json"`
+	CreatedAt  time.Time  `json:"createdAt" gorm:"index"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+	ResolvedAt *time.Time `json:"resolvedAt,omitempty"`
+}
+
+// StatusSample counts, for a component and UTC day, the checks of it and
+// how many of them found it working.
+type StatusSample struct {
+	Component string    `gorm:"primaryKey"`
+	Day       time.Time `gorm:"primaryKey;type:date"`
+	Up        int
+	Total     int
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// RecipeSubscription asks for an email when a recipe is updated.
+type RecipeSubscription struct {
+	ID       string `json:"id" gorm:"primaryKey"`
+	RecipeID string `json:"recipeId" gorm:"uniqueIndex:idx_subscription_recipe_email"`
+	Email    string `json:"email" gorm:"uniqueIndex:idx_subscription_recipe_email"`
+	// Fields limits notifications to changes of these recipe fields; empty means any.
+	Fields    []string  `json:"fields" gorm:"serializer:json"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Wants reports whether a change to the field should be sent to the subscriber.
+func (s RecipeSubscription) Wants(field string) bool {
+	if len(s.Fields) == 0 {
+		return true
+	}
+	for _, f := range s.Fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// RecipeSummary is a row of the recipes_list projection: the compact fields
+// list views need, kept in sync on every recipe write so listing doesn't
+// have to decode the full recipe rows.
+type RecipeSummary struct {
+	ID          string    `json:"id" gorm:"primaryKey"`
+	Name        string    `json:"name"`
+	Slug        string    `json:"slug,omitempty"`
+	Tags        []string  `json:"tags" gorm:"serializer:json"`
+	Thumb       string    `json:"thumb,omitempty"`
+	PublishedAt time.Time `json:"publishedAt" gorm:"index"`
+	// TotalTimeMinutes, Difficulty, CuisineID and CategoryID are copied
+	// from the recipe so listings can be filtered by them.
+	TotalTimeMinutes int    `json:"totalTimeMinutes,omitempty"`
+	Difficulty       string `json:"difficulty,omitempty"`
+	CuisineID        string `json:"cuisineId,omitempty"`
+	CategoryID       string `json:"categoryId,omitempty"`
+	// Quality is the recipe's quality score, used to rank listings when
+	// the rankByQuality setting is on. It is only shown to admins.
+	Quality int `json:"-"`
+	// Draft is copied from the recipe so listings can leave drafts out.
+	Draft bool `json:"-"`
+}
+
+func (RecipeSummary) TableName() string {
+	return "recipes_list"
+}
+
+func NewRecipeSummary(recipe Recipe) RecipeSummary {
+	summary := RecipeSummary{
+		ID:          recipe.ID,
+		Name:        recipe.Name,
+		Slug:        recipe.Slug,
+		Tags:        recipe.Tags,
+		PublishedAt: recipe.PublishedAt,
+		Draft:       recipe.Draft,
+
+		TotalTimeMinutes: recipe.TotalTimeMinutes,
+		Difficulty:       recipe.Difficulty,
+		CuisineID:        recipe.CuisineID,
+		CategoryID:       recipe.CategoryID,
+	}
+
+	if recipe.Image != nil {
+		summary.Thumb = recipe.Image.URL
+		if thumb, ok := recipe.Image.Variants["thumb"]; ok {
+			summary.Thumb = thumb.URL
+		}
+	}
+
+	return summary
+}
@@ -0,0 +1,26 @@
+package models
+
+import "strings"
+
+// Tag is a tag recipes are filed under. Name is lowercased so spellings
+// differing only in case are one tag; Label is the spelling it was first
+// given, which is the one shown.
+type Tag struct {
+	ID    int64  `json:"id" gorm:"primaryKey"`
+	Name  string `json:"name" gorm:"uniqueIndex"`
+	Label string `json:"label"`
+}
+
+// RecipeTag links a recipe to one of its tags. The links are written with
+// the recipe, and are what recipes are queried and counted by tag with;
+// Recipe.Tags stays the recipe's own copy, in the order and spelling the
+// author gave.
+type RecipeTag struct {
+	RecipeID string `gorm:"primaryKey"`
+	TagID    int64  `gorm:"primaryKey;index"`
+}
+
+// TagName returns the name a tag is kept under in the tags table.
+func TagName(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
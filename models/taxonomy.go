@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Term is a cuisine or a category. Unlike tags, which authors make up
+// freely, terms are managed by admins and a recipe has at most one of
+// each. Cuisines and categories are kept in tables of their own.
+type Term struct {
+	ID          string    `json:"id" gorm:"primaryKey"`
+	Name        string    `json:"name" gorm:"uniqueIndex"`
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// RecipeTemplate is a starting point for new recipes, e.g. a bread
+// template with the usual tags, staple ingredients and placeholder steps.
+type RecipeTemplate struct {
+	ID               string    `json:"id" gorm:"primaryKey"`
+	Name             string    `json:"name" gorm:"uniqueIndex"`
+	Description      string    `json:"description,omitempty"`
+	Tags             []string  `json:"tags" gorm:"serializer:json"`
+	Ingredients      []string  `json:"ingredients" gorm:"serializer:json"`
+	Instructions     []string  `json:"instructions" gorm:"serializer:json"`
+	TotalTimeMinutes int       `json:"totalTimeMinutes,omitempty"`
+	CreatedAt        time.Time `json:"createdAt"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+}
+
+// Recipe returns a new recipe filled in from the template.
+func (t RecipeTemplate) Recipe(name string) Recipe {
+	return Recipe{
+		Name:             name,
+		Tags:             append([]string(nil), t.Tags...),
+		Ingredients:      append([]string(nil), t.Ingredients...),
+		Instructions:     append([]string(nil), t.Instructions...),
+		TotalTimeMinutes: t.TotalTimeMinutes,
+	}
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// RecipeTranslation holds a recipe's name, ingredients and steps in
+// another language. Fields left empty aren't translated.
+type RecipeTranslation struct {
+	RecipeID     string    `json:"recipeId" gorm:"primaryKey"`
+	Locale       string    `json:"locale" gorm:"primaryKey"`
+	Name         string    `json:"name,omitempty"`
+	Ingredients  []string  `json:"ingredients,omitempty" gorm:"serializer:json"`
+	Instructions []string  `json:"instructions,omitempty" gorm:"serializer:json"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+// Apply puts the translated fields into recipe, keeping the original
+// where the translation has none.
+func (t RecipeTranslation) Apply(recipe *Recipe) {
+	if t.Name != "" {
+		recipe.Name = t.Name
+	}
+	if len(t.Ingredients) > 0 {
+		recipe.Ingredients = t.Ingredients
+	}
+	if len(t.Instructions) > 0 {
+		recipe.Instructions = t.Instructions
+	}
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// RecipeViews is how many times a recipe has been read, up to the last
+// time the counts kept in Redis were flushed.
+type RecipeViews struct {
+	RecipeID  string `gorm:"primaryKey"`
+	Views     int64
+	UpdatedAt time.Time
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// Webhook is a target URL that receives signed recipe events.
+type Webhook struct {
+	ID     string `json:"id" gorm:"primaryKey"`
+	URL    string `json:"url"`
+	Secret string `json:"-"`
+	// Events lists the event types delivered to this webhook; empty means all.
+	Events        []string  `json:"events" gorm:"serializer:json"`
+	SchemaVersion string    `json:"schemaVersion"`
+	Active        bool      `json:"active"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// Wants reports whether the webhook subscribes to the event type.
+func (w Webhook) Wants(eventType string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, t := range w.Events {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery records a single attempt to deliver an event to a webhook.
+type WebhookDelivery struct {
+	ID         string    `json:"id" gorm:"primaryKey"`
+	WebhookID  string    `json:"webhookId" gorm:"index"`
+	EventID    string    `json:"eventId" gorm:"index"`
+	EventType  string    `json:"eventType"`
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"statusCode,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS int64     `json:"durationMs"`
+	Success    bool      `json:"success"`
+	Captured   bool      `json:"captured,omitempty"`
+	CreatedAt  time.Time `json:"createdAt" gorm:"index"`
+}
@@ -0,0 +1,131 @@
+package nutrition
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"recipes-api/models"
+	"recipes-api/outbound"
+)
+
+// Provider looks up the nutrition facts of a single ingredient line.
+type Provider interface {
+	Lookup(ctx context.Context, ingredient string) (models.Nutrition, error)
+}
+
+// NewProvider returns the provider selected by name, or nil when name is empty.
+func NewProvider(name, appID, apiKey string) (Provider, error) {
+	client := outbound.New(10*time.Second, 2)
+
+	switch strings.ToLower(name) {
+	case "":
+		return nil, nil
+	case "edamam":
+		return &Edamam{AppID: appID, AppKey: apiKey, client: client}, nil
+	case "usda":
+		return &USDA{APIKey: apiKey, client: client}, nil
+	default:
+		return nil, fmt.Errorf("unknown nutrition provider %q", name)
+	}
+}
+
+// Edamam uses the Edamam nutrition data API, which parses quantities from the ingredient line itself.
+type Edamam struct {
+	AppID  string
+	AppKey string
+	client *outbound.Client
+}
+
+func (e *Edamam) Lookup(ctx context.Context, ingredient string) (models.Nutrition, error) {
+	params := url.Values{}
+	params.Set("app_id", e.AppID)
+	params.Set("app_key", e.AppKey)
+	params.Set("ingr", ingredient)
+
+	var body struct {
+		Calories       float64 `json:"calories"`
+		TotalNutrients map[string]struct {
+			Quantity float64 `json:"quantity"`
+		} `json:"totalNutrients"`
+	}
+	if err := getJSON(ctx, e.client, "https://api.edamam.com/api/nutrition-data?"+params.Encode(), &body); err != nil {
+		return models.Nutrition{}, err
+	}
+
+	return models.Nutrition{
+		Calories: body.Calories,
+		Protein:  body.TotalNutrients["PROCNT"].Quantity,
+		Fat:      body.TotalNutrients["FAT"].Quantity,
+		Carbs:    body.TotalNutrients["CHOCDF"].Quantity,
+	}, nil
+}
+
+// USDA uses the FoodData Central search API. Values are those of the best
+// matching food per 100g, since FDC does not parse ingredient quantities.
+type USDA struct {
+	APIKey string
+	client *outbound.Client
+}
+
+func (u *USDA) Lookup(ctx context.Context, ingredient string) (models.Nutrition, error) {
+	params := url.Values{}
+	params.Set("api_key", u.APIKey)
+	params.Set("query", ingredient)
+	params.Set("pageSize", "1")
+
+	var body struct {
+		Foods []struct {
+			FoodNutrients []struct {
+				NutrientNumber string  `json:"nutrientNumber"`
+				Value          float64 `json:"value"`
+			} `json:"foodNutrients"`
+		} `json:"foods"`
+	}
+	if err := getJSON(ctx, u.client, "https://api.nal.usda.gov/fdc/v1/foods/search?"+params.Encode(), &body); err != nil {
+		return models.Nutrition{}, err
+	}
+
+	var facts models.Nutrition
+	if len(body.Foods) == 0 {
+		return facts, nil
+	}
+
+	for _, n := range body.Foods[0].FoodNutrients {
+		switch n.NutrientNumber {
+		case "208":
+			facts.Calories = n.Value
+		case "203":
+			facts.Protein = n.Value
+		case "204":
+			facts.Fat = n.Value
+		case "205":
+			facts.Carbs = n.Value
+		}
+	}
+
+	return facts, nil
+}
+
+func getJSON(ctx context.Context, client *outbound.Client, endpoint string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("nutrition provider returned %s", resp.Status)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
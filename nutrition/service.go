@@ -0,0 +1,109 @@
+package nutrition
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"recipes-api/cache"
+	"recipes-api/events"
+	"recipes-api/models"
+
+	"github.com/go-redis/redis"
+	"gorm.io/gorm"
+)
+
+// Service computes recipe nutrition totals in the background.
+type Service struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+	provider    Provider
+	events      *events.Bus
+	queue       chan job
+}
+
+type job struct {
+	ctx      context.Context
+	recipeID string
+}
+
+// NewService starts the given number of workers, which publish an update
+// of each recipe they compute nutrition for. With a nil provider the
+// service is disabled and Enqueue does nothing.
+func NewService(db *gorm.DB, redisClient *redis.Client, provider Provider, bus *events.Bus, workers int) *Service {
+	s := &Service{db: db, redisClient: redisClient, provider: provider, events: bus, queue: make(chan job, 100)}
+
+	if provider != nil {
+		for i := 0; i < workers; i++ {
+			go s.work()
+		}
+	}
+
+	return s
+}
+
+// Enqueue schedules a nutrition lookup for the recipe without blocking the
+// caller. The job keeps the request IDs from ctx but not its cancellation.
+func (s *Service) Enqueue(ctx context.Context, recipeID string) {
+	if s.provider == nil {
+		return
+	}
+
+	select {
+	case s.queue <- job{ctx: context.WithoutCancel(ctx), recipeID: recipeID}:
+	default:
+		slog.WarnContext(ctx, "Nutrition queue full, skipping recipe", "recipe_id", recipeID)
+	}
+}
+
+func (s *Service) work() {
+	for j := range s.queue {
+		if err := s.process(j.ctx, j.recipeID); err != nil {
+			slog.ErrorContext(j.ctx, "Error computing nutrition", "recipe_id", j.recipeID, "error", err)
+		}
+	}
+}
+
+func (s *Service) process(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	db := s.db.WithContext(ctx)
+
+	var recipe models.Recipe
+	if err := db.Where("id = ?", id).First(&recipe).Error; err != nil {
+		return err
+	}
+
+	var total models.Nutrition
+	for _, ingredient := range recipe.Ingredients {
+		ingredient = strings.TrimSpace(ingredient)
+		if ingredient == "" {
+			continue
+		}
+
+		lookupCtx, cancelLookup := context.WithTimeout(ctx, 15*time.Second)
+		facts, err := s.provider.Lookup(lookupCtx, ingredient)
+		cancelLookup()
+		if err != nil {
+			return err
+		}
+		total.Add(facts)
+	}
+
+	before := recipe
+	if err := db.Model(&recipe).Select("nutrition").Updates(models.Recipe{Nutrition: &total}).Error; err != nil {
+		return err
+	}
+	recipe.Nutrition = &total
+
+	cache.InvalidateRecipes(ctx, s.redisClient, recipe.ID)
+
+	// the listing's quality score counts nutrition, and the projection
+	// keeping it follows the bus
+	event := events.NewEvent(events.RecipeUpdated, recipe)
+	event.Changes = events.Diff(before, recipe)
+	s.events.Publish(ctx, event)
+	return nil
+}
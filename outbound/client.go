@@ -0,0 +1,126 @@
+// Package outbound is the shared HTTP client for calls to other services.
+// It forwards the originating request ID and trace context and applies a
+// per-call timeout and retries. Fetches of user-supplied URLs go through a
+// guarded client, which also enforces a Policy against SSRF.
+package outbound
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"recipes-api/reqid"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const baseBackoff = 200 * time.Millisecond
+
+var tracer = otel.Tracer("recipes-api/outbound")
+
+type Client struct {
+	http    *http.Client
+	retries int
+	// policy is only set for guarded clients.
+	policy *Policy
+}
+
+// New returns a client whose calls each time out after timeout and are
+// retried up to retries times on network errors, 429 and 5xx gateway errors.
+func New(timeout time.Duration, retries int) *Client {
+	return &Client{http: &http.Client{Timeout: timeout}, retries: retries}
+}
+
+// Do sends the request with the IDs from its context and records it as a
+// client span. Requests with a body are only retried when the body can be
+// replayed.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	if c.policy != nil {
+		if err := c.policy.CheckURL(req.URL.String()); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx := req.Context()
+	ids := reqid.FromContext(ctx)
+	if ids.RequestID != "" {
+		req.Header.Set(reqid.Header, ids.RequestID)
+	}
+	// background jobs only carry the trace context as a string
+	if !trace.SpanContextFromContext(ctx).IsValid() && ids.Traceparent != "" {
+		ctx = propagation.TraceContext{}.Extract(ctx, propagation.MapCarrier{reqid.TraceparentHeader: ids.Traceparent})
+	}
+
+	ctx, span := tracer.Start(ctx, "HTTP "+req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", req.Method),
+			attribute.String("url.full", req.URL.Redacted()),
+		))
+	defer span.End()
+
+	req = req.WithContext(ctx)
+	propagation.TraceContext{}.Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := c.do(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	if resp.StatusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+	return resp, nil
+}
+
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	retries := c.retries
+	if req.Body != nil && req.GetBody == nil {
+		retries = 0
+	}
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		resp, err := c.http.Do(req)
+		if attempt >= retries || !retryable(resp, err) {
+			if err == nil && c.policy != nil && c.policy.MaxResponseBytes > 0 {
+				resp.Body = &limitedBody{ReadCloser: resp.Body, remaining: c.policy.MaxResponseBytes}
+			}
+			return resp, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		select {
+		case <-time.After(baseBackoff << attempt):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, ErrBlocked)
+	}
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
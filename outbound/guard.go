@@ -0,0 +1,201 @@
+package outbound
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var (
+	ErrBlocked  = errors.New("destination is not allowed")
+	ErrTooLarge = errors.New("response body too large")
+)
+
+// sharedAddressSpace (RFC 6598) isn't covered by netip.Addr.IsPrivate.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// Policy restricts where a guarded client may connect. Entries in
+// AllowHosts and DenyHosts are host names, which match subdomains too, or
+// CIDR ranges, which match IP literals and resolved addresses.
+type Policy struct {
+	// AllowHosts, when not empty, is the only set of destinations allowed.
+	// Host names must be listed by name. Allowed destinations are reachable
+	// even on private addresses, so internal receivers can be let through.
+	AllowHosts []string
+	DenyHosts  []string
+	// AllowPrivate permits loopback, private and link-local addresses,
+	// which are blocked by default.
+	AllowPrivate     bool
+	MaxResponseBytes int64
+}
+
+// NewGuarded returns a client for fetching user-supplied URLs. Besides
+// what New does, every request, redirect and dialled address is checked
+// against the policy and response bodies are cut off at MaxResponseBytes.
+// Proxies from the environment are ignored, since they would hide the
+// address being connected to.
+func NewGuarded(policy Policy, timeout time.Duration, retries int) *Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	transport := &http.Transport{
+		DialContext:           policy.dialContext(dialer),
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: timeout,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+	}
+
+	return &Client{
+		http: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= 5 {
+					return errors.New("stopped after 5 redirects")
+				}
+				return policy.CheckURL(req.URL.String())
+			},
+		},
+		retries: retries,
+		policy:  &policy,
+	}
+}
+
+// CheckURL reports whether raw is an absolute http(s) URL the policy
+// allows. Host names are only checked by name here; the addresses they
+// resolve to are checked when connecting.
+func (p Policy) CheckURL(raw string) error {
+	target, err := url.Parse(raw)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Hostname() == "" {
+		return errors.New("URL must be an absolute http or https URL")
+	}
+	return p.checkHost(normalizeHost(target.Hostname()))
+}
+
+func normalizeHost(host string) string {
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
+
+func (p Policy) checkHost(host string) error {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return p.checkAddr(host, addr)
+	}
+	if matchHost(p.DenyHosts, host) {
+		return fmt.Errorf("%w: %s", ErrBlocked, host)
+	}
+	if len(p.AllowHosts) > 0 {
+		if !matchHost(p.AllowHosts, host) {
+			return fmt.Errorf("%w: %s", ErrBlocked, host)
+		}
+		return nil
+	}
+	if !p.AllowPrivate && (host == "localhost" || strings.HasSuffix(host, ".localhost")) {
+		return fmt.Errorf("%w: %s", ErrBlocked, host)
+	}
+	return nil
+}
+
+// checkAddr decides whether addr, which host resolved to, may be dialled.
+func (p Policy) checkAddr(host string, addr netip.Addr) error {
+	addr = addr.Unmap()
+	if matchPrefix(p.DenyHosts, addr) || matchHost(p.DenyHosts, addr.String()) {
+		return fmt.Errorf("%w: %s", ErrBlocked, addr)
+	}
+	if len(p.AllowHosts) > 0 {
+		if matchHost(p.AllowHosts, host) || matchPrefix(p.AllowHosts, addr) {
+			return nil
+		}
+		return fmt.Errorf("%w: %s", ErrBlocked, addr)
+	}
+	if !p.AllowPrivate && isPrivate(addr) {
+		return fmt.Errorf("%w: %s is a private address", ErrBlocked, addr)
+	}
+	return nil
+}
+
+// dialContext resolves the host itself and only dials addresses that pass
+// the policy, so a name can't be pointed at an internal address between
+// the check and the connection.
+func (p Policy) dialContext(dialer *net.Dialer) func(ctx context.Context, network, address string) (net.Conn, error) {
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		host = normalizeHost(host)
+		if err := p.checkHost(host); err != nil {
+			return nil, err
+		}
+
+		addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+		if err != nil {
+			return nil, err
+		}
+		lastErr := fmt.Errorf("%w: %s has no addresses", ErrBlocked, host)
+		for _, addr := range addrs {
+			if err := p.checkAddr(host, addr); err != nil {
+				lastErr = err
+				continue
+			}
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(addr.Unmap().String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+		return nil, lastErr
+	}
+}
+
+func isPrivate(addr netip.Addr) bool {
+	return addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() || addr.IsUnspecified() || sharedAddressSpace.Contains(addr)
+}
+
+func matchHost(list []string, host string) bool {
+	for _, entry := range list {
+		entry = strings.ToLower(entry)
+		if host == entry || strings.HasSuffix(host, "."+entry) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchPrefix(list []string, addr netip.Addr) bool {
+	for _, entry := range list {
+		if prefix, err := netip.ParsePrefix(entry); err == nil && prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// limitedBody fails reads once more than max bytes have been read, rather
+// than silently truncating the body.
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, ErrTooLarge
+	}
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return n, ErrTooLarge
+	}
+	return n, err
+}
@@ -0,0 +1,95 @@
+// Package previews issues signed links to recipes that aren't published
+// yet, so authors can share drafts and scheduled recipes with reviewers.
+package previews
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"recipes-api/models"
+
+	"github.com/rs/xid"
+	"gorm.io/gorm"
+)
+
+// ErrNotFound is returned when revoking a preview that doesn't exist.
+var ErrNotFound = errors.New("preview not found")
+
+// Service creates and checks preview tokens. A token is the preview's id
+// and an HMAC of it and the recipe id, so tokens can't be guessed from
+// ids and each one only opens its own recipe. Expiry and revocation are
+// kept in the database.
+type Service struct {
+	db     *gorm.DB
+	secret []byte
+}
+
+func NewService(db *gorm.DB, secret []byte) *Service {
+	return &Service{db: db, secret: secret}
+}
+
+// Create issues a preview of the recipe valid for ttl and returns it with
+// its token.
+func (s *Service) Create(ctx context.Context, recipeID string, ttl time.Duration) (models.RecipePreview, string, error) {
+	now := time.Now().UTC()
+	preview := models.RecipePreview{
+		ID:        xid.New().String(),
+		RecipeID:  recipeID,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}
+	if err := s.db.WithContext(ctx).Create(&preview).Error; err != nil {
+		return models.RecipePreview{}, "", err
+	}
+	return preview, preview.ID + "." + s.sign(preview.ID, recipeID), nil
+}
+
+// List returns the previews of a recipe, newest first.
+func (s *Service) List(ctx context.Context, recipeID string) ([]models.RecipePreview, error) {
+	previews := []models.RecipePreview{}
+	err := s.db.WithContext(ctx).Where("recipe_id = ?", recipeID).Order("created_at DESC").Find(&previews).Error
+	return previews, err
+}
+
+// Revoke stops a preview of the recipe from working before it expires.
+func (s *Service) Revoke(ctx context.Context, recipeID, id string) error {
+	result := s.db.WithContext(ctx).Model(&models.RecipePreview{}).
+		Where("id = ? AND recipe_id = ? AND revoked_at IS NULL", id, recipeID).
+		Update("revoked_at", time.Now().UTC())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Allows reports whether the token is an active preview of the recipe.
+func (s *Service) Allows(ctx context.Context, token, recipeID string) (bool, error) {
+	id, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(s.sign(id, recipeID))) {
+		return false, nil
+	}
+
+	var preview models.RecipePreview
+	err := s.db.WithContext(ctx).Where("id = ? AND recipe_id = ?", id, recipeID).Take(&preview).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return preview.Active(time.Now()), nil
+}
+
+func (s *Service) sign(id, recipeID string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(id + "." + recipeID))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
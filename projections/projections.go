@@ -0,0 +1,91 @@
+// Package projections maintains denormalized read models derived from recipes.
+package projections
+
+import (
+	"context"
+	"log/slog"
+
+	"recipes-api/cache"
+	"recipes-api/events"
+	"recipes-api/models"
+	"recipes-api/quality"
+	"recipes-api/service"
+	"recipes-api/tracing"
+
+	"github.com/go-redis/redis"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RecipeList keeps the recipes_list table in step with the recipes table.
+// The recipe_tags links are written along with the recipes; a rebuild
+// recomputes them too, as they can't be derived from events after seeding
+// and restores.
+type RecipeList struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+}
+
+func NewRecipeList(db *gorm.DB, redisClient *redis.Client) *RecipeList {
+	return &RecipeList{db: db, redisClient: redisClient}
+}
+
+// Handle applies a recipe event to the projection. It is meant to be
+// subscribed to the event bus.
+func (p *RecipeList) Handle(e events.Event) {
+	ctx := e.Context()
+	db := p.db.WithContext(ctx)
+
+	var err error
+	if e.Type == events.RecipeDeleted {
+		err = db.Where("id = ?", e.Recipe.ID).Delete(&models.RecipeSummary{}).Error
+	} else {
+		summary := newSummary(e.Recipe)
+		err = db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&summary).Error
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Error updating recipes_list", "recipe_id", e.Recipe.ID, "error", err)
+		return
+	}
+
+	tracing.Redis(ctx, p.redisClient).Del(cache.RecipeSummariesKey, cache.RankedSummariesKey, cache.TagsKey)
+}
+
+// Rebuild recomputes the whole projection from the recipes table, e.g.
+// after seeding which bypasses the event bus.
+func (p *RecipeList) Rebuild(ctx context.Context) error {
+	err := p.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&models.RecipeSummary{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("1 = 1").Delete(&models.RecipeTag{}).Error; err != nil {
+			return err
+		}
+
+		var batch []models.Recipe
+		return tx.Model(&models.Recipe{}).FindInBatches(&batch, 500, func(batchTx *gorm.DB, _ int) error {
+			summaries := make([]models.RecipeSummary, 0, len(batch))
+			for _, recipe := range batch {
+				summaries = append(summaries, newSummary(recipe))
+			}
+			if err := tx.Create(&summaries).Error; err != nil {
+				return err
+			}
+			return service.LinkTags(tx, batch...)
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	// like other invalidations this is best effort, so a rebuild still
+	// succeeds while Redis is unreachable
+	tracing.Redis(ctx, p.redisClient).Del(cache.RecipeSummariesKey, cache.RankedSummariesKey, cache.TagsKey)
+	return nil
+}
+
+func newSummary(recipe models.Recipe) models.RecipeSummary {
+	summary := models.NewRecipeSummary(recipe)
+	summary.Quality = quality.Score(recipe).Score
+	return summary
+}
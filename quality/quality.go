@@ -0,0 +1,77 @@
+// Package quality scores how complete a recipe is, so thin recipes can be
+// found and cleaned up and the complete ones ranked first.
+package quality
+
+import (
+	"recipes-api/ingredients"
+	"recipes-api/models"
+)
+
+// Checks, each worth the same share of the score.
+const (
+	HasImage       = "image"
+	HasNutrition   = "nutrition"
+	StructuredList = "structured-ingredients"
+	HasTimes       = "times"
+	EnoughSteps    = "steps"
+)
+
+const (
+	// MinSteps is how many steps a recipe needs to pass EnoughSteps.
+	MinSteps = 4
+	// LowScore is the score below which a recipe is listed for cleanup by
+	// default.
+	LowScore = 60
+
+	// minConfidence is the parse confidence every ingredient line needs
+	// for the list to count as structured.
+	minConfidence = 0.6
+)
+
+// Check is the outcome of one criterion.
+type Check struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+}
+
+// Report is a recipe's score, 0 to 100, and the checks it is made of.
+type Report struct {
+	Score  int     `json:"score"`
+	Checks []Check `json:"checks"`
+}
+
+// Score rates a recipe. Recipes whose instructions are offloaded pass the
+// steps check without them being loaded: only long instructions are
+// offloaded.
+func Score(recipe models.Recipe) Report {
+	checks := []Check{
+		{Name: HasImage, Passed: recipe.Image != nil},
+		{Name: HasNutrition, Passed: recipe.Nutrition != nil},
+		{Name: StructuredList, Passed: structured(recipe.Ingredients)},
+		{Name: HasTimes, Passed: recipe.TotalTimeMinutes > 0 && !recipe.TotalTimeEstimated || recipe.PrepMinutes+recipe.CookMinutes > 0},
+		{Name: EnoughSteps, Passed: recipe.InstructionsOffloaded || len(recipe.Instructions) >= MinSteps},
+	}
+
+	passed := 0
+	for _, check := range checks {
+		if check.Passed {
+			passed++
+		}
+	}
+
+	return Report{Score: passed * 100 / len(checks), Checks: checks}
+}
+
+// structured reports whether every ingredient line parses cleanly into
+// quantity, unit and item.
+func structured(lines []string) bool {
+	if len(lines) == 0 {
+		return false
+	}
+	for _, line := range lines {
+		if ingredients.Parse(line).Confidence < minConfidence {
+			return false
+		}
+	}
+	return true
+}
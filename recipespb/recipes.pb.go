@@ -0,0 +1,712 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        v5.29.3
+// source: recipes.proto
+
+package recipespb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Nutrition struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Calories      float64                `protobuf:"fixed64,1,opt,name=calories,proto3" json:"calories,omitempty"`
+	Protein       float64                `protobuf:"fixed64,2,opt,name=protein,proto3" json:"protein,omitempty"`
+	Fat           float64                `protobuf:"fixed64,3,opt,name=fat,proto3" json:"fat,omitempty"`
+	Carbs         float64                `protobuf:"fixed64,4,opt,name=carbs,proto3" json:"carbs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Nutrition) Reset() {
+	*x = Nutrition{}
+	mi := &file_recipes_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Nutrition) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Nutrition) ProtoMessage() {}
+
+func (x *Nutrition) ProtoReflect() protoreflect.Message {
+	mi := &file_recipes_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Nutrition.ProtoReflect.Descriptor instead.
+func (*Nutrition) Descriptor() ([]byte, []int) {
+	return file_recipes_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Nutrition) GetCalories() float64 {
+	if x != nil {
+		return x.Calories
+	}
+	return 0
+}
+
+func (x *Nutrition) GetProtein() float64 {
+	if x != nil {
+		return x.Protein
+	}
+	return 0
+}
+
+func (x *Nutrition) GetFat() float64 {
+	if x != nil {
+		return x.Fat
+	}
+	return 0
+}
+
+func (x *Nutrition) GetCarbs() float64 {
+	if x != nil {
+		return x.Carbs
+	}
+	return 0
+}
+
+type Recipe struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Id           string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name         string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Tags         []string               `protobuf:"bytes,3,rep,name=tags,proto3" json:"tags,omitempty"`
+	Ingredients  []string               `protobuf:"bytes,4,rep,name=ingredients,proto3" json:"ingredients,omitempty"`
+	Instructions []string               `protobuf:"bytes,5,rep,name=instructions,proto3" json:"instructions,omitempty"`
+	Nutrition    *Nutrition             `protobuf:"bytes,6,opt,name=nutrition,proto3" json:"nutrition,omitempty"`
+	ImageUrl     string                 `protobuf:"bytes,7,opt,name=image_url,json=imageUrl,proto3" json:"image_url,omitempty"`
+	PublishedAt  *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=published_at,json=publishedAt,proto3" json:"published_at,omitempty"`
+	// version is bumped on every change. Update needs the version the
+	// change was made from and fails with ABORTED when it is stale.
+	Version       int64 `protobuf:"varint,9,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Recipe) Reset() {
+	*x = Recipe{}
+	mi := &file_recipes_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Recipe) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Recipe) ProtoMessage() {}
+
+func (x *Recipe) ProtoReflect() protoreflect.Message {
+	mi := &file_recipes_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Recipe.ProtoReflect.Descriptor instead.
+func (*Recipe) Descriptor() ([]byte, []int) {
+	return file_recipes_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Recipe) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Recipe) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Recipe) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *Recipe) GetIngredients() []string {
+	if x != nil {
+		return x.Ingredients
+	}
+	return nil
+}
+
+func (x *Recipe) GetInstructions() []string {
+	if x != nil {
+		return x.Instructions
+	}
+	return nil
+}
+
+func (x *Recipe) GetNutrition() *Nutrition {
+	if x != nil {
+		return x.Nutrition
+	}
+	return nil
+}
+
+func (x *Recipe) GetImageUrl() string {
+	if x != nil {
+		return x.ImageUrl
+	}
+	return ""
+}
+
+func (x *Recipe) GetPublishedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.PublishedAt
+	}
+	return nil
+}
+
+func (x *Recipe) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type ListRecipesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRecipesRequest) Reset() {
+	*x = ListRecipesRequest{}
+	mi := &file_recipes_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRecipesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRecipesRequest) ProtoMessage() {}
+
+func (x *ListRecipesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_recipes_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRecipesRequest.ProtoReflect.Descriptor instead.
+func (*ListRecipesRequest) Descriptor() ([]byte, []int) {
+	return file_recipes_proto_rawDescGZIP(), []int{2}
+}
+
+type ListRecipesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Recipes       []*Recipe              `protobuf:"bytes,1,rep,name=recipes,proto3" json:"recipes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRecipesResponse) Reset() {
+	*x = ListRecipesResponse{}
+	mi := &file_recipes_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRecipesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRecipesResponse) ProtoMessage() {}
+
+func (x *ListRecipesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_recipes_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRecipesResponse.ProtoReflect.Descriptor instead.
+func (*ListRecipesResponse) Descriptor() ([]byte, []int) {
+	return file_recipes_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListRecipesResponse) GetRecipes() []*Recipe {
+	if x != nil {
+		return x.Recipes
+	}
+	return nil
+}
+
+type GetRecipeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRecipeRequest) Reset() {
+	*x = GetRecipeRequest{}
+	mi := &file_recipes_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRecipeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRecipeRequest) ProtoMessage() {}
+
+func (x *GetRecipeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_recipes_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRecipeRequest.ProtoReflect.Descriptor instead.
+func (*GetRecipeRequest) Descriptor() ([]byte, []int) {
+	return file_recipes_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetRecipeRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type CreateRecipeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Recipe        *Recipe                `protobuf:"bytes,1,opt,name=recipe,proto3" json:"recipe,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateRecipeRequest) Reset() {
+	*x = CreateRecipeRequest{}
+	mi := &file_recipes_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateRecipeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateRecipeRequest) ProtoMessage() {}
+
+func (x *CreateRecipeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_recipes_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateRecipeRequest.ProtoReflect.Descriptor instead.
+func (*CreateRecipeRequest) Descriptor() ([]byte, []int) {
+	return file_recipes_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *CreateRecipeRequest) GetRecipe() *Recipe {
+	if x != nil {
+		return x.Recipe
+	}
+	return nil
+}
+
+type UpdateRecipeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Recipe        *Recipe                `protobuf:"bytes,2,opt,name=recipe,proto3" json:"recipe,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateRecipeRequest) Reset() {
+	*x = UpdateRecipeRequest{}
+	mi := &file_recipes_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateRecipeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateRecipeRequest) ProtoMessage() {}
+
+func (x *UpdateRecipeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_recipes_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateRecipeRequest.ProtoReflect.Descriptor instead.
+func (*UpdateRecipeRequest) Descriptor() ([]byte, []int) {
+	return file_recipes_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *UpdateRecipeRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *UpdateRecipeRequest) GetRecipe() *Recipe {
+	if x != nil {
+		return x.Recipe
+	}
+	return nil
+}
+
+type DeleteRecipeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteRecipeRequest) Reset() {
+	*x = DeleteRecipeRequest{}
+	mi := &file_recipes_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteRecipeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteRecipeRequest) ProtoMessage() {}
+
+func (x *DeleteRecipeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_recipes_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteRecipeRequest.ProtoReflect.Descriptor instead.
+func (*DeleteRecipeRequest) Descriptor() ([]byte, []int) {
+	return file_recipes_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *DeleteRecipeRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type DeleteRecipeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteRecipeResponse) Reset() {
+	*x = DeleteRecipeResponse{}
+	mi := &file_recipes_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteRecipeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteRecipeResponse) ProtoMessage() {}
+
+func (x *DeleteRecipeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_recipes_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteRecipeResponse.ProtoReflect.Descriptor instead.
+func (*DeleteRecipeResponse) Descriptor() ([]byte, []int) {
+	return file_recipes_proto_rawDescGZIP(), []int{8}
+}
+
+type SearchRecipesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Tag           string                 `protobuf:"bytes,1,opt,name=tag,proto3" json:"tag,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchRecipesRequest) Reset() {
+	*x = SearchRecipesRequest{}
+	mi := &file_recipes_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchRecipesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchRecipesRequest) ProtoMessage() {}
+
+func (x *SearchRecipesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_recipes_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchRecipesRequest.ProtoReflect.Descriptor instead.
+func (*SearchRecipesRequest) Descriptor() ([]byte, []int) {
+	return file_recipes_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *SearchRecipesRequest) GetTag() string {
+	if x != nil {
+		return x.Tag
+	}
+	return ""
+}
+
+type SearchRecipesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Recipes       []*Recipe              `protobuf:"bytes,1,rep,name=recipes,proto3" json:"recipes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchRecipesResponse) Reset() {
+	*x = SearchRecipesResponse{}
+	mi := &file_recipes_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchRecipesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchRecipesResponse) ProtoMessage() {}
+
+func (x *SearchRecipesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_recipes_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchRecipesResponse.ProtoReflect.Descriptor instead.
+func (*SearchRecipesResponse) Descriptor() ([]byte, []int) {
+	return file_recipes_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *SearchRecipesResponse) GetRecipes() []*Recipe {
+	if x != nil {
+		return x.Recipes
+	}
+	return nil
+}
+
+var File_recipes_proto protoreflect.FileDescriptor
+
+const file_recipes_proto_rawDesc = "" +
+	"\n" +
+	"\rrecipes.proto\x12\n" +
+	"recipes.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"i\n" +
+	"\tNutrition\x12\x1a\n" +
+	"\bcalories\x18\x01 \x01(\x01R\bcalories\x12\x18\n" +
+	"\aprotein\x18\x02 \x01(\x01R\aprotein\x12\x10\n" +
+	"\x03fat\x18\x03 \x01(\x01R\x03fat\x12\x14\n" +
+	"\x05carbs\x18\x04 \x01(\x01R\x05carbs\"\xb1\x02\n" +
+	"\x06Recipe\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x12\n" +
+	"\x04tags\x18\x03 \x03(\tR\x04tags\x12 \n" +
+	"\vingredients\x18\x04 \x03(\tR\vingredients\x12\"\n" +
+	"\finstructions\x18\x05 \x03(\tR\finstructions\x123\n" +
+	"\tnutrition\x18\x06 \x01(\v2\x15.recipes.v1.NutritionR\tnutrition\x12\x1b\n" +
+	"\timage_url\x18\a \x01(\tR\bimageUrl\x12=\n" +
+	"\fpublished_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\vpublishedAt\x12\x18\n" +
+	"\aversion\x18\t \x01(\x03R\aversion\"\x14\n" +
+	"\x12ListRecipesRequest\"C\n" +
+	"\x13ListRecipesResponse\x12,\n" +
+	"\arecipes\x18\x01 \x03(\v2\x12.recipes.v1.RecipeR\arecipes\"\"\n" +
+	"\x10GetRecipeRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"A\n" +
+	"\x13CreateRecipeRequest\x12*\n" +
+	"\x06recipe\x18\x01 \x01(\v2\x12.recipes.v1.RecipeR\x06recipe\"Q\n" +
+	"\x13UpdateRecipeRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12*\n" +
+	"\x06recipe\x18\x02 \x01(\v2\x12.recipes.v1.RecipeR\x06recipe\"%\n" +
+	"\x13DeleteRecipeRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\x16\n" +
+	"\x14DeleteRecipeResponse\"(\n" +
+	"\x14SearchRecipesRequest\x12\x10\n" +
+	"\x03tag\x18\x01 \x01(\tR\x03tag\"E\n" +
+	"\x15SearchRecipesResponse\x12,\n" +
+	"\arecipes\x18\x01 \x03(\v2\x12.recipes.v1.RecipeR\arecipes2\xab\x03\n" +
+	"\rRecipeService\x12G\n" +
+	"\x04List\x12\x1e.recipes.v1.ListRecipesRequest\x1a\x1f.recipes.v1.ListRecipesResponse\x127\n" +
+	"\x03Get\x12\x1c.recipes.v1.GetRecipeRequest\x1a\x12.recipes.v1.Recipe\x12=\n" +
+	"\x06Create\x12\x1f.recipes.v1.CreateRecipeRequest\x1a\x12.recipes.v1.Recipe\x12=\n" +
+	"\x06Update\x12\x1f.recipes.v1.UpdateRecipeRequest\x1a\x12.recipes.v1.Recipe\x12K\n" +
+	"\x06Delete\x12\x1f.recipes.v1.DeleteRecipeRequest\x1a .recipes.v1.DeleteRecipeResponse\x12M\n" +
+	"\x06Search\x12 .recipes.v1.SearchRecipesRequest\x1a!.recipes.v1.SearchRecipesResponseB\x17Z\x15recipes-api/recipespbb\x06proto3"
+
+var (
+	file_recipes_proto_rawDescOnce sync.Once
+	file_recipes_proto_rawDescData []byte
+)
+
+func file_recipes_proto_rawDescGZIP() []byte {
+	file_recipes_proto_rawDescOnce.Do(func() {
+		file_recipes_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_recipes_proto_rawDesc), len(file_recipes_proto_rawDesc)))
+	})
+	return file_recipes_proto_rawDescData
+}
+
+var file_recipes_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_recipes_proto_goTypes = []any{
+	(*Nutrition)(nil),             // 0: recipes.v1.Nutrition
+	(*Recipe)(nil),                // 1: recipes.v1.Recipe
+	(*ListRecipesRequest)(nil),    // 2: recipes.v1.ListRecipesRequest
+	(*ListRecipesResponse)(nil),   // 3: recipes.v1.ListRecipesResponse
+	(*GetRecipeRequest)(nil),      // 4: recipes.v1.GetRecipeRequest
+	(*CreateRecipeRequest)(nil),   // 5: recipes.v1.CreateRecipeRequest
+	(*UpdateRecipeRequest)(nil),   // 6: recipes.v1.UpdateRecipeRequest
+	(*DeleteRecipeRequest)(nil),   // 7: recipes.v1.DeleteRecipeRequest
+	(*DeleteRecipeResponse)(nil),  // 8: recipes.v1.DeleteRecipeResponse
+	(*SearchRecipesRequest)(nil),  // 9: recipes.v1.SearchRecipesRequest
+	(*SearchRecipesResponse)(nil), // 10: recipes.v1.SearchRecipesResponse
+	(*timestamppb.Timestamp)(nil), // 11: google.protobuf.Timestamp
+}
+var file_recipes_proto_depIdxs = []int32{
+	0,  // 0: recipes.v1.Recipe.nutrition:type_name -> recipes.v1.Nutrition
+	11, // 1: recipes.v1.Recipe.published_at:type_name -> google.protobuf.Timestamp
+	1,  // 2: recipes.v1.ListRecipesResponse.recipes:type_name -> recipes.v1.Recipe
+	1,  // 3: recipes.v1.CreateRecipeRequest.recipe:type_name -> recipes.v1.Recipe
+	1,  // 4: recipes.v1.UpdateRecipeRequest.recipe:type_name -> recipes.v1.Recipe
+	1,  // 5: recipes.v1.SearchRecipesResponse.recipes:type_name -> recipes.v1.Recipe
+	2,  // 6: recipes.v1.RecipeService.List:input_type -> recipes.v1.ListRecipesRequest
+	4,  // 7: recipes.v1.RecipeService.Get:input_type -> recipes.v1.GetRecipeRequest
+	5,  // 8: recipes.v1.RecipeService.Create:input_type -> recipes.v1.CreateRecipeRequest
+	6,  // 9: recipes.v1.RecipeService.Update:input_type -> recipes.v1.UpdateRecipeRequest
+	7,  // 10: recipes.v1.RecipeService.Delete:input_type -> recipes.v1.DeleteRecipeRequest
+	9,  // 11: recipes.v1.RecipeService.Search:input_type -> recipes.v1.SearchRecipesRequest
+	3,  // 12: recipes.v1.RecipeService.List:output_type -> recipes.v1.ListRecipesResponse
+	1,  // 13: recipes.v1.RecipeService.Get:output_type -> recipes.v1.Recipe
+	1,  // 14: recipes.v1.RecipeService.Create:output_type -> recipes.v1.Recipe
+	1,  // 15: recipes.v1.RecipeService.Update:output_type -> recipes.v1.Recipe
+	8,  // 16: recipes.v1.RecipeService.Delete:output_type -> recipes.v1.DeleteRecipeResponse
+	10, // 17: recipes.v1.RecipeService.Search:output_type -> recipes.v1.SearchRecipesResponse
+	12, // [12:18] is the sub-list for method output_type
+	6,  // [6:12] is the sub-list for method input_type
+	6,  // [6:6] is the sub-list for extension type_name
+	6,  // [6:6] is the sub-list for extension extendee
+	0,  // [0:6] is the sub-list for field type_name
+}
+
+func init() { file_recipes_proto_init() }
+func file_recipes_proto_init() {
+	if File_recipes_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_recipes_proto_rawDesc), len(file_recipes_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   11,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_recipes_proto_goTypes,
+		DependencyIndexes: file_recipes_proto_depIdxs,
+		MessageInfos:      file_recipes_proto_msgTypes,
+	}.Build()
+	File_recipes_proto = out.File
+	file_recipes_proto_goTypes = nil
+	file_recipes_proto_depIdxs = nil
+}
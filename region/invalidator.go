@@ -0,0 +1,80 @@
+// Package region keeps a deployment outside the primary region in step
+// with the writes made in the primary one.
+package region
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"recipes-api/cache"
+	"recipes-api/models"
+	"recipes-api/service"
+
+	"github.com/go-redis/redis"
+	"gorm.io/gorm"
+)
+
+// lag is how far back each poll looks before the previous one, as a
+// replica can make an event visible after later ones.
+const lag = time.Minute
+
+// Invalidator drops the recipes cached in the local Redis once they are
+// changed in the primary region. Writes there only clear the primary's
+// Redis, which needs nothing more as every write path clears what it
+// changed; the changes reach other regions through the events outbox,
+// which it follows on a local read replica.
+type Invalidator struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+
+	since time.Time
+	// seen holds the events handled within lag of since, so events read
+	// again aren't invalidated twice.
+	seen map[string]time.Time
+}
+
+func NewInvalidator(db *gorm.DB, redisClient *redis.Client) *Invalidator {
+	return &Invalidator{db: db, redisClient: redisClient, since: time.Now().UTC(), seen: map[string]time.Time{}}
+}
+
+// Run polls the outbox every interval. It never returns.
+func (i *Invalidator) Run(interval time.Duration) {
+	for range time.Tick(interval) {
+		if err := i.Poll(context.Background()); err != nil {
+			slog.Error("Error following changes from the primary region", "error", err)
+		}
+	}
+}
+
+// Poll invalidates the recipes of the events recorded since the last poll.
+func (i *Invalidator) Poll(ctx context.Context) error {
+	var entries []models.OutboxEvent
+	err := service.ReadReplica(i.db.WithContext(ctx)).Select("id", "recipe_id", "occurred_at").
+		Where("occurred_at >= ?", i.since.Add(-lag)).Order("occurred_at").Find(&entries).Error
+	if err != nil {
+		return err
+	}
+
+	var ids []string
+	for _, entry := range entries {
+		if _, ok := i.seen[entry.ID]; ok {
+			continue
+		}
+		i.seen[entry.ID] = entry.OccurredAt
+		ids = append(ids, entry.RecipeID)
+		if entry.OccurredAt.After(i.since) {
+			i.since = entry.OccurredAt
+		}
+	}
+	if len(ids) > 0 {
+		cache.InvalidateRecipes(ctx, i.redisClient, ids...)
+	}
+
+	for id, at := range i.seen {
+		if at.Before(i.since.Add(-lag)) {
+			delete(i.seen, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+// Package related finds the recipes most like a recipe, for "You might
+// also like" sections.
+package related
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"recipes-api/cache"
+	"recipes-api/ingredients"
+	"recipes-api/models"
+	"recipes-api/service"
+	"recipes-api/shopping"
+	"recipes-api/tracing"
+
+	"github.com/go-redis/redis"
+	"gorm.io/gorm"
+)
+
+// MaxRecipes is the most related recipes returned for a recipe.
+const MaxRecipes = 20
+
+// candidates is how many of the recipes sharing the most tags are scored
+// on their ingredients too.
+const candidates = 100
+
+// Recipe is a related recipe with what it has in common with the recipe
+// it is related to.
+type Recipe struct {
+	models.RecipeSummary
+	SharedTags        int `json:"sharedTags"`
+	SharedIngredients int `json:"sharedIngredients"`
+}
+
+type Service struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+	recipes     *service.RecipeService
+	ttl         time.Duration
+}
+
+// NewService creates the service. Related recipes are cached for ttl, and
+// dropped sooner when the recipe changes.
+func NewService(db *gorm.DB, redisClient *redis.Client, recipeService *service.RecipeService, ttl time.Duration) *Service {
+	return &Service{db: db, redisClient: redisClient, recipes: recipeService, ttl: ttl}
+}
+
+// Related returns up to limit published recipes related to recipe, most
+// related first. The published recipes sharing the most tags with it are
+// scored by the tags and ingredients they share, a shared tag counting
+// twice as much as a shared ingredient.
+func (s *Service) Related(ctx context.Context, recipe models.Recipe, limit int) ([]Recipe, error) {
+	key := cache.RelatedKey(recipe.ID)
+	cached, err := tracing.Redis(ctx, s.redisClient).Get(key).Result()
+	if err == nil {
+		var related []Recipe
+		if json.Unmarshal([]byte(cached), &related) == nil {
+			return related[:min(limit, len(related))], nil
+		}
+	}
+
+	related, err := s.score(ctx, recipe)
+	if err != nil {
+		return nil, err
+	}
+
+	data, _ := json.Marshal(related)
+	tracing.Redis(ctx, s.redisClient).Set(key, data, s.ttl)
+	return related[:min(limit, len(related))], nil
+}
+
+// score finds the MaxRecipes recipes most related to recipe.
+func (s *Service) score(ctx context.Context, recipe models.Recipe) ([]Recipe, error) {
+	var shared []struct {
+		RecipeID string
+		Shared   int
+	}
+	err := service.Published(service.ReadReplica(s.db.WithContext(ctx))).Table("recipe_tags").
+		Joins("JOIN recipes_list ON recipes_list.id = recipe_tags.recipe_id").
+		Where("recipe_tags.tag_id IN (SELECT tag_id FROM recipe_tags WHERE recipe_id = ?) AND recipe_tags.recipe_id <> ?", recipe.ID, recipe.ID).
+		Select("recipe_tags.recipe_id, COUNT(*) AS shared").Group("recipe_tags.recipe_id").
+		Order("shared DESC, recipe_tags.recipe_id").Limit(candidates).Scan(&shared).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(shared) == 0 {
+		return []Recipe{}, nil
+	}
+
+	ids := make([]string, len(shared))
+	for i, row := range shared {
+		ids[i] = row.RecipeID
+	}
+	loaded, err := s.recipes.GetMany(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]models.Recipe, len(loaded))
+	for _, candidate := range loaded {
+		byID[candidate.ID] = candidate
+	}
+
+	items := itemsOf(recipe)
+	related := make([]Recipe, 0, len(shared))
+	for _, row := range shared {
+		candidate, ok := byID[row.RecipeID]
+		if !ok {
+			continue
+		}
+		common := 0
+		for item := range itemsOf(candidate) {
+			if items[item] {
+				common++
+			}
+		}
+		related = append(related, Recipe{RecipeSummary: models.NewRecipeSummary(candidate), SharedTags: row.Shared, SharedIngredients: common})
+	}
+	slices.SortStableFunc(related, func(a, b Recipe) int {
+		return cmp.Compare(2*b.SharedTags+b.SharedIngredients, 2*a.SharedTags+a.SharedIngredients)
+	})
+	return related[:min(MaxRecipes, len(related))], nil
+}
+
+// itemsOf returns the normalized items of a recipe's ingredients, so "2
+// tomatoes" and "1 tomato, diced" are the same ingredient.
+func itemsOf(recipe models.Recipe) map[string]bool {
+	items := map[string]bool{}
+	for _, line := range recipe.Ingredients {
+		if item := ingredients.Parse(line).Item; item != "" {
+			items[shopping.Normalize(item)] = true
+		}
+	}
+	return items
+}
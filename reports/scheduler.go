@@ -0,0 +1,123 @@
+package reports
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"recipes-api/email"
+	"recipes-api/models"
+	"recipes-api/outbound"
+	"recipes-api/sandbox"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Scheduler sends the report for each completed week (Monday to Monday,
+// UTC) once, to the configured email recipients and webhook. With several
+// instances running, whichever claims the week first sends it.
+type Scheduler struct {
+	db         *gorm.DB
+	sender     *email.Sender
+	client     *outbound.Client
+	recorder   *sandbox.Recorder
+	recipients []string
+	webhookURL string
+}
+
+func NewScheduler(db *gorm.DB, sender *email.Sender, policy outbound.Policy, recorder *sandbox.Recorder, recipients []string, webhookURL string) *Scheduler {
+	return &Scheduler{
+		db:         db,
+		sender:     sender,
+		client:     outbound.NewGuarded(policy, 30*time.Second, 2),
+		recorder:   recorder,
+		recipients: recipients,
+		webhookURL: webhookURL,
+	}
+}
+
+// Run checks at the given interval whether a report is due. It never returns.
+func (s *Scheduler) Run(interval time.Duration) {
+	for {
+		if err := s.sendDue(context.Background()); err != nil {
+			slog.Error("Error sending weekly report", "error", err)
+		}
+		time.Sleep(interval)
+	}
+}
+
+func (s *Scheduler) sendDue(ctx context.Context) error {
+	to := weekStart(time.Now())
+	from := to.AddDate(0, 0, -7)
+
+	claim := s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&models.ReportRun{PeriodStart: from})
+	if claim.Error != nil {
+		return claim.Error
+	}
+	if claim.RowsAffected == 0 {
+		return nil
+	}
+
+	report, err := Build(ctx, s.db, from, to)
+	if err != nil {
+		return err
+	}
+
+	subject := fmt.Sprintf("Weekly recipes report, week of %s", from.Format(time.DateOnly))
+	for _, to := range s.recipients {
+		if err := s.sender.Send(to, subject, report.Text()); err != nil {
+			slog.Error("Error emailing weekly report", "to", to, "error", err)
+		}
+	}
+	if s.webhookURL != "" {
+		if err := s.post(ctx, report); err != nil {
+			slog.Error("Error posting weekly report", "error", err)
+		}
+	}
+	return nil
+}
+
+func (s *Scheduler) post(ctx context.Context, report Report) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	if s.recorder != nil {
+		s.recorder.Capture(sandbox.Message{
+			Kind:    sandbox.KindWebhook,
+			Target:  s.webhookURL,
+			Headers: map[string]string{"Content-Type": "application/json"},
+			Body:    string(body),
+		})
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+// weekStart returns midnight UTC of the Monday starting t's week.
+func weekStart(t time.Time) time.Time {
+	day := t.UTC().Truncate(24 * time.Hour)
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
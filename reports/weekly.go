@@ -0,0 +1,157 @@
+// Package reports assembles the weekly admin report from the analytics
+// tables and delivers it by email or webhook.
+package reports
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"recipes-api/models"
+
+	"gorm.io/gorm"
+)
+
+const topN = 10
+
+type Report struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+
+	NewRecipeCount int64         `json:"newRecipeCount"`
+	NewRecipes     []RecipeEntry `json:"newRecipes"`
+	SearchTrends   []SearchTrend `json:"searchTrends"`
+
+	Requests  int64   `json:"requests"`
+	Errors    int64   `json:"errors"`
+	ErrorRate float64 `json:"errorRate"`
+	// CacheHitRate is nil when there were no cache lookups.
+	CacheHitRate     *float64        `json:"cacheHitRate"`
+	SlowestEndpoints []EndpointStats `json:"slowestEndpoints"`
+	ErrorEndpoints   []EndpointStats `json:"errorEndpoints"`
+}
+
+type RecipeEntry struct {
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	PublishedAt time.Time `json:"publishedAt"`
+}
+
+// SearchTrend compares how often a tag was searched with the week before.
+type SearchTrend struct {
+	Query         string `json:"query"`
+	Count         int64  `json:"count"`
+	PreviousCount int64  `json:"previousCount"`
+}
+
+type EndpointStats struct {
+	Route    string  `json:"route"`
+	Requests int64   `json:"requests"`
+	Errors   int64   `json:"errors"`
+	AvgMS    float64 `json:"avgMs"`
+	MaxMS    int64   `json:"maxMs"`
+}
+
+// Build assembles the report for days in [from, to). Both are truncated to
+// whole UTC days.
+func Build(ctx context.Context, db *gorm.DB, from, to time.Time) (Report, error) {
+	from, to = from.UTC().Truncate(24*time.Hour), to.UTC().Truncate(24*time.Hour)
+	report := Report{From: from, To: to}
+	db = db.WithContext(ctx)
+
+	newRecipes := db.Model(&models.Recipe{}).Where("published_at >= ? AND published_at < ?", from, to).Session(&gorm.Session{})
+	if err := newRecipes.Count(&report.NewRecipeCount).Error; err != nil {
+		return report, err
+	}
+	err := newRecipes.Select("id", "name", "published_at").Order("published_at DESC").Limit(topN).
+		Scan(&report.NewRecipes).Error
+	if err != nil {
+		return report, err
+	}
+
+	previousFrom := from.Add(-to.Sub(from))
+	err = db.Model(&models.SearchStat{}).
+		Select(`query,
+			SUM(CASE WHEN day >= ? THEN count ELSE 0 END) AS count,
+			SUM(CASE WHEN day < ? THEN count ELSE 0 END) AS previous_count`, from, from).
+		Where("day >= ? AND day < ?", previousFrom, to).
+		Group("query").Having("SUM(CASE WHEN day >= ? THEN count ELSE 0 END) > 0", from).
+		Order("count DESC").Limit(topN).Scan(&report.SearchTrends).Error
+	if err != nil {
+		return report, err
+	}
+
+	endpoints := db.Model(&models.RequestStat{}).
+		Select("route, SUM(requests) AS requests, SUM(errors) AS errors, SUM(total_ms)::float / SUM(requests) AS avg_ms, MAX(max_ms) AS max_ms").
+		Where("day >= ? AND day < ?", from, to).Group("route").Session(&gorm.Session{})
+	if err := endpoints.Order("avg_ms DESC").Limit(5).Scan(&report.SlowestEndpoints).Error; err != nil {
+		return report, err
+	}
+	if err := endpoints.Having("SUM(errors) > 0").Order("errors DESC").Limit(5).Scan(&report.ErrorEndpoints).Error; err != nil {
+		return report, err
+	}
+
+	var totals struct{ Requests, Errors int64 }
+	err = db.Model(&models.RequestStat{}).Select("COALESCE(SUM(requests), 0) AS requests, COALESCE(SUM(errors), 0) AS errors").
+		Where("day >= ? AND day < ?", from, to).Scan(&totals).Error
+	if err != nil {
+		return report, err
+	}
+	report.Requests, report.Errors = totals.Requests, totals.Errors
+	if totals.Requests > 0 {
+		report.ErrorRate = float64(totals.Errors) / float64(totals.Requests)
+	}
+
+	var cache struct{ Hits, Misses int64 }
+	err = db.Model(&models.CacheStat{}).Select("COALESCE(SUM(hits), 0) AS hits, COALESCE(SUM(misses), 0) AS misses").
+		Where("day >= ? AND day < ?", from, to).Scan(&cache).Error
+	if err != nil {
+		return report, err
+	}
+	if lookups := cache.Hits + cache.Misses; lookups > 0 {
+		rate := float64(cache.Hits) / float64(lookups)
+		report.CacheHitRate = &rate
+	}
+
+	return report, nil
+}
+
+// Text renders the report as a plain-text email body.
+func (r Report) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Recipes API report for %s to %s\n\n", r.From.Format(time.DateOnly), r.To.Add(-time.Nanosecond).Format(time.DateOnly))
+
+	fmt.Fprintf(&b, "New recipes: %d\n", r.NewRecipeCount)
+	for _, recipe := range r.NewRecipes {
+		fmt.Fprintf(&b, "  %s (%s)\n", recipe.Name, recipe.ID)
+	}
+
+	b.WriteString("\nTop searched tags:\n")
+	if len(r.SearchTrends) == 0 {
+		b.WriteString("  none\n")
+	}
+	for _, t := range r.SearchTrends {
+		fmt.Fprintf(&b, "  %-24s %6d (previous week %d)\n", t.Query, t.Count, t.PreviousCount)
+	}
+
+	fmt.Fprintf(&b, "\nRequests: %d, server errors: %d (%.2f%%)\n", r.Requests, r.Errors, r.ErrorRate*100)
+	if r.CacheHitRate != nil {
+		fmt.Fprintf(&b, "Cache hit rate: %.1f%%\n", *r.CacheHitRate*100)
+	} else {
+		b.WriteString("Cache hit rate: no lookups\n")
+	}
+
+	b.WriteString("\nSlowest endpoints (average):\n")
+	for _, e := range r.SlowestEndpoints {
+		fmt.Fprintf(&b, "  %-40s %8.1fms avg %6dms max %8d requests\n", e.Route, e.AvgMS, e.MaxMS, e.Requests)
+	}
+	if len(r.ErrorEndpoints) > 0 {
+		b.WriteString("\nEndpoints with server errors:\n")
+		for _, e := range r.ErrorEndpoints {
+			fmt.Fprintf(&b, "  %-40s %6d of %d requests\n", e.Route, e.Errors, e.Requests)
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,30 @@
+// Package reqid carries the ID and trace context of the originating request
+// through contexts, so background work and outgoing calls can be correlated
+// with it.
+package reqid
+
+import "context"
+
+const (
+	Header            = "X-Request-ID"
+	TraceparentHeader = "traceparent"
+)
+
+// IDs identify the request that caused some piece of work.
+type IDs struct {
+	RequestID string
+	// Traceparent is the W3C trace context of the request, if any.
+	Traceparent string
+}
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, ids IDs) context.Context {
+	return context.WithValue(ctx, contextKey{}, ids)
+}
+
+// FromContext returns the IDs stored in ctx, or zero IDs.
+func FromContext(ctx context.Context) IDs {
+	ids, _ := ctx.Value(contextKey{}).(IDs)
+	return ids
+}
@@ -0,0 +1,313 @@
+// Package retag adds a tag to, or removes it from, every recipe matching a
+// search, in the background. A revision is saved before each recipe is
+// changed, so a job can be undone.
+package retag
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"recipes-api/events"
+	"recipes-api/models"
+	"recipes-api/search"
+	"recipes-api/service"
+
+	"github.com/go-redis/redis"
+	"github.com/rs/xid"
+	"gorm.io/gorm"
+)
+
+// Actions of a job.
+const (
+	Add    = "add"
+	Remove = "remove"
+)
+
+// Statuses of a job.
+const (
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+	StatusUndoing = "undoing"
+	StatusUndone  = "undone"
+)
+
+var (
+	ErrNotFound    = errors.New("retag job not found")
+	ErrNotUndoable = errors.New("only finished jobs can be undone")
+)
+
+// progressEvery is how many recipes are processed between saves of the
+// job's progress.
+const progressEvery = 100
+
+type Runner struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+	events      *events.Bus
+}
+
+func NewRunner(db *gorm.DB, redisClient *redis.Client, bus *events.Bus) *Runner {
+	return &Runner{db: db, redisClient: redisClient, events: bus}
+}
+
+// Preview counts the recipes the job's search matches and those of them
+// it would change, without changing anything.
+func (r *Runner) Preview(ctx context.Context, job models.RetagJob) (matched, changed int, err error) {
+	recipes, err := r.matching(ctx, job)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, recipe := range recipes {
+		if _, ok := apply(job.Action, job.Tag, recipe.Tags); ok {
+			changed++
+		}
+	}
+	return len(recipes), changed, nil
+}
+
+// Start records the job and runs it in the background.
+func (r *Runner) Start(ctx context.Context, job models.RetagJob) (models.RetagJob, error) {
+	job.ID = xid.New().String()
+	job.Status = StatusRunning
+	job.Revisions = map[string]int{}
+	job.CreatedAt = time.Now().UTC()
+	if err := r.db.WithContext(ctx).Create(&job).Error; err != nil {
+		return models.RetagJob{}, err
+	}
+
+	go r.run(context.WithoutCancel(ctx), job)
+	return job, nil
+}
+
+func (r *Runner) Get(ctx context.Context, id string) (models.RetagJob, error) {
+	var job models.RetagJob
+	err := r.db.WithContext(ctx).Where("id = ?", id).Take(&job).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return job, ErrNotFound
+	}
+	return job, err
+}
+
+// Undo puts back, in the background, the tags of the recipes a finished
+// or failed job changed. Recipes whose tags were edited since are left
+// alone.
+func (r *Runner) Undo(ctx context.Context, id string) (models.RetagJob, error) {
+	job, err := r.Get(ctx, id)
+	if err != nil {
+		return job, err
+	}
+	if job.Status != StatusDone && job.Status != StatusFailed {
+		return job, ErrNotUndoable
+	}
+
+	job.Status = StatusUndoing
+	job.Skipped = 0
+	job.Error = ""
+	job.FinishedAt = nil
+	if err := r.db.WithContext(ctx).Save(&job).Error; err != nil {
+		return job, err
+	}
+
+	go r.undo(context.WithoutCancel(ctx), job)
+	return job, nil
+}
+
+func (r *Runner) run(ctx context.Context, job models.RetagJob) {
+	recipes, err := r.matching(ctx, job)
+	if err == nil {
+		job.Matched = len(recipes)
+		for i, recipe := range recipes {
+			if err = r.retag(ctx, &job, recipe.ID); err != nil {
+				break
+			}
+			r.progress(ctx, &job, i)
+		}
+	}
+	r.finish(ctx, &job, StatusDone, err)
+}
+
+func (r *Runner) undo(ctx context.Context, job models.RetagJob) {
+	var err error
+	i := 0
+	for id, revision := range job.Revisions {
+		if err = r.restore(ctx, &job, id, revision); err != nil {
+			break
+		}
+		r.progress(ctx, &job, i)
+		i++
+	}
+	r.finish(ctx, &job, StatusUndone, err)
+}
+
+// matching returns the recipes, drafts included, the job's search matches,
+// with the fields matching needs.
+func (r *Runner) matching(ctx context.Context, job models.RetagJob) ([]models.Recipe, error) {
+	q := search.Query{Text: job.Q, Tags: job.Tags, MaxTotalTime: job.MaxTotalTime}
+
+	var matches, batch []models.Recipe
+	err := r.db.WithContext(ctx).Select("id", "name", "tags", "ingredients", "total_time_minutes").
+		FindInBatches(&batch, 500, func(_ *gorm.DB, _ int) error {
+			for _, recipe := range batch {
+				if search.Matches(recipe, q) {
+					matches = append(matches, recipe)
+				}
+			}
+			return nil
+		}).Error
+	return matches, err
+}
+
+// retag changes the tags of one recipe, unless they already are as the
+// job wants them.
+func (r *Runner) retag(ctx context.Context, job *models.RetagJob, id string) error {
+	var before, recipe models.Recipe
+	changed := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ?", id).Take(&recipe).Error; err != nil {
+			// deleted since the search
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil
+			}
+			return err
+		}
+		tags, ok := apply(job.Action, job.Tag, recipe.Tags)
+		if !ok {
+			return nil
+		}
+
+		revision, err := r.saveRevision(tx, &recipe)
+		if err != nil {
+			return err
+		}
+		before = recipe
+		if err := r.setTags(tx, before, &recipe, tags); err != nil {
+			return err
+		}
+		job.Revisions[id] = revision
+		changed = true
+		return nil
+	})
+	if err != nil || !changed {
+		return err
+	}
+
+	job.Changed++
+	r.publish(ctx, before, recipe)
+	return nil
+}
+
+// restore puts back the tags a recipe had before the job.
+func (r *Runner) restore(ctx context.Context, job *models.RetagJob, id string, revision int) error {
+	var before, recipe models.Recipe
+	changed := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var saved models.RecipeRevision
+		if err := tx.Where("recipe_id = ? AND revision = ?", id, revision).Take(&saved).Error; err != nil {
+			return err
+		}
+		err := tx.Where("id = ?", id).Take(&recipe).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			job.Skipped++
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if expected, _ := apply(job.Action, job.Tag, saved.Snapshot.Tags); !slices.Equal(recipe.Tags, expected) {
+			job.Skipped++
+			return nil
+		}
+
+		if _, err := r.saveRevision(tx, &recipe); err != nil {
+			return err
+		}
+		before = recipe
+		if err := r.setTags(tx, before, &recipe, saved.Snapshot.Tags); err != nil {
+			return err
+		}
+		changed = true
+		return nil
+	})
+	if err != nil || !changed {
+		return err
+	}
+
+	r.publish(ctx, before, recipe)
+	return nil
+}
+
+// saveRevision snapshots the recipe, with its instructions, and returns
+// the number of the revision.
+func (r *Runner) saveRevision(tx *gorm.DB, recipe *models.Recipe) (int, error) {
+	if err := service.LoadInstructions(tx, recipe); err != nil {
+		return 0, err
+	}
+	if err := service.SaveRevision(tx, *recipe); err != nil {
+		return 0, err
+	}
+	var revision int
+	err := tx.Model(&models.RecipeRevision{}).Where("recipe_id = ?", recipe.ID).Select("MAX(revision)").Scan(&revision).Error
+	return revision, err
+}
+
+func (r *Runner) setTags(tx *gorm.DB, before models.Recipe, recipe *models.Recipe, tags []string) error {
+	if err := tx.Model(recipe).Select("tags").Updates(models.Recipe{Tags: tags}).Error; err != nil {
+		return err
+	}
+	recipe.Tags = tags
+	if err := service.LinkTags(tx, *recipe); err != nil {
+		return err
+	}
+	return service.StampChanges(tx, before, recipe)
+}
+
+func (r *Runner) publish(ctx context.Context, before, recipe models.Recipe) {
+	service.ClearCache(ctx, r.redisClient, recipe.ID)
+	event := events.NewEvent(events.RecipeUpdated, recipe)
+	event.Changes = events.Diff(before, recipe)
+	r.events.Publish(ctx, event)
+}
+
+// progress saves the job every progressEvery recipes, so it can be
+// followed while it runs.
+func (r *Runner) progress(ctx context.Context, job *models.RetagJob, i int) {
+	if (i+1)%progressEvery != 0 {
+		return
+	}
+	if err := r.db.WithContext(ctx).Save(job).Error; err != nil {
+		slog.ErrorContext(ctx, "Error saving retag job progress", "job_id", job.ID, "error", err)
+	}
+}
+
+func (r *Runner) finish(ctx context.Context, job *models.RetagJob, status string, err error) {
+	now := time.Now().UTC()
+	job.Status = status
+	job.FinishedAt = &now
+	if err != nil {
+		slog.ErrorContext(ctx, "Retag job failed", "job_id", job.ID, "error", err)
+		job.Status = StatusFailed
+		job.Error = err.Error()
+	}
+	if err := r.db.WithContext(ctx).Save(job).Error; err != nil {
+		slog.ErrorContext(ctx, "Error saving retag job", "job_id", job.ID, "error", err)
+	}
+}
+
+// apply returns tags with the action applied, and whether that changed
+// them. Tags compare ignoring case.
+func apply(action, tag string, tags []string) ([]string, bool) {
+	same := func(t string) bool { return strings.EqualFold(t, tag) }
+	has := slices.ContainsFunc(tags, same)
+	switch {
+	case action == Add && !has:
+		return append(slices.Clone(tags), tag), true
+	case action == Remove && has:
+		return slices.DeleteFunc(slices.Clone(tags), same), true
+	}
+	return tags, false
+}
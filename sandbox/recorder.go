@@ -0,0 +1,68 @@
+package sandbox
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rs/xid"
+)
+
+const (
+	KindWebhook = "webhook"
+	KindEmail   = "email"
+)
+
+// Message is an outgoing email or webhook that was captured instead of sent.
+type Message struct {
+	ID         string            `json:"id"`
+	Kind       string            `json:"kind"`
+	Target     string            `json:"target"`
+	Subject    string            `json:"subject,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body"`
+	CapturedAt time.Time         `json:"capturedAt"`
+}
+
+// Recorder keeps the most recent captured messages in memory. In sandbox
+// mode every outgoing delivery goes here instead of over the network; a
+// nil *Recorder means sandbox mode is off.
+type Recorder struct {
+	mu       sync.Mutex
+	messages []Message
+	limit    int
+}
+
+func NewRecorder(limit int) *Recorder {
+	return &Recorder{limit: limit}
+}
+
+func (r *Recorder) Capture(m Message) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m.ID = xid.New().String()
+	m.CapturedAt = time.Now().UTC()
+
+	r.messages = append(r.messages, m)
+	if len(r.messages) > r.limit {
+		r.messages = r.messages[len(r.messages)-r.limit:]
+	}
+}
+
+// List returns captured messages, newest first.
+func (r *Recorder) List() []Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list := make([]Message, len(r.messages))
+	for i, m := range r.messages {
+		list[len(r.messages)-1-i] = m
+	}
+	return list
+}
+
+func (r *Recorder) Clear() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.messages = nil
+}
@@ -0,0 +1,260 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"recipes-api/models"
+)
+
+// document is what is stored per recipe.
+type document struct {
+	Name             string    `json:"name"`
+	Tags             []string  `json:"tags"`
+	Ingredients      []string  `json:"ingredients"`
+	Instructions     []string  `json:"instructions"`
+	TotalTimeMinutes int       `json:"totalTimeMinutes"`
+	PublishedAt      time.Time `json:"publishedAt"`
+	Draft            bool      `json:"draft"`
+}
+
+var mapping = map[string]any{
+	"mappings": map[string]any{
+		"properties": map[string]any{
+			"name":             map[string]any{"type": "text"},
+			"tags":             map[string]any{"type": "keyword", "normalizer": "lowercase"},
+			"ingredients":      map[string]any{"type": "text"},
+			"instructions":     map[string]any{"type": "text"},
+			"totalTimeMinutes": map[string]any{"type": "integer"},
+			"publishedAt":      map[string]any{"type": "date"},
+			"draft":            map[string]any{"type": "boolean"},
+		},
+	},
+	"settings": map[string]any{
+		"analysis": map[string]any{
+			"normalizer": map[string]any{
+				"lowercase": map[string]any{"type": "custom", "filter": []string{"lowercase"}},
+			},
+		},
+	},
+}
+
+// Elasticsearch searches an Elasticsearch or OpenSearch index, which speak
+// the same API, ranking by relevance.
+type Elasticsearch struct {
+	baseURL string
+	index   string
+	client  *http.Client
+}
+
+func NewElasticsearch(baseURL, index string) *Elasticsearch {
+	return &Elasticsearch{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		index:   index,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// EnsureIndex creates the index with its mapping unless it exists.
+func (es *Elasticsearch) EnsureIndex(ctx context.Context) error {
+	err := es.call(ctx, http.MethodHead, es.index, nil, nil)
+	if statusOf(err) != http.StatusNotFound {
+		return err
+	}
+	return es.call(ctx, http.MethodPut, es.index, mapping, nil)
+}
+
+func (es *Elasticsearch) Index(ctx context.Context, recipe models.Recipe) error {
+	return es.call(ctx, http.MethodPut, es.index+"/_doc/"+url.PathEscape(recipe.ID), newDocument(recipe), nil)
+}
+
+// Delete removes a recipe from the index. Recipes that aren't indexed are
+// not an error.
+func (es *Elasticsearch) Delete(ctx context.Context, id string) error {
+	err := es.call(ctx, http.MethodDelete, es.index+"/_doc/"+url.PathEscape(id), nil, nil)
+	if statusOf(err) == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
+
+// IndexBatch indexes many recipes in one bulk request.
+func (es *Elasticsearch) IndexBatch(ctx context.Context, recipes []models.Recipe) error {
+	var body bytes.Buffer
+	enc := json.NewEncoder(&body)
+	for _, recipe := range recipes {
+		enc.Encode(map[string]any{"index": map[string]string{"_index": es.index, "_id": recipe.ID}})
+		enc.Encode(newDocument(recipe))
+	}
+
+	var resp struct {
+		Errors bool `json:"errors"`
+	}
+	if err := es.send(ctx, http.MethodPost, "_bulk", "application/x-ndjson", &body, &resp); err != nil {
+		return err
+	}
+	if resp.Errors {
+		return errors.New("bulk indexing recipes failed")
+	}
+	return nil
+}
+
+func (es *Elasticsearch) Query(ctx context.Context, q Query) (Result, error) {
+	if q.Limit <= 0 {
+		q.Limit = DefaultLimit
+	}
+
+	var must any = map[string]any{"match_all": map[string]any{}}
+	sort := []any{map[string]string{"publishedAt": "desc"}}
+	if q.Text != "" {
+		must = map[string]any{"multi_match": map[string]any{
+			"query":  q.Text,
+			"fields": []string{"name^3", "tags^2", "ingredients", "instructions"},
+		}}
+		sort = append([]any{"_score"}, sort...)
+	}
+
+	// drafts and scheduled recipes are indexed but not found, so the
+	// total and facets only count published ones; documents indexed
+	// before drafts were have no draft field and count as published
+	filter := []any{
+		map[string]any{"range": map[string]any{"publishedAt": map[string]string{"lte": "now"}}},
+		map[string]any{"bool": map[string]any{"must_not": map[string]any{"term": map[string]bool{"draft": true}}}},
+	}
+	for _, tag := range q.Tags {
+		filter = append(filter, map[string]any{"term": map[string]string{"tags": tag}})
+	}
+	if q.MaxTotalTime > 0 {
+		filter = append(filter, map[string]any{"range": map[string]any{"totalTimeMinutes": map[string]int{"gt": 0, "lte": q.MaxTotalTime}}})
+	}
+
+	body := map[string]any{
+		"size":             q.Limit,
+		"track_total_hits": true,
+		"_source":          false,
+		"query":            map[string]any{"bool": map[string]any{"must": must, "filter": filter}},
+		"sort":             sort,
+		"aggs": map[string]any{
+			"tags": map[string]any{"terms": map[string]any{"field": "tags", "size": tagFacetSize}},
+			"totalTime": map[string]any{"range": map[string]any{
+				"field": "totalTimeMinutes",
+				"ranges": []map[string]any{
+					{"key": TimeUnder30, "from": 1, "to": 31},
+					{"key": Time30To60, "from": 31, "to": 61},
+					{"key": TimeOver60, "from": 61},
+				},
+			}},
+		},
+	}
+
+	var resp struct {
+		Hits struct {
+			Total struct {
+				Value int `json:"value"`
+			} `json:"total"`
+			Hits []struct {
+				ID string `json:"_id"`
+			} `json:"hits"`
+		} `json:"hits"`
+		Aggregations map[string]struct {
+			Buckets []struct {
+				Key      any `json:"key"`
+				DocCount int `json:"doc_count"`
+			} `json:"buckets"`
+		} `json:"aggregations"`
+	}
+	if err := es.call(ctx, http.MethodPost, es.index+"/_search", body, &resp); err != nil {
+		return Result{}, err
+	}
+
+	result := Result{Total: resp.Hits.Total.Value, IDs: make([]string, 0, len(resp.Hits.Hits)), Facets: map[string][]Bucket{}}
+	for _, hit := range resp.Hits.Hits {
+		result.IDs = append(result.IDs, hit.ID)
+	}
+	for name, agg := range resp.Aggregations {
+		buckets := make([]Bucket, 0, len(agg.Buckets))
+		for _, b := range agg.Buckets {
+			if b.DocCount > 0 {
+				buckets = append(buckets, Bucket{Value: fmt.Sprint(b.Key), Count: b.DocCount})
+			}
+		}
+		result.Facets[name] = buckets
+	}
+	return result, nil
+}
+
+func newDocument(recipe models.Recipe) document {
+	return document{
+		Name:             recipe.Name,
+		Tags:             recipe.Tags,
+		Ingredients:      recipe.Ingredients,
+		Instructions:     recipe.Instructions,
+		TotalTimeMinutes: recipe.TotalTimeMinutes,
+		PublishedAt:      recipe.PublishedAt,
+		Draft:            recipe.Draft,
+	}
+}
+
+// statusError is a response from Elasticsearch other than 2xx.
+type statusError struct {
+	status int
+	body   string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("elasticsearch returned %d: %s", e.status, e.body)
+}
+
+func statusOf(err error) int {
+	var e *statusError
+	if errors.As(err, &e) {
+		return e.status
+	}
+	return 0
+}
+
+// call sends body as JSON and decodes the response into out, if not nil.
+func (es *Elasticsearch) call(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	return es.send(ctx, method, path, "application/json", reader, out)
+}
+
+func (es *Elasticsearch) send(ctx context.Context, method, path, contentType string, body io.Reader, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, es.baseURL+"/"+path, body)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := es.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &statusError{status: resp.StatusCode, body: string(data)}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
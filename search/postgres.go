@@ -0,0 +1,130 @@
+package search
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+
+	"recipes-api/models"
+	"recipes-api/service"
+)
+
+// Postgres searches the recipes themselves, through the recipe service
+// and its caches, so there is no index to maintain. Tags match whole, as
+// in the other backends, and Text when the name or an ingredient contains
+// it, ignoring case. The recipe service's tag search, which matches part
+// of a tag, only narrows down the recipes to check.
+type Postgres struct {
+	recipes *service.RecipeService
+}
+
+func NewPostgres(recipes *service.RecipeService) *Postgres {
+	return &Postgres{recipes: recipes}
+}
+
+func (p *Postgres) Index(context.Context, models.Recipe) error { return nil }
+func (p *Postgres) Delete(context.Context, string) error       { return nil }
+
+func (p *Postgres) Query(ctx context.Context, q Query) (Result, error) {
+	var candidates []models.Recipe
+	var err error
+	if len(q.Tags) > 0 {
+		candidates, err = p.recipes.Search(ctx, q.Tags[0])
+	} else {
+		candidates, err = p.recipes.List(ctx)
+	}
+	if err != nil {
+		return Result{}, err
+	}
+
+	matches := make([]models.Recipe, 0, len(candidates))
+	for _, recipe := range candidates {
+		if Matches(recipe, q) {
+			matches = append(matches, recipe)
+		}
+	}
+	if q.Text == "" {
+		slices.SortStableFunc(matches, func(a, b models.Recipe) int {
+			return b.PublishedAt.Compare(a.PublishedAt)
+		})
+	}
+
+	result := Result{Total: len(matches), IDs: make([]string, 0, len(matches)), Facets: facets(matches)}
+	for _, recipe := range matches {
+		if q.Limit > 0 && len(result.IDs) == q.Limit {
+			break
+		}
+		result.IDs = append(result.IDs, recipe.ID)
+	}
+	return result, nil
+}
+
+// Matches reports whether the recipe matches the query the way the
+// Postgres backend matches them.
+func Matches(recipe models.Recipe, q Query) bool {
+	for _, tag := range q.Tags {
+		if !slices.ContainsFunc(recipe.Tags, sameTag(tag)) {
+			return false
+		}
+	}
+	if q.Text != "" && !containsFold(q.Text)(recipe.Name) && !slices.ContainsFunc(recipe.Ingredients, containsFold(q.Text)) {
+		return false
+	}
+	// recipes without any time can't be said to be quick
+	if q.MaxTotalTime > 0 && (recipe.TotalTimeMinutes <= 0 || recipe.TotalTimeMinutes > q.MaxTotalTime) {
+		return false
+	}
+	return true
+}
+
+func sameTag(tag string) func(string) bool {
+	name := models.TagName(tag)
+	return func(s string) bool {
+		return models.TagName(s) == name
+	}
+}
+
+func containsFold(sub string) func(string) bool {
+	sub = strings.ToLower(sub)
+	return func(s string) bool {
+		return strings.Contains(strings.ToLower(s), sub)
+	}
+}
+
+// facets counts the tags and total time buckets of recipes.
+func facets(recipes []models.Recipe) map[string][]Bucket {
+	tags := map[string]int{}
+	times := map[string]int{}
+	for _, recipe := range recipes {
+		for _, tag := range recipe.Tags {
+			tags[strings.ToLower(tag)]++
+		}
+		switch minutes := recipe.TotalTimeMinutes; {
+		case minutes <= 0:
+		case minutes <= 30:
+			times[TimeUnder30]++
+		case minutes <= 60:
+			times[Time30To60]++
+		default:
+			times[TimeOver60]++
+		}
+	}
+
+	tagBuckets := make([]Bucket, 0, len(tags))
+	for tag, count := range tags {
+		tagBuckets = append(tagBuckets, Bucket{Value: tag, Count: count})
+	}
+	slices.SortFunc(tagBuckets, func(a, b Bucket) int {
+		return cmp.Or(b.Count-a.Count, strings.Compare(a.Value, b.Value))
+	})
+
+	timeBuckets := []Bucket{}
+	for _, key := range []string{TimeUnder30, Time30To60, TimeOver60} {
+		if times[key] > 0 {
+			timeBuckets = append(timeBuckets, Bucket{Value: key, Count: times[key]})
+		}
+	}
+
+	return map[string][]Bucket{"tags": tagBuckets[:min(len(tagBuckets), tagFacetSize)], "totalTime": timeBuckets}
+}
@@ -0,0 +1,222 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"recipes-api/models"
+	"recipes-api/tracing"
+
+	"github.com/go-redis/redis"
+)
+
+// RediSearch keeps the index in Redis with the RediSearch module (part of
+// Redis Stack), one hash per recipe.
+type RediSearch struct {
+	client *redis.Client
+	index  string
+	prefix string
+}
+
+func NewRediSearch(client *redis.Client, index string) *RediSearch {
+	return &RediSearch{client: client, index: index, prefix: "search:" + index + ":"}
+}
+
+// EnsureIndex creates the index unless it exists, and adds the fields
+// later versions index to one that does.
+func (rs *RediSearch) EnsureIndex(ctx context.Context) error {
+	c := tracing.Redis(ctx, rs.client)
+	if err := c.Do("FT.INFO", rs.index).Err(); err == nil {
+		err := c.Do("FT.ALTER", rs.index, "SCHEMA", "ADD", "draft", "NUMERIC").Err()
+		if err != nil && !strings.Contains(strings.ToLower(err.Error()), "duplicate") {
+			return err
+		}
+		return nil
+	} else if !strings.Contains(strings.ToLower(err.Error()), "unknown index") && !strings.Contains(strings.ToLower(err.Error()), "no such index") {
+		return err
+	}
+
+	return c.Do("FT.CREATE", rs.index, "ON", "HASH", "PREFIX", 1, rs.prefix, "SCHEMA",
+		"name", "TEXT", "WEIGHT", 3,
+		"tags", "TAG", "SEPARATOR", ",",
+		"ingredients", "TEXT",
+		"instructions", "TEXT",
+		"totalTimeMinutes", "NUMERIC", "SORTABLE",
+		"publishedAt", "NUMERIC", "SORTABLE",
+		"draft", "NUMERIC",
+	).Err()
+}
+
+func (rs *RediSearch) Index(ctx context.Context, recipe models.Recipe) error {
+	tags := make([]string, len(recipe.Tags))
+	for i, tag := range recipe.Tags {
+		tags[i] = strings.ToLower(strings.ReplaceAll(tag, ",", " "))
+	}
+
+	draft := 0
+	if recipe.Draft {
+		draft = 1
+	}
+
+	return tracing.Redis(ctx, rs.client).HMSet(rs.prefix+recipe.ID, map[string]any{
+		"name":             recipe.Name,
+		"tags":             strings.Join(tags, ","),
+		"ingredients":      strings.Join(recipe.Ingredients, "\n"),
+		"instructions":     strings.Join(recipe.Instructions, "\n"),
+		"totalTimeMinutes": recipe.TotalTimeMinutes,
+		"publishedAt":      recipe.PublishedAt.Unix(),
+		"draft":            draft,
+	}).Err()
+}
+
+func (rs *RediSearch) Delete(ctx context.Context, id string) error {
+	return tracing.Redis(ctx, rs.client).Del(rs.prefix + id).Err()
+}
+
+func (rs *RediSearch) Query(ctx context.Context, q Query) (Result, error) {
+	if q.Limit <= 0 {
+		q.Limit = DefaultLimit
+	}
+	c := tracing.Redis(ctx, rs.client)
+	query := rs.queryString(q)
+
+	args := []any{"FT.SEARCH", rs.index, query, "NOCONTENT", "LIMIT", 0, q.Limit}
+	if q.Text == "" {
+		args = append(args, "SORTBY", "publishedAt", "DESC")
+	}
+	reply, err := c.Do(args...).Result()
+	if err != nil {
+		return Result{}, err
+	}
+	total, keys, err := parseSearchReply(reply)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result := Result{Total: total, IDs: make([]string, 0, len(keys)), Facets: map[string][]Bucket{}}
+	for _, key := range keys {
+		result.IDs = append(result.IDs, strings.TrimPrefix(key, rs.prefix))
+	}
+
+	if result.Facets["tags"], err = rs.tagFacet(c, query); err != nil {
+		return Result{}, err
+	}
+	if result.Facets["totalTime"], err = rs.timeFacet(c, query); err != nil {
+		return Result{}, err
+	}
+	return result, nil
+}
+
+// queryString builds the query for q. Drafts and scheduled recipes are
+// indexed but not found, so the total and facets only count published
+// ones; hashes indexed before drafts were have no draft field and pass.
+func (rs *RediSearch) queryString(q Query) string {
+	parts := []string{fmt.Sprintf("@publishedAt:[-inf %d] -@draft:[1 1]", time.Now().Unix())}
+	if text := escapeQuery(q.Text, true); text != "" {
+		parts = append(parts, text)
+	}
+	for _, tag := range q.Tags {
+		parts = append(parts, "@tags:{"+escapeQuery(strings.ToLower(tag), false)+"}")
+	}
+	if q.MaxTotalTime > 0 {
+		parts = append(parts, fmt.Sprintf("@totalTimeMinutes:[1 %d]", q.MaxTotalTime))
+	}
+	return strings.Join(parts, " ")
+}
+
+func (rs *RediSearch) tagFacet(c *redis.Client, query string) ([]Bucket, error) {
+	reply, err := c.Do("FT.AGGREGATE", rs.index, query,
+		"LOAD", 1, "@tags",
+		"APPLY", "split(@tags)", "AS", "tag",
+		"GROUPBY", 1, "@tag", "REDUCE", "COUNT", 0, "AS", "count",
+		"SORTBY", 2, "@count", "DESC", "MAX", tagFacetSize,
+	).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, ok := reply.([]any)
+	if !ok || len(rows) == 0 {
+		return nil, fmt.Errorf("unexpected FT.AGGREGATE reply %T", reply)
+	}
+	buckets := []Bucket{}
+	for _, row := range rows[1:] {
+		fields, _ := row.([]any)
+		var bucket Bucket
+		for i := 0; i+1 < len(fields); i += 2 {
+			switch fmt.Sprint(fields[i]) {
+			case "tag":
+				bucket.Value = fmt.Sprint(fields[i+1])
+			case "count":
+				bucket.Count, _ = strconv.Atoi(fmt.Sprint(fields[i+1]))
+			}
+		}
+		if bucket.Value != "" && bucket.Count > 0 {
+			buckets = append(buckets, bucket)
+		}
+	}
+	return buckets, nil
+}
+
+func (rs *RediSearch) timeFacet(c *redis.Client, query string) ([]Bucket, error) {
+	ranges := []struct {
+		key      string
+		from, to string
+	}{
+		{TimeUnder30, "1", "30"},
+		{Time30To60, "31", "60"},
+		{TimeOver60, "61", "+inf"},
+	}
+
+	buckets := []Bucket{}
+	for _, r := range ranges {
+		reply, err := c.Do("FT.SEARCH", rs.index, fmt.Sprintf("%s @totalTimeMinutes:[%s %s]", query, r.from, r.to), "NOCONTENT", "LIMIT", 0, 0).Result()
+		if err != nil {
+			return nil, err
+		}
+		count, _, err := parseSearchReply(reply)
+		if err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			buckets = append(buckets, Bucket{Value: r.key, Count: count})
+		}
+	}
+	return buckets, nil
+}
+
+// parseSearchReply reads the total and the keys of an FT.SEARCH NOCONTENT
+// reply.
+func parseSearchReply(reply any) (int, []string, error) {
+	items, ok := reply.([]any)
+	if !ok || len(items) == 0 {
+		return 0, nil, fmt.Errorf("unexpected FT.SEARCH reply %T", reply)
+	}
+	total, ok := items[0].(int64)
+	if !ok {
+		return 0, nil, fmt.Errorf("unexpected FT.SEARCH total %T", items[0])
+	}
+
+	keys := make([]string, 0, len(items)-1)
+	for _, item := range items[1:] {
+		keys = append(keys, fmt.Sprint(item))
+	}
+	return int(total), keys, nil
+}
+
+// escapeQuery escapes the characters the query syntax treats specially.
+// Spaces separate words in text but are part of the value in tags.
+func escapeQuery(s string, text bool) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(s) {
+		if (r == ' ' && !text) || (r != ' ' && (unicode.IsPunct(r) || unicode.IsSymbol(r))) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,152 @@
+// Package search answers recipe searches. The backend is chosen at
+// startup: Postgres, the default, scans the recipes; RediSearch and
+// Elasticsearch keep an index that mirrors them and rank by relevance.
+package search
+
+import (
+	"context"
+	"log/slog"
+
+	"recipes-api/events"
+	"recipes-api/models"
+	"recipes-api/service"
+
+	"gorm.io/gorm"
+)
+
+// Facet buckets of totalTimeMinutes.
+const (
+	TimeUnder30 = "under-30"
+	Time30To60  = "30-60"
+	TimeOver60  = "over-60"
+)
+
+const (
+	// DefaultLimit is how many hits the indexed backends return unless the
+	// query says otherwise.
+	DefaultLimit = 50
+	// tagFacetSize is how many of the most common tags are counted.
+	tagFacetSize = 20
+	bulkSize     = 500
+)
+
+// Searcher is a search backend. Index and Delete keep its index in step
+// with the recipes; backends reading the database directly ignore them.
+type Searcher interface {
+	Index(ctx context.Context, recipe models.Recipe) error
+	// Delete removes a recipe. Recipes that aren't indexed are not an error.
+	Delete(ctx context.Context, id string) error
+	Query(ctx context.Context, q Query) (Result, error)
+}
+
+// batchIndexer is implemented by backends that index many recipes at once
+// faster than one by one.
+type batchIndexer interface {
+	IndexBatch(ctx context.Context, recipes []models.Recipe) error
+}
+
+// Query narrows a search. Text is matched against names, tags, ingredients
+// and steps, ranked by relevance where the backend can; without it the
+// newest come first. Tags must all be present, each matched whole but
+// ignoring case, like the tag facet's values. A zero Limit leaves the
+// number of hits to the backend.
+type Query struct {
+	Text         string
+	Tags         []string
+	MaxTotalTime int
+	Limit        int
+}
+
+type Bucket struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// Result holds the matching recipe IDs, best first, and facet counts over
+// all matches, keyed by "tags" and "totalTime".
+type Result struct {
+	Total  int                 `json:"total"`
+	IDs    []string            `json:"ids"`
+	Facets map[string][]Bucket `json:"facets"`
+}
+
+// Indexer applies recipe events to a backend's index from a background
+// worker, so writes don't wait on it.
+type Indexer struct {
+	searcher Searcher
+	queue    chan events.Event
+}
+
+func NewIndexer(searcher Searcher) *Indexer {
+	i := &Indexer{searcher: searcher, queue: make(chan events.Event, 1000)}
+	go i.work()
+	return i
+}
+
+// Handle queues the index update for a recipe event. It is meant to be
+// subscribed to the bus.
+func (i *Indexer) Handle(e events.Event) {
+	select {
+	case i.queue <- e:
+	default:
+		slog.ErrorContext(e.Context(), "Search index queue full, dropping update", "recipe_id", e.Recipe.ID, "event_type", e.Type)
+	}
+}
+
+// Backlog returns how many updates are queued and how many fit.
+func (i *Indexer) Backlog() (queued, capacity int) {
+	return len(i.queue), cap(i.queue)
+}
+
+func (i *Indexer) work() {
+	for e := range i.queue {
+		ctx := e.Context()
+		var err error
+		if e.Type == events.RecipeDeleted {
+			err = i.searcher.Delete(ctx, e.Recipe.ID)
+		} else {
+			err = i.searcher.Index(ctx, e.Recipe)
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "Error updating search index", "recipe_id", e.Recipe.ID, "event_type", e.Type, "error", err)
+		}
+	}
+}
+
+// Reindex indexes every recipe in the database, e.g. to fill a new index,
+// and returns how many were indexed. Recipes deleted meanwhile are only
+// removed by their delete events.
+func Reindex(ctx context.Context, db *gorm.DB, searcher Searcher) (int, error) {
+	count := 0
+	var batch []models.Recipe
+	err := db.WithContext(ctx).FindInBatches(&batch, bulkSize, func(tx *gorm.DB, _ int) error {
+		recipes := make([]*models.Recipe, len(batch))
+		for i := range batch {
+			recipes[i] = &batch[i]
+		}
+		if err := service.LoadInstructions(tx, recipes...); err != nil {
+			return err
+		}
+
+		if err := indexBatch(ctx, searcher, batch); err != nil {
+			return err
+		}
+		count += len(batch)
+		return nil
+	}).Error
+	return count, err
+}
+
+// indexBatch indexes recipes at once where the backend can, one by one
+// otherwise.
+func indexBatch(ctx context.Context, searcher Searcher, recipes []models.Recipe) error {
+	if b, ok := searcher.(batchIndexer); ok {
+		return b.IndexBatch(ctx, recipes)
+	}
+	for _, recipe := range recipes {
+		if err := searcher.Index(ctx, recipe); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+
+	"recipes-api/metrics"
+	"recipes-api/models"
+)
+
+const (
+	shadowTimeout = 10 * time.Second
+	// maxShadowReads bounds the comparisons in flight. Searches beyond it
+	// are served but not compared, so a slow shadow can't pile them up.
+	maxShadowReads = 8
+	// maxListedIDs caps the IDs logged per divergence.
+	maxListedIDs = 10
+)
+
+// Shadow serves searches from a primary backend and replays a share of
+// them against a shadow backend in the background, logging where the
+// results diverge, so a new backend can be vetted on real traffic before
+// it is switched to. Index and Delete go to both, keeping both indexes
+// current.
+type Shadow struct {
+	primary Searcher
+	shadow  Searcher
+	percent int
+	slots   chan struct{}
+}
+
+// NewShadow replays percent of the searches, 0 to 100, against shadow.
+func NewShadow(primary, shadow Searcher, percent int) *Shadow {
+	return &Shadow{primary: primary, shadow: shadow, percent: percent, slots: make(chan struct{}, maxShadowReads)}
+}
+
+func (s *Shadow) Index(ctx context.Context, recipe models.Recipe) error {
+	return errors.Join(s.primary.Index(ctx, recipe), s.shadow.Index(ctx, recipe))
+}
+
+func (s *Shadow) Delete(ctx context.Context, id string) error {
+	return errors.Join(s.primary.Delete(ctx, id), s.shadow.Delete(ctx, id))
+}
+
+func (s *Shadow) IndexBatch(ctx context.Context, recipes []models.Recipe) error {
+	return errors.Join(indexBatch(ctx, s.primary, recipes), indexBatch(ctx, s.shadow, recipes))
+}
+
+// Query answers from the primary. Its result is compared with the
+// shadow's once the shadow answers too; failed searches aren't compared.
+func (s *Shadow) Query(ctx context.Context, q Query) (Result, error) {
+	result, err := s.primary.Query(ctx, q)
+	if err != nil || rand.IntN(100) >= s.percent {
+		return result, err
+	}
+
+	select {
+	case s.slots <- struct{}{}:
+		go func() {
+			defer func() { <-s.slots }()
+			s.compare(context.WithoutCancel(ctx), q, result)
+		}()
+	default:
+	}
+	return result, nil
+}
+
+func (s *Shadow) compare(ctx context.Context, q Query, want Result) {
+	ctx, cancel := context.WithTimeout(ctx, shadowTimeout)
+	defer cancel()
+
+	start := time.Now()
+	got, err := s.shadow.Query(ctx, q)
+	if err != nil {
+		metrics.SearchShadowReads.WithLabelValues("error").Inc()
+		slog.WarnContext(ctx, "Shadow search failed", "q", q.Text, "tags", q.Tags, "error", err)
+		return
+	}
+
+	diffs := differences(want, got)
+	if len(diffs) == 0 {
+		metrics.SearchShadowReads.WithLabelValues("match").Inc()
+		return
+	}
+	metrics.SearchShadowReads.WithLabelValues("diverged").Inc()
+	slog.WarnContext(ctx, "Shadow search diverged",
+		"q", q.Text, "tags", q.Tags, "max_total_time", q.MaxTotalTime, "limit", q.Limit,
+		"differences", diffs, "shadow_latency_ms", time.Since(start).Milliseconds())
+}
+
+// differences describes how got differs from want: in the number of
+// matches, the recipes found and the facet counts. The order of the
+// recipes isn't compared, as backends rank differently.
+func differences(want, got Result) []string {
+	var diffs []string
+	if want.Total != got.Total {
+		diffs = append(diffs, fmt.Sprintf("total %d, shadow %d", want.Total, got.Total))
+	}
+
+	// a page cut short by the limit may end on different recipes of equal
+	// rank, so pages are only compared when they hold every match
+	if len(want.IDs) == want.Total && len(got.IDs) == got.Total {
+		if missing := subtract(want.IDs, got.IDs); len(missing) > 0 {
+			diffs = append(diffs, fmt.Sprintf("missing from shadow: %v", missing))
+		}
+		if extra := subtract(got.IDs, want.IDs); len(extra) > 0 {
+			diffs = append(diffs, fmt.Sprintf("only in shadow: %v", extra))
+		}
+	}
+
+	wantTimes, gotTimes := counts(want.Facets["totalTime"]), counts(got.Facets["totalTime"])
+	if !maps.Equal(wantTimes, gotTimes) {
+		diffs = append(diffs, fmt.Sprintf("totalTime facets %v, shadow %v", wantTimes, gotTimes))
+	}
+	// backends count different numbers of the most common tags, so only
+	// the tags both counted are compared
+	wantTags, gotTags := counts(want.Facets["tags"]), counts(got.Facets["tags"])
+	var tagDiffs []string
+	for tag, n := range wantTags {
+		if m, ok := gotTags[tag]; ok && m != n {
+			tagDiffs = append(tagDiffs, fmt.Sprintf("%s %d, shadow %d", tag, n, m))
+		}
+	}
+	if len(tagDiffs) > 0 {
+		slices.Sort(tagDiffs)
+		diffs = append(diffs, "tag facets: "+strings.Join(tagDiffs, "; "))
+	}
+	return diffs
+}
+
+// subtract returns the IDs of a not in b, at most maxListedIDs of them.
+func subtract(a, b []string) []string {
+	var out []string
+	for _, id := range a {
+		if !slices.Contains(b, id) {
+			out = append(out, id)
+		}
+	}
+	return out[:min(len(out), maxListedIDs)]
+}
+
+func counts(buckets []Bucket) map[string]int {
+	m := make(map[string]int, len(buckets))
+	for _, b := range buckets {
+		m[b.Value] = b.Count
+	}
+	return m
+}
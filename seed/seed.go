@@ -0,0 +1,142 @@
+package seed
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"recipes-api/ids"
+	"recipes-api/models"
+	"recipes-api/service"
+
+	"gorm.io/gorm"
+)
+
+// Summary counts what Load did with the recipes in a seed file.
+type Summary struct {
+	Inserted int `json:"inserted"`
+	// Skipped recipes already exist, matched by ID or name.
+	// Deleted recipes count as existing, so seeding doesn't bring them back.
+	Skipped int `json:"skipped"`
+	Failed  int `json:"failed"`
+}
+
+// Load adds the recipes in the seed file that are not in the database yet
+// and leaves everything else alone, so it is safe to run repeatedly and
+// against a database with real data. A recipe that fails to insert is
+// logged and counted; the others are still loaded. Recipes without an ID
+// get one from generator.
+func Load(ctx context.Context, db *gorm.DB, path string, generator ids.Generator) (Summary, error) {
+	var summary Summary
+
+	recipes, err := readFile(path)
+	if err != nil {
+		return summary, err
+	}
+
+	db = db.WithContext(ctx)
+	for _, recipe := range recipes {
+		exists, err := exists(db, recipe)
+		if err == nil && exists {
+			summary.Skipped++
+			continue
+		}
+		if err == nil {
+			err = db.Transaction(func(tx *gorm.DB) error {
+				return insert(tx, recipe, generator)
+			})
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "Error seeding recipe", "name", recipe.Name, "error", err)
+			summary.Failed++
+			continue
+		}
+		summary.Inserted++
+	}
+
+	return summary, nil
+}
+
+// Reset replaces all recipes with the ones in the seed file and returns how
+// many were loaded.
+func Reset(db *gorm.DB, path string, generator ids.Generator) (int, error) {
+	recipes, err := readFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM recipes").Error; err != nil {
+			return fmt.Errorf("clearing recipes table: %w", err)
+		}
+		if err := tx.Exec("DELETE FROM recipe_instructions").Error; err != nil {
+			return fmt.Errorf("clearing recipe_instructions table: %w", err)
+		}
+
+		for _, recipe := range recipes {
+			if err := insert(tx, recipe, generator); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(recipes), nil
+}
+
+func readFile(path string) ([]models.Recipe, error) {
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	var recipes []models.Recipe
+	if err := json.Unmarshal(file, &recipes); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return recipes, nil
+}
+
+func exists(db *gorm.DB, recipe models.Recipe) (bool, error) {
+	query := db.Unscoped().Select("id").Where("name = ?", recipe.Name)
+	if recipe.ID != "" {
+		query = query.Or("id = ?", recipe.ID)
+	}
+
+	var existing models.Recipe
+	err := query.Take(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func insert(tx *gorm.DB, recipe models.Recipe, generator ids.Generator) error {
+	if recipe.ID == "" {
+		recipe.ID = generator.NewID()
+	}
+	if recipe.PublishedAt.IsZero() {
+		recipe.PublishedAt = time.Now().UTC()
+	}
+	service.ApplyTotalTime(&recipe)
+	recipe.Slug = ""
+	if err := service.AssignSlug(tx, &recipe, nil); err != nil {
+		return fmt.Errorf("picking a slug for %s: %w", recipe.Name, err)
+	}
+
+	row, err := service.OffloadInstructions(tx, recipe)
+	if err != nil {
+		return fmt.Errorf("storing instructions of %s: %w", recipe.Name, err)
+	}
+	if err := tx.Create(&row).Error; err != nil {
+		return fmt.Errorf("inserting recipe %s: %w", recipe.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package serializer
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	CaseCamel = "camel"
+	CaseSnake = "snake"
+
+	// CaseHeader selects the key style when the case query parameter is absent.
+	CaseHeader = "X-Response-Case"
+)
+
+// RequestedCase returns the key style asked for via ?case= or the
+// X-Response-Case header. Responses are camelCase by default.
+func RequestedCase(c *gin.Context) (string, error) {
+	style := c.Query("case")
+	if style == "" {
+		style = c.GetHeader(CaseHeader)
+	}
+
+	switch strings.ToLower(style) {
+	case "", CaseCamel:
+		return CaseCamel, nil
+	case CaseSnake:
+		return CaseSnake, nil
+	default:
+		return "", fmt.Errorf("unsupported response case %q", style)
+	}
+}
+
+// ConvertCase rewrites every object key in doc to the given style.
+func ConvertCase(doc any, style string) any {
+	if style == CaseCamel {
+		return doc
+	}
+
+	switch value := doc.(type) {
+	case []any:
+		for i, item := range value {
+			value[i] = ConvertCase(item, style)
+		}
+		return value
+	case map[string]any:
+		out := make(map[string]any, len(value))
+		for key, child := range value {
+			out[ToSnake(key)] = ConvertCase(child, style)
+		}
+		return out
+	default:
+		return doc
+	}
+}
+
+// ToSnake converts a camelCase key to snake_case, keeping acronyms together
+// ("recipeID" becomes "recipe_id").
+func ToSnake(key string) string {
+	runes := []rune(key)
+
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			prevLower := i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]))
+			nextLower := i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1])
+			if prevLower || nextLower {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,73 @@
+package serializer
+
+import (
+	"net/http"
+	"reflect"
+	"time"
+
+	"recipes-api/apierrors"
+
+	"github.com/gin-gonic/gin"
+)
+
+const startedKey = "serializer.started"
+
+// Timing records when the request started, for the took_ms of list
+// envelopes. It should run before the other middleware.
+func Timing() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(startedKey, time.Now())
+		c.Next()
+	}
+}
+
+// Meta describes the list a response holds. Lists aren't paged, so a
+// response holds the one page of them.
+type Meta struct {
+	// Total is how many items there are, which for searches may be more
+	// than were returned. It defaults to the number of items.
+	Total int
+	// Facets are the counts of a search by tag, if asked for.
+	Facets any
+}
+
+// List writes a list of items. Versions with an envelope get them as
+// {"data": [...], "meta": {"total", "page", "took_ms"}, "links": {"self"}},
+// JSON:API documents get the same meta, and older versions get the bare
+// list.
+func List(c *gin.Context, items any, meta Meta) {
+	if meta.Total == 0 {
+		if value := reflect.ValueOf(items); value.Kind() == reflect.Slice {
+			meta.Total = value.Len()
+		}
+	}
+	render(c, http.StatusOK, items, &meta)
+}
+
+// Enveloped reports whether lists are sent to the client with their meta,
+// in an envelope or a JSON:API document.
+func Enveloped(c *gin.Context) bool {
+	version, err := RequestedVersion(c)
+	return err == nil && version.Envelope || apierrors.AcceptsJSONAPI(c.Request)
+}
+
+// fields returns the envelope meta of a list, timed until now.
+func (m Meta) fields(c *gin.Context) map[string]any {
+	fields := map[string]any{"total": m.Total, "page": 1}
+	if started := c.GetTime(startedKey); !started.IsZero() {
+		fields["took_ms"] = time.Since(started).Milliseconds()
+	}
+	if m.Facets != nil {
+		fields["facets"] = m.Facets
+	}
+	return fields
+}
+
+// envelope wraps doc, a list, with its meta and links.
+func envelope(c *gin.Context, doc any, meta Meta) map[string]any {
+	return map[string]any{
+		"data":  doc,
+		"meta":  meta.fields(c),
+		"links": map[string]any{"self": c.Request.URL.String()},
+	}
+}
@@ -0,0 +1,57 @@
+package serializer
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestedFields returns the fields asked for via ?fields=, e.g.
+// "id,name,tags", or nil for all of them.
+func RequestedFields(c *gin.Context) []string {
+	var fields []string
+	for _, field := range strings.Split(c.Query("fields"), ",") {
+		if field = strings.TrimSpace(field); field != "" && !slices.Contains(fields, field) {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// SelectFields leaves only the given fields, and id, in the objects with an
+// id in doc: doc itself, the items of a list, or those of a list in a
+// wrapping object, such as the recipes of a search. Fields may be named in
+// camelCase or snake_case.
+func SelectFields(doc any, fields []string) any {
+	switch value := doc.(type) {
+	case []any:
+		for i, item := range value {
+			if object, ok := item.(map[string]any); ok && object["id"] != nil {
+				value[i] = selectFields(object, fields)
+			}
+		}
+		return value
+	case map[string]any:
+		if value["id"] != nil {
+			return selectFields(value, fields)
+		}
+		for key, child := range value {
+			if items, ok := child.([]any); ok {
+				value[key] = SelectFields(items, fields)
+			}
+		}
+		return value
+	default:
+		return doc
+	}
+}
+
+func selectFields(object map[string]any, fields []string) map[string]any {
+	for key := range object {
+		if key != "id" && !slices.Contains(fields, key) && !slices.Contains(fields, ToSnake(key)) {
+			delete(object, key)
+		}
+	}
+	return object
+}
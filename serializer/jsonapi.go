@@ -0,0 +1,249 @@
+package serializer
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode"
+
+	"recipes-api/apierrors"
+	"recipes-api/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Resource describes how values of a model are shaped as JSON:API
+// resources.
+type Resource struct {
+	Type string
+	// Relationships maps attributes holding the ID of another resource to
+	// the relationship they are shown as instead.
+	Relationships map[string]Relationship
+}
+
+// Relationship is a to-one link to a resource of Type.
+type Relationship struct {
+	Name string
+	Type string
+}
+
+var recipeResource = Resource{
+	Type: "recipes",
+	Relationships: map[string]Relationship{
+		"cuisineId":  {Name: "cuisine", Type: "cuisines"},
+		"categoryId": {Name: "category", Type: "categories"},
+	},
+}
+
+// Resources are the models with a resource type of their own. Other
+// values with an id are typed after their Go type, e.g. "terms" for a
+// Term.
+var Resources = map[reflect.Type]Resource{
+	reflect.TypeOf(models.Recipe{}):        recipeResource,
+	reflect.TypeOf(models.RecipeSummary{}): recipeResource,
+}
+
+// Includer loads the resources of one type with the given ids, for
+// ?include=.
+type Includer func(ctx context.Context, ids []string) (any, error)
+
+var includers = map[string]Includer{}
+
+// RegisterIncluder lets the resources of type typ be included in JSON:API
+// documents. It should be called before the server starts.
+func RegisterIncluder(typ string, include Includer) {
+	includers[typ] = include
+}
+
+// jsonAPIDocument wraps doc, the transformed v, in a JSON:API document.
+// Values with an id, and lists of them, are the primary data; a list of
+// resources inside another value is too, with the value's other fields as
+// meta. Anything else is sent as meta alone.
+func jsonAPIDocument(c *gin.Context, v any, doc any, version Version) (map[string]any, error) {
+	out := map[string]any{
+		"jsonapi": map[string]any{"version": "1.1"},
+		"links":   map[string]any{"self": c.Request.URL.String()},
+	}
+
+	var resources []map[string]any
+	resource, data, meta := primaryData(reflect.TypeOf(v), doc)
+	switch data := data.(type) {
+	case map[string]any:
+		object := resourceObject(resource, data)
+		out["data"], resources = object, []map[string]any{object}
+	case []any:
+		objects := make([]any, len(data))
+		for i, item := range data {
+			object := resourceObject(resource, item.(map[string]any))
+			objects[i], resources = object, append(resources, object)
+		}
+		out["data"] = objects
+	default:
+		meta, _ = doc.(map[string]any)
+		if meta == nil {
+			meta = map[string]any{"value": doc}
+		}
+	}
+	if len(meta) > 0 {
+		out["meta"] = meta
+	}
+
+	if names := c.Query("include"); names != "" {
+		included, err := include(c, resource, resources, strings.Split(names, ","), version)
+		if err != nil {
+			return nil, err
+		}
+		out["included"] = included
+	}
+	return out, nil
+}
+
+// primaryData finds the resources in doc, the document of a value of type
+// t. It returns what they are, the attributes of the one resource or the
+// list of them, and the rest of the document when they were wrapped in
+// another value.
+func primaryData(t reflect.Type, doc any) (Resource, any, map[string]any) {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	object, isObject := doc.(map[string]any)
+	switch {
+	case t == nil:
+		return Resource{}, nil, nil
+	case t.Kind() == reflect.Slice && isResourceList(doc):
+		return resourceOf(t.Elem()), doc, nil
+	case isObject && object["id"] != nil:
+		return resourceOf(t), object, nil
+	case isObject && t.Kind() == reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			key, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if field.Type.Kind() != reflect.Slice || key == "" || key == "-" || !isResourceList(object[key]) {
+				continue
+			}
+			// an empty list is only known to be of resources for the
+			// models that have a resource type
+			items := object[key].([]any)
+			if _, known := Resources[field.Type.Elem()]; !known && len(items) == 0 {
+				continue
+			}
+			delete(object, key)
+			return resourceOf(field.Type.Elem()), items, object
+		}
+	}
+	return Resource{}, nil, nil
+}
+
+// resourceOf returns the description of the values of t, or one named
+// after t when it has none.
+func resourceOf(t reflect.Type) Resource {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if resource, ok := Resources[t]; ok {
+		return resource
+	}
+	name := []rune(t.Name())
+	if len(name) == 0 {
+		return Resource{Type: "resources"}
+	}
+	name[0] = unicode.ToLower(name[0])
+	return Resource{Type: string(name) + "s"}
+}
+
+// isResourceList reports whether doc is a list of objects with ids.
+func isResourceList(doc any) bool {
+	items, ok := doc.([]any)
+	if !ok {
+		return false
+	}
+	for _, item := range items {
+		object, ok := item.(map[string]any)
+		if !ok || object["id"] == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// resourceObject moves the attributes of a resource, but its id and the
+// ids of related resources, under "attributes".
+func resourceObject(resource Resource, attributes map[string]any) map[string]any {
+	object := map[string]any{"type": resource.Type, "id": fmt.Sprint(attributes["id"])}
+	delete(attributes, "id")
+
+	relationships := map[string]any{}
+	for key, rel := range resource.Relationships {
+		var data any
+		if id, ok := attributes[key].(string); ok && id != "" {
+			data = map[string]any{"type": rel.Type, "id": id}
+		}
+		relationships[rel.Name] = map[string]any{"data": data}
+		delete(attributes, key)
+	}
+
+	object["attributes"] = attributes
+	if len(relationships) > 0 {
+		object["relationships"] = relationships
+	}
+	return object
+}
+
+// include loads the resources the named relationships of resources link
+// to.
+func include(c *gin.Context, resource Resource, resources []map[string]any, names []string, version Version) ([]any, error) {
+	ids := map[string][]string{}
+	var types []string
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		rel, ok := relationshipNamed(resource, name)
+		if !ok || includers[rel.Type] == nil {
+			return nil, apierrors.BadRequest("Unknown relationship {name} in include").With("name", name)
+		}
+		if _, seen := ids[rel.Type]; !seen {
+			types = append(types, rel.Type)
+			ids[rel.Type] = []string{}
+		}
+		for _, object := range resources {
+			relationships, _ := object["relationships"].(map[string]any)
+			linkage, _ := relationships[rel.Name].(map[string]any)
+			if target, ok := linkage["data"].(map[string]any); ok {
+				if id := target["id"].(string); !slices.Contains(ids[rel.Type], id) {
+					ids[rel.Type] = append(ids[rel.Type], id)
+				}
+			}
+		}
+	}
+
+	loc, _ := RequestedLocation(c)
+	included := []any{}
+	for _, typ := range types {
+		values, err := includers[typ](c.Request.Context(), ids[typ])
+		if err != nil {
+			return nil, err
+		}
+		doc, _, err := Transform(values, version)
+		if err != nil {
+			return nil, err
+		}
+		doc = ConvertTimes(doc, loc)
+		items, _ := doc.([]any)
+		for _, item := range items {
+			if attributes, ok := item.(map[string]any); ok {
+				included = append(included, resourceObject(Resource{Type: typ}, attributes))
+			}
+		}
+	}
+	return included, nil
+}
+
+func relationshipNamed(resource Resource, name string) (Relationship, bool) {
+	for _, rel := range resource.Relationships {
+		if rel.Name == name {
+			return rel, true
+		}
+	}
+	return Relationship{}, false
+}
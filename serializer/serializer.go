@@ -0,0 +1,276 @@
+// Package serializer shapes API response bodies per API version so the
+// models can evolve without breaking existing clients. It renames and
+// aliases fields, strips internal ones and reports deprecated fields.
+package serializer
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"recipes-api/apierrors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VersionHeader selects the response version on requests and echoes the
+// version used on responses.
+const VersionHeader = "API-Version"
+
+// Deprecation marks a field that is still emitted but scheduled for removal.
+type Deprecation struct {
+	Field       string
+	Replacement string
+	Sunset      time.Time
+}
+
+// Version describes how payloads are shaped for one API version. Fields are
+// dot-separated JSON key paths matched against the end of the full path;
+// arrays are transparent and "*" matches any map key.
+type Version struct {
+	Name string
+	// Renames moves a field to a new name.
+	Renames map[string]string
+	// Aliases copies a field under an additional name, e.g. to let clients
+	// migrate to the new name before the old one goes away.
+	Aliases      map[string]string
+	Deprecations []Deprecation
+	// Envelope wraps lists in {data, meta, links}.
+	Envelope bool
+}
+
+// Internal fields are stripped from every response regardless of version.
+var Internal = []string{
+	"image.key",
+	"image.variants.*.key",
+}
+
+var Versions = map[string]Version{
+	"1": {
+		Name:    "1",
+		Aliases: map[string]string{"publishedAt": "published_at"},
+		Deprecations: []Deprecation{
+			{Field: "publishedAt", Replacement: "published_at", Sunset: time.Date(2027, time.June, 30, 0, 0, 0, 0, time.UTC)},
+		},
+	},
+	"2": {
+		Name:    "2",
+		Renames: map[string]string{"publishedAt": "published_at"},
+	},
+	"3": {
+		Name:     "3",
+		Renames:  map[string]string{"publishedAt": "published_at"},
+		Envelope: true,
+	},
+}
+
+const DefaultVersion = "1"
+
+// RequestedVersion returns the version asked for by the request, or the default.
+func RequestedVersion(c *gin.Context) (Version, error) {
+	name := c.GetHeader(VersionHeader)
+	if name == "" {
+		name = DefaultVersion
+	}
+
+	version, ok := Versions[name]
+	if !ok {
+		return Version{}, fmt.Errorf("unsupported API version %q", name)
+	}
+	return version, nil
+}
+
+// Transform converts v to its JSON document shape for the given version and
+// returns the deprecated fields it contained.
+func Transform(v any, version Version) (any, []Deprecation, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, nil, err
+	}
+
+	used := map[string]Deprecation{}
+	doc = transform(doc, "", version, used)
+
+	deprecations := make([]Deprecation, 0, len(used))
+	for _, d := range version.Deprecations {
+		if _, ok := used[d.Field]; ok {
+			deprecations = append(deprecations, d)
+		}
+	}
+
+	return doc, deprecations, nil
+}
+
+func transform(node any, path string, version Version, used map[string]Deprecation) any {
+	switch value := node.(type) {
+	case []any:
+		for i, item := range value {
+			value[i] = transform(item, path, version, used)
+		}
+		return value
+	case map[string]any:
+		out := make(map[string]any, len(value))
+		for key, child := range value {
+			childPath := join(path, key)
+			if matchesAny(Internal, childPath) {
+				continue
+			}
+
+			child = transform(child, childPath, version, used)
+
+			for _, d := range version.Deprecations {
+				if matches(d.Field, childPath) {
+					used[d.Field] = d
+				}
+			}
+			for field, alias := range version.Aliases {
+				if matches(field, childPath) {
+					out[alias] = child
+				}
+			}
+
+			name := key
+			for field, rename := range version.Renames {
+				if matches(field, childPath) {
+					name = rename
+				}
+			}
+			out[name] = child
+		}
+		return out
+	default:
+		return node
+	}
+}
+
+// matches reports whether path ends with pattern, where "*" in the pattern
+// stands for any single key. Matching the tail lets rules apply to recipes
+// nested in other payloads, such as revision snapshots.
+func matches(pattern, path string) bool {
+	want := strings.Split(pattern, ".")
+	got := strings.Split(path, ".")
+	if len(want) > len(got) {
+		return false
+	}
+
+	got = got[len(got)-len(want):]
+	for i := range want {
+		if want[i] != "*" && want[i] != got[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesAny(patterns []string, path string) bool {
+	for _, pattern := range patterns {
+		if matches(pattern, path) {
+			return true
+		}
+	}
+	return false
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// JSON writes v shaped for the API version, fields, key case, timezone and
+// format requested by the client and sets the version and deprecation
+// headers.
+func JSON(c *gin.Context, status int, v any) {
+	render(c, status, v, nil)
+}
+
+// render writes v as JSON does. When v is a list described by meta, it is
+// wrapped in an envelope for the versions that have one.
+func render(c *gin.Context, status int, v any, meta *Meta) {
+	version, err := RequestedVersion(c)
+	if err != nil {
+		apierrors.Write(c, apierrors.BadRequest(err.Error()))
+		return
+	}
+
+	style, err := RequestedCase(c)
+	if err != nil {
+		apierrors.Write(c, apierrors.BadRequest(err.Error()))
+		return
+	}
+
+	loc, err := RequestedLocation(c)
+	if err != nil {
+		apierrors.Write(c, apierrors.BadRequest(err.Error()))
+		return
+	}
+
+	doc, deprecations, err := Transform(v, version)
+	if err != nil {
+		apierrors.Write(c, apierrors.Internal("Failed to encode response"))
+		return
+	}
+	doc = ConvertTimes(doc, loc)
+	if fields := RequestedFields(c); len(fields) > 0 {
+		doc = SelectFields(doc, fields)
+		// fields left out aren't in use either
+		deprecations = slices.DeleteFunc(deprecations, func(d Deprecation) bool {
+			return !slices.Contains(fields, d.Field)
+		})
+	}
+
+	jsonAPI := apierrors.AcceptsJSONAPI(c.Request)
+	switch {
+	case jsonAPI:
+		document, err := jsonAPIDocument(c, v, doc, version)
+		if err != nil {
+			apierrors.Write(c, err)
+			return
+		}
+		if meta != nil {
+			document["meta"] = meta.fields(c)
+		}
+		doc = document
+	case meta != nil && version.Envelope:
+		doc = envelope(c, doc, *meta)
+	}
+
+	if style == CaseSnake {
+		doc = ConvertCase(doc, style)
+		// snake_case clients already get the replacement name of fields
+		// that are only deprecated for their casing
+		kept := deprecations[:0]
+		for _, d := range deprecations {
+			if ToSnake(d.Field) != d.Replacement {
+				kept = append(kept, d)
+			}
+		}
+		deprecations = kept
+	}
+
+	c.Header(VersionHeader, version.Name)
+	c.Writer.Header().Add("Vary", "Accept")
+	if jsonAPI {
+		c.Header("Content-Type", apierrors.JSONAPIMediaType)
+	}
+	if len(deprecations) > 0 {
+		c.Header("Deprecation", "true")
+		for _, d := range deprecations {
+			c.Writer.Header().Add("Warning", fmt.Sprintf(`299 - "Field %s is deprecated, use %s instead"`, d.Field, d.Replacement))
+			if !d.Sunset.IsZero() {
+				c.Header("Sunset", d.Sunset.Format(http.TimeFormat))
+			}
+		}
+	}
+
+	c.JSON(status, doc)
+}
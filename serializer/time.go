@@ -0,0 +1,68 @@
+package serializer
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TimezoneHeader selects the offset of timestamps when the tz query parameter is absent.
+const TimezoneHeader = "X-Timezone"
+
+var offsetPattern = regexp.MustCompile(`^[+-]\d{2}:\d{2}$`)
+
+// RequestedLocation returns the zone timestamps should be rendered in, taken
+// from ?tz= or the X-Timezone header. It accepts a fixed offset ("+03:00")
+// or an IANA zone name ("Africa/Nairobi") and defaults to UTC.
+func RequestedLocation(c *gin.Context) (*time.Location, error) {
+	tz := c.Query("tz")
+	if tz == "" {
+		tz = c.GetHeader(TimezoneHeader)
+	}
+
+	switch {
+	case tz == "" || tz == "Z" || tz == "UTC":
+		return time.UTC, nil
+	case offsetPattern.MatchString(tz):
+		t, err := time.Parse("-07:00", tz)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timezone offset %q", tz)
+		}
+		_, offset := t.Zone()
+		return time.FixedZone(tz, offset), nil
+	default:
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			return nil, fmt.Errorf("unknown timezone %q", tz)
+		}
+		return loc, nil
+	}
+}
+
+// ConvertTimes rewrites every RFC 3339 timestamp in doc to second precision
+// in the given location, so responses don't depend on how the value was
+// stored or which zone the database session used.
+func ConvertTimes(doc any, loc *time.Location) any {
+	switch value := doc.(type) {
+	case []any:
+		for i, item := range value {
+			value[i] = ConvertTimes(item, loc)
+		}
+		return value
+	case map[string]any:
+		for key, child := range value {
+			value[key] = ConvertTimes(child, loc)
+		}
+		return value
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return value
+		}
+		return t.In(loc).Format(time.RFC3339)
+	default:
+		return doc
+	}
+}
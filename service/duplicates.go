@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"recipes-api/ingredients"
+	"recipes-api/models"
+	"recipes-api/shopping"
+)
+
+// duplicateOverlap is the share of their ingredients two recipes of the
+// same name must have in common to be duplicates.
+const duplicateOverlap = 0.5
+
+// FindDuplicate returns a recipe that recipe looks like a copy of: one
+// whose name is the same but for case, spacing and punctuation, and that
+// shares at least half the ingredients. Drafts count, recipes in the trash
+// don't.
+func (s *RecipeService) FindDuplicate(ctx context.Context, recipe models.Recipe) (models.Recipe, bool, error) {
+	name := nameKey(recipe.Name)
+	if name == "" {
+		return models.Recipe{}, false, nil
+	}
+
+	var candidates []models.Recipe
+	err := s.db.WithContext(ctx).
+		Where("regexp_replace(LOWER(name), '[^[:alnum:]]+', '', 'g') = ?", name).
+		Order("published_at").Find(&candidates).Error
+	if err != nil {
+		return models.Recipe{}, false, err
+	}
+
+	items := ingredientItems(recipe.Ingredients)
+	for _, candidate := range candidates {
+		if overlap(items, ingredientItems(candidate.Ingredients)) >= duplicateOverlap {
+			return candidate, true, nil
+		}
+	}
+	return models.Recipe{}, false, nil
+}
+
+// nameKey reduces a recipe name to its lowercase letters and digits.
+func nameKey(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+}
+
+func ingredientItems(lines []string) map[string]bool {
+	items := map[string]bool{}
+	for _, line := range lines {
+		if item := ingredients.Parse(line).Item; item != "" {
+			items[shopping.Normalize(item)] = true
+		}
+	}
+	return items
+}
+
+// overlap is the share of the items of a and b that both have.
+func overlap(a, b map[string]bool) float64 {
+	common := 0
+	for item := range a {
+		if b[item] {
+			common++
+		}
+	}
+	all := len(a) + len(b) - common
+	if all == 0 {
+		return 1
+	}
+	return float64(common) / float64(all)
+}
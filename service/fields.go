@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"recipes-api/models"
+	"recipes-api/serializer"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// UnknownFieldError is a field asked for that the model doesn't show.
+type UnknownFieldError struct {
+	Field string
+}
+
+func (e *UnknownFieldError) Error() string {
+	return fmt.Sprintf("unknown field %q", e.Field)
+}
+
+// Columns returns the columns of model, a pointer to a model, that hold
+// the fields with the given JSON names, its primary key first. Fields that
+// aren't stored in a column, such as counts filled in on read, are left
+// out; fields the model doesn't show are an UnknownFieldError.
+func Columns(db *gorm.DB, model any, fields []string) ([]string, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return nil, err
+	}
+
+	columns := []string{stmt.Schema.PrioritizedPrimaryField.DBName}
+	for _, name := range fields {
+		field := jsonField(stmt.Schema.Fields, name)
+		if field == nil {
+			return nil, &UnknownFieldError{Field: name}
+		}
+		if field.DBName != "" && !slices.Contains(columns, field.DBName) {
+			columns = append(columns, field.DBName)
+		}
+	}
+	return columns, nil
+}
+
+// jsonField finds the field shown under name, in camelCase or snake_case.
+func jsonField(fields []*schema.Field, name string) *schema.Field {
+	for _, field := range fields {
+		key, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if key != "" && key != "-" && (key == name || serializer.ToSnake(key) == name) {
+			return field
+		}
+	}
+	return nil
+}
+
+// ListColumns returns the published recipes narrowed down by scope, with
+// only the given columns loaded. Unlike List it isn't cached, since the
+// columns vary.
+func (s *RecipeService) ListColumns(ctx context.Context, columns []string, scope func(*gorm.DB) *gorm.DB) ([]models.Recipe, error) {
+	recipes := []models.Recipe{}
+	err := Published(ReadReplica(s.db.WithContext(ctx))).Scopes(scope).Select(columns).Find(&recipes).Error
+	return recipes, err
+}
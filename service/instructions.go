@@ -0,0 +1,66 @@
+package service
+
+import (
+	"recipes-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// InstructionsInlineLimit is the combined size in bytes above which a
+// recipe's instructions are moved out of the recipes row, so list queries
+// and cached copies stay small.
+const InstructionsInlineLimit = 8 << 10
+
+// OffloadInstructions returns the row to write for recipe. Oversized
+// instructions are saved to recipe_instructions and left out of the row;
+// otherwise any copy offloaded earlier is removed.
+func OffloadInstructions(tx *gorm.DB, recipe models.Recipe) (models.Recipe, error) {
+	size := 0
+	for _, step := range recipe.Instructions {
+		size += len(step)
+	}
+
+	if size <= InstructionsInlineLimit {
+		recipe.InstructionsOffloaded = false
+		return recipe, tx.Where("recipe_id = ?", recipe.ID).Delete(&models.RecipeInstructions{}).Error
+	}
+
+	body := models.RecipeInstructions{RecipeID: recipe.ID, Instructions: recipe.Instructions}
+	if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&body).Error; err != nil {
+		return recipe, err
+	}
+
+	recipe.Instructions = nil
+	recipe.InstructionsOffloaded = true
+	return recipe, nil
+}
+
+// LoadInstructions fills in the instructions of offloaded recipes in place.
+func LoadInstructions(db *gorm.DB, recipes ...*models.Recipe) error {
+	var ids []string
+	for _, recipe := range recipes {
+		if recipe.InstructionsOffloaded {
+			ids = append(ids, recipe.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var bodies []models.RecipeInstructions
+	if err := db.Where("recipe_id IN ?", ids).Find(&bodies).Error; err != nil {
+		return err
+	}
+
+	byID := make(map[string][]string, len(bodies))
+	for _, body := range bodies {
+		byID[body.RecipeID] = body.Instructions
+	}
+	for _, recipe := range recipes {
+		if instructions, ok := byID[recipe.ID]; ok {
+			recipe.Instructions = instructions
+		}
+	}
+	return nil
+}
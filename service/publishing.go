@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"recipes-api/events"
+	"recipes-api/models"
+
+	"gorm.io/gorm"
+)
+
+// Published limits a query of recipes or summaries to the ones that are
+// public now.
+func Published(db *gorm.DB) *gorm.DB {
+	return db.Where("draft = ? AND published_at <= ?", false, time.Now().UTC())
+}
+
+// Publish makes a draft or scheduled recipe public at the given time,
+// which may be in the future to schedule it.
+func (s *RecipeService) Publish(ctx context.Context, id string, at time.Time) (models.Recipe, error) {
+	if s.readOnly() {
+		return models.Recipe{}, ErrReadOnly
+	}
+
+	var recipe models.Recipe
+	if err := s.db.WithContext(ctx).Where("id = ?", id).First(&recipe).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.Recipe{}, ErrNotFound
+		}
+		return models.Recipe{}, err
+	}
+	if err := LoadInstructions(s.db.WithContext(ctx), &recipe); err != nil {
+		return models.Recipe{}, err
+	}
+	before := recipe
+
+	recipe.Draft = false
+	recipe.PublishedAt = at.UTC()
+	err := s.db.WithContext(ctx).Model(&recipe).Select("draft", "published_at").Updates(&recipe).Error
+	if err != nil {
+		return models.Recipe{}, err
+	}
+
+	ClearCache(ctx, s.redisClient, recipe.ID)
+
+	event := events.NewEvent(events.RecipeUpdated, recipe)
+	event.Changes = events.Diff(before, recipe)
+	s.events.Publish(ctx, event)
+
+	return recipe, nil
+}
@@ -0,0 +1,521 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"recipes-api/cache"
+	"recipes-api/events"
+	"recipes-api/ids"
+	"recipes-api/models"
+	"recipes-api/nutrition"
+	"recipes-api/tracing"
+
+	"github.com/go-redis/redis"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrNotFound is returned when a recipe doesn't exist or was deleted.
+var ErrNotFound = errors.New("recipe not found")
+
+// ErrInvalid is returned when a change would leave a recipe invalid.
+var ErrInvalid = errors.New("invalid recipe")
+
+// ErrConflict is returned when a write was made from an older version of
+// the recipe than the stored one.
+var ErrConflict = errors.New("recipe has changed since the version given")
+
+// ErrVersionRequired is returned when a write doesn't say which version
+// of the recipe it was made from.
+var ErrVersionRequired = errors.New("the version the change was made from is required")
+
+// AnyVersion stands in for the version of a write made regardless of
+// what the recipe is now, as with If-Match: *.
+const AnyVersion int64 = -1
+
+// ErrReadOnly is returned for writes while the API runs in degraded read-only mode.
+var ErrReadOnly = errors.New("the API is temporarily read-only")
+
+// RecipeService holds the recipe business logic shared by the REST and
+// GraphQL APIs: persistence, caching, revisions, events and nutrition jobs.
+type RecipeService struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+	nutrition   *nutrition.Service
+	events      *events.Bus
+	readOnly    func() bool
+	cacheTTL    time.Duration
+	ids         ids.Generator
+}
+
+// NewRecipeService creates the service. Recipes are cached for cacheTTL,
+// and new ones get their IDs from generator. Writes fail with ErrReadOnly
+// whenever readOnly reports true.
+func NewRecipeService(db *gorm.DB, redisClient *redis.Client, nutritionService *nutrition.Service, bus *events.Bus, cacheTTL time.Duration, readOnly func() bool, generator ids.Generator) *RecipeService {
+	return &RecipeService{db: db, redisClient: redisClient, nutrition: nutritionService, events: bus, readOnly: readOnly, cacheTTL: cacheTTL, ids: generator}
+}
+
+// ClearCache runs after a write has committed, so it must not be
+// cancelled along with the request when the client goes away.
+func ClearCache(ctx context.Context, redisClient *redis.Client, ids ...string) {
+	cache.InvalidateRecipes(context.WithoutCancel(ctx), redisClient, ids...)
+}
+
+// SaveRevision records the current state of a recipe as its next revision.
+func SaveRevision(tx *gorm.DB, recipe models.Recipe) error {
+	var latest int
+	if err := tx.Model(&models.RecipeRevision{}).
+		Where("recipe_id = ?", recipe.ID).
+		Select("COALESCE(MAX(revision), 0)").
+		Scan(&latest).Error; err != nil {
+		return err
+	}
+
+	return tx.Create(&models.RecipeRevision{
+		RecipeID: recipe.ID,
+		Revision: latest + 1,
+		Snapshot: recipe,
+	}).Error
+}
+
+// StampChanges records the time of every field that differs between before
+// and after in after.FieldsUpdatedAt and saves it, along with the next
+// version.
+func StampChanges(tx *gorm.DB, before models.Recipe, after *models.Recipe) error {
+	changes := events.Diff(before, *after)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	stamps := make(map[string]time.Time, len(before.FieldsUpdatedAt)+len(changes))
+	maps.Copy(stamps, before.FieldsUpdatedAt)
+	for field := range changes {
+		stamps[field] = now
+	}
+	after.FieldsUpdatedAt = stamps
+	after.Version = before.Version + 1
+
+	return tx.Model(after).Select("fields_updated_at", "version").Updates(models.Recipe{FieldsUpdatedAt: stamps, Version: after.Version}).Error
+}
+
+// lockVersion reads the recipe into recipe and locks its row until tx
+// ends, so of two writes made from the same version only the first gets
+// through. version has to be the stored one, or ErrConflict is returned,
+// unless it is AnyVersion; without one ErrVersionRequired is.
+func lockVersion(tx *gorm.DB, id string, version int64, recipe *models.Recipe) error {
+	if version < 1 && version != AnyVersion {
+		return ErrVersionRequired
+	}
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(recipe).Error; err != nil {
+		return notFound(err)
+	}
+	if version != AnyVersion && version != recipe.Version {
+		return ErrConflict
+	}
+	return nil
+}
+
+// Create stores a new recipe. Server-managed fields sent by the client are
+// ignored, except that it may be a draft or scheduled for later.
+func (s *RecipeService) Create(ctx context.Context, recipe models.Recipe) (models.Recipe, error) {
+	if s.readOnly() {
+		return models.Recipe{}, ErrReadOnly
+	}
+
+	recipe.ID = s.ids.NewID()
+	// a publication time in the future schedules the recipe
+	if now := time.Now().UTC(); !recipe.PublishedAt.After(now) {
+		recipe.PublishedAt = now
+	}
+	recipe.Nutrition = nil
+	recipe.Image = nil
+	recipe.FieldsUpdatedAt = nil
+	recipe.Slug = ""
+	recipe.Version = 1
+	ApplyTotalTime(&recipe)
+	if err := Validate(recipe); err != nil {
+		return models.Recipe{}, err
+	}
+	if err := checkTimes(recipe); err != nil {
+		return models.Recipe{}, err
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkTerms(tx, recipe); err != nil {
+			return err
+		}
+		if err := AssignSlug(tx, &recipe, nil); err != nil {
+			return err
+		}
+		row, err := OffloadInstructions(tx, recipe)
+		if err != nil {
+			return err
+		}
+		recipe.InstructionsOffloaded = row.InstructionsOffloaded
+		if err := tx.Create(&row).Error; err != nil {
+			return err
+		}
+		return LinkTags(tx, recipe)
+	})
+	if err != nil {
+		return models.Recipe{}, err
+	}
+
+	ClearCache(ctx, s.redisClient)
+	s.nutrition.Enqueue(ctx, recipe.ID)
+	s.events.Publish(ctx, events.NewEvent(events.RecipeCreated, recipe))
+
+	return recipe, nil
+}
+
+// List returns all published recipes.
+func (s *RecipeService) List(ctx context.Context) ([]models.Recipe, error) {
+	cached, err := tracing.Redis(ctx, s.redisClient).Get(cache.RecipesAllKey).Result()
+	if err == nil {
+		var recipes []models.Recipe
+		if json.Unmarshal([]byte(cached), &recipes) == nil {
+			return recipes, nil
+		}
+	}
+
+	var recipes []models.Recipe
+	if err := Published(ReadReplica(s.db.WithContext(ctx))).Find(&recipes).Error; err != nil {
+		return nil, err
+	}
+
+	data, _ := json.Marshal(recipes)
+	tracing.Redis(ctx, s.redisClient).Set(cache.RecipesAllKey, data, s.cacheTTL)
+
+	return recipes, nil
+}
+
+// Get returns a single recipe with its full instructions, or ErrNotFound.
+func (s *RecipeService) Get(ctx context.Context, id string) (models.Recipe, error) {
+	recipes, err := s.GetMany(ctx, []string{id})
+	if err != nil {
+		return models.Recipe{}, err
+	}
+	if len(recipes) == 0 {
+		return models.Recipe{}, ErrNotFound
+	}
+
+	recipe := recipes[0]
+	if err := LoadInstructions(s.db.WithContext(ctx), &recipe); err != nil {
+		return models.Recipe{}, err
+	}
+	return recipe, nil
+}
+
+// GetMany returns the recipes with the given IDs in the same order,
+// reading cached copies in one batch and loading only the misses from the
+// database. IDs that don't exist are left out. Offloaded instructions are
+// not loaded.
+func (s *RecipeService) GetMany(ctx context.Context, ids []string) ([]models.Recipe, error) {
+	found, missing, err := cache.GetRecipes(ctx, s.redisClient, ids)
+	if err != nil && err != redis.Nil {
+		missing = ids
+	}
+
+	if len(missing) > 0 {
+		var loaded []models.Recipe
+		if err := s.db.WithContext(ctx).Where("id IN ?", missing).Find(&loaded).Error; err != nil {
+			return nil, err
+		}
+		for _, recipe := range loaded {
+			found[recipe.ID] = recipe
+		}
+		cache.SetRecipes(ctx, s.redisClient, loaded, s.cacheTTL)
+	}
+
+	recipes := make([]models.Recipe, 0, len(ids))
+	for _, id := range ids {
+		if recipe, ok := found[id]; ok {
+			recipes = append(recipes, recipe)
+		}
+	}
+	return recipes, nil
+}
+
+// Update replaces the content of a recipe, saving its previous state as a
+// revision. recipe.Version must be the stored version, or AnyVersion, as
+// for lockVersion.
+func (s *RecipeService) Update(ctx context.Context, id string, recipe models.Recipe) (models.Recipe, error) {
+	if s.readOnly() {
+		return models.Recipe{}, ErrReadOnly
+	}
+
+	var existingRecipe models.Recipe
+	if err := s.db.WithContext(ctx).Where("id = ?", id).First(&existingRecipe).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.Recipe{}, ErrNotFound
+		}
+		return models.Recipe{}, err
+	}
+
+	recipe.ID = existingRecipe.ID
+	recipe.PublishedAt = existingRecipe.PublishedAt
+	recipe.Draft = existingRecipe.Draft
+	recipe.Nutrition = nil
+	recipe.Image = nil
+	recipe.FieldsUpdatedAt = nil
+	recipe.Slug = existingRecipe.Slug
+	var before models.Recipe
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockVersion(tx, id, recipe.Version, &existingRecipe); err != nil {
+			return err
+		}
+		recipe.Version = existingRecipe.Version
+		if err := LoadInstructions(tx, &existingRecipe); err != nil {
+			return err
+		}
+		before = existingRecipe
+
+		if err := SaveRevision(tx, existingRecipe); err != nil {
+			return err
+		}
+
+		// the total time is worked out on the merged recipe, since empty
+		// fields keep their current values
+		merged := existingRecipe
+		if recipe.Name != "" {
+			merged.Name = recipe.Name
+		}
+		if len(recipe.Ingredients) > 0 {
+			merged.Ingredients = recipe.Ingredients
+		}
+		if len(recipe.Instructions) > 0 {
+			merged.Instructions = recipe.Instructions
+		}
+		if recipe.PrepMinutes > 0 {
+			merged.PrepMinutes = recipe.PrepMinutes
+		}
+		if recipe.CookMinutes > 0 {
+			merged.CookMinutes = recipe.CookMinutes
+		}
+		if recipe.TotalTimeMinutes > 0 && !recipe.TotalTimeEstimated {
+			merged.TotalTimeMinutes, merged.TotalTimeEstimated = recipe.TotalTimeMinutes, false
+		}
+		ApplyTotalTime(&merged)
+		// the recipe as it will be stored: the fields sent, with the
+		// required ones left empty kept from before
+		stored := recipe
+		stored.Name, stored.Ingredients, stored.Instructions = merged.Name, merged.Ingredients, merged.Instructions
+		if err := Validate(stored); err != nil {
+			return err
+		}
+		if err := checkTimes(merged); err != nil {
+			return err
+		}
+		if err := checkTerms(tx, recipe); err != nil {
+			return err
+		}
+		if err := renameSlug(tx, &merged); err != nil {
+			return err
+		}
+		recipe.Slug = merged.Slug
+		recipe.TotalTimeMinutes, recipe.TotalTimeEstimated = merged.TotalTimeMinutes, merged.TotalTimeEstimated
+		if err := tx.Model(&existingRecipe).Select("total_time_minutes", "total_time_estimated").Updates(&recipe).Error; err != nil {
+			return err
+		}
+		existingRecipe.TotalTimeMinutes, existingRecipe.TotalTimeEstimated = recipe.TotalTimeMinutes, recipe.TotalTimeEstimated
+
+		if len(recipe.Tags) > 0 {
+			existingRecipe.Tags = recipe.Tags
+			if err := LinkTags(tx, existingRecipe); err != nil {
+				return err
+			}
+		}
+
+		// empty instructions keep the current ones, like any other empty field
+		if len(recipe.Instructions) == 0 {
+			if err := tx.Model(&existingRecipe).Updates(&recipe).Error; err != nil {
+				return err
+			}
+			return StampChanges(tx, before, &existingRecipe)
+		}
+
+		row, err := OffloadInstructions(tx, recipe)
+		if err != nil {
+			return err
+		}
+		if err := tx.Model(&existingRecipe).Updates(&row).Error; err != nil {
+			return err
+		}
+		// an offloaded row has no instructions, which Updates would skip
+		if err := tx.Model(&existingRecipe).Select("instructions", "instructions_offloaded").Updates(&row).Error; err != nil {
+			return err
+		}
+		existingRecipe.Instructions = recipe.Instructions
+		return StampChanges(tx, before, &existingRecipe)
+	})
+	if err != nil {
+		return models.Recipe{}, err
+	}
+
+	ClearCache(ctx, s.redisClient, existingRecipe.ID)
+	s.nutrition.Enqueue(ctx, existingRecipe.ID)
+
+	event := events.NewEvent(events.RecipeUpdated, existingRecipe)
+	event.Changes = events.Diff(before, existingRecipe)
+	s.events.Publish(ctx, event)
+
+	return existingRecipe, nil
+}
+
+// Patch changes a recipe through apply and saves the content fields as
+// they are afterwards, saving the previous state as a revision. Unlike
+// Update, empty values clear fields. Server-managed fields apply changes
+// are ignored, and errors from apply are wrapped in ErrInvalid. version
+// must be the stored version, or AnyVersion, as for lockVersion.
+func (s *RecipeService) Patch(ctx context.Context, id string, version int64, apply func(*models.Recipe) error) (models.Recipe, error) {
+	if s.readOnly() {
+		return models.Recipe{}, ErrReadOnly
+	}
+
+	var recipe, before models.Recipe
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := lockVersion(tx, id, version, &before); err != nil {
+			return err
+		}
+		if err := LoadInstructions(tx, &before); err != nil {
+			return err
+		}
+
+		recipe = before
+		recipe.Tags = slices.Clone(before.Tags)
+		recipe.Ingredients = slices.Clone(before.Ingredients)
+		recipe.Instructions = slices.Clone(before.Instructions)
+		if err := apply(&recipe); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalid, err)
+		}
+		recipe.ID = before.ID
+		recipe.PublishedAt = before.PublishedAt
+		recipe.Draft = before.Draft
+		recipe.Nutrition = before.Nutrition
+		recipe.Image = before.Image
+		recipe.FieldsUpdatedAt = before.FieldsUpdatedAt
+		recipe.Slug = before.Slug
+		recipe.Version = before.Version
+		// an estimated time follows the new times and steps
+		ApplyTotalTime(&recipe)
+		if err := Validate(recipe); err != nil {
+			return err
+		}
+		if err := checkTimes(recipe); err != nil {
+			return err
+		}
+		if err := checkTerms(tx, recipe); err != nil {
+			return err
+		}
+		if err := renameSlug(tx, &recipe); err != nil {
+			return err
+		}
+
+		if err := SaveRevision(tx, before); err != nil {
+			return err
+		}
+		row, err := OffloadInstructions(tx, recipe)
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&models.Recipe{ID: id}).
+			Select("name", "slug", "tags", "ingredients", "instructions", "instructions_offloaded", "total_time_minutes", "total_time_estimated", "prep_minutes", "cook_minutes", "difficulty", "cuisine_id", "category_id", "servings").
+			Updates(&row).Error
+		if err != nil {
+			return err
+		}
+		recipe.InstructionsOffloaded = row.InstructionsOffloaded
+		if err := LinkTags(tx, recipe); err != nil {
+			return err
+		}
+		return StampChanges(tx, before, &recipe)
+	})
+	if err != nil {
+		return models.Recipe{}, err
+	}
+
+	ClearCache(ctx, s.redisClient, recipe.ID)
+	s.nutrition.Enqueue(ctx, recipe.ID)
+
+	event := events.NewEvent(events.RecipeUpdated, recipe)
+	event.Changes = events.Diff(before, recipe)
+	s.events.Publish(ctx, event)
+
+	return recipe, nil
+}
+
+// Delete moves a recipe to the trash.
+func (s *RecipeService) Delete(ctx context.Context, id string) error {
+	if s.readOnly() {
+		return ErrReadOnly
+	}
+
+	var recipe models.Recipe
+	if err := s.db.WithContext(ctx).Where("id = ?", id).First(&recipe).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&recipe).Error; err != nil {
+			return err
+		}
+		return UnlinkTags(tx, recipe.ID)
+	})
+	if err != nil {
+		return err
+	}
+
+	ClearCache(ctx, s.redisClient, recipe.ID)
+	s.events.Publish(ctx, events.NewEvent(events.RecipeDeleted, recipe))
+
+	return nil
+}
+
+// likeEscaper escapes the wildcards of text put in LIKE patterns.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// Search returns the published recipes with a tag containing the given text, case-insensitively.
+func (s *RecipeService) Search(ctx context.Context, tag string) ([]models.Recipe, error) {
+	// the search cache only holds matching IDs; the recipes themselves
+	// are read from the per-recipe cache in one batch
+	cached, err := tracing.Redis(ctx, s.redisClient).Get(cache.SearchKey(tag)).Result()
+	if err == nil {
+		var ids []string
+		if json.Unmarshal([]byte(cached), &ids) == nil {
+			if recipes, err := s.GetMany(ctx, ids); err == nil {
+				return recipes, nil
+			}
+		}
+	}
+
+	// the matching tags are found in the small tags table, and their
+	// recipes through the index on recipe_tags
+	var listOfRecipes []models.Recipe
+	err = Published(ReadReplica(s.db.WithContext(ctx))).
+		Where("id IN (SELECT recipe_tags.recipe_id FROM recipe_tags JOIN tags ON tags.id = recipe_tags.tag_id WHERE tags.name LIKE ?)", "%"+likeEscaper.Replace(models.TagName(tag))+"%").
+		Find(&listOfRecipes).Error
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(listOfRecipes))
+	for _, recipe := range listOfRecipes {
+		ids = append(ids, recipe.ID)
+	}
+	cache.SetSearch(ctx, s.redisClient, tag, ids, s.cacheTTL)
+	cache.SetRecipes(ctx, s.redisClient, listOfRecipes, s.cacheTTL)
+
+	return listOfRecipes, nil
+}
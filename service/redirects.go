@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"recipes-api/events"
+	"recipes-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AddRedirect sends links to from on to to, along with the links that
+// already led to from, so redirects never chain.
+func AddRedirect(tx *gorm.DB, from, to string) error {
+	if err := tx.Model(&models.Redirect{}).Where("to_id = ?", from).Update("to_id", to).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("from_id = to_id").Delete(&models.Redirect{}).Error; err != nil {
+		return err
+	}
+	redirect := models.Redirect{FromID: from, ToID: to, CreatedAt: time.Now().UTC()}
+	return tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&redirect).Error
+}
+
+// RemoveRedirect stops redirecting from, e.g. when the recipe is restored
+// or a new recipe takes an old slug.
+func RemoveRedirect(tx *gorm.DB, from string) error {
+	return tx.Where("from_id = ?", from).Delete(&models.Redirect{}).Error
+}
+
+// FindRedirect returns where links to id lead, or "" when they don't
+// redirect.
+func FindRedirect(db *gorm.DB, id string) (string, error) {
+	var redirect models.Redirect
+	err := db.Where("from_id = ?", id).Take(&redirect).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	return redirect.ToID, err
+}
+
+// Merge folds a duplicate recipe into another. The target gains the tags
+// and subscribers of the source, which goes to the trash, and links to the
+// source redirect to the target from then on.
+func (s *RecipeService) Merge(ctx context.Context, sourceID, targetID string) (models.Recipe, error) {
+	if s.readOnly() {
+		return models.Recipe{}, ErrReadOnly
+	}
+	if sourceID == targetID {
+		return models.Recipe{}, fmt.Errorf("%w: a recipe can't be merged into itself", ErrInvalid)
+	}
+
+	var source, target, before models.Recipe
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ?", sourceID).First(&source).Error; err != nil {
+			return notFound(err)
+		}
+		if err := tx.Where("id = ?", targetID).First(&target).Error; err != nil {
+			return notFound(err)
+		}
+		if err := LoadInstructions(tx, &target); err != nil {
+			return err
+		}
+		before = target
+
+		tags := slices.Clone(target.Tags)
+		for _, tag := range source.Tags {
+			if !slices.ContainsFunc(tags, func(t string) bool { return strings.EqualFold(t, tag) }) {
+				tags = append(tags, tag)
+			}
+		}
+		if len(tags) > len(target.Tags) {
+			if err := SaveRevision(tx, target); err != nil {
+				return err
+			}
+			if err := tx.Model(&target).Select("tags").Updates(models.Recipe{Tags: tags}).Error; err != nil {
+				return err
+			}
+			target.Tags = tags
+			if err := LinkTags(tx, target); err != nil {
+				return err
+			}
+			if err := StampChanges(tx, before, &target); err != nil {
+				return err
+			}
+		}
+
+		// subscribers of both keep their subscription to the target
+		subscribed := tx.Model(&models.RecipeSubscription{}).Select("email").Where("recipe_id = ?", target.ID)
+		if err := tx.Where("recipe_id = ? AND email IN (?)", source.ID, subscribed).Delete(&models.RecipeSubscription{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.RecipeSubscription{}).Where("recipe_id = ?", source.ID).Update("recipe_id", target.ID).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Delete(&source).Error; err != nil {
+			return err
+		}
+		if err := UnlinkTags(tx, source.ID); err != nil {
+			return err
+		}
+		if source.Slug != "" && target.Slug != "" {
+			if err := AddRedirect(tx, source.Slug, target.Slug); err != nil {
+				return err
+			}
+		}
+		return AddRedirect(tx, source.ID, target.ID)
+	})
+	if err != nil {
+		return models.Recipe{}, err
+	}
+
+	ClearCache(ctx, s.redisClient, source.ID, target.ID)
+
+	if changes := events.Diff(before, target); len(changes) > 0 {
+		event := events.NewEvent(events.RecipeUpdated, target)
+		event.Changes = changes
+		s.events.Publish(ctx, event)
+	}
+	s.events.Publish(ctx, events.NewEvent(events.RecipeDeleted, source))
+
+	return target, nil
+}
+
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,18 @@
+package service
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// ReplicaResolver names the dbresolver resolver for the read replicas.
+// Queries are only sent to replicas when they ask for it with
+// ReadReplica, so reads that must see a write just made stay on the
+// primary.
+const ReplicaResolver = "replicas"
+
+// ReadReplica routes the reads of db to a read replica, if any are
+// configured. Writes still go to the primary.
+func ReadReplica(db *gorm.DB) *gorm.DB {
+	return db.Clauses(dbresolver.Use(ReplicaResolver))
+}
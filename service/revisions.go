@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"recipes-api/models"
+
+	"gorm.io/gorm"
+)
+
+// ErrRevisionNotFound is returned when a recipe has no revision by the
+// number asked for.
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// Restore rolls a recipe back to the name, tags, ingredients and steps it
+// had at revision rev. It is a Patch like any other: made from version,
+// saving the current state as a new revision, with the slug following the
+// restored name and an estimated time worked out again.
+func (s *RecipeService) Restore(ctx context.Context, id string, rev int, version int64) (models.Recipe, error) {
+	var revision models.RecipeRevision
+	if err := s.db.WithContext(ctx).Where("recipe_id = ? AND revision = ?", id, rev).First(&revision).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.Recipe{}, ErrRevisionNotFound
+		}
+		return models.Recipe{}, err
+	}
+
+	return s.Patch(ctx, id, version, func(recipe *models.Recipe) error {
+		recipe.Name = revision.Snapshot.Name
+		recipe.Tags = revision.Snapshot.Tags
+		recipe.Ingredients = revision.Snapshot.Ingredients
+		recipe.Instructions = revision.Snapshot.Instructions
+		return nil
+	})
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"recipes-api/models"
+
+	"golang.org/x/text/unicode/norm"
+	"gorm.io/gorm"
+)
+
+// maxSlugLength keeps slugs short enough to read in a URL.
+const maxSlugLength = 80
+
+// Slugify turns a recipe name into the URL-safe part of a slug: lowercase
+// letters and digits without accents, words joined by hyphens.
+func Slugify(name string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range norm.NFD.String(strings.ToLower(name)) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// accents, split off their letters by NFD
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			hyphen = false
+		default:
+			hyphen = true
+		}
+	}
+	slug := b.String()
+	if len(slug) > maxSlugLength {
+		slug = strings.TrimRight(slug[:maxSlugLength], "-")
+	}
+	if slug == "" {
+		return "recipe"
+	}
+	return slug
+}
+
+// AssignSlug gives the recipe a slug made from its name, suffixed with -2,
+// -3 and so on when another recipe, trashed ones included, has it, or when
+// it is in claimed. The slug picked is added to claimed, so recipes
+// inserted together get different ones; claimed may be nil. A recipe that
+// already has a slug its name makes keeps it.
+func AssignSlug(tx *gorm.DB, recipe *models.Recipe, claimed map[string]bool) error {
+	base := Slugify(recipe.Name)
+	if recipe.Slug == base || strings.HasPrefix(recipe.Slug, base+"-") && isSuffix(strings.TrimPrefix(recipe.Slug, base+"-")) {
+		return nil
+	}
+	for n := 1; ; n++ {
+		slug := base
+		if n > 1 {
+			slug += "-" + strconv.Itoa(n)
+		}
+		if claimed[slug] {
+			continue
+		}
+		var taken models.Recipe
+		err := tx.Unscoped().Select("id").Where("slug = ? AND id <> ?", slug, recipe.ID).Take(&taken).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			recipe.Slug = slug
+			if claimed != nil {
+				claimed[slug] = true
+			}
+			// an old slug taken by a new recipe stops redirecting
+			return RemoveRedirect(tx, slug)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func isSuffix(s string) bool {
+	n, err := strconv.Atoi(s)
+	return err == nil && n > 1
+}
+
+// renameSlug gives the recipe a new slug if its name changed, and
+// redirects links to the old one to it.
+func renameSlug(tx *gorm.DB, recipe *models.Recipe) error {
+	old := recipe.Slug
+	if err := AssignSlug(tx, recipe, nil); err != nil {
+		return err
+	}
+	if old == "" || old == recipe.Slug {
+		return nil
+	}
+	return AddRedirect(tx, old, recipe.Slug)
+}
+
+// IDForSlug returns the ID of the recipe with the slug, or ErrNotFound.
+func (s *RecipeService) IDForSlug(ctx context.Context, slug string) (string, error) {
+	var recipe models.Recipe
+	err := s.db.WithContext(ctx).Select("id").Where("slug = ?", slug).Take(&recipe).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", ErrNotFound
+	}
+	return recipe.ID, err
+}
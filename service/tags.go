@@ -0,0 +1,74 @@
+package service
+
+import (
+	"slices"
+	"strings"
+
+	"recipes-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LinkTags replaces the recipe_tags links of the recipes with links to the
+// tags they have now, adding the tags that are new. Writes call it in the
+// transaction storing the recipes, so the links, which tag queries go by,
+// never lag behind them.
+func LinkTags(tx *gorm.DB, recipes ...models.Recipe) error {
+	ids := make([]string, 0, len(recipes))
+	for _, recipe := range recipes {
+		ids = append(ids, recipe.ID)
+	}
+	if err := UnlinkTags(tx, ids...); err != nil {
+		return err
+	}
+
+	var names []string
+	var newTags []models.Tag
+	for _, recipe := range recipes {
+		for _, tag := range recipe.Tags {
+			if name := models.TagName(tag); name != "" && !slices.Contains(names, name) {
+				names = append(names, name)
+				newTags = append(newTags, models.Tag{Name: name, Label: strings.TrimSpace(tag)})
+			}
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&newTags).Error; err != nil {
+		return err
+	}
+	var existing []models.Tag
+	if err := tx.Where("name IN ?", names).Find(&existing).Error; err != nil {
+		return err
+	}
+	tagIDs := make(map[string]int64, len(existing))
+	for _, tag := range existing {
+		tagIDs[tag.Name] = tag.ID
+	}
+
+	var links []models.RecipeTag
+	for _, recipe := range recipes {
+		seen := map[int64]bool{}
+		for _, tag := range recipe.Tags {
+			id, ok := tagIDs[models.TagName(tag)]
+			if !ok || seen[id] {
+				continue
+			}
+			seen[id] = true
+			links = append(links, models.RecipeTag{RecipeID: recipe.ID, TagID: id})
+		}
+	}
+	return tx.Create(&links).Error
+}
+
+// UnlinkTags removes the recipe_tags links of the recipes, as they leave
+// the tag listings when trashed.
+func UnlinkTags(tx *gorm.DB, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return tx.Where("recipe_id IN ?", ids).Delete(&models.RecipeTag{}).Error
+}
@@ -0,0 +1,35 @@
+package service
+
+import (
+	"fmt"
+
+	"recipes-api/models"
+	"recipes-api/taxonomy"
+
+	"gorm.io/gorm"
+)
+
+// checkTerms rejects a recipe filed under a cuisine or category that
+// doesn't exist.
+func checkTerms(db *gorm.DB, recipe models.Recipe) error {
+	for _, link := range []struct {
+		kind  taxonomy.Kind
+		field string
+		id    string
+	}{
+		{taxonomy.Cuisines, "cuisineId", recipe.CuisineID},
+		{taxonomy.Categories, "categoryId", recipe.CategoryID},
+	} {
+		if link.id == "" {
+			continue
+		}
+		ok, err := taxonomy.Exists(db, link.kind, link.id)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("%w: %s %q doesn't exist", ErrInvalid, link.field, link.id)
+		}
+	}
+	return nil
+}
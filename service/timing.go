@@ -0,0 +1,35 @@
+package service
+
+import (
+	"fmt"
+
+	"recipes-api/models"
+	"recipes-api/timing"
+)
+
+// ApplyTotalTime works out the total time of a recipe that doesn't give
+// one: the prep and cook times added up, or estimated from the steps when
+// neither is given. A time that was worked out before is worked out again,
+// so it follows changes to the times and steps.
+func ApplyTotalTime(recipe *models.Recipe) {
+	if recipe.TotalTimeMinutes > 0 && !recipe.TotalTimeEstimated {
+		return
+	}
+	recipe.TotalTimeMinutes = recipe.PrepMinutes + recipe.CookMinutes
+	if recipe.TotalTimeMinutes == 0 {
+		recipe.TotalTimeMinutes = timing.Estimate(recipe.Ingredients, recipe.Instructions)
+	}
+	recipe.TotalTimeEstimated = recipe.TotalTimeMinutes > 0
+}
+
+// checkTimes rejects a total time the author gave that is shorter than the
+// prep and cook times together.
+func checkTimes(recipe models.Recipe) error {
+	if recipe.TotalTimeEstimated || recipe.TotalTimeMinutes == 0 {
+		return nil
+	}
+	if parts := recipe.PrepMinutes + recipe.CookMinutes; recipe.TotalTimeMinutes < parts {
+		return fmt.Errorf("%w: totalTimeMinutes is less than prepMinutes and cookMinutes together", ErrInvalid)
+	}
+	return nil
+}
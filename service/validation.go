@@ -0,0 +1,31 @@
+package service
+
+import (
+	"fmt"
+
+	"recipes-api/models"
+
+	"github.com/gin-gonic/gin/binding"
+	"gorm.io/gorm"
+)
+
+// Validate checks a recipe against its binding tags with the validator the
+// REST API binds bodies with, so GraphQL and gRPC writes are held to the
+// same rules. Failures wrap ErrInvalid and keep the validator's errors for
+// middleware.FieldErrors.
+func Validate(recipe models.Recipe) error {
+	if err := binding.Validator.ValidateStruct(recipe); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalid, err)
+	}
+	return nil
+}
+
+// Check makes the checks Create makes beyond Validate, of the times and of
+// the cuisine and category, for recipes stored in bulk without it such as
+// imported ones. The total time must have been worked out already.
+func Check(db *gorm.DB, recipe models.Recipe) error {
+	if err := checkTimes(recipe); err != nil {
+		return err
+	}
+	return checkTerms(db, recipe)
+}
@@ -0,0 +1,224 @@
+// Package settings holds the options that can be changed while the server
+// runs. They are read from a JSON file and reloaded on SIGHUP or through
+// the admin API, without restarting or dropping connections.
+package settings
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"sync/atomic"
+	"syscall"
+
+	"recipes-api/authz"
+	"recipes-api/conflicts"
+)
+
+// Log levels, from most to least verbose.
+const (
+	LevelDebug = "debug"
+	LevelInfo  = "info"
+	LevelWarn  = "warn"
+	LevelError = "error"
+)
+
+// Features that can be switched off at runtime.
+const (
+	FeatureGraphQL = "graphql"
+	FeatureFeeds   = "feeds"
+)
+
+type Settings struct {
+	LogLevel  string    `json:"logLevel"`
+	RateLimit RateLimit `json:"rateLimit"`
+	// Features switches optional features; features not listed are on.
+	Features map[string]bool `json:"features"`
+	// CORSOrigins are the origins browsers may call the API from; "*"
+	// allows any. CORS says what those calls may do.
+	CORSOrigins []string   `json:"corsOrigins"`
+	CORS        CORS       `json:"cors"`
+	JSON        JSONLimits `json:"json"`
+	// ConflictPolicy is how POST /recipes/:id/conflicts merges versions
+	// when the request doesn't pick a policy.
+	ConflictPolicy string `json:"conflictPolicy"`
+	// RankByQuality lists recipe summaries by quality score before date.
+	RankByQuality bool `json:"rankByQuality"`
+	// Policy decides which roles may call which routes.
+	Policy []authz.Rule `json:"policy"`
+}
+
+// RateLimit is applied per client IP. A zero RequestsPerSecond disables it.
+type RateLimit struct {
+	RequestsPerSecond float64 `json:"requestsPerSecond"`
+	Burst             int     `json:"burst"`
+}
+
+// CORS shapes the answers to cross-origin requests. Empty Headers allows
+// whichever request headers the browser asks for. AllowCredentials lets
+// browsers send cookies and Authorization along, so pages can use the
+// routes that need a token; it can't be combined with any origin.
+type CORS struct {
+	Methods          []string `json:"methods"`
+	Headers          []string `json:"headers"`
+	ExposeHeaders    []string `json:"exposeHeaders"`
+	AllowCredentials bool     `json:"allowCredentials"`
+	// MaxAge is how many seconds browsers may cache a preflight answer.
+	MaxAge int `json:"maxAge"`
+}
+
+// JSONLimits restrict JSON request bodies. Zero MaxBytes or MaxDepth means
+// no limit; Strict rejects fields the endpoint doesn't know.
+type JSONLimits struct {
+	Strict   bool  `json:"strict"`
+	MaxBytes int64 `json:"maxBytes"`
+	MaxDepth int   `json:"maxDepth"`
+}
+
+func Defaults() Settings {
+	return Settings{
+		LogLevel: LevelInfo,
+		JSON:     JSONLimits{MaxBytes: 1 << 20, MaxDepth: 32},
+		CORS: CORS{
+			Methods:       []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+			ExposeHeaders: []string{"ETag", "Retry-After", "X-Request-ID", "Idempotent-Replayed"},
+			MaxAge:        600,
+		},
+
+		ConflictPolicy: conflicts.LastWriterWins,
+		Policy:         authz.DefaultPolicy(),
+	}
+}
+
+func (s Settings) Validate() error {
+	switch s.LogLevel {
+	case LevelDebug, LevelInfo, LevelWarn, LevelError:
+	default:
+		return fmt.Errorf("unknown log level %q", s.LogLevel)
+	}
+	if s.RateLimit.RequestsPerSecond < 0 || s.RateLimit.Burst < 0 {
+		return errors.New("rate limit must not be negative")
+	}
+	if s.CORS.AllowCredentials && slices.Contains(s.CORSOrigins, "*") {
+		return errors.New(`cors.allowCredentials can't be used with the "*" origin`)
+	}
+	if len(s.CORS.Methods) == 0 {
+		return errors.New("cors.methods must not be empty")
+	}
+	if s.CORS.MaxAge < 0 {
+		return errors.New("cors.maxAge must not be negative")
+	}
+	if s.JSON.MaxBytes < 0 || s.JSON.MaxDepth < 0 {
+		return errors.New("JSON limits must not be negative")
+	}
+	if !conflicts.IsPolicy(s.ConflictPolicy) {
+		return fmt.Errorf("unknown conflict policy %q", s.ConflictPolicy)
+	}
+	for i, rule := range s.Policy {
+		if err := rule.Validate(); err != nil {
+			return fmt.Errorf("policy rule %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// Store holds the current settings and swaps them atomically on reload.
+type Store struct {
+	path    string
+	current atomic.Pointer[Settings]
+}
+
+// Load reads the settings file. A missing file leaves the defaults in place.
+func Load(path string) (*Store, error) {
+	s := &Store{path: path}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload re-reads the settings file. On error the current settings are kept.
+func (s *Store) Reload() error {
+	next := Defaults()
+
+	data, err := os.ReadFile(s.path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return fmt.Errorf("reading %s: %w", s.path, err)
+	default:
+		if err := json.Unmarshal(data, &next); err != nil {
+			return fmt.Errorf("parsing %s: %w", s.path, err)
+		}
+	}
+
+	if err := next.Validate(); err != nil {
+		return fmt.Errorf("invalid settings in %s: %w", s.path, err)
+	}
+
+	s.current.Store(&next)
+	return nil
+}
+
+func (s *Store) Get() Settings {
+	return *s.current.Load()
+}
+
+func (s *Store) LogLevel() string {
+	return s.Get().LogLevel
+}
+
+func (s *Store) RateLimit() RateLimit {
+	return s.Get().RateLimit
+}
+
+func (s *Store) CORSOrigins() []string {
+	return s.Get().CORSOrigins
+}
+
+func (s *Store) CORS() CORS {
+	return s.Get().CORS
+}
+
+func (s *Store) JSONLimits() JSONLimits {
+	return s.Get().JSON
+}
+
+func (s *Store) ConflictPolicy() string {
+	return s.Get().ConflictPolicy
+}
+
+func (s *Store) RankByQuality() bool {
+	return s.Get().RankByQuality
+}
+
+func (s *Store) Policy() []authz.Rule {
+	return s.Get().Policy
+}
+
+// Enabled reports whether a feature is on. Features are on unless the
+// settings switch them off.
+func (s *Store) Enabled(feature string) bool {
+	on, ok := s.Get().Features[feature]
+	return !ok || on
+}
+
+// ReloadOnSignal reloads the settings every time the process gets SIGHUP.
+func (s *Store) ReloadOnSignal() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	go func() {
+		for range signals {
+			if err := s.Reload(); err != nil {
+				slog.Error("Error reloading settings", "path", s.path, "error", err)
+				continue
+			}
+			slog.Info("Reloaded settings", "path", s.path)
+		}
+	}()
+}
@@ -0,0 +1,82 @@
+package shopping
+
+import "strings"
+
+// Aisles, in the order a list is grouped by.
+const (
+	AisleProduce = "produce"
+	AisleMeat    = "meat & seafood"
+	AisleDairy   = "dairy & eggs"
+	AisleBakery  = "bakery"
+	AisleSpices  = "spices"
+	AisleFrozen  = "frozen"
+	AislePantry  = "pantry"
+	AisleOther   = "other"
+)
+
+var aisleOrder = []string{AisleProduce, AisleMeat, AisleDairy, AisleBakery, AisleSpices, AisleFrozen, AislePantry, AisleOther}
+
+// aisleWords maps the words of item names to their aisle. They are tried
+// from the last word of the item backwards, since the last word usually
+// names the thing ("chicken stock" is pantry, "chicken breast" meat).
+var aisleWords = map[string]string{
+	"apple": AisleProduce, "avocado": AisleProduce, "banana": AisleProduce, "basil": AisleProduce,
+	"bean sprout": AisleProduce, "berry": AisleProduce, "broccoli": AisleProduce, "cabbage": AisleProduce,
+	"carrot": AisleProduce, "cauliflower": AisleProduce, "celery": AisleProduce, "chili": AisleProduce,
+	"chive": AisleProduce, "cilantro": AisleProduce, "coriander": AisleProduce, "cucumber": AisleProduce,
+	"dill": AisleProduce, "eggplant": AisleProduce, "garlic": AisleProduce, "ginger": AisleProduce,
+	"kale": AisleProduce, "leek": AisleProduce, "lemon": AisleProduce, "lettuce": AisleProduce,
+	"lime": AisleProduce, "mango": AisleProduce, "mint": AisleProduce, "mushroom": AisleProduce,
+	"onion": AisleProduce, "orange": AisleProduce, "parsley": AisleProduce, "pea": AisleProduce,
+	"pepper": AisleProduce, "potato": AisleProduce, "rosemary": AisleProduce, "scallion": AisleProduce,
+	"shallot": AisleProduce, "spinach": AisleProduce, "squash": AisleProduce, "thyme": AisleProduce,
+	"tomato": AisleProduce, "zucchini": AisleProduce,
+
+	"bacon": AisleMeat, "beef": AisleMeat, "breast": AisleMeat, "chicken": AisleMeat,
+	"chop": AisleMeat, "cod": AisleMeat, "fillet": AisleMeat, "fish": AisleMeat,
+	"ham": AisleMeat, "lamb": AisleMeat, "pork": AisleMeat, "prawn": AisleMeat,
+	"salmon": AisleMeat, "sausage": AisleMeat, "shrimp": AisleMeat, "steak": AisleMeat,
+	"thigh": AisleMeat, "tuna": AisleMeat, "turkey": AisleMeat,
+
+	"butter": AisleDairy, "buttermilk": AisleDairy, "cheddar": AisleDairy, "cheese": AisleDairy,
+	"cream": AisleDairy, "egg": AisleDairy, "feta": AisleDairy, "milk": AisleDairy,
+	"mozzarella": AisleDairy, "parmesan": AisleDairy, "ricotta": AisleDairy, "yogurt": AisleDairy,
+
+	"bagel": AisleBakery, "baguette": AisleBakery, "bread": AisleBakery, "bun": AisleBakery,
+	"roll": AisleBakery, "tortilla": AisleBakery,
+
+	"cinnamon": AisleSpices, "clove": AisleSpices, "cumin": AisleSpices, "nutmeg": AisleSpices,
+	"oregano": AisleSpices, "paprika": AisleSpices, "peppercorn": AisleSpices, "salt": AisleSpices,
+	"turmeric": AisleSpices, "vanilla": AisleSpices,
+
+	"bean": AislePantry, "broth": AislePantry, "chickpea": AislePantry, "flour": AislePantry,
+	"honey": AislePantry, "lentil": AislePantry, "noodle": AislePantry, "oat": AislePantry,
+	"oil": AislePantry, "pasta": AislePantry, "rice": AislePantry, "sauce": AislePantry,
+	"spaghetti": AislePantry, "stock": AislePantry, "sugar": AislePantry, "vinegar": AislePantry,
+	"yeast": AislePantry, "baking powder": AislePantry, "baking soda": AislePantry,
+	"coconut milk": AislePantry, "peanut butter": AislePantry,
+
+	"black pepper": AisleSpices, "white pepper": AisleSpices, "ice cream": AisleFrozen,
+}
+
+// Aisle returns the aisle an item is found in, AisleOther for items it
+// doesn't know.
+func Aisle(item string) string {
+	words := strings.Fields(Normalize(item))
+	// "frozen peas" are found with the frozen food
+	if len(words) > 0 && words[0] == "frozen" {
+		return AisleFrozen
+	}
+	for i := len(words) - 1; i >= 0; i-- {
+		// two word names like "baking soda" first
+		if i > 0 {
+			if aisle, ok := aisleWords[words[i-1]+" "+words[i]]; ok {
+				return aisle
+			}
+		}
+		if aisle, ok := aisleWords[words[i]]; ok {
+			return aisle
+		}
+	}
+	return AisleOther
+}
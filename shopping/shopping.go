@@ -0,0 +1,197 @@
+// Package shopping turns recipes into shopping lists: their ingredients
+// merged by item, with the quantities summed where the units match, and
+// grouped by aisle.
+package shopping
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"recipes-api/ingredients"
+	"recipes-api/models"
+
+	"github.com/rs/xid"
+	"gorm.io/gorm"
+)
+
+var ErrNotFound = errors.New("shopping list not found")
+
+// Source is a recipe to shop for. A recipe cooked twice is given twice.
+type Source struct {
+	RecipeID    string
+	Ingredients []string
+}
+
+// List is a shopping list with its items by aisle.
+type List struct {
+	models.ShoppingList
+	Groups []Group `json:"groups"`
+}
+
+type Group struct {
+	Aisle string                    `json:"aisle"`
+	Items []models.ShoppingListItem `json:"items"`
+}
+
+// Normalize reduces an item name to what is compared when merging:
+// lowercase and singular, so "Tomatoes" and "tomato" are one item.
+func Normalize(item string) string {
+	words := strings.Fields(strings.ToLower(item))
+	for i, word := range words {
+		switch {
+		case strings.HasSuffix(word, "ies") && len(word) > 4:
+			words[i] = strings.TrimSuffix(word, "ies") + "y"
+		case strings.HasSuffix(word, "oes"):
+			words[i] = strings.TrimSuffix(word, "es")
+		case strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") && !strings.HasSuffix(word, "us") && !strings.HasSuffix(word, "is"):
+			words[i] = strings.TrimSuffix(word, "s")
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// Merge parses the ingredients of the sources into items, one per
+// normalized item name, grouped by aisle and otherwise in the order they
+// first appear.
+func Merge(sources []Source) []models.ShoppingListItem {
+	var items []models.ShoppingListItem
+	index := map[string]int{}
+	for _, source := range sources {
+		for _, input := range source.Ingredients {
+			line := ingredients.Parse(input)
+			if line.Item == "" {
+				continue
+			}
+			key := Normalize(line.Item)
+			i, ok := index[key]
+			if !ok {
+				i = len(items)
+				index[key] = i
+				items = append(items, models.ShoppingListItem{Aisle: Aisle(line.Item), Item: line.Item, RecipeIDs: []string{}})
+			}
+			item := &items[i]
+			if !slices.Contains(item.RecipeIDs, source.RecipeID) {
+				item.RecipeIDs = append(item.RecipeIDs, source.RecipeID)
+			}
+			if line.Quantity > 0 {
+				item.Quantities = add(item.Quantities, models.Quantity{Amount: line.Quantity, AmountMax: line.QuantityMax, Unit: line.Unit})
+			}
+		}
+	}
+
+	slices.SortStableFunc(items, func(a, b models.ShoppingListItem) int {
+		return slices.Index(aisleOrder, a.Aisle) - slices.Index(aisleOrder, b.Aisle)
+	})
+	for i := range items {
+		items[i].Position = i
+	}
+	return items
+}
+
+// add adds q to the quantity of the same unit, or lists it apart if there
+// is none. A range stays a range: its bounds are summed with the amounts
+// that had none.
+func add(quantities []models.Quantity, q models.Quantity) []models.Quantity {
+	i := slices.IndexFunc(quantities, func(existing models.Quantity) bool { return existing.Unit == q.Unit })
+	if i < 0 {
+		return append(quantities, q)
+	}
+	sum := &quantities[i]
+	if sum.AmountMax > 0 || q.AmountMax > 0 {
+		sum.AmountMax = round(upper(*sum) + upper(q))
+	}
+	sum.Amount = round(sum.Amount + q.Amount)
+	return quantities
+}
+
+func upper(q models.Quantity) float64 {
+	return max(q.Amount, q.AmountMax)
+}
+
+func round(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
+
+type Service struct {
+	db *gorm.DB
+}
+
+func NewService(db *gorm.DB) *Service {
+	return &Service{db: db}
+}
+
+// Create builds a list from the sources and saves it.
+func (s *Service) Create(ctx context.Context, name string, sources []Source) (List, error) {
+	list := models.ShoppingList{ID: xid.New().String(), Name: name, RecipeIDs: []string{}, CreatedAt: time.Now().UTC()}
+	for _, source := range sources {
+		if !slices.Contains(list.RecipeIDs, source.RecipeID) {
+			list.RecipeIDs = append(list.RecipeIDs, source.RecipeID)
+		}
+	}
+	list.Items = Merge(sources)
+	for i := range list.Items {
+		list.Items[i].ID = xid.New().String()
+		list.Items[i].ListID = list.ID
+	}
+
+	if err := s.db.WithContext(ctx).Create(&list).Error; err != nil {
+		return List{}, err
+	}
+	return group(list), nil
+}
+
+func (s *Service) Get(ctx context.Context, id string) (List, error) {
+	var list models.ShoppingList
+	err := s.db.WithContext(ctx).Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position")
+	}).Where("id = ?", id).First(&list).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return List{}, ErrNotFound
+	}
+	if err != nil {
+		return List{}, err
+	}
+	return group(list), nil
+}
+
+// Check checks an item of a list off, or back on when checked is false.
+func (s *Service) Check(ctx context.Context, listID, itemID string, checked bool) (models.ShoppingListItem, error) {
+	var item models.ShoppingListItem
+	db := s.db.WithContext(ctx)
+	if err := db.Where("id = ? AND list_id = ?", itemID, listID).First(&item).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return item, ErrNotFound
+		}
+		return item, err
+	}
+	item.Checked = checked
+	err := db.Model(&item).Update("checked", checked).Error
+	return item, err
+}
+
+func (s *Service) Delete(ctx context.Context, id string) error {
+	result := s.db.WithContext(ctx).Where("id = ?", id).Delete(&models.ShoppingList{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// group sorts the items of list into their aisles.
+func group(list models.ShoppingList) List {
+	out := List{ShoppingList: list, Groups: []Group{}}
+	for _, item := range list.Items {
+		if n := len(out.Groups); n == 0 || out.Groups[n-1].Aisle != item.Aisle {
+			out.Groups = append(out.Groups, Group{Aisle: item.Aisle})
+		}
+		out.Groups[len(out.Groups)-1].Items = append(out.Groups[len(out.Groups)-1].Items, item)
+	}
+	return out
+}
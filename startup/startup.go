@@ -0,0 +1,78 @@
+// Package startup helps the server come up when its dependencies are slow
+// to become available, as is common when containers start together.
+package startup
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"recipes-api/cache"
+
+	"github.com/go-redis/redis"
+)
+
+const maxRetryDelay = 30 * time.Second
+
+// RetryPolicy is how often and how patiently a dependency is retried.
+type RetryPolicy struct {
+	Attempts int
+	Delay    time.Duration
+}
+
+// Retry calls fn until it succeeds or the attempts run out, doubling the
+// delay after each failure up to 30s. It returns the last error.
+func Retry(name string, policy RetryPolicy, fn func() error) error {
+	delay := policy.Delay
+	var err error
+	for attempt := 1; attempt <= policy.Attempts; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		if attempt == policy.Attempts {
+			break
+		}
+		slog.Warn(name+" not available, retrying", "attempt", attempt, "attempts", policy.Attempts, "retry_in", delay.String(), "error", err)
+		time.Sleep(delay)
+		delay = min(delay*2, maxRetryDelay)
+	}
+	return err
+}
+
+// RedisMonitor tracks whether Redis is reachable. While it isn't, the API
+// runs read-only: reads fall back to the database, and writes are refused
+// because their cache invalidations would be lost.
+type RedisMonitor struct {
+	client   *redis.Client
+	readOnly atomic.Bool
+}
+
+func NewRedisMonitor(client *redis.Client, available bool) *RedisMonitor {
+	m := &RedisMonitor{client: client}
+	m.readOnly.Store(!available)
+	return m
+}
+
+// ReadOnly reports whether the API is in degraded read-only mode.
+func (m *RedisMonitor) ReadOnly() bool {
+	return m.readOnly.Load()
+}
+
+// Watch pings Redis at the given interval and switches read-only mode on
+// and off. On recovery the recipe caches are flushed, since invalidations
+// may have been lost while Redis was unreachable.
+func (m *RedisMonitor) Watch(interval time.Duration) {
+	for range time.Tick(interval) {
+		err := m.client.Ping().Err()
+		switch {
+		case err != nil && !m.readOnly.Load():
+			slog.Error("Redis unreachable, switching to read-only mode", "error", err)
+			m.readOnly.Store(true)
+		case err == nil && m.readOnly.Load():
+			slog.Info("Redis is back, leaving read-only mode")
+			cache.FlushRecipes(context.Background(), m.client)
+			m.readOnly.Store(false)
+		}
+	}
+}
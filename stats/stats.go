@@ -0,0 +1,173 @@
+// Package stats sums up the catalog for admins: how many recipes there are
+// and in what state, how they are tagged, how fast they are added, which
+// are read most and how much storage their photos take.
+package stats
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"recipes-api/cache"
+	"recipes-api/models"
+	"recipes-api/service"
+	"recipes-api/tags"
+	"recipes-api/tracing"
+
+	"github.com/go-redis/redis"
+	"gorm.io/gorm"
+)
+
+const (
+	// Days is how many days, up to today, recipes added are counted for.
+	Days = 30
+	// topViewed is how many of the most read recipes are listed.
+	topViewed = 10
+)
+
+type Stats struct {
+	GeneratedAt time.Time    `json:"generatedAt"`
+	Recipes     Totals       `json:"recipes"`
+	Tags        []tags.Count `json:"tags"`
+	AddedPerDay []DayCount   `json:"addedPerDay"`
+	TopViewed   []Viewed     `json:"topViewed"`
+	Storage     Storage      `json:"storage"`
+}
+
+// Totals counts recipes by state. Published, Drafts and Scheduled add up
+// to Total; trashed recipes are counted apart.
+type Totals struct {
+	Total     int64 `json:"total"`
+	Published int64 `json:"published"`
+	Drafts    int64 `json:"drafts"`
+	Scheduled int64 `json:"scheduled"`
+	Trashed   int64 `json:"trashed"`
+}
+
+// DayCount is how many recipes were published on a UTC day.
+type DayCount struct {
+	Day   string `json:"day"`
+	Count int64  `json:"count"`
+}
+
+// Viewed is a recipe and how many times it has been read, as of the last
+// time views were flushed.
+type Viewed struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Views int64  `json:"views"`
+}
+
+// Storage is what the recipes' photos take up in storage. Only originals
+// are counted; the resized variants are small next to them.
+type Storage struct {
+	Images int64 `json:"images"`
+	Bytes  int64 `json:"bytes"`
+}
+
+type Service struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+	ttl         time.Duration
+}
+
+// NewService creates the service. Stats are cached for ttl and not
+// dropped when recipes change, as they are only a summary.
+func NewService(db *gorm.DB, redisClient *redis.Client, ttl time.Duration) *Service {
+	return &Service{db: db, redisClient: redisClient, ttl: ttl}
+}
+
+// Get returns the cached stats, or computes them when they aren't cached
+// or refresh is set.
+func (s *Service) Get(ctx context.Context, refresh bool) (Stats, error) {
+	if !refresh {
+		cached, err := tracing.Redis(ctx, s.redisClient).Get(cache.StatsKey).Result()
+		if err == nil {
+			var stats Stats
+			if json.Unmarshal([]byte(cached), &stats) == nil {
+				return stats, nil
+			}
+		}
+	}
+
+	stats, err := s.compute(ctx)
+	if err != nil {
+		return Stats{}, err
+	}
+
+	data, _ := json.Marshal(stats)
+	tracing.Redis(ctx, s.redisClient).Set(cache.StatsKey, data, s.ttl)
+	return stats, nil
+}
+
+// compute runs the aggregate queries, on a replica as they scan whole
+// tables.
+func (s *Service) compute(ctx context.Context) (Stats, error) {
+	db := service.ReadReplica(s.db.WithContext(ctx))
+	now := time.Now().UTC()
+	stats := Stats{GeneratedAt: now, Tags: []tags.Count{}, TopViewed: []Viewed{}}
+
+	err := db.Model(&models.Recipe{}).Select(
+		"COUNT(*) AS total, "+
+			"COUNT(*) FILTER (WHERE NOT draft AND published_at <= ?) AS published, "+
+			"COUNT(*) FILTER (WHERE draft) AS drafts, "+
+			"COUNT(*) FILTER (WHERE NOT draft AND published_at > ?) AS scheduled", now, now).
+		Scan(&stats.Recipes).Error
+	if err != nil {
+		return Stats{}, err
+	}
+	if err := db.Model(&models.Recipe{}).Unscoped().Where("deleted_at IS NOT NULL").Count(&stats.Recipes.Trashed).Error; err != nil {
+		return Stats{}, err
+	}
+
+	err = db.Table("recipe_tags").
+		Joins("JOIN tags ON tags.id = recipe_tags.tag_id").
+		Joins("JOIN recipes ON recipes.id = recipe_tags.recipe_id AND recipes.deleted_at IS NULL").
+		Select("tags.label AS tag, COUNT(*) AS count").Group("tags.id").Order("count DESC, tags.name").
+		Scan(&stats.Tags).Error
+	if err != nil {
+		return Stats{}, err
+	}
+
+	today := now.Truncate(24 * time.Hour)
+	from := today.AddDate(0, 0, -(Days - 1))
+	var days []struct {
+		Day   time.Time
+		Count int64
+	}
+	err = db.Model(&models.Recipe{}).
+		Where("published_at >= ? AND published_at <= ?", from, now).
+		Select("date_trunc('day', published_at AT TIME ZONE 'UTC') AS day, COUNT(*) AS count").
+		Group("day").Scan(&days).Error
+	if err != nil {
+		return Stats{}, err
+	}
+	perDay := map[string]int64{}
+	for _, d := range days {
+		perDay[d.Day.Format(time.DateOnly)] = d.Count
+	}
+	// days nothing was added on are listed too, so the series has no gaps
+	for day := from; !day.After(today); day = day.AddDate(0, 0, 1) {
+		key := day.Format(time.DateOnly)
+		stats.AddedPerDay = append(stats.AddedPerDay, DayCount{Day: key, Count: perDay[key]})
+	}
+
+	err = db.Table("recipe_views").
+		Joins("JOIN recipes ON recipes.id = recipe_views.recipe_id AND recipes.deleted_at IS NULL").
+		Select("recipes.id, recipes.name, recipe_views.views").
+		Order("recipe_views.views DESC, recipes.id").Limit(topViewed).
+		Scan(&stats.TopViewed).Error
+	if err != nil {
+		return Stats{}, err
+	}
+
+	// the image column holds the photo's metadata as JSON text
+	err = db.Model(&models.Recipe{}).Unscoped().Where("image IS NOT NULL").
+		Select("COUNT(*) AS images, COALESCE(SUM((image::jsonb->>'size')::bigint), 0) AS bytes").
+		Scan(&stats.Storage).Error
+	if err != nil {
+		return Stats{}, err
+	}
+
+	return stats, nil
+}
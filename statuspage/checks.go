@@ -0,0 +1,66 @@
+package statuspage
+
+import (
+	"context"
+	"fmt"
+
+	"recipes-api/search"
+	"recipes-api/storage"
+	"recipes-api/tracing"
+
+	"github.com/go-redis/redis"
+	"gorm.io/gorm"
+)
+
+// queueFullAt is how full, in percent, a job queue may get before the jobs
+// count as degraded: past it, new jobs may soon be dropped.
+const queueFullAt = 90
+
+// Queue returns how many jobs a background queue holds and how many fit.
+type Queue func() (queued, capacity int)
+
+func PingDB(db *gorm.DB) Check {
+	return func(ctx context.Context) error {
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}
+}
+
+func PingRedis(client *redis.Client) Check {
+	return func(ctx context.Context) error {
+		return tracing.Redis(ctx, client).Ping().Err()
+	}
+}
+
+// SearchIndex checks an indexed search backend with a query for one
+// recipe.
+func SearchIndex(searcher search.Searcher) Check {
+	return func(ctx context.Context) error {
+		_, err := searcher.Query(ctx, search.Query{Limit: 1})
+		return err
+	}
+}
+
+// Storage checks that the store can be listed.
+func Storage(store storage.Store) Check {
+	return func(ctx context.Context) error {
+		_, err := store.List(ctx, "status-check/")
+		return err
+	}
+}
+
+// Queues checks that none of the job queues is nearly full.
+func Queues(queues map[string]Queue) Check {
+	return func(ctx context.Context) error {
+		for name, queue := range queues {
+			queued, capacity := queue()
+			if capacity > 0 && queued*100 >= capacity*queueFullAt {
+				return fmt.Errorf("%w: %s queue holds %d of %d jobs", ErrDegraded, name, queued, capacity)
+			}
+		}
+		return nil
+	}
+}
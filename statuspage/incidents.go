@@ -0,0 +1,92 @@
+package statuspage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"recipes-api/models"
+
+	"github.com/rs/xid"
+	"gorm.io/gorm"
+)
+
+// Impacts of an incident.
+const (
+	ImpactMinor    = "minor"
+	ImpactMajor    = "major"
+	ImpactCritical = "critical"
+)
+
+// Statuses of an incident.
+const (
+	Investigating = "investigating"
+	Identified    = "identified"
+	Monitoring    = "monitoring"
+	Resolved      = "resolved"
+)
+
+var ErrIncidentNotFound = errors.New("incident not found")
+
+// CreateIncident records a new incident, as investigated unless it says
+// otherwise.
+func (m *Monitor) CreateIncident(ctx context.Context, incident models.Incident) (models.Incident, error) {
+	incident.ID = xid.New().String()
+	if incident.Status == "" {
+		incident.Status = Investigating
+	}
+	if incident.Components == nil {
+		incident.Components = []string{}
+	}
+	stampResolved(&incident, nil)
+	return incident, m.db.WithContext(ctx).Create(&incident).Error
+}
+
+// UpdateIncident changes an incident. Resolving it records when; reopening
+// it clears that again.
+func (m *Monitor) UpdateIncident(ctx context.Context, id string, apply func(*models.Incident)) (models.Incident, error) {
+	var incident models.Incident
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ?", id).Take(&incident).Error; err != nil {
+			return err
+		}
+		resolvedAt := incident.ResolvedAt
+		apply(&incident)
+		incident.ID = id
+		if incident.Components == nil {
+			incident.Components = []string{}
+		}
+		stampResolved(&incident, resolvedAt)
+		return tx.Save(&incident).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return incident, ErrIncidentNotFound
+	}
+	return incident, err
+}
+
+// stampResolved keeps ResolvedAt, previously resolvedAt, in step with the
+// status.
+func stampResolved(incident *models.Incident, resolvedAt *time.Time) {
+	incident.ResolvedAt = nil
+	if incident.Status != Resolved {
+		return
+	}
+	if resolvedAt == nil {
+		now := time.Now().UTC()
+		resolvedAt = &now
+	}
+	incident.ResolvedAt = resolvedAt
+}
+
+// recentIncidents returns the unresolved incidents and those resolved in
+// the last incidentDays, newest first.
+func (m *Monitor) recentIncidents(ctx context.Context, now time.Time) ([]models.Incident, error) {
+	incidents := []models.Incident{}
+	err := m.db.WithContext(ctx).
+		Where("resolved_at IS NULL OR resolved_at > ?", now.AddDate(0, 0, -incidentDays)).
+		Order("created_at DESC").
+		Limit(maxIncidents).
+		Find(&incidents).Error
+	return incidents, err
+}
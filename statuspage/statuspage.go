@@ -0,0 +1,253 @@
+// Package statuspage checks the components the API depends on at an
+// interval and keeps their daily uptime, and records the incidents admins
+// report, for a public status page.
+package statuspage
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"maps"
+	"sync"
+	"time"
+
+	"recipes-api/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Components checked.
+const (
+	ComponentDB      = "db"
+	ComponentRedis   = "redis"
+	ComponentSearch  = "search"
+	ComponentJobs    = "jobs"
+	ComponentStorage = "storage"
+)
+
+// Statuses of a component.
+const (
+	Up       = "up"
+	Degraded = "degraded"
+	Down     = "down"
+)
+
+// Overall statuses.
+const (
+	Operational = "operational"
+	// PartialOutage means some component is degraded, Outage that one is
+	// down.
+	PartialOutage = "partial_outage"
+	Outage        = "outage"
+)
+
+const (
+	checkTimeout = 5 * time.Second
+	// retention is how many days of samples are kept, enough for the
+	// longest uptime window.
+	retention = 90
+	// incidentDays is how far back resolved incidents are shown.
+	incidentDays = 30
+	maxIncidents = 50
+)
+
+// uptimeWindows are the periods uptime is reported for, in UTC days
+// including today.
+var uptimeWindows = []struct {
+	name string
+	days int
+}{{"1d", 1}, {"7d", 7}, {"30d", 30}, {"90d", 90}}
+
+// ErrDegraded is wrapped by checks that find a component working, but
+// impaired.
+var ErrDegraded = errors.New("degraded")
+
+// Check reports whether a component works.
+type Check func(ctx context.Context) error
+
+type Component struct {
+	Status    string `json:"status"`
+	LatencyMS int64  `json:"latencyMs"`
+	// Uptime is the percentage of checks, per window, that found the
+	// component up or degraded. It is missing while the database is down.
+	Uptime map[string]float64 `json:"uptime,omitempty"`
+}
+
+type Report struct {
+	Status     string               `json:"status"`
+	CheckedAt  time.Time            `json:"checkedAt"`
+	Components map[string]Component `json:"components"`
+	// Incidents are the unresolved ones and those resolved recently,
+	// newest first.
+	Incidents []models.Incident `json:"incidents"`
+}
+
+// Monitor runs the checks and remembers the latest results. Checks' error
+// messages are logged, not reported, as the report is public.
+type Monitor struct {
+	db     *gorm.DB
+	checks map[string]Check
+
+	mu        sync.RWMutex
+	latest    map[string]Component
+	checkedAt time.Time
+}
+
+func NewMonitor(db *gorm.DB, checks map[string]Check) *Monitor {
+	return &Monitor{db: db, checks: checks}
+}
+
+// Run checks the components every interval, starting now. It never
+// returns.
+func (m *Monitor) Run(interval time.Duration) {
+	for {
+		m.checkAll(context.Background())
+		time.Sleep(interval)
+	}
+}
+
+// checkAll runs the checks at once, records the results as samples and
+// keeps them as the latest.
+func (m *Monitor) checkAll(ctx context.Context) map[string]Component {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := map[string]Component{}
+	for name, check := range m.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			err := check(ctx)
+
+			component := Component{Status: Up, LatencyMS: time.Since(start).Milliseconds()}
+			if err != nil {
+				component.Status = Down
+				if errors.Is(err, ErrDegraded) {
+					component.Status = Degraded
+				}
+				slog.WarnContext(ctx, "Status check failed", "component", name, "status", component.Status, "error", err)
+			}
+			mu.Lock()
+			results[name] = component
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	now := time.Now().UTC()
+	if err := m.record(context.WithoutCancel(ctx), now, results); err != nil {
+		slog.ErrorContext(ctx, "Error recording status samples", "error", err)
+	}
+
+	m.mu.Lock()
+	m.latest, m.checkedAt = results, now
+	m.mu.Unlock()
+	return results
+}
+
+// record adds the results to today's samples and drops the samples too old
+// to be reported.
+func (m *Monitor) record(ctx context.Context, now time.Time, results map[string]Component) error {
+	db := m.db.WithContext(ctx)
+	day := now.Truncate(24 * time.Hour)
+	for name, component := range results {
+		up := 0
+		if component.Status != Down {
+			up = 1
+		}
+		sample := models.StatusSample{Component: name, Day: day, Up: up, Total: 1}
+		err := db.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "component"}, {Name: "day"}},
+			DoUpdates: clause.Assignments(map[string]any{
+				"up":    gorm.Expr("status_samples.up + ?", up),
+				"total": gorm.Expr("status_samples.total + 1"),
+			}),
+		}).Create(&sample).Error
+		if err != nil {
+			return err
+		}
+	}
+	return db.Where("day < ?", day.AddDate(0, 0, -retention)).Delete(&models.StatusSample{}).Error
+}
+
+// Report returns the latest results with uptime and recent incidents. The
+// components are checked first if they haven't been yet. Uptime and
+// incidents are left out when they can't be loaded, since the report
+// matters most when something is down.
+func (m *Monitor) Report(ctx context.Context) Report {
+	m.mu.RLock()
+	latest, checkedAt := maps.Clone(m.latest), m.checkedAt
+	m.mu.RUnlock()
+	if latest == nil {
+		latest = m.checkAll(ctx)
+		checkedAt = time.Now().UTC()
+	}
+
+	report := Report{Status: Operational, CheckedAt: checkedAt, Components: latest, Incidents: []models.Incident{}}
+	for _, component := range latest {
+		switch {
+		case component.Status == Down:
+			report.Status = Outage
+		case component.Status == Degraded && report.Status == Operational:
+			report.Status = PartialOutage
+		}
+	}
+
+	uptime, err := m.uptime(ctx, checkedAt)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error loading uptime", "error", err)
+	}
+	for name, component := range report.Components {
+		component.Uptime = uptime[name]
+		report.Components[name] = component
+	}
+
+	incidents, err := m.recentIncidents(ctx, checkedAt)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error loading incidents", "error", err)
+	} else {
+		report.Incidents = incidents
+	}
+	return report
+}
+
+// uptime returns the uptime percentages per component and window.
+func (m *Monitor) uptime(ctx context.Context, now time.Time) (map[string]map[string]float64, error) {
+	today := now.Truncate(24 * time.Hour)
+	var samples []models.StatusSample
+	err := m.db.WithContext(ctx).Where("day > ?", today.AddDate(0, 0, -retention)).Find(&samples).Error
+	if err != nil {
+		return nil, err
+	}
+
+	type counts struct{ up, total int }
+	sums := map[string][]counts{}
+	for _, sample := range samples {
+		if sums[sample.Component] == nil {
+			sums[sample.Component] = make([]counts, len(uptimeWindows))
+		}
+		age := int(today.Sub(sample.Day.UTC().Truncate(24*time.Hour)) / (24 * time.Hour))
+		for i, window := range uptimeWindows {
+			if age < window.days {
+				sums[sample.Component][i].up += sample.Up
+				sums[sample.Component][i].total += sample.Total
+			}
+		}
+	}
+
+	uptime := map[string]map[string]float64{}
+	for name, windows := range sums {
+		uptime[name] = map[string]float64{}
+		for i, c := range windows {
+			if c.total > 0 {
+				// two decimals, as status pages show them
+				uptime[name][uptimeWindows[i].name] = float64(c.up*10000/c.total) / 100
+			}
+		}
+	}
+	return uptime, nil
+}
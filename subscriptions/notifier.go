@@ -0,0 +1,130 @@
+// Package subscriptions emails subscribers when a recipe they follow is
+// updated. Rapid edits are collected into one digest per recipe.
+package subscriptions
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"recipes-api/email"
+	"recipes-api/events"
+	"recipes-api/models"
+
+	"gorm.io/gorm"
+)
+
+// digest is the pending changes of one recipe.
+type digest struct {
+	recipe  models.Recipe
+	changes map[string]events.Change
+}
+
+// Notifier collects recipe updates for Window after the first one and
+// then sends each subscriber a single email with the net changes to the
+// fields they follow.
+type Notifier struct {
+	db     *gorm.DB
+	sender *email.Sender
+	window time.Duration
+
+	mu      sync.Mutex
+	pending map[string]*digest
+}
+
+func NewNotifier(db *gorm.DB, sender *email.Sender, window time.Duration) *Notifier {
+	return &Notifier{db: db, sender: sender, window: window, pending: map[string]*digest{}}
+}
+
+// Handle adds an update to the recipe's digest. It is meant to be
+// subscribed to the bus.
+func (n *Notifier) Handle(e events.Event) {
+	if e.Type != events.RecipeUpdated || len(e.Changes) == 0 || !n.sender.Enabled() {
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	d, ok := n.pending[e.Recipe.ID]
+	if !ok {
+		d = &digest{changes: map[string]events.Change{}}
+		n.pending[e.Recipe.ID] = d
+		id := e.Recipe.ID
+		time.AfterFunc(n.window, func() { n.flush(id) })
+	}
+
+	d.recipe = e.Recipe
+	for field, change := range e.Changes {
+		// keep the value from before the first edit in the window
+		if previous, ok := d.changes[field]; ok {
+			change.Old = previous.Old
+		}
+		d.changes[field] = change
+	}
+}
+
+func (n *Notifier) flush(recipeID string) {
+	n.mu.Lock()
+	d := n.pending[recipeID]
+	delete(n.pending, recipeID)
+	n.mu.Unlock()
+
+	// edits that were undone within the window cancel out
+	for field, change := range d.changes {
+		if reflect.DeepEqual(change.Old, change.New) {
+			delete(d.changes, field)
+		}
+	}
+	if len(d.changes) == 0 {
+		return
+	}
+
+	var subs []models.RecipeSubscription
+	if err := n.db.Where("recipe_id = ?", recipeID).Find(&subs).Error; err != nil {
+		slog.Error("Error loading subscriptions", "recipe_id", recipeID, "error", err)
+		return
+	}
+
+	for _, sub := range subs {
+		var fields []string
+		for field := range d.changes {
+			if sub.Wants(field) {
+				fields = append(fields, field)
+			}
+		}
+		if len(fields) == 0 {
+			continue
+		}
+		slices.Sort(fields)
+
+		subject := fmt.Sprintf("%q was updated", d.recipe.Name)
+		if err := n.sender.Send(sub.Email, subject, body(d, fields)); err != nil {
+			slog.Error("Error notifying subscriber", "subscription_id", sub.ID, "error", err)
+		}
+	}
+}
+
+func body(d *digest, fields []string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "The recipe %q has changed:\n\n", d.recipe.Name)
+	for _, field := range fields {
+		change := d.changes[field]
+		fmt.Fprintf(&b, "%s\n  before: %s\n  after:  %s\n\n", field, format(change.Old), format(change.New))
+	}
+	b.WriteString("You are receiving this because you subscribed to updates of this recipe.\n")
+	return b.String()
+}
+
+func format(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
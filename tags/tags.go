@@ -0,0 +1,86 @@
+// Package tags counts the tags of published recipes, for tag clouds and
+// autocompletion.
+package tags
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"time"
+
+	"recipes-api/cache"
+	"recipes-api/service"
+	"recipes-api/tracing"
+
+	"github.com/go-redis/redis"
+	"gorm.io/gorm"
+)
+
+// Count is a tag and how many published recipes have it. Spellings
+// differing in case are counted as one tag, shown as first spelled.
+type Count struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+type Service struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+	ttl         time.Duration
+}
+
+// NewService creates the service. Counts are cached for ttl, and dropped
+// sooner when recipes change.
+func NewService(db *gorm.DB, redisClient *redis.Client, ttl time.Duration) *Service {
+	return &Service{db: db, redisClient: redisClient, ttl: ttl}
+}
+
+// Counts returns every tag in use, by name.
+func (s *Service) Counts(ctx context.Context) ([]Count, error) {
+	cached, err := tracing.Redis(ctx, s.redisClient).Get(cache.TagsKey).Result()
+	if err == nil {
+		var counts []Count
+		if json.Unmarshal([]byte(cached), &counts) == nil {
+			return counts, nil
+		}
+	}
+
+	counts := []Count{}
+	err = service.Published(service.ReadReplica(s.db.WithContext(ctx))).Table("recipe_tags").
+		Joins("JOIN tags ON tags.id = recipe_tags.tag_id").
+		Joins("JOIN recipes_list ON recipes_list.id = recipe_tags.recipe_id").
+		Select("tags.label AS tag, COUNT(*) AS count").Group("tags.id").Order("tags.name").Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	data, _ := json.Marshal(counts)
+	tracing.Redis(ctx, s.redisClient).Set(cache.TagsKey, data, s.ttl)
+	return counts, nil
+}
+
+// Search returns the tags starting with prefix, ignoring case, by name.
+func (s *Service) Search(ctx context.Context, prefix string) ([]Count, error) {
+	counts, err := s.Counts(ctx)
+	if err != nil || prefix == "" {
+		return counts, err
+	}
+	prefix = strings.ToLower(prefix)
+	return slices.DeleteFunc(counts, func(c Count) bool {
+		return !strings.HasPrefix(strings.ToLower(c.Tag), prefix)
+	}), nil
+}
+
+// Popular returns the limit most used tags, most used first.
+func (s *Service) Popular(ctx context.Context, limit int) ([]Count, error) {
+	counts, err := s.Counts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(counts, func(a, b Count) int {
+		return cmp.Compare(b.Count, a.Count)
+	})
+	return counts[:min(limit, len(counts))], nil
+}
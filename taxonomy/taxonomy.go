@@ -0,0 +1,138 @@
+// Package taxonomy manages the cuisines and categories recipes are filed
+// under.
+package taxonomy
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"recipes-api/models"
+
+	"github.com/rs/xid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNotFound  = errors.New("term not found")
+	ErrNameTaken = errors.New("term name already taken")
+	ErrInUse     = errors.New("term is in use")
+)
+
+// Kind is a kind of term: the table its terms are kept in and the recipes
+// column linking to them.
+type Kind struct {
+	Table  string
+	Column string
+}
+
+var (
+	Cuisines   = Kind{Table: "cuisines", Column: "cuisine_id"}
+	Categories = Kind{Table: "categories", Column: "category_id"}
+)
+
+type Service struct {
+	db   *gorm.DB
+	kind Kind
+}
+
+func NewService(db *gorm.DB, kind Kind) *Service {
+	return &Service{db: db, kind: kind}
+}
+
+// List returns the terms by name.
+func (s *Service) List(ctx context.Context) ([]models.Term, error) {
+	terms := []models.Term{}
+	err := s.db.WithContext(ctx).Table(s.kind.Table).Order("name").Find(&terms).Error
+	return terms, err
+}
+
+func (s *Service) Get(ctx context.Context, id string) (models.Term, error) {
+	var term models.Term
+	err := s.db.WithContext(ctx).Table(s.kind.Table).Where("id = ?", id).First(&term).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return term, ErrNotFound
+	}
+	return term, err
+}
+
+// Find returns the terms with the ids. Ids without a term are left out.
+func (s *Service) Find(ctx context.Context, ids []string) ([]models.Term, error) {
+	terms := []models.Term{}
+	if len(ids) == 0 {
+		return terms, nil
+	}
+	err := s.db.WithContext(ctx).Table(s.kind.Table).Where("id IN ?", ids).Order("name").Find(&terms).Error
+	return terms, err
+}
+
+func (s *Service) Create(ctx context.Context, name, description string) (models.Term, error) {
+	now := time.Now().UTC()
+	term := models.Term{ID: xid.New().String(), Name: name, Description: description, CreatedAt: now, UpdatedAt: now}
+	if err := s.checkName(ctx, term); err != nil {
+		return models.Term{}, err
+	}
+	if err := s.db.WithContext(ctx).Table(s.kind.Table).Create(&term).Error; err != nil {
+		return models.Term{}, err
+	}
+	return term, nil
+}
+
+// Update renames a term and replaces its description. Recipes keep
+// linking to it.
+func (s *Service) Update(ctx context.Context, id, name, description string) (models.Term, error) {
+	term, err := s.Get(ctx, id)
+	if err != nil {
+		return term, err
+	}
+	term.Name, term.Description, term.UpdatedAt = name, description, time.Now().UTC()
+	if err := s.checkName(ctx, term); err != nil {
+		return models.Term{}, err
+	}
+	if err := s.db.WithContext(ctx).Table(s.kind.Table).Save(&term).Error; err != nil {
+		return models.Term{}, err
+	}
+	return term, nil
+}
+
+// Delete deletes a term, or returns ErrInUse with the number of recipes
+// still filed under it. Recipes in the trash count too, as they can be
+// restored.
+func (s *Service) Delete(ctx context.Context, id string) (int64, error) {
+	db := s.db.WithContext(ctx)
+	if _, err := s.Get(ctx, id); err != nil {
+		return 0, err
+	}
+	var count int64
+	if err := db.Unscoped().Model(&models.Recipe{}).Where(s.kind.Column+" = ?", id).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	if count > 0 {
+		return count, ErrInUse
+	}
+	return 0, db.Table(s.kind.Table).Where("id = ?", id).Delete(&models.Term{}).Error
+}
+
+// Exists reports whether there is a term with the id.
+func (s *Service) Exists(ctx context.Context, id string) (bool, error) {
+	return Exists(s.db.WithContext(ctx), s.kind, id)
+}
+
+// Exists reports whether db has a term of kind with the id.
+func Exists(db *gorm.DB, kind Kind, id string) (bool, error) {
+	var count int64
+	err := db.Table(kind.Table).Where("id = ?", id).Count(&count).Error
+	return count > 0, err
+}
+
+func (s *Service) checkName(ctx context.Context, term models.Term) error {
+	var count int64
+	err := s.db.WithContext(ctx).Table(s.kind.Table).Where("name = ? AND id <> ?", term.Name, term.ID).Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrNameTaken
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package thumbnails
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	_ "image/png"
+	"log/slog"
+	"path"
+	"strings"
+	"time"
+
+	"recipes-api/cache"
+	"recipes-api/events"
+	"recipes-api/models"
+	"recipes-api/storage"
+
+	"github.com/go-redis/redis"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Sizes maps each variant name to its maximum width in pixels.
+var Sizes = map[string]int{
+	"thumb":  150,
+	"medium": 600,
+	"large":  1200,
+}
+
+type job struct {
+	ctx      context.Context
+	recipeID string
+	key      string
+}
+
+// Service generates resized variants of uploaded recipe images in the background.
+type Service struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+	store       storage.Store
+	events      *events.Bus
+	queue       chan job
+}
+
+func NewService(db *gorm.DB, redisClient *redis.Client, store storage.Store, bus *events.Bus, workers int) *Service {
+	s := &Service{db: db, redisClient: redisClient, store: store, events: bus, queue: make(chan job, 100)}
+
+	if store != nil {
+		for i := 0; i < workers; i++ {
+			go s.work()
+		}
+	}
+
+	return s
+}
+
+// Enqueue schedules variant generation for the image stored under key. The
+// job keeps the request IDs from ctx but not its cancellation.
+func (s *Service) Enqueue(ctx context.Context, recipeID, key string) {
+	if s.store == nil {
+		return
+	}
+
+	select {
+	case s.queue <- job{ctx: context.WithoutCancel(ctx), recipeID: recipeID, key: key}:
+	default:
+		slog.WarnContext(ctx, "Thumbnail queue full, skipping image", "key", key)
+	}
+}
+
+// Backlog returns how many images are queued and how many fit.
+func (s *Service) Backlog() (queued, capacity int) {
+	return len(s.queue), cap(s.queue)
+}
+
+func (s *Service) work() {
+	for j := range s.queue {
+		if err := s.process(j); err != nil {
+			slog.ErrorContext(j.ctx, "Error generating thumbnails", "key", j.key, "error", err)
+		}
+	}
+}
+
+func (s *Service) process(j job) error {
+	ctx, cancel := context.WithTimeout(j.ctx, 2*time.Minute)
+	defer cancel()
+
+	obj, err := s.store.Get(ctx, j.key)
+	if err != nil {
+		return err
+	}
+	src, _, err := image.Decode(obj)
+	obj.Close()
+	if err != nil {
+		return err
+	}
+
+	base := strings.TrimSuffix(j.key, path.Ext(j.key))
+	variants := map[string]models.ImageVariant{}
+
+	for name, width := range Sizes {
+		resized := resize(src, width)
+
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, resized, &jpeg.Options{Quality: 85}); err != nil {
+			return err
+		}
+
+		key := fmt.Sprintf("%s_%s.jpg", base, name)
+		url, err := s.store.Put(ctx, key, &buf, "image/jpeg")
+		if err != nil {
+			return err
+		}
+
+		bounds := resized.Bounds()
+		variants[name] = models.ImageVariant{Key: key, URL: url, Width: bounds.Dx(), Height: bounds.Dy()}
+	}
+
+	stale := false
+	var before, after models.Recipe
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var recipe models.Recipe
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", j.recipeID).First(&recipe).Error; err != nil {
+			return err
+		}
+
+		// the image was replaced or removed while we were resizing
+		if recipe.Image == nil || recipe.Image.Key != j.key {
+			stale = true
+			return nil
+		}
+
+		before = recipe
+		image := *recipe.Image
+		image.Variants = variants
+		recipe.Image = &image
+		after = recipe
+		return tx.Model(&recipe).Select("image").Updates(models.Recipe{Image: recipe.Image}).Error
+	})
+
+	if err != nil || stale {
+		for _, v := range variants {
+			s.store.Delete(ctx, v.Key)
+		}
+		return err
+	}
+
+	cache.InvalidateRecipes(ctx, s.redisClient, j.recipeID)
+
+	event := events.NewEvent(events.RecipeUpdated, after)
+	event.Changes = events.Diff(before, after)
+	s.events.Publish(ctx, event)
+	return nil
+}
+
+// resize scales src down to the given width keeping its aspect ratio.
+// Images that are already narrower are left at their original size.
+func resize(src image.Image, width int) image.Image {
+	bounds := src.Bounds()
+	if bounds.Dx() <= width {
+		return src
+	}
+
+	height := bounds.Dy() * width / bounds.Dx()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	// JPEG has no alpha channel, so flatten transparent images onto white
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
+
+	return dst
+}
@@ -0,0 +1,84 @@
+// Package timing estimates how long a recipe takes when its author didn't
+// say, from the durations mentioned in its steps.
+package timing
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// minutes of prep assumed per ingredient for measuring and chopping
+	prepPerIngredient = 2
+	// minutes assumed for a step that doesn't mention a duration
+	untimedStep = 3
+)
+
+var (
+	// "25 minutes", "1.5 hrs", "20-25 mins", "10 to 12 minutes"
+	durationPattern = regexp.MustCompile(`(?i)(\d+(?:[.,]\d+)?)(?:\s*(?:-|–|to)\s*(\d+(?:[.,]\d+)?))?\s*(hours?|hrs?|minutes?|mins?|seconds?|secs?)\b`)
+	phrasePattern   = regexp.MustCompile(`(?i)\b(half an hour|an hour|overnight)\b`)
+)
+
+var phraseMinutes = map[string]float64{
+	"half an hour": 30,
+	"an hour":      60,
+	"overnight":    8 * 60,
+}
+
+// StepMinutes returns the total duration mentioned in a step, using the
+// upper bound of ranges, and whether it mentions one at all.
+func StepMinutes(step string) (float64, bool) {
+	total, found := 0.0, false
+
+	for _, m := range durationPattern.FindAllStringSubmatch(step, -1) {
+		value := parseNumber(m[1])
+		if m[2] != "" {
+			value = max(value, parseNumber(m[2]))
+		}
+
+		unit := strings.ToLower(m[3])
+		switch {
+		case strings.HasPrefix(unit, "h"):
+			value *= 60
+		case strings.HasPrefix(unit, "s"):
+			value /= 60
+		}
+		total += value
+		found = true
+	}
+
+	// "half an hour" also contains "an hour", so phrases don't overlap
+	for _, m := range phrasePattern.FindAllString(step, -1) {
+		total += phraseMinutes[strings.ToLower(m)]
+		found = true
+	}
+
+	return total, found
+}
+
+// Estimate returns the total time of a recipe in minutes: the durations in
+// its steps plus prep time for each ingredient and untimed step, rounded
+// up to 5 minutes. It returns 0 for a recipe without ingredients or steps.
+func Estimate(ingredients, instructions []string) int {
+	minutes := float64(prepPerIngredient * len(ingredients))
+	for _, step := range instructions {
+		if d, ok := StepMinutes(step); ok {
+			minutes += d
+		} else {
+			minutes += untimedStep
+		}
+	}
+
+	if minutes == 0 {
+		return 0
+	}
+	return int(math.Ceil(minutes/5) * 5)
+}
+
+func parseNumber(s string) float64 {
+	f, _ := strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64)
+	return f
+}
@@ -0,0 +1,112 @@
+// Package tracing sets up OpenTelemetry. Spans are exported over OTLP/HTTP
+// when OTEL_EXPORTER_OTLP_ENDPOINT or OTEL_EXPORTER_OTLP_TRACES_ENDPOINT is
+// set; the other standard OTEL_* variables (headers, sampler, service name)
+// apply as usual. Without an endpoint W3C trace context is still
+// propagated, but nothing is recorded.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/go-redis/redis"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+	gormtracing "gorm.io/plugin/opentelemetry/tracing"
+)
+
+const ServiceName = "recipes-api"
+
+var tracer = otel.Tracer(ServiceName)
+
+// Setup installs the propagator and, when an endpoint is configured, the
+// OTLP exporter. Spans are tagged with region, if not empty. The returned
+// function flushes pending spans.
+func Setup(ctx context.Context, region string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+	attrs := []attribute.KeyValue{attribute.String("service.name", ServiceName)}
+	if region != "" {
+		attrs = append(attrs, attribute.String("cloud.region", region))
+	}
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attrs...),
+		// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES win over the default name
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// InstrumentGORM records a span for every query.
+func InstrumentGORM(db *gorm.DB) error {
+	return db.Use(gormtracing.NewPlugin(gormtracing.WithoutMetrics(), gormtracing.WithoutQueryVariables()))
+}
+
+// Redis returns client bound to ctx with every command and pipeline
+// recorded as a span. go-redis v6 hooks can't see the context of the
+// client they run on, so this takes the place of client.WithContext(ctx).
+func Redis(ctx context.Context, client *redis.Client) *redis.Client {
+	c := client.WithContext(ctx)
+	if !trace.SpanFromContext(ctx).IsRecording() {
+		return c
+	}
+
+	c.WrapProcess(func(next func(redis.Cmder) error) func(redis.Cmder) error {
+		return func(cmd redis.Cmder) error {
+			_, span := startRedisSpan(ctx, cmd.Name(), 1)
+			err := next(cmd)
+			endRedisSpan(span, err)
+			return err
+		}
+	})
+	c.WrapProcessPipeline(func(next func([]redis.Cmder) error) func([]redis.Cmder) error {
+		return func(cmds []redis.Cmder) error {
+			_, span := startRedisSpan(ctx, "pipeline", len(cmds))
+			err := next(cmds)
+			endRedisSpan(span, err)
+			return err
+		}
+	})
+	return c
+}
+
+func startRedisSpan(ctx context.Context, operation string, commands int) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "redis "+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system.name", "redis"),
+			attribute.String("db.operation.name", operation),
+			attribute.Int("db.operation.batch.size", commands),
+		))
+}
+
+func endRedisSpan(span trace.Span, err error) {
+	// a missing key is a normal cache miss, not an error
+	if err != nil && !errors.Is(err, redis.Nil) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,104 @@
+// Package translations keeps recipes' names, ingredients and steps in
+// other languages and picks the one a reader asked for.
+package translations
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"recipes-api/models"
+
+	"golang.org/x/text/language"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrNotFound = errors.New("translation not found")
+	// ErrOriginal is returned for a translation into the language recipes
+	// are written in.
+	ErrOriginal = errors.New("recipes are already written in this language")
+)
+
+// Service stores translations. Original is the language recipes are
+// written in; readers preferring it get the recipe as written.
+type Service struct {
+	db       *gorm.DB
+	original language.Tag
+}
+
+func NewService(db *gorm.DB, original language.Tag) *Service {
+	return &Service{db: db, original: original}
+}
+
+// Locale returns the canonical form of a BCP 47 language tag, such as
+// "sw" or "pt-BR".
+func Locale(tag string) (string, error) {
+	t, err := language.Parse(tag)
+	if err != nil {
+		return "", err
+	}
+	return t.String(), nil
+}
+
+// Put adds the translation or replaces the one into the same language.
+func (s *Service) Put(ctx context.Context, translation models.RecipeTranslation) (models.RecipeTranslation, error) {
+	tag, err := language.Parse(translation.Locale)
+	if err != nil {
+		return translation, err
+	}
+	if tag == s.original {
+		return translation, ErrOriginal
+	}
+	translation.Locale = tag.String()
+	translation.UpdatedAt = time.Now().UTC()
+
+	err = s.db.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(&translation).Error
+	return translation, err
+}
+
+// List returns the translations of a recipe by locale.
+func (s *Service) List(ctx context.Context, recipeID string) ([]models.RecipeTranslation, error) {
+	translations := []models.RecipeTranslation{}
+	err := s.db.WithContext(ctx).Where("recipe_id = ?", recipeID).Order("locale").Find(&translations).Error
+	return translations, err
+}
+
+func (s *Service) Delete(ctx context.Context, recipeID, locale string) error {
+	result := s.db.WithContext(ctx).Where("recipe_id = ? AND locale = ?", recipeID, locale).Delete(&models.RecipeTranslation{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Localize translates recipe into the best match of wanted, an
+// Accept-Language value or a single tag, among the original language and
+// the recipe's translations. It returns the language the recipe is in
+// afterwards, the original one when nothing better matches.
+func (s *Service) Localize(ctx context.Context, recipe *models.Recipe, wanted string) (language.Tag, error) {
+	tags, _, err := language.ParseAcceptLanguage(wanted)
+	if err != nil || len(tags) == 0 {
+		return s.original, nil
+	}
+
+	translations, err := s.List(ctx, recipe.ID)
+	if err != nil || len(translations) == 0 {
+		return s.original, err
+	}
+
+	supported := []language.Tag{s.original}
+	for _, translation := range translations {
+		supported = append(supported, language.Make(translation.Locale))
+	}
+	_, i, confidence := language.NewMatcher(supported).Match(tags...)
+	if i == 0 || confidence == language.No {
+		return s.original, nil
+	}
+	translations[i-1].Apply(recipe)
+	return supported[i], nil
+}
@@ -0,0 +1,203 @@
+// Package views counts the times recipes are read, in total and for
+// ranking what is trending.
+package views
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"recipes-api/models"
+	"recipes-api/tracing"
+
+	"github.com/go-redis/redis"
+	"github.com/rs/xid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	hourKeyPrefix     = "views:hour:"
+	trendingKeyPrefix = "views:trending:"
+	// pendingKey holds the views not flushed to Postgres yet, per recipe.
+	pendingKey = "views:pending"
+)
+
+// Windows are the periods trending recipes can be ranked over.
+var Windows = map[string]time.Duration{
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+}
+
+// longestWindow is how long hourly counts are kept.
+const longestWindow = 7 * 24 * time.Hour
+
+// trendingTTL is how long a ranking is reused before it is summed again.
+const trendingTTL = time.Minute
+
+// Entry is a recipe and how many times it was read in a window.
+type Entry struct {
+	RecipeID string
+	Views    int
+}
+
+// Tracker counts views in Redis, in a sorted set per hour, so rankings
+// over any window of whole hours are the sum of its sets and every
+// instance shares them. Totals are counted in Redis too and added to
+// recipe_views in Postgres from time to time.
+type Tracker struct {
+	db          *gorm.DB
+	redisClient *redis.Client
+}
+
+func NewTracker(db *gorm.DB, redisClient *redis.Client) *Tracker {
+	return &Tracker{db: db, redisClient: redisClient}
+}
+
+// Record counts a view of the recipe. Like other cache writes it is best
+// effort: views are lost while Redis is unreachable.
+func (t *Tracker) Record(ctx context.Context, recipeID string) {
+	key := hourKey(time.Now().UTC())
+	pipe := tracing.Redis(ctx, t.redisClient).TxPipeline()
+	defer pipe.Close()
+	pipe.ZIncrBy(key, 1, recipeID)
+	pipe.Expire(key, longestWindow+time.Hour)
+	pipe.HIncrBy(pendingKey, recipeID, 1)
+	pipe.Exec()
+}
+
+// Flushed returns how many times the recipe had been read when the views
+// were last flushed to Postgres.
+func (t *Tracker) Flushed(ctx context.Context, recipeID string) (int64, error) {
+	var row models.RecipeViews
+	err := t.db.WithContext(ctx).Where("recipe_id = ?", recipeID).Limit(1).Find(&row).Error
+	return row.Views, err
+}
+
+// Count returns how many times the recipe has been read: the views
+// flushed to Postgres and those still pending in Redis.
+func (t *Tracker) Count(ctx context.Context, recipeID string) (int64, error) {
+	flushed, err := t.Flushed(ctx, recipeID)
+	if err != nil {
+		return 0, err
+	}
+	pending, _ := tracing.Redis(ctx, t.redisClient).HGet(pendingKey, recipeID).Int64()
+	return flushed + pending, nil
+}
+
+// Since returns how many times the recipe was read in the window. The
+// hour in progress counts as a whole one.
+func (t *Tracker) Since(ctx context.Context, recipeID string, window time.Duration) (int64, error) {
+	pipe := tracing.Redis(ctx, t.redisClient).Pipeline()
+	defer pipe.Close()
+	var scores []*redis.FloatCmd
+	for _, key := range hourKeys(window) {
+		scores = append(scores, pipe.ZScore(key, recipeID))
+	}
+	if _, err := pipe.Exec(); err != nil && err != redis.Nil {
+		return 0, err
+	}
+	var views int64
+	for _, score := range scores {
+		views += int64(score.Val())
+	}
+	return views, nil
+}
+
+// RunFlush flushes the pending views every interval. It never returns.
+func (t *Tracker) RunFlush(interval time.Duration) {
+	for range time.Tick(interval) {
+		if err := t.Flush(context.Background()); err != nil {
+			slog.Error("Error flushing recipe views", "error", err)
+		}
+	}
+}
+
+// Flush adds the views pending in Redis to recipe_views. The pending
+// counts are moved aside first, so views recorded meanwhile are kept for
+// the next flush, and put back if Postgres can't be written.
+func (t *Tracker) Flush(ctx context.Context) error {
+	client := tracing.Redis(ctx, t.redisClient)
+	flushing := pendingKey + ":" + xid.New().String()
+	if err := client.Rename(pendingKey, flushing).Err(); err != nil {
+		// nothing was viewed since the last flush
+		if err.Error() == "ERR no such key" {
+			return nil
+		}
+		return err
+	}
+	counts, err := client.HGetAll(flushing).Result()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	rows := make([]models.RecipeViews, 0, len(counts))
+	for id, count := range counts {
+		views, _ := strconv.ParseInt(count, 10, 64)
+		rows = append(rows, models.RecipeViews{RecipeID: id, Views: views, UpdatedAt: now})
+	}
+	err = t.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "recipe_id"}},
+		DoUpdates: clause.Assignments(map[string]any{"views": gorm.Expr("recipe_views.views + excluded.views"), "updated_at": now}),
+	}).CreateInBatches(&rows, 500).Error
+	if err != nil {
+		pipe := client.Pipeline()
+		defer pipe.Close()
+		for _, row := range rows {
+			pipe.HIncrBy(pendingKey, row.RecipeID, row.Views)
+		}
+		pipe.Del(flushing)
+		pipe.Exec()
+		return err
+	}
+	return client.Del(flushing).Err()
+}
+
+// Trending returns the limit recipes read the most in the window, most
+// read first. The hour in progress counts as a whole one.
+func (t *Tracker) Trending(ctx context.Context, window time.Duration, limit int) ([]Entry, error) {
+	client := tracing.Redis(ctx, t.redisClient)
+	dest := trendingKeyPrefix + window.String()
+
+	exists, err := client.Exists(dest).Result()
+	if err != nil {
+		return nil, err
+	}
+	if exists == 0 {
+		pipe := client.TxPipeline()
+		pipe.ZUnionStore(dest, redis.ZStore{Aggregate: "SUM"}, hourKeys(window)...)
+		pipe.Expire(dest, trendingTTL)
+		_, err := pipe.Exec()
+		pipe.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	scores, err := client.ZRevRangeWithScores(dest, 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, 0, len(scores))
+	for _, z := range scores {
+		id, _ := z.Member.(string)
+		entries = append(entries, Entry{RecipeID: id, Views: int(z.Score)})
+	}
+	return entries, nil
+}
+
+// hourKeys returns the keys of the hours in the window up to now.
+func hourKeys(window time.Duration) []string {
+	now := time.Now().UTC()
+	keys := make([]string, 0, int(window/time.Hour))
+	for at := now; at.After(now.Add(-window)); at = at.Add(-time.Hour) {
+		keys = append(keys, hourKey(at))
+	}
+	return keys
+}
+
+func hourKey(at time.Time) string {
+	return hourKeyPrefix + strconv.FormatInt(at.Unix()/3600, 10)
+}
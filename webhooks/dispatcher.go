@@ -0,0 +1,233 @@
+// Package webhooks delivers recipe events to registered webhook targets.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"recipes-api/events"
+	"recipes-api/models"
+	"recipes-api/outbound"
+	"recipes-api/sandbox"
+
+	"github.com/rs/xid"
+	"gorm.io/gorm"
+)
+
+const (
+	// MaxAttempts is how often a delivery is tried before giving up.
+	MaxAttempts = 5
+	baseBackoff = 2 * time.Second
+
+	SignatureHeader = "X-Webhook-Signature"
+	TimestampHeader = "X-Webhook-Timestamp"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+)
+
+type job struct {
+	webhook models.Webhook
+	event   events.Event
+	attempt int
+}
+
+// Dispatcher delivers events to webhooks from a pool of workers, retrying
+// failures with exponential backoff. Targets are only reached when the
+// outbound policy allows them. With a non-nil recorder deliveries are
+// captured instead of sent.
+type Dispatcher struct {
+	db       *gorm.DB
+	policy   outbound.Policy
+	client   *outbound.Client
+	recorder *sandbox.Recorder
+	queue    chan job
+}
+
+func NewDispatcher(db *gorm.DB, recorder *sandbox.Recorder, policy outbound.Policy, workers int) *Dispatcher {
+	d := &Dispatcher{
+		db:       db,
+		policy:   policy,
+		client:   outbound.NewGuarded(policy, 10*time.Second, 0), // retried by the dispatcher with a longer backoff
+		recorder: recorder,
+		queue:    make(chan job, 1000),
+	}
+
+	for i := 0; i < workers; i++ {
+		go d.work()
+	}
+
+	return d
+}
+
+// Handle enqueues the event for every active webhook subscribed to it. It
+// is meant to be subscribed to the bus.
+func (d *Dispatcher) Handle(e events.Event) {
+	var hooks []models.Webhook
+	if err := d.db.WithContext(e.Context()).Where("active = ?", true).Find(&hooks).Error; err != nil {
+		slog.ErrorContext(e.Context(), "Error loading webhooks", "event_id", e.ID, "error", err)
+		return
+	}
+
+	for _, hook := range hooks {
+		if hook.Wants(e.Type) {
+			d.enqueue(job{webhook: hook, event: e, attempt: 1})
+		}
+	}
+}
+
+// CheckURL reports whether target may be registered as a webhook URL.
+func (d *Dispatcher) CheckURL(target string) error {
+	return d.policy.CheckURL(target)
+}
+
+// Replay enqueues the given events for a single webhook, skipping types it
+// doesn't subscribe to. It stops at the first event the full queue has no
+// room for and returns how many were queued and how many of list were
+// dealt with, queued or skipped, so the rest can be replayed later.
+func (d *Dispatcher) Replay(hook models.Webhook, list []events.Event) (queued, done int) {
+	for _, e := range list {
+		if hook.Wants(e.Type) {
+			if !d.enqueue(job{webhook: hook, event: e, attempt: 1}) {
+				break
+			}
+			queued++
+		}
+		done++
+	}
+	return queued, done
+}
+
+// enqueue queues the job unless the queue is full, and reports whether it
+// did.
+func (d *Dispatcher) enqueue(j job) bool {
+	select {
+	case d.queue <- j:
+		return true
+	default:
+		slog.WarnContext(j.event.Context(), "Webhook queue full, dropping event", "event_id", j.event.ID, "webhook_id", j.webhook.ID)
+		return false
+	}
+}
+
+// Backlog returns how many deliveries are queued and how many fit.
+func (d *Dispatcher) Backlog() (queued, capacity int) {
+	return len(d.queue), cap(d.queue)
+}
+
+func (d *Dispatcher) work() {
+	for j := range d.queue {
+		delivery := d.deliver(j)
+		if err := d.db.WithContext(j.event.Context()).Create(&delivery).Error; err != nil {
+			slog.ErrorContext(j.event.Context(), "Error recording webhook delivery", "webhook_id", j.webhook.ID, "error", err)
+		}
+
+		if !delivery.Success && j.attempt < MaxAttempts {
+			next := j
+			next.attempt++
+			time.AfterFunc(backoff(j.attempt), func() { d.enqueue(next) })
+		}
+	}
+}
+
+// backoff doubles the wait after each failed attempt, with up to 20% jitter.
+func backoff(attempt int) time.Duration {
+	wait := baseBackoff << (attempt - 1)
+	return wait + time.Duration(rand.Int64N(int64(wait)/5+1))
+}
+
+func (d *Dispatcher) deliver(j job) models.WebhookDelivery {
+	delivery := models.WebhookDelivery{
+		ID:        xid.New().String(),
+		WebhookID: j.webhook.ID,
+		EventID:   j.event.ID,
+		EventType: j.event.Type,
+		Attempt:   j.attempt,
+	}
+
+	version := j.webhook.SchemaVersion
+	if version == "" {
+		version = events.CurrentSchemaVersion
+	}
+	payload, err := j.event.Versioned(version)
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	headers := map[string]string{
+		"Content-Type":  "application/json",
+		EventHeader:     j.event.Type,
+		DeliveryHeader:  j.event.ID,
+		TimestampHeader: timestamp,
+		SignatureHeader: Sign(j.webhook.Secret, timestamp, body),
+	}
+
+	if d.recorder != nil {
+		d.recorder.Capture(sandbox.Message{
+			Kind:    sandbox.KindWebhook,
+			Target:  j.webhook.URL,
+			Headers: headers,
+			Body:    string(body),
+		})
+		delivery.Success = true
+		delivery.Captured = true
+		return delivery
+	}
+
+	ctx, cancel := context.WithTimeout(j.event.Context(), 15*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, j.webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	start := time.Now()
+	resp, err := d.client.Do(req)
+	delivery.DurationMS = time.Since(start).Milliseconds()
+	if err != nil {
+		delivery.Error = err.Error()
+		return delivery
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	delivery.StatusCode = resp.StatusCode
+	delivery.Success = resp.StatusCode >= 200 && resp.StatusCode < 300
+	if !delivery.Success {
+		delivery.Error = fmt.Sprintf("target returned %s", resp.Status)
+	}
+	return delivery
+}
+
+// Sign returns the signature header value for a payload: the hex HMAC-SHA256
+// of "<timestamp>.<body>" keyed with the webhook secret, prefixed "sha256=".
+// Receivers should recompute it and reject stale timestamps.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}